@@ -0,0 +1,172 @@
+package mq
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// runAdaptiveWriteLoop runs writeLoop with a 400ms keepalive for d,
+// publishing (send) and signaling received packets (receive) every 50ms, and
+// returns the number of PINGREQs sent.
+func runAdaptiveWriteLoop(t *testing.T, adaptive, send, receive bool, d time.Duration) uint64 {
+	t.Helper()
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	client := &Client{
+		opts: &clientOptions{
+			KeepAlive:         400 * time.Millisecond,
+			AdaptiveKeepAlive: adaptive,
+			Server:            "tcp://test:1883",
+			Logger:            testLogger(),
+			ProtocolVersion:   ProtocolV311,
+		},
+		conn:           clientConn,
+		outgoing:       make(chan packets.Packet, 10),
+		packetReceived: make(chan struct{}, 1),
+		pingPendingCh:  make(chan struct{}, 1),
+		stop:           make(chan struct{}),
+		disconnected:   make(chan struct{}, 1),
+	}
+	client.connected.Store(true)
+
+	// Answer every PINGREQ
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, err := serverConn.Read(buf)
+			if err != nil {
+				return
+			}
+			for i := 0; i < n-1; i++ {
+				if buf[i] == 0xc0 && buf[i+1] == 0x00 {
+					select {
+					case client.packetReceived <- struct{}{}:
+					default:
+					}
+					select {
+					case client.pingPendingCh <- struct{}{}:
+					default:
+					}
+				}
+			}
+		}
+	}()
+
+	done := make(chan struct{})
+	client.wg.Add(1)
+	go func() {
+		client.writeLoop()
+		close(done)
+	}()
+
+	deadline := time.After(d)
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+loop:
+	for {
+		select {
+		case <-ticker.C:
+			if send {
+				client.outgoing <- &packets.PublishPacket{Topic: "test", Payload: []byte("data")}
+			}
+			if receive {
+				select {
+				case client.packetReceived <- struct{}{}:
+				default:
+				}
+			}
+		case <-done:
+			t.Fatal("writeLoop exited unexpectedly")
+		case <-deadline:
+			break loop
+		}
+	}
+	close(client.stop)
+	<-done
+	return client.pingsSent.Load()
+}
+
+// TestAdaptiveKeepAliveBusy verifies that no PINGREQ is sent while packets
+// flow in both directions.
+func TestAdaptiveKeepAliveBusy(t *testing.T) {
+	if got := runAdaptiveWriteLoop(t, true, true, true, time.Second); got != 0 {
+		t.Errorf("pingsSent = %d, want 0 while traffic flows", got)
+	}
+}
+
+// TestAdaptiveKeepAlivePublishOnly verifies that no PINGREQ is sent while
+// the client publishes, even if nothing is received. It stops before the
+// 600ms receive timeout.
+func TestAdaptiveKeepAlivePublishOnly(t *testing.T) {
+	if got := runAdaptiveWriteLoop(t, true, true, false, 500*time.Millisecond); got != 0 {
+		t.Errorf("pingsSent = %d, want 0 while publishing", got)
+	}
+}
+
+// TestAdaptiveKeepAliveReceiveOnly verifies that a client that receives
+// but sends nothing still pings within the keepalive interval.
+func TestAdaptiveKeepAliveReceiveOnly(t *testing.T) {
+	if got := runAdaptiveWriteLoop(t, true, false, true, time.Second); got == 0 {
+		t.Error("no PINGREQ sent although nothing was sent")
+	}
+}
+
+// TestAdaptiveKeepAliveIdle verifies that an idle connection is still
+// probed.
+func TestAdaptiveKeepAliveIdle(t *testing.T) {
+	if got := runAdaptiveWriteLoop(t, true, false, false, time.Second); got == 0 {
+		t.Error("no PINGREQ sent on an idle connection")
+	}
+}
+
+// TestKeepAliveNotAdaptive verifies that by default a PINGREQ is sent when
+// nothing is sent, even while packets are received.
+func TestKeepAliveNotAdaptive(t *testing.T) {
+	if got := runAdaptiveWriteLoop(t, false, false, true, time.Second); got == 0 {
+		t.Error("no PINGREQ sent although nothing was sent")
+	}
+}
+
+func TestPingReason(t *testing.T) {
+	const keepAlive = 400 * time.Millisecond
+	tests := []struct {
+		name                     string
+		adaptive                 bool
+		sinceSent, sinceReceived time.Duration
+		want                     string
+	}{
+		{"busy", false, 100 * time.Millisecond, 100 * time.Millisecond, ""},
+		{"idle", false, 300 * time.Millisecond, 300 * time.Millisecond, "no activity"},
+		{"no send", false, 300 * time.Millisecond, 100 * time.Millisecond, "no send"},
+		{"no receive", false, 100 * time.Millisecond, 300 * time.Millisecond, "no receive"},
+		{"adaptive busy", true, 100 * time.Millisecond, 100 * time.Millisecond, ""},
+		{"adaptive idle", true, 300 * time.Millisecond, 300 * time.Millisecond, "no activity"},
+		{"adaptive no send", true, 300 * time.Millisecond, 100 * time.Millisecond, "no send"},
+		{"adaptive no receive", true, 100 * time.Millisecond, 300 * time.Millisecond, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{opts: &clientOptions{KeepAlive: keepAlive, AdaptiveKeepAlive: tt.adaptive}}
+			if got := c.pingReason(tt.sinceSent, tt.sinceReceived); got != tt.want {
+				t.Errorf("pingReason(%v, %v) = %q, want %q", tt.sinceSent, tt.sinceReceived, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithAdaptiveKeepAlive(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	if opts.AdaptiveKeepAlive {
+		t.Error("AdaptiveKeepAlive enabled by default")
+	}
+	WithAdaptiveKeepAlive()(opts)
+	if !opts.AdaptiveKeepAlive {
+		t.Error("WithAdaptiveKeepAlive did not enable AdaptiveKeepAlive")
+	}
+}
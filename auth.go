@@ -11,6 +11,12 @@ package mq
 //  2. HandleChallenge() is called for each AUTH packet from server
 //  3. Complete() is called when CONNACK is received (authentication succeeded)
 //
+// The same Authenticator is reused for every connection, including automatic
+// reconnects and re-authentication, so implementations must be able to start
+// a new exchange at any time. Authenticators that keep per-exchange state
+// (e.g. a SCRAM nonce) should also implement AuthenticatorResetter, whose
+// Reset method is called before each new exchange.
+//
 // Example implementation (simple token):
 //
 //	type TokenAuth struct {
@@ -84,3 +90,30 @@ type Authenticator interface {
 	// but won't affect the connection (CONNACK was already successful).
 	Complete() error
 }
+
+// AuthenticatorResetter is an optional interface for stateful authenticators.
+//
+// If the configured Authenticator implements it, Reset is called before every
+// authentication exchange: before each CONNECT (including automatic
+// reconnects) and before each re-authentication. This gives challenge/response
+// mechanisms such as SCRAM a fresh start instead of reusing state left over
+// from a previous, possibly interrupted, exchange.
+//
+// Example:
+//
+//	func (s *ScramAuth) Reset() {
+//	    s.clientNonce = ""
+//	    s.serverNonce = ""
+//	    s.authMsg = ""
+//	}
+type AuthenticatorResetter interface {
+	// Reset discards any state from a previous authentication exchange.
+	Reset()
+}
+
+// resetAuthenticator gives a stateful authenticator a fresh start.
+func (c *Client) resetAuthenticator() {
+	if r, ok := c.opts.Authenticator.(AuthenticatorResetter); ok {
+		r.Reset()
+	}
+}
@@ -2,6 +2,7 @@ package mq
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/gonzalop/mq/internal/packets"
 )
@@ -16,15 +17,19 @@ func (c *Client) handleAuth(p *packets.AuthPacket) {
 	// Reset counter on success
 	if p.ReasonCode == uint8(ReasonCodeSuccess) {
 		c.authExchangeCount.Store(0)
-		if err := c.opts.Authenticator.Complete(); err != nil {
+		err := c.opts.Authenticator.Complete()
+		if err != nil {
 			c.opts.Logger.Warn("authenticator completion failed", "error", err)
+			err = fmt.Errorf("authenticator completion failed: %w", err)
 		}
+		c.finishReauth(err)
 		return
 	}
 
 	count := c.authExchangeCount.Add(1)
 	if c.opts.MaxAuthExchanges > 0 && count > uint32(c.opts.MaxAuthExchanges) {
 		c.opts.Logger.Error("maximum authentication exchanges exceeded", "limit", c.opts.MaxAuthExchanges)
+		c.finishReauth(fmt.Errorf("maximum authentication exchanges (%d) exceeded", c.opts.MaxAuthExchanges))
 		_ = c.disconnectWithReason(context.Background(), uint8(ReasonCodeBadAuthenticationMethod), nil)
 		return
 	}
@@ -40,6 +45,7 @@ func (c *Client) handleAuth(p *packets.AuthPacket) {
 			c.opts.Logger.Error("authentication method mismatch",
 				"expected", c.opts.Authenticator.Method(),
 				"received", p.Properties.AuthenticationMethod)
+			c.finishReauth(fmt.Errorf("authentication method mismatch: server sent %q", p.Properties.AuthenticationMethod))
 			return
 		}
 	}
@@ -47,6 +53,7 @@ func (c *Client) handleAuth(p *packets.AuthPacket) {
 	responseData, err := c.opts.Authenticator.HandleChallenge(challengeData, p.ReasonCode)
 	if err != nil {
 		c.opts.Logger.Error("authentication challenge failed", "error", err)
+		c.finishReauth(fmt.Errorf("authentication challenge failed: %w", err))
 		// Note: We can't use disconnectWithReason here because we're in logicLoop
 		return
 	}
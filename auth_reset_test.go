@@ -0,0 +1,177 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// nonceAuthenticator is a stateful challenge/response authenticator: the
+// response must echo the nonce generated for the current exchange.
+type nonceAuthenticator struct {
+	mu       sync.Mutex
+	counter  int
+	nonce    string
+	finished bool
+	resets   int
+}
+
+func (a *nonceAuthenticator) Method() string { return "NONCE" }
+
+func (a *nonceAuthenticator) InitialData() ([]byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.nonce != "" {
+		// A real mechanism would leak the old nonce into the new exchange
+		return []byte(a.nonce), nil
+	}
+	a.counter++
+	a.nonce = fmt.Sprintf("nonce-%d", a.counter)
+	return []byte(a.nonce), nil
+}
+
+func (a *nonceAuthenticator) HandleChallenge(data []byte, _ uint8) ([]byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.finished {
+		return nil, fmt.Errorf("stale exchange state")
+	}
+	return append([]byte(a.nonce+":"), data...), nil
+}
+
+func (a *nonceAuthenticator) Complete() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.finished = true
+	return nil
+}
+
+func (a *nonceAuthenticator) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.nonce = ""
+	a.finished = false
+	a.resets++
+}
+
+func TestAuthenticatorResetOnReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	nonces := make(chan string, 2)
+	serverErr := make(chan error, 2)
+	secondDone := make(chan struct{})
+
+	handshake := func(conn net.Conn) error {
+		pkt, err := packets.ReadPacket(conn, ProtocolV50, 0)
+		if err != nil {
+			return err
+		}
+		connect, ok := pkt.(*packets.ConnectPacket)
+		if !ok || connect.Properties == nil {
+			return fmt.Errorf("expected CONNECT with properties, got %T", pkt)
+		}
+		nonce := string(connect.Properties.AuthenticationData)
+		nonces <- nonce
+
+		challenge := &packets.AuthPacket{
+			Version:    ProtocolV50,
+			ReasonCode: packets.AuthReasonContinue,
+			Properties: &packets.Properties{
+				AuthenticationMethod: "NONCE",
+				AuthenticationData:   []byte("challenge"),
+				Presence:             packets.PresAuthenticationMethod,
+			},
+		}
+		if _, err := challenge.WriteTo(conn); err != nil {
+			return err
+		}
+
+		pkt, err = packets.ReadPacket(conn, ProtocolV50, 0)
+		if err != nil {
+			return err
+		}
+		resp, ok := pkt.(*packets.AuthPacket)
+		if !ok {
+			return fmt.Errorf("expected AUTH, got %T", pkt)
+		}
+		if want := nonce + ":challenge"; string(resp.Properties.AuthenticationData) != want {
+			return fmt.Errorf("expected response %q, got %q", want, resp.Properties.AuthenticationData)
+		}
+
+		connack := &packets.ConnackPacket{ReturnCode: packets.ConnAccepted}
+		_, err = connack.WriteTo(conn)
+		return err
+	}
+
+	go func() {
+		conn1, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		serverErr <- handshake(conn1)
+		// Drop the connection to force a reconnect
+		conn1.Close()
+
+		conn2, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn2.Close()
+		serverErr <- handshake(conn2)
+		<-secondDone
+	}()
+	defer close(secondDone)
+
+	auth := &nonceAuthenticator{}
+	reconnected := make(chan struct{}, 2)
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("auth-reset"),
+		WithAuthenticator(auth),
+		WithConnectTimeout(2*time.Second),
+		WithOnConnect(func(_ *Client) { reconnected <- struct{}{} }),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	for i := range 2 {
+		select {
+		case err := <-serverErr:
+			if err != nil {
+				t.Fatalf("handshake %d failed: %v", i+1, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for handshake %d", i+1)
+		}
+	}
+
+	first, second := <-nonces, <-nonces
+	if first == second {
+		t.Errorf("expected a fresh nonce after reconnect, got %q twice", first)
+	}
+
+	// Wait for both OnConnect callbacks so the second handshake has fully completed
+	for range 2 {
+		select {
+		case <-reconnected:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for OnConnect")
+		}
+	}
+
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+	if auth.resets != 2 {
+		t.Errorf("expected Reset before each of 2 handshakes, got %d", auth.resets)
+	}
+}
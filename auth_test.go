@@ -2,6 +2,7 @@ package mq
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"strings"
@@ -126,10 +127,8 @@ func TestReauthenticate(t *testing.T) {
 	}
 	client.connected.Store(true)
 
-	err := client.Reauthenticate(context.TODO())
-	if err != nil {
-		t.Fatalf("Reauthenticate failed: %v", err)
-	}
+	result := make(chan error, 1)
+	go func() { result <- client.Reauthenticate(context.TODO()) }()
 
 	// Verify AUTH packet was sent
 	select {
@@ -147,8 +146,94 @@ func TestReauthenticate(t *testing.T) {
 			t.Errorf("expected method TOKEN, got %s", authPkt.Properties.AuthenticationMethod)
 		}
 
-	default:
-		t.Error("expected AUTH packet to be sent")
+	case <-time.After(time.Second):
+		t.Fatal("expected AUTH packet to be sent")
+	}
+
+	// The server challenges: still in progress
+	client.handleAuth(&packets.AuthPacket{ReasonCode: packets.AuthReasonContinue})
+	if pkt, ok := (<-client.outgoing).(*packets.AuthPacket); !ok || pkt.ReasonCode != packets.AuthReasonContinue {
+		t.Fatalf("expected AUTH response to the challenge, got %#v", pkt)
+	}
+	select {
+	case err := <-result:
+		t.Fatalf("Reauthenticate returned before success: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := client.Reauthenticate(context.TODO()); err == nil || !strings.Contains(err.Error(), "in progress") {
+		t.Errorf("expected an in-progress error, got %v", err)
+	}
+
+	client.handleAuth(&packets.AuthPacket{ReasonCode: uint8(ReasonCodeSuccess)})
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("Reauthenticate failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Reauthenticate did not return after AUTH success")
+	}
+	if auth.challengeCount != 1 {
+		t.Errorf("expected 1 challenge, got %d", auth.challengeCount)
+	}
+}
+
+func TestReauthenticateRejected(t *testing.T) {
+	client := &Client{
+		opts: &clientOptions{
+			ProtocolVersion: ProtocolV50,
+			Authenticator:   &tokenAuthenticator{token: "expired"},
+			Logger:          testLogger(),
+		},
+		outgoing: make(chan packets.Packet, 1),
+	}
+	client.connected.Store(true)
+
+	result := make(chan error, 1)
+	go func() { result <- client.Reauthenticate(context.TODO()) }()
+	<-client.outgoing
+
+	client.handleDisconnectPacket(&packets.DisconnectPacket{ReasonCode: uint8(ReasonCodeNotAuthorized)})
+
+	select {
+	case err := <-result:
+		var de *DisconnectError
+		if !errors.As(err, &de) || de.ReasonCode != ReasonCodeNotAuthorized {
+			t.Errorf("expected a DisconnectError with Not authorized, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Reauthenticate did not return after DISCONNECT")
+	}
+}
+
+func TestReauthenticateContext(t *testing.T) {
+	client := &Client{
+		opts: &clientOptions{
+			ProtocolVersion: ProtocolV50,
+			Authenticator:   &tokenAuthenticator{},
+			Logger:          testLogger(),
+		},
+		outgoing: make(chan packets.Packet, 2),
+	}
+	client.connected.Store(true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := client.Reauthenticate(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	// A late answer is ignored, and a new exchange can be started
+	client.handleAuth(&packets.AuthPacket{ReasonCode: uint8(ReasonCodeSuccess)})
+
+	result := make(chan error, 1)
+	go func() { result <- client.Reauthenticate(context.TODO()) }()
+	<-client.outgoing
+	<-client.outgoing
+	client.handleAuth(&packets.AuthPacket{ReasonCode: uint8(ReasonCodeSuccess)})
+	if err := <-result; err != nil {
+		t.Errorf("second Reauthenticate failed: %v", err)
 	}
 }
 
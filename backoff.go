@@ -0,0 +1,103 @@
+package mq
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// ReconnectNow makes a disconnected client attempt to reconnect right away,
+// instead of waiting for the current reconnect backoff to expire. Use it when
+// the application learns that the network is back (e.g. from an OS
+// connectivity event) before the backoff timer would fire.
+//
+// It does not wait for the attempt: use WithOnConnect or IsConnected to learn
+// the outcome. If the attempt succeeds the backoff is reset to its initial
+// value as usual; if it fails, the backoff keeps growing from where it was.
+//
+// If the client is connected, ReconnectNow does nothing and returns nil. It
+// returns an error if automatic reconnection is disabled or the client has
+// been stopped with Disconnect.
+//
+// Example:
+//
+//	netmon.OnOnline(func() {
+//	    if err := client.ReconnectNow(); err != nil {
+//	        log.Printf("reconnect: %v", err)
+//	    }
+//	})
+func (c *Client) ReconnectNow() error {
+	if c.IsConnected() {
+		return nil
+	}
+	if !c.opts.AutoReconnect {
+		return fmt.Errorf("ReconnectNow requires automatic reconnection")
+	}
+	select {
+	case <-c.stop:
+		return ErrClientDisconnected
+	default:
+	}
+
+	select {
+	case c.reconnectNow <- struct{}{}:
+	default: // Already requested
+	}
+	return nil
+}
+
+// ReconnectBackoff returns the delay the client will wait before its next
+// automatic reconnection attempt, as configured with WithReconnectBackoff.
+//
+// While connected this is the initial delay; after failed attempts it
+// reflects the grown delay. It is meant for debugging and status reporting.
+func (c *Client) ReconnectBackoff() time.Duration {
+	if d := time.Duration(c.reconnectBackoff.Load()); d > 0 {
+		return d
+	}
+	return c.initialBackoff()
+}
+
+// initialBackoff returns the first reconnect delay.
+func (c *Client) initialBackoff() time.Duration {
+	if c.opts.ReconnectBackoffInitial <= 0 {
+		return time.Second
+	}
+	return c.opts.ReconnectBackoffInitial
+}
+
+// nextBackoff returns the delay that follows d after a failed attempt.
+func (c *Client) nextBackoff(d time.Duration) time.Duration {
+	maxBackoff := c.opts.ReconnectBackoffMax
+	if maxBackoff <= 0 {
+		maxBackoff = 2 * time.Minute
+	}
+	multiplier := c.opts.ReconnectBackoffMultiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	// Compare as floats: the product may not fit in a Duration
+	next := float64(d) * multiplier
+	if next >= float64(maxBackoff) {
+		return maxBackoff
+	}
+	return time.Duration(next)
+}
+
+// jittered returns d randomized by up to ±ReconnectJitter of its value.
+// It is only called from reconnectLoop.
+func (c *Client) jittered(d time.Duration) time.Duration {
+	fraction := c.opts.ReconnectJitter
+	if fraction <= 0 || d <= 0 {
+		return d
+	}
+	if c.jitterRand == nil {
+		// Seeded from the global source, so each client gets its own sequence
+		c.jitterRand = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+
+	// Uniform in [-fraction, +fraction)
+	offset := (2*c.jitterRand.Float64() - 1) * fraction
+	return max(time.Duration(float64(d)*(1+offset)), 0)
+}
@@ -0,0 +1,246 @@
+package mq
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestWithReconnectBackoff_Validation(t *testing.T) {
+	tests := []struct {
+		name       string
+		initial    time.Duration
+		max        time.Duration
+		multiplier float64
+		applied    bool
+	}{
+		{"valid", 500 * time.Millisecond, 30 * time.Second, 1.5, true},
+		{"constant interval", time.Second, time.Second, 1, true},
+		{"multiplier below 1", time.Second, time.Minute, 0.5, false},
+		{"zero multiplier", time.Second, time.Minute, 0, false},
+		{"zero initial", 0, time.Minute, 2, false},
+		{"max below initial", time.Minute, time.Second, 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultOptions("tcp://localhost:1883")
+			WithReconnectBackoff(tt.initial, tt.max, tt.multiplier)(opts)
+
+			want := [3]any{time.Second, 2 * time.Minute, 2.0}
+			if tt.applied {
+				want = [3]any{tt.initial, tt.max, tt.multiplier}
+			}
+			got := [3]any{opts.ReconnectBackoffInitial, opts.ReconnectBackoffMax, opts.ReconnectBackoffMultiplier}
+			if got != want {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestNextBackoff(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	WithReconnectBackoff(100*time.Millisecond, time.Second, 1.5)(opts)
+	c := newTestClient(opts)
+
+	want := []time.Duration{
+		100 * time.Millisecond,
+		150 * time.Millisecond,
+		225 * time.Millisecond,
+		337500 * time.Microsecond,
+		506250 * time.Microsecond,
+		759375 * time.Microsecond,
+		time.Second,
+		time.Second,
+	}
+	d := c.initialBackoff()
+	for i, w := range want {
+		if d != w {
+			t.Errorf("step %d: backoff = %v, want %v", i, d, w)
+		}
+		d = c.nextBackoff(d)
+	}
+
+	// Huge values saturate at the maximum instead of overflowing
+	WithReconnectBackoff(time.Second, time.Duration(1<<62), 1000)(opts)
+	d = time.Duration(1 << 61)
+	if got := c.nextBackoff(d); got != time.Duration(1<<62) {
+		t.Errorf("expected saturation at max, got %v", got)
+	}
+
+	// Defaults
+	c = newTestClient(defaultOptions("tcp://localhost:1883"))
+	if got := c.ReconnectBackoff(); got != time.Second {
+		t.Errorf("default ReconnectBackoff() = %v, want 1s", got)
+	}
+	if got := c.nextBackoff(time.Minute + time.Second); got != 2*time.Minute {
+		t.Errorf("expected default max of 2m, got %v", got)
+	}
+}
+
+func TestReconnectBackoff_Cycle(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// Attempt 1 connects and drops, 2 and 3 are refused (closed before
+	// CONNACK), 4 connects and stays up.
+	attempts := make(chan time.Time, 8)
+	stopServer := make(chan struct{})
+	defer close(stopServer)
+	go func() {
+		for n := 1; ; n++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			attempts <- time.Now()
+			go func(conn net.Conn, n int) {
+				defer conn.Close()
+				if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+					return
+				}
+				if n == 2 || n == 3 {
+					return
+				}
+				if _, err := (&packets.ConnackPacket{ReturnCode: 0}).WriteTo(conn); err != nil {
+					return
+				}
+				if n == 1 {
+					time.Sleep(20 * time.Millisecond)
+					return
+				}
+				<-stopServer
+			}(conn, n)
+		}
+	}()
+
+	initial := 20 * time.Millisecond
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("backoff"),
+		WithReconnectBackoff(initial, time.Second, 4),
+		WithLogger(testLogger()),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	var times []time.Time
+	for len(times) < 4 {
+		select {
+		case at := <-attempts:
+			times = append(times, at)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for attempt %d", len(times)+1)
+		}
+	}
+
+	// Delays grow by the multiplier: 20ms before attempt 2, 80ms before 3,
+	// 320ms before 4
+	if gap := times[2].Sub(times[1]); gap < 4*initial {
+		t.Errorf("attempt 3 came %v after attempt 2, expected at least %v", gap, 4*initial)
+	}
+	if gap := times[3].Sub(times[2]); gap < 16*initial {
+		t.Errorf("attempt 4 came %v after attempt 3, expected at least %v", gap, 16*initial)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !client.IsConnected() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !client.IsConnected() {
+		t.Fatal("client did not reconnect")
+	}
+
+	// A successful connection resets the delay for the next cycle
+	deadline = time.Now().Add(time.Second)
+	for client.ReconnectBackoff() != initial && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := client.ReconnectBackoff(); got != initial {
+		t.Errorf("ReconnectBackoff() after reconnect = %v, want %v", got, initial)
+	}
+}
+
+func TestWithReconnectJitter(t *testing.T) {
+	for _, tt := range []struct {
+		fraction float64
+		want     float64
+	}{
+		{0.25, 0.25},
+		{1, 1},
+		{-0.1, 0},
+		{1.5, 0},
+	} {
+		opts := defaultOptions("tcp://localhost:1883")
+		WithReconnectJitter(tt.fraction)(opts)
+		if opts.ReconnectJitter != tt.want {
+			t.Errorf("WithReconnectJitter(%v): got %v, want %v", tt.fraction, opts.ReconnectJitter, tt.want)
+		}
+	}
+}
+
+func TestJittered(t *testing.T) {
+	const d = 10 * time.Second
+
+	t.Run("zero fraction is deterministic", func(t *testing.T) {
+		c := newTestClient(nil)
+		for range 100 {
+			if got := c.jittered(d); got != d {
+				t.Fatalf("jittered(%v) = %v, want unchanged", d, got)
+			}
+		}
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+		for _, fraction := range []float64{0.3, 1} {
+			opts := defaultOptions("tcp://localhost:1883")
+			WithReconnectJitter(fraction)(opts)
+			c := newTestClient(opts)
+
+			lo := time.Duration(float64(d) * (1 - fraction))
+			hi := time.Duration(float64(d) * (1 + fraction))
+			minSeen, maxSeen := hi, lo
+			for range 1000 {
+				got := c.jittered(d)
+				if got < lo || got > hi || got < 0 {
+					t.Fatalf("fraction %v: jittered(%v) = %v, outside [%v, %v]", fraction, d, got, lo, hi)
+				}
+				minSeen = min(minSeen, got)
+				maxSeen = max(maxSeen, got)
+			}
+
+			// Both sides of the range are used
+			spread := time.Duration(float64(d) * fraction / 2)
+			if minSeen > d-spread || maxSeen < d+spread {
+				t.Errorf("fraction %v: samples span only [%v, %v]", fraction, minSeen, maxSeen)
+			}
+		}
+	})
+
+	t.Run("clients diverge", func(t *testing.T) {
+		newClient := func() *Client {
+			opts := defaultOptions("tcp://localhost:1883")
+			WithReconnectJitter(0.5)(opts)
+			return newTestClient(opts)
+		}
+		a, b := newClient(), newClient()
+
+		same := 0
+		for range 10 {
+			if a.jittered(d) == b.jittered(d) {
+				same++
+			}
+		}
+		if same == 10 {
+			t.Error("expected clients to produce different jitter sequences")
+		}
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"maps"
+	"math/rand/v2"
 	"net"
 	"net/url"
 	"sync"
@@ -48,12 +49,28 @@ type serverCapabilities struct {
 
 	// SharedSubscriptionAvailable indicates if the server supports shared subscriptions.
 	SharedSubscriptionAvailable bool
+
+	// fromConnack is set once the capabilities come from a CONNACK. Until
+	// then, the MQTT v5.0 defaults apply whatever the fields say.
+	fromConnack bool
 }
 
 type subscriptionEntry struct {
 	handler MessageHandler
+
+	// contextHandler replaces handler when set (WithSubscriptionContext).
+	contextHandler SubscriptionContextHandler
+
 	options SubscribeOptions
-	qos     uint8
+	qos     uint8 // Requested QoS (used when resubscribing)
+
+	// granted is the QoS from the most recent SUBACK, valid if hasGranted.
+	granted    uint8
+	hasGranted bool
+
+	// suppressRetained drops retained messages resent by the server after
+	// a reconnect (WithSuppressRetainedOnReconnect).
+	suppressRetained bool
 }
 
 // Client represents an MQTT client connection.
@@ -63,6 +80,7 @@ type Client struct {
 
 	// Connection
 	conn     net.Conn
+	connDone chan struct{} // Closed when the current connection is torn down
 	connLock sync.RWMutex
 
 	// Channels for goroutine communication
@@ -71,19 +89,21 @@ type Client struct {
 	packetReceived chan struct{}       // Signal when packet received (for keepalive)
 	pingPendingCh  chan struct{}       // Signal when PINGRESP received
 	stop           chan struct{}       // Shutdown signal
-	pingPending    bool                // True if PINGREQ sent but no PINGRESP received yet
 
 	// Session State Lock guards:
 	// - pending
 	// - subscriptions
 	// - receivedQoS2
 	// - inFlightCount
+	// - inFlightBytes
 	// - publishQueue
+	// - withheld
 	// - nextPacketID
 	sessionLock sync.Mutex
 
 	// Internal queues
 	publishQueue []*publishRequest
+	withheld     []*pendingOp // Pending PUBLISH not resent after connect, over the server's ReceiveMaximum
 
 	// State (managed by logicLoop to avoid races)
 	nextPacketID  uint16
@@ -91,6 +111,15 @@ type Client struct {
 	subscriptions map[string]subscriptionEntry
 	receivedQoS2  map[uint16]struct{} // Track received QoS 2 packet IDs to prevent duplicates
 	inFlightCount int                 // Number of QoS 1 special & QoS 2 packets currently in flight (outgoing)
+	inFlightBytes int                 // Payload bytes of outgoing QoS 1/2 publishes (pending + queued)
+
+	// inFlightBytesFreed is closed (and replaced) whenever in-flight bytes are
+	// released, waking publishers blocked by WithMaxInflightBytes.
+	inFlightBytesFreed chan struct{}
+
+	// pendingSettled is closed (and cleared) when no operation is pending or
+	// queued any more, waking WaitForPending.
+	pendingSettled chan struct{}
 
 	// Lifecycle
 	connected atomic.Bool
@@ -121,18 +150,38 @@ type Client struct {
 	// serverReference is a server URI that the client should use for reconnection.
 	// This is used for server redirects, load balancing, or maintenance scenarios.
 	// Only populated for MQTT v5.0 connections when the server provides this property.
-	// The library does not automatically redirect; users must handle this manually.
+	// The library only follows it automatically with WithAutoServerRedirect.
 	serverReference string
 
 	// Topic alias management (MQTT v5.0, client → server only)
-	topicAliases     map[string]uint16 // topic → alias ID
-	nextAliasID      uint16            // next ID to assign (1-based)
-	maxAliases       uint16            // server's limit from CONNACK
-	topicAliasesLock sync.Mutex        // protect concurrent access
+	topicAliases     map[string]uint16   // topic → alias ID
+	nextAliasID      uint16              // next ID to assign (1-based)
+	maxAliases       uint16              // server's limit from CONNACK
+	reservedAliases  map[string]uint16   // aliases carried over a reconnect, not yet announced
+	previousAliases  map[string]uint16   // aliases in use when the last connection was reset
+	aliasCandidates  map[string]struct{} // topics published once, not yet aliased (auto-tune)
+	manualAliases    map[uint16]struct{} // IDs assigned with WithAliasID
+	aliasLRU         aliasLRU            // recency of automatic aliases (TopicAliasLRU)
+	topicAliasesLock sync.Mutex          // protect concurrent access
+
+	// observedTopics holds the distinct incoming topics seen (auto-tune,
+	// guarded by sessionLock)
+	observedTopics map[string]struct{}
+
+	// tunedAliasMaximum is the receive alias maximum picked by auto-tune for
+	// the current connection, or 0 to use WithTopicAliasMaximum
+	tunedAliasMaximum atomic.Uint32
+
+	// topicStats maps subscription filters to *topicCounter (WithTopicStats)
+	topicStats sync.Map
 
 	// Flow control (MQTT v5.0, server → client)
 	inboundUnacked           map[uint16]struct{} // Packet IDs of received QoS 1/2 messages not yet acked
 	receiveMaxExceededLogged bool                // Warn once per connection
+	inboundProcessing        int                 // Messages whose ack waits on handlers (LimitPolicyBackpressure, ManualAck)
+	inboundAcked             chan struct{}       // Signal when a deferred ack has been sent
+	inboundGen               uint64              // Incremented per connection, tags deferred acks
+	withholdAcks             atomic.Bool         // Set by Disconnect: deferred acks are no longer sent
 
 	// Receive-side topic aliases (MQTT v5.0, server → client)
 	receivedAliases     map[uint16]string // alias ID → topic
@@ -141,49 +190,199 @@ type Client struct {
 	// Concurrency control for message handlers
 	handlerSem chan struct{}
 
+	// handlerPool runs non-ordered handlers when WithHandlerConcurrency is
+	// set; nil means one goroutine per handler invocation.
+	handlerPool *handlerPool
+
+	// overflow queues incoming packets ahead of the incoming channel with
+	// IncomingBackpressureDropOldest; nil otherwise.
+	overflow *overflowBuffer
+
 	// authExchangeCount tracks the number of AUTH packet exchanges
 	// to prevent infinite authentication loops.
 	authExchangeCount atomic.Uint32
 
+	// reauthDone receives the outcome of the re-authentication started by
+	// Reauthenticate, nil if none is in progress (guarded by reauthLock).
+	reauthDone chan error
+	reauthLock sync.Mutex
+
+	// Connection state reported by StateChanges (guarded by stateLock)
+	stateCh     chan ConnectionState
+	state       ConnectionState
+	stateClosed bool
+	stateLock   sync.Mutex
+
 	// Session expiry interval (MQTT v5.0)
 	requestedSessionExpiry uint32 // Original user request (preserved on reconnect)
 	sessionExpiryInterval  uint32 // Actual value from server (may override request)
 
+	// Pending OnConnectionLost notification (WithConnectionLostGracePeriod)
+	connLostLock  sync.Mutex
+	connLostTimer *time.Timer
+
+	// sessionPresent is the Session Present flag of the most recent CONNACK
+	sessionPresent atomic.Bool
+
 	// User Properties received in CONNACK (MQTT v5.0)
 	connackUserProperties map[string]string
 
+	// Reason String received in a successful CONNACK (MQTT v5.0)
+	connectReasonString string
+
+	// Properties of the most recent successful CONNACK (MQTT v5.0)
+	connackProperties atomic.Pointer[packets.Properties]
+
 	// Stats (atomic)
 	packetsSent     atomic.Uint64
 	packetsReceived atomic.Uint64
+	incomingDropped atomic.Uint64 // QoS 0 messages discarded by the IncomingBackpressureStrategy
+	retransmissions atomic.Uint64 // Packets sent again, see noteRetransmit
 	bytesSent       atomic.Uint64
 	bytesReceived   atomic.Uint64
 	reconnectCount  atomic.Uint64
 
+	// Copy of inFlightCount, readable without sessionLock (GetStats)
+	inFlightPublishes atomic.Int64
+
+	// Delay before the next reconnect attempt (nanoseconds)
+	reconnectBackoff atomic.Int64
+
+	// Random source for WithReconnectJitter (used by reconnectLoop only)
+	jitterRand *rand.Rand
+
+	// reconnectNow interrupts the reconnect backoff (ReconnectNow)
+	reconnectNow chan struct{}
+
+	// Round-trip latency averages
+	publishLatency   latencyEMA
+	subscribeLatency latencyEMA
+
+	// Connection quality inputs
+	pingsSent       atomic.Uint64
+	pingsFailed     atomic.Uint64
+	qualityLock     sync.Mutex
+	disconnectTimes []time.Time // Oldest first, guarded by qualityLock
+
 	// For reconnection
 	disconnected chan struct{}
 
 	// Last disconnect reason (if any) received from server via DISCONNECT packet
 	lastDisconnectReason error
 
+	// Server redirects (WithAutoServerRedirect). homeServer is the server
+	// given to Dial; server is the one connected to and redirect the one to
+	// follow next (both guarded by connLock). onMovedServer and redirects are
+	// only used by the goroutine connecting (DialContext, then reconnectLoop).
+	homeServer    string
+	server        string
+	redirect      *serverRedirect
+	onMovedServer bool
+	redirects     int // Consecutive redirects without a connection
+
+	// preDialed is the connection given to DialConn, used by the first
+	// connect instead of dialing.
+	preDialed net.Conn
+
+	// sessionTakenOver is set when the server disconnected us with 0x8E
+	sessionTakenOver atomic.Bool
+
+	// disconnectRequested is set by Disconnect so that operations cancelled
+	// by the shutdown complete with ErrGracefulDisconnect.
+	disconnectRequested atomic.Bool
+
 	// The wrapped publish function (including interceptors)
 	publish PublishFunc
 
 	// The wrapped default message handler (including interceptors)
 	defaultHandler MessageHandler
+	idHandlers     map[int]MessageHandler // WithSubscriptionIDHandler, wrapped
+
+	// Subscriptions requested while disconnected, waiting for their SUBACK
+	// after the reconnect (guarded by sessionLock)
+	deferredSubscribes []*deferredSubscribe
+
+	// Per-subscription handler queues for ordered delivery (guarded by
+	// sessionLock, see WithOrderedDelivery)
+	orderedQueues map[string]*orderedQueue
+
+	// Will sent in CONNECT, replaceable with SetWill (guards opts.will)
+	willLock sync.Mutex
+	// Set while RefreshWill reconnects
+	willRefresh atomic.Bool
+
+	// User-defined metadata (see SetMetadata)
+	metadata sync.Map
+
+	// Per-prefix payload limits (see SetTopicPayloadLimit)
+	topicLimitsLock sync.RWMutex
+	topicLimits     map[string]int
+
+	// Per-topic handler caches of dynamic subscriptions (see SubscribeDynamic)
+	dynamicLock sync.Mutex
+	dynamicSubs map[string]*dynamicSubscription
 }
 
 // publishRequest represents a request to publish a message.
 type publishRequest struct {
 	packet *packets.PublishPacket
 	token  *token
+
+	// ctx bounds the enqueue step (PublishContext); nil means no limit.
+	ctx context.Context
+	// stopCancel unregisters the cancellation of a queued request.
+	stopCancel func() bool
+}
+
+// done returns the channel closed when the request's context is done, or
+// nil (never ready) if it has none.
+func (r *publishRequest) done() <-chan struct{} {
+	if r.ctx == nil {
+		return nil
+	}
+	return r.ctx.Done()
+}
+
+// dequeued unregisters the cancellation set up by queuePublishLocked once
+// the request has left the publish queue.
+func (r *publishRequest) dequeued() {
+	if r.stopCancel != nil {
+		r.stopCancel()
+		r.stopCancel = nil
+	}
 }
 
 // subscribeRequest represents a request to subscribe to a topic.
 type subscribeRequest struct {
-	packet      *packets.SubscribePacket
-	handler     MessageHandler
-	token       *token
-	persistence bool
+	packet         *packets.SubscribePacket
+	handler        MessageHandler
+	contextHandler SubscriptionContextHandler
+	token          *token
+	persistence    bool
+	ordered        bool
+
+	// targets overrides handler, contextHandler, persistence and ordered
+	// per filter, indexed like packet.Topics (SubscribeMultiple).
+	targets []subscribeTarget
+
+	// ctx bounds the enqueue step (SubscribeContext); nil means no limit.
+	ctx context.Context
+}
+
+func (r *subscribeRequest) done() <-chan struct{} {
+	if r.ctx == nil {
+		return nil
+	}
+	return r.ctx.Done()
+}
+
+// subscribeTarget is where messages for one filter of a subscribeRequest
+// are delivered.
+type subscribeTarget struct {
+	handler        MessageHandler
+	contextHandler SubscriptionContextHandler
+	persistence    bool
+	ordered        bool
 }
 
 // unsubscribeRequest represents a request to unsubscribe from topics.
@@ -191,6 +390,16 @@ type unsubscribeRequest struct {
 	packet *packets.UnsubscribePacket
 	topics []string
 	token  *token
+
+	// ctx bounds the enqueue step (UnsubscribeContext); nil means no limit.
+	ctx context.Context
+}
+
+func (r *unsubscribeRequest) done() <-chan struct{} {
+	if r.ctx == nil {
+		return nil
+	}
+	return r.ctx.Done()
 }
 
 // pendingOp tracks an in-flight operation (publish, subscribe, etc.)
@@ -198,7 +407,11 @@ type pendingOp struct {
 	packet    packets.Packet
 	token     *token
 	qos       uint8
-	timestamp time.Time
+	timestamp time.Time // last (re)transmission
+	created   time.Time // first transmission, used for latency tracking
+	size      int       // payload bytes counted in inFlightBytes
+	withheld  bool      // in Client.withheld, not sent on this connection yet
+	resent    int       // retransmissions so far
 }
 
 // MessageHandler is called when a message is received on a subscribed topic.
@@ -225,6 +438,15 @@ type MessageHandler func(*Client, Message)
 //	client, err := mq.DialContext(ctx, "tcp://localhost:1883",
 //	    mq.WithClientID("my-client"))
 func DialContext(ctx context.Context, server string, opts ...Option) (*Client, error) {
+	c := newClient(server, opts)
+	if err := c.start(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// newClient returns a client configured with opts, not yet connected.
+func newClient(server string, opts []Option) *Client {
 	options := defaultOptions(server)
 	for _, opt := range opts {
 		opt(options)
@@ -235,9 +457,11 @@ func DialContext(ctx context.Context, server string, opts ...Option) (*Client, e
 	}
 
 	c := &Client{
-		opts:     options,
-		outgoing: make(chan packets.Packet, options.OutgoingQueueSize),
-		incoming: make(chan packets.Packet, options.IncomingQueueSize),
+		opts:       options,
+		homeServer: options.Server,
+		server:     options.Server,
+		outgoing:   make(chan packets.Packet, options.OutgoingQueueSize),
+		incoming:   make(chan packets.Packet, options.IncomingQueueSize),
 
 		packetReceived:  make(chan struct{}, 1),
 		pingPendingCh:   make(chan struct{}, 1),
@@ -247,15 +471,31 @@ func DialContext(ctx context.Context, server string, opts ...Option) (*Client, e
 		receivedAliases: make(map[uint16]string),
 		receivedQoS2:    make(map[uint16]struct{}),
 		inboundUnacked:  make(map[uint16]struct{}),
+		inboundAcked:    make(chan struct{}, 1),
 		disconnected:    make(chan struct{}, 1),
+		reconnectNow:    make(chan struct{}, 1),
+		stateCh:         make(chan ConnectionState, stateChangesBuffer),
 	}
 
 	if options.MaxHandlerConcurrency > 0 {
 		c.handlerSem = make(chan struct{}, options.MaxHandlerConcurrency)
 	}
+	if options.IncomingStrategy == IncomingBackpressureDropOldest {
+		c.overflow = newOverflowBuffer(options.IncomingQueueSize)
+	}
+
+	for key, value := range options.Metadata {
+		c.SetMetadata(key, value)
+	}
 
 	c.publish = applyPublishInterceptors(c.basePublish, options.PublishInterceptors)
 	c.defaultHandler = c.wrapHandler(options.DefaultPublishHandler)
+	for id, handler := range options.SubscriptionIDHandlers {
+		if c.idHandlers == nil {
+			c.idHandlers = make(map[int]MessageHandler)
+		}
+		c.idHandlers[id] = c.wrapHandler(handler)
+	}
 
 	for topic, handler := range options.InitialSubscriptions {
 		c.subscriptions[topic] = subscriptionEntry{
@@ -270,34 +510,43 @@ func DialContext(ctx context.Context, server string, opts ...Option) (*Client, e
 		}
 	}
 
-	if err := c.connect(ctx); err != nil {
+	return c
+}
+
+// start makes the initial connection and starts the background goroutines.
+func (c *Client) start(ctx context.Context) error {
+	options := c.opts
+
+	c.setState(StateConnecting)
+	if err := c.connectFollowingRedirects(ctx); err != nil {
 		// Version negotiation: if v5.0 fails with "unacceptable protocol", try v3.1.1
 		if c.opts.AutoProtocolVersion && c.opts.ProtocolVersion == ProtocolV50 {
-			isProtoError := false
-			if errors.Is(err, ErrUnacceptableProtocolVersion) {
-				isProtoError = true
-			} else if mqErr, ok := err.(*MqttError); ok && mqErr.ReasonCode == 0x84 {
-				// 0x84 is MQTT v5.0 "Unsupported Protocol Version"
-				isProtoError = true
-			} else if mqErr, ok := err.(*MqttError); ok && mqErr.ReasonCode == ReasonCode(packets.ConnRefusedUnacceptableProtocol) {
-				// Some servers might return 0x01 even in v5.0-like responses
-				isProtoError = true
-			}
+			// Covers 0x84 "Unsupported Protocol Version" and servers that
+			// answer with the v3.1.1 return code 0x01 even to a v5.0 CONNECT.
+			isProtoError := errors.Is(err, ErrUnacceptableProtocolVersion)
 
 			if isProtoError {
 				c.opts.Logger.Debug("v5.0 connection refused with unacceptable protocol, falling back to v3.1.1")
 				c.opts.ProtocolVersion = ProtocolV311
 				if err := c.connect(ctx); err != nil {
-					return nil, err
+					return err
 				}
 			} else {
-				return nil, err
+				return err
 			}
 		} else {
-			return nil, err
+			return err
 		}
 	}
 
+	if options.HandlerConcurrency > 0 {
+		c.handlerPool = newHandlerPool(options.HandlerConcurrency, c.stop)
+	}
+	if c.overflow != nil {
+		c.wg.Add(1)
+		go c.forwardOverflow()
+	}
+
 	c.wg.Add(1)
 	go c.logicLoop()
 
@@ -306,7 +555,7 @@ func DialContext(ctx context.Context, server string, opts ...Option) (*Client, e
 		go c.reconnectLoop()
 	}
 
-	return c, nil
+	return nil
 }
 
 // wrapHandler applies handler interceptors to a MessageHandler.
@@ -326,6 +575,8 @@ func (c *Client) wrapHandler(handler MessageHandler) MessageHandler {
 // Supported schemes:
 //   - tcp://  or mqtt://  - Unencrypted connection (default port 1883)
 //   - tls://, ssl://, or mqtts:// - TLS encrypted connection (default port 8883)
+//   - unix:// - Unix domain socket, e.g. unix:///var/run/mqtt.sock (TLS only
+//     with WithTLS)
 //
 // Options can be provided to configure the client behavior. Common options include
 // WithClientID, WithCredentials, WithKeepAlive, WithTLS, and WithAutoReconnect.
@@ -382,7 +633,7 @@ func Dial(server string, opts ...Option) (*Client, error) {
 
 // connect establishes the TCP connection and performs MQTT handshake.
 func (c *Client) connect(ctx context.Context) error {
-	c.opts.Logger.Debug("connecting to MQTT server", "server", c.opts.Server)
+	c.opts.Logger.Debug("connecting to MQTT server", "server", c.currentServer())
 
 	// Validate configuration for MQTT compliance
 	// MQTT 3.1.1: Empty ClientID requires CleanSession=true
@@ -402,6 +653,7 @@ func (c *Client) connect(ctx context.Context) error {
 	}
 
 	c.resetAllTopicAliases()
+	c.resetInbound()
 
 	c.receivedAliasesLock.Lock()
 	c.receivedAliases = make(map[uint16]string)
@@ -414,12 +666,18 @@ func (c *Client) connect(ctx context.Context) error {
 
 	c.connLock.Lock()
 	c.conn = conn
+	c.connDone = make(chan struct{})
 	c.lastDisconnectReason = nil
 	c.connLock.Unlock()
 
 	cr := &countingReader{Reader: conn, c: c}
 	cw := &countingWriter{Writer: conn, c: c}
 
+	if c.opts.TopicAliasAutoTune {
+		c.tuneTopicAliasMaximum()
+	}
+
+	c.resetAuthenticator()
 	connectPkt := c.buildConnectPacket()
 	if _, err := connectPkt.WriteTo(cw); err != nil {
 		conn.Close()
@@ -435,49 +693,62 @@ func (c *Client) connect(ctx context.Context) error {
 
 	if connack.ReturnCode != packets.ConnAccepted {
 		conn.Close()
+		return newConnectRefusedError(connack, c.opts.ProtocolVersion)
+	}
 
+	// Reset keepalive to requested value before processing server override
+	// If server doesn't send ServerKeepAlive, we should use the requested value
+	c.opts.KeepAlive = c.requestedKeepAlive
+
+	if err := c.processConnackProperties(connack); err != nil {
+		c.opts.Logger.Error("invalid CONNACK, disconnecting", "error", err)
 		if c.opts.ProtocolVersion >= ProtocolV50 {
-			err := &MqttError{
-				ReasonCode: ReasonCode(connack.ReturnCode),
-				Parent:     ErrConnectionRefused,
+			disconnectPkt := &packets.DisconnectPacket{
+				Version:    c.opts.ProtocolVersion,
+				ReasonCode: uint8(ReasonCodeProtocolError),
 			}
-			if connack.Properties != nil && connack.Properties.Presence&packets.PresReasonString != 0 {
-				err.Message = connack.Properties.ReasonString
+			if _, werr := disconnectPkt.WriteTo(cw); werr == nil {
+				c.packetsSent.Add(1)
 			}
-			return err
-		}
-
-		switch connack.ReturnCode {
-		case packets.ConnRefusedUnacceptableProtocol:
-			return ErrUnacceptableProtocolVersion
-		case packets.ConnRefusedIdentifierRejected:
-			return ErrIdentifierRejected
-		case packets.ConnRefusedServerUnavailable:
-			return ErrServerUnavailable
-		case packets.ConnRefusedBadUsernameOrPassword:
-			return ErrBadUsernameOrPassword
-		case packets.ConnRefusedNotAuthorized:
-			return ErrNotAuthorized
-		default:
-			return fmt.Errorf("%w: code %d", ErrConnectionRefused, connack.ReturnCode)
 		}
+		conn.Close()
+		return err
 	}
 
-	// Reset keepalive to requested value before processing server override
-	// If server doesn't send ServerKeepAlive, we should use the requested value
-	c.opts.KeepAlive = c.requestedKeepAlive
+	if c.opts.EagerAliasReestablish {
+		c.reestablishTopicAliases()
+	}
 
-	c.processConnackProperties(connack)
+	if c.opts.ConnectValidator != nil {
+		props := toPublicProperties(connack.Properties)
+		if props == nil {
+			props = &Properties{}
+		}
+		if err := c.opts.ConnectValidator(c.ServerCapabilities(), props); err != nil {
+			c.opts.Logger.Debug("connection rejected by validator", "error", err)
+			disconnectPkt := &packets.DisconnectPacket{
+				Version:    c.opts.ProtocolVersion,
+				ReasonCode: uint8(ReasonCodeNormalDisconnect),
+			}
+			if _, werr := disconnectPkt.WriteTo(cw); werr == nil {
+				c.packetsSent.Add(1)
+			}
+			conn.Close()
+			return err
+		}
+	}
 
+	c.sessionPresent.Store(connack.SessionPresent)
 	if !c.opts.CleanSession {
 		if err := c.checkSessionPresent(connack.SessionPresent); err != nil {
 			c.opts.Logger.Warn("failed to check session present", "error", err)
 		}
 	}
 
-	c.opts.Logger.Debug("connection established", "server", c.opts.Server)
+	c.opts.Logger.Debug("connection established", "server", c.currentServer())
 
 	c.connected.Store(true)
+	c.setState(StateConnected)
 
 	if c.opts.Authenticator != nil {
 		if err := c.opts.Authenticator.Complete(); err != nil {
@@ -485,10 +756,18 @@ func (c *Client) connect(ctx context.Context) error {
 		}
 	}
 
-	if c.opts.OnConnect != nil {
+	if c.willRefresh.Swap(false) {
+		c.opts.Logger.Debug("reconnected with refreshed will, suppressing connection callbacks")
+	} else if c.cancelConnectionLost() {
+		c.opts.Logger.Debug("reconnected within grace period, suppressing connection callbacks")
+	} else if c.opts.OnConnect != nil {
 		go c.opts.OnConnect(c)
 	}
 
+	// Before the write loop starts, so that the outgoing queue can be
+	// inspected and requeued without racing with the writer
+	c.resendPending()
+
 	c.wg.Add(2)
 	go c.readLoop()
 	go c.writeLoop()
@@ -497,28 +776,42 @@ func (c *Client) connect(ctx context.Context) error {
 	return nil
 }
 
-// dialServer establishes a TCP, TLS, or custom connection to the MQTT server.
+// dialServer establishes a TCP, TLS, Unix socket or custom connection to the
+// MQTT server.
 func (c *Client) dialServer(ctx context.Context) (net.Conn, error) {
+	if conn := c.preDialed; conn != nil {
+		c.preDialed = nil
+		c.applyTCPKeepAlive(conn)
+		return conn, nil
+	}
+
+	server := c.currentServer()
+
 	// If a custom dialer is provided, trust it to handle the scheme and address.
 	// Pass the raw server string as the address to allow flexibility (e.g. WebSocket paths).
 	if c.opts.Dialer != nil {
 		network := "tcp"
-		if u, err := url.Parse(c.opts.Server); err == nil && u.Scheme != "" {
+		if u, err := url.Parse(server); err == nil && u.Scheme != "" {
 			network = u.Scheme
 		}
 
-		conn, err := c.opts.Dialer.DialContext(ctx, network, c.opts.Server)
+		conn, err := c.opts.Dialer.DialContext(ctx, network, server)
 		if err != nil {
 			return nil, fmt.Errorf("custom dialer failed: %w", err)
 		}
+		c.applyTCPKeepAlive(conn)
 		return conn, nil
 	}
 
-	u, err := url.Parse(c.opts.Server)
+	u, err := url.Parse(server)
 	if err != nil {
 		return nil, fmt.Errorf("invalid server URL: %w", err)
 	}
 
+	if u.Scheme == "unix" {
+		return c.dialUnix(ctx, u)
+	}
+
 	if u.Port() == "" {
 		switch u.Scheme {
 		case "tls", "ssl", "mqtts":
@@ -528,34 +821,124 @@ func (c *Client) dialServer(ctx context.Context) (net.Conn, error) {
 		}
 	}
 
-	useTLS := u.Scheme == "tls" || u.Scheme == "ssl" || u.Scheme == "mqtts" || c.opts.TLSConfig != nil
+	useTLS := u.Scheme == "tls" || u.Scheme == "ssl" || u.Scheme == "mqtts" || c.tlsConfigured()
 	if !useTLS && u.Scheme != "tcp" && u.Scheme != "mqtt" {
-		return nil, fmt.Errorf("unsupported scheme: %s (supported: tcp, mqtt, tls, ssl, mqtts)", u.Scheme)
+		return nil, fmt.Errorf("unsupported scheme: %s (supported: tcp, mqtt, tls, ssl, mqtts, unix)", u.Scheme)
 	}
 
 	var conn net.Conn
 	if useTLS {
-		tlsConfig := c.opts.TLSConfig
-		if tlsConfig == nil {
-			tlsConfig = &tls.Config{}
-		}
 		dialer := &tls.Dialer{
-			NetDialer: &net.Dialer{},
-			Config:    tlsConfig,
+			NetDialer: c.netDialer(),
+			Config:    c.tlsConfig(),
 		}
 		conn, err = dialer.DialContext(ctx, "tcp", u.Host)
 	} else {
-		var d net.Dialer
-		conn, err = d.DialContext(ctx, "tcp", u.Host)
+		conn, err = c.netDialer().DialContext(ctx, "tcp", u.Host)
 	}
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to server: %w", err)
 	}
 
+	c.applyTCPKeepAlive(conn)
 	return conn, nil
 }
 
+// tlsConfigured reports whether TLS was requested through options, so that
+// it is used regardless of the URL scheme.
+func (c *Client) tlsConfigured() bool {
+	return c.opts.TLSConfig != nil || c.opts.TLSServerName != "" || len(c.opts.TLSNextProtos) > 0 ||
+		c.opts.GetClientCertificate != nil
+}
+
+// tlsConfig returns the TLS configuration for a connection: the WithTLS
+// configuration, or an empty one, with the WithTLSServerName,
+// WithTLSNextProtos and WithClientCertificateGetter settings applied to a
+// copy.
+func (c *Client) tlsConfig() *tls.Config {
+	cfg := c.opts.TLSConfig
+	if cfg == nil {
+		cfg = &tls.Config{}
+	}
+	if c.opts.TLSServerName == "" && len(c.opts.TLSNextProtos) == 0 &&
+		c.opts.GetClientCertificate == nil {
+		return cfg
+	}
+
+	cfg = cfg.Clone()
+	if c.opts.TLSServerName != "" {
+		cfg.ServerName = c.opts.TLSServerName
+	}
+	if len(c.opts.TLSNextProtos) > 0 {
+		cfg.NextProtos = c.opts.TLSNextProtos
+	}
+	if c.opts.GetClientCertificate != nil {
+		cfg.GetClientCertificate = c.opts.GetClientCertificate
+		cfg.Certificates = nil
+	}
+	return cfg
+}
+
+// dialUnix connects to a server listening on a Unix domain socket, given as
+// unix:///path/to/socket. There is no port, and TLS is only used when
+// configured with WithTLS.
+func (c *Client) dialUnix(ctx context.Context, u *url.URL) (net.Conn, error) {
+	path := u.Host + u.Path
+	if u.Opaque != "" {
+		path = u.Opaque // unix:relative.sock
+	}
+	if path == "" {
+		return nil, fmt.Errorf("invalid server URL: missing socket path")
+	}
+
+	// LocalAddr (WithLocalAddr) is a TCP address and does not apply here
+	conn, err := (&net.Dialer{}).DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
+	}
+
+	if c.tlsConfigured() {
+		tlsConn := tls.Client(conn, c.tlsConfig())
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to connect to server: %w", err)
+		}
+		return tlsConn, nil
+	}
+	return conn, nil
+}
+
+// netDialer returns the dialer for TCP connections, also wrapped by the
+// TLS dialer.
+func (c *Client) netDialer() *net.Dialer {
+	return &net.Dialer{LocalAddr: c.opts.LocalAddr}
+}
+
+// applyTCPKeepAlive applies the WithTCPKeepAlive settings to the TCP socket
+// underlying conn, unwrapping TLS (or any NetConn provider) if needed.
+func (c *Client) applyTCPKeepAlive(conn net.Conn) {
+	if c.opts.TCPKeepAlive == nil {
+		return
+	}
+
+	for conn != nil {
+		if tcp, ok := conn.(*net.TCPConn); ok {
+			if err := tcp.SetKeepAliveConfig(*c.opts.TCPKeepAlive); err != nil {
+				c.opts.Logger.Warn("failed to set TCP keepalive", "error", err)
+			}
+			return
+		}
+		wrapper, ok := conn.(interface{ NetConn() net.Conn })
+		if !ok {
+			break
+		}
+		conn = wrapper.NetConn()
+	}
+
+	c.opts.Logger.Debug("TCP keepalive not applied: connection is not TCP")
+}
+
 // buildConnectPacket creates a CONNECT packet with the client's configuration.
 func (c *Client) buildConnectPacket() *packets.ConnectPacket {
 	// Use the original requested keepalive, not the potentially server-overridden value
@@ -586,8 +969,8 @@ func (c *Client) buildConnectPacket() *packets.ConnectPacket {
 			pkt.Properties.Presence |= packets.PresRequestResponseInformation
 		}
 
-		if c.opts.TopicAliasMaximum > 0 {
-			pkt.Properties.TopicAliasMaximum = c.opts.TopicAliasMaximum
+		if aliasMax := c.topicAliasMaximum(); aliasMax > 0 {
+			pkt.Properties.TopicAliasMaximum = aliasMax
 			pkt.Properties.Presence |= packets.PresTopicAliasMaximum
 		}
 
@@ -638,15 +1021,22 @@ func (c *Client) buildConnectPacket() *packets.ConnectPacket {
 		pkt.Password = c.opts.Password
 	}
 
-	if c.opts.will != nil {
+	if will := c.currentWill(); will != nil {
 		pkt.WillFlag = true
-		pkt.WillTopic = c.opts.will.Topic
-		pkt.WillMessage = c.opts.will.Payload
-		pkt.WillQoS = c.opts.will.QoS
-		pkt.WillRetain = c.opts.will.Retained
+		pkt.WillTopic = will.Topic
+		pkt.WillMessage = will.Payload
+		pkt.WillQoS = will.QoS
+		pkt.WillRetain = will.Retained
 
-		if c.opts.will.Properties != nil {
-			pkt.WillProperties = toInternalProperties(c.opts.will.Properties)
+		if will.Properties != nil {
+			pkt.WillProperties = toInternalProperties(will.Properties)
+		}
+		if c.opts.WillDelaySet {
+			if pkt.WillProperties == nil {
+				pkt.WillProperties = &packets.Properties{}
+			}
+			pkt.WillProperties.WillDelayInterval = c.opts.WillDelay
+			pkt.WillProperties.Presence |= packets.PresWillDelayInterval
 		}
 	}
 
@@ -660,6 +1050,7 @@ func (c *Client) readLoop() {
 
 	c.connLock.RLock()
 	conn := c.conn
+	connDone := c.connDone
 	c.connLock.RUnlock()
 
 	if conn == nil {
@@ -670,19 +1061,17 @@ func (c *Client) readLoop() {
 	cr := &countingReader{Reader: conn, c: c}
 	br := bufio.NewReader(cr)
 
+	backpressure := c.opts.ManualAck || (c.opts.ProtocolVersion >= ProtocolV50 &&
+		c.opts.ReceiveMaximumPolicy == LimitPolicyBackpressure)
+
 	for {
+		if backpressure && !c.waitForReceiveCapacity(connDone) {
+			return
+		}
+
 		pkt, err := packets.ReadPacket(br, c.opts.ProtocolVersion, c.opts.MaxIncomingPacket)
 		if err != nil {
-			var protoErr *packets.ProtocolError
-			if errors.As(err, &protoErr) {
-				c.opts.Logger.Error("protocol error, disconnecting", "error", err)
-				if c.opts.ProtocolVersion >= ProtocolV50 {
-					// Section 4.13: receiver SHOULD send a DISCONNECT with Reason Code 0x82 (Protocol Error)
-					_ = c.disconnectWithReason(context.Background(), uint8(ReasonCodeProtocolError), nil)
-				}
-			} else {
-				c.opts.Logger.Debug("read error, disconnecting", "error", err)
-			}
+			c.handleReadError(err, connDone)
 			return
 		}
 		c.packetsReceived.Add(1)
@@ -694,6 +1083,10 @@ func (c *Client) readLoop() {
 		default:
 		}
 
+		if c.opts.IncomingStrategy != IncomingBackpressureBlock && c.queueOrOverflow(pkt, connDone) {
+			continue
+		}
+
 		select {
 		case c.incoming <- pkt:
 		case <-c.stop:
@@ -710,15 +1103,21 @@ func (c *Client) writeLoop() {
 	var ticker *time.Ticker
 	var tickerCh <-chan time.Time
 
-	if c.opts.KeepAlive > 0 {
-		// Ticker runs 4 times per keepalive interval for better resolution
-		ticker = time.NewTicker(c.opts.KeepAlive / 4)
+	readTimeout := c.readTimeout()
+	if c.opts.KeepAlive > 0 || readTimeout > 0 {
+		// Ticker runs 4 times per interval for better resolution
+		interval := c.opts.KeepAlive
+		if interval == 0 || (readTimeout > 0 && readTimeout < interval) {
+			interval = readTimeout
+		}
+		ticker = time.NewTicker(interval / 4)
 		defer ticker.Stop()
 		tickerCh = ticker.C
 	}
 
 	c.connLock.RLock()
 	conn := c.conn
+	connDone := c.connDone
 	c.connLock.RUnlock()
 
 	if conn == nil {
@@ -728,41 +1127,65 @@ func (c *Client) writeLoop() {
 
 	cw := &countingWriter{Writer: conn, c: c}
 	bw := bufio.NewWriter(cw)
+	pingPending := false // PINGREQ sent but no PINGRESP received yet
 	lastReceived := time.Now()
 	lastSent := lastReceived
 
+	// Tokens waiting for their packet to be flushed (QoS0TokenCompleteOnWrite)
+	var notify writeNotifier
+
+	// Fires if the pending PINGREQ is not answered within PingTimeout
+	var pingTimer *time.Timer
+	var pingTimeoutCh <-chan time.Time
+	defer func() {
+		if pingTimer != nil {
+			pingTimer.Stop()
+		}
+	}()
+
 	for {
 		select {
 		case pkt := <-c.outgoing:
+			c.setWriteDeadline(conn)
+			notify.track(pkt)
 			c.opts.Logger.Debug("sending packet", "type", packets.PacketNames[pkt.Type()])
-			if _, err := pkt.WriteTo(bw); err != nil {
+			if _, err := c.withCurrentAlias(pkt).WriteTo(bw); err != nil {
 				c.opts.Logger.Debug("write error, disconnecting", "error", err)
+				notify.done(ErrClientDisconnected)
 				c.handleDisconnect()
 				return
 			}
-			c.packetsSent.Add(1)
-			lastSent = time.Now()
+			if !isFlushMarker(pkt) {
+				c.packetsSent.Add(1)
+				lastSent = time.Now()
+			}
 
 			// Batching: try to drain channel to fill buffer
 			count := len(c.outgoing)
 			for range count {
 				pkt := <-c.outgoing
+				notify.track(pkt)
 				c.opts.Logger.Debug("sending packet (batch)", "type", packets.PacketNames[pkt.Type()])
-				if _, err := pkt.WriteTo(bw); err != nil {
+				if _, err := c.withCurrentAlias(pkt).WriteTo(bw); err != nil {
 					c.opts.Logger.Debug("write error (batch), disconnecting", "error", err)
+					notify.done(ErrClientDisconnected)
 					c.handleDisconnect()
 					return
 				}
-				c.packetsSent.Add(1)
-				lastSent = time.Now()
+				if !isFlushMarker(pkt) {
+					c.packetsSent.Add(1)
+					lastSent = time.Now()
+				}
 			}
 
 			// Flush after batch
 			if err := bw.Flush(); err != nil {
 				c.opts.Logger.Debug("flush error, disconnecting", "error", err)
+				notify.done(ErrClientDisconnected)
 				c.handleDisconnect()
 				return
 			}
+			notify.done(nil)
 
 		case <-c.packetReceived:
 			// Update lastReceived timestamp when any packet arrives
@@ -770,40 +1193,53 @@ func (c *Client) writeLoop() {
 
 		case <-c.pingPendingCh:
 			// PINGRESP received, clear pending flag
-			c.pingPending = false
+			pingPending = false
+			if pingTimer != nil {
+				pingTimer.Stop()
+				pingTimeoutCh = nil
+			}
+
+		case <-pingTimeoutCh:
+			pingTimeoutCh = nil
+			if !pingPending {
+				continue
+			}
+			c.pingsFailed.Add(1)
+			c.opts.Logger.Debug("ping timeout, no PINGRESP received",
+				"timeout", c.opts.PingTimeout)
+			c.handleDisconnect()
+			return
 
 		case <-tickerCh:
-			// Check if we've received anything recently (1.5x keepalive timeout)
-			timeout := c.opts.KeepAlive + c.opts.KeepAlive/2 // 1.5x keepalive
-			if time.Since(lastReceived) >= timeout {
+			// Check if we've received anything recently (1.5x keepalive
+			// timeout unless WithReadTimeout is set)
+			if readTimeout > 0 && time.Since(lastReceived) >= readTimeout {
+				if pingPending {
+					c.pingsFailed.Add(1)
+				}
 				c.opts.Logger.Debug("keepalive timeout, no packets received",
-					"timeout", timeout,
+					"timeout", readTimeout,
 					"last_received", time.Since(lastReceived))
 				c.handleDisconnect()
 				return
 			}
+			if c.opts.KeepAlive == 0 {
+				// Keepalive disabled: only the read timeout applies
+				continue
+			}
 
-			// Send PINGREQ if we haven't sent anything for 3/4 of the keepalive interval
-			// OR if we haven't received anything for 3/4 of the keepalive interval.
-			// This ensures we actively probe the connection even when publishing regularly.
+			// Send PINGREQ when the connection looks idle (see pingReason).
 			// Only send if no PINGREQ is currently pending (waiting for PINGRESP).
-			threshold := c.opts.KeepAlive - (c.opts.KeepAlive / 4)
 			timeSinceSent := time.Since(lastSent)
 			timeSinceReceived := time.Since(lastReceived)
 
-			if !c.pingPending && (timeSinceSent >= threshold || timeSinceReceived >= threshold) {
-				// Determine reason for PINGREQ
-				reason := "no receive"
-				if timeSinceSent >= threshold && timeSinceReceived >= threshold {
-					reason = "no activity"
-				} else if timeSinceSent >= threshold {
-					reason = "no send"
-				}
+			if reason := c.pingReason(timeSinceSent, timeSinceReceived); !pingPending && reason != "" {
 				c.opts.Logger.Debug("sending PINGREQ",
 					"reason", reason,
 					"time_since_sent", timeSinceSent,
 					"time_since_received", timeSinceReceived)
 
+				c.setWriteDeadline(conn)
 				ping := &packets.PingreqPacket{}
 				if _, err := ping.WriteTo(bw); err != nil {
 					c.handleDisconnect()
@@ -814,9 +1250,24 @@ func (c *Client) writeLoop() {
 					return
 				}
 				lastSent = time.Now()
-				c.pingPending = true
+				pingPending = true
+				c.pingsSent.Add(1)
+
+				if c.opts.PingTimeout > 0 {
+					if pingTimer == nil {
+						pingTimer = time.NewTimer(c.opts.PingTimeout)
+					} else {
+						pingTimer.Reset(c.opts.PingTimeout)
+					}
+					pingTimeoutCh = pingTimer.C
+				}
 			}
 
+		case <-connDone:
+			// Connection torn down by the read side; leave queued packets
+			// for the writeLoop of the next connection.
+			return
+
 		case <-c.stop:
 			c.opts.Logger.Debug("writeLoop stopped")
 			return
@@ -824,6 +1275,46 @@ func (c *Client) writeLoop() {
 	}
 }
 
+// pingReason returns why a PINGREQ is due after nothing was sent for
+// sinceSent and nothing received for sinceReceived, or "" if none is.
+//
+// A PINGREQ is sent if we haven't sent anything for 3/4 of the keepalive
+// interval OR haven't received anything for 3/4 of the keepalive interval.
+// This ensures we actively probe the connection even when publishing
+// regularly. With WithAdaptiveKeepAlive, nothing received is not enough:
+// only a lack of sending triggers one, which the server must never see for
+// longer than the keepalive interval.
+func (c *Client) pingReason(sinceSent, sinceReceived time.Duration) string {
+	threshold := c.opts.KeepAlive - (c.opts.KeepAlive / 4)
+
+	switch {
+	case sinceSent >= threshold && sinceReceived >= threshold:
+		return "no activity"
+	case sinceSent >= threshold:
+		return "no send"
+	case sinceReceived >= threshold && !c.opts.AdaptiveKeepAlive:
+		return "no receive"
+	}
+	return ""
+}
+
+// setWriteDeadline bounds the next writes to conn by WithIOTimeout, if set.
+// A write that misses the deadline fails, and writeLoop disconnects.
+func (c *Client) setWriteDeadline(conn net.Conn) {
+	if c.opts.IOTimeout > 0 {
+		_ = conn.SetWriteDeadline(time.Now().Add(c.opts.IOTimeout))
+	}
+}
+
+// readTimeout returns how long the connection may stay silent before
+// writeLoop considers it lost, or 0 if it never does.
+func (c *Client) readTimeout() time.Duration {
+	if c.opts.ReadTimeout > 0 {
+		return c.opts.ReadTimeout
+	}
+	return c.opts.KeepAlive + c.opts.KeepAlive/2 // 1.5x keepalive
+}
+
 // handleDisconnect handles connection loss.
 func (c *Client) handleDisconnect() {
 	if !c.connected.Swap(false) {
@@ -835,6 +1326,13 @@ func (c *Client) handleDisconnect() {
 		c.conn.Close()
 		c.conn = nil
 	}
+	if c.connDone != nil {
+		close(c.connDone)
+		c.connDone = nil
+	}
+	if c.overflow != nil {
+		c.overflow.clear()
+	}
 	// Check if we have a specific disconnect reason from the server
 	reason := fmt.Errorf("connection lost")
 	if c.lastDisconnectReason != nil {
@@ -843,8 +1341,28 @@ func (c *Client) handleDisconnect() {
 	}
 	c.connLock.Unlock()
 
-	if c.opts.OnConnectionLost != nil {
-		go c.opts.OnConnectionLost(c, reason)
+	takenOver := errors.Is(reason, ReasonCodeSessionTakenOver)
+	if takenOver {
+		c.sessionTakenOver.Store(true)
+		if c.opts.OnSessionTakenOver != nil {
+			go c.opts.OnSessionTakenOver(c, reason)
+		}
+	}
+	c.noteRedirect(reason)
+
+	c.finishReauth(fmt.Errorf("re-authentication interrupted: %w", reason))
+	c.setState(StateDisconnected)
+
+	if !c.willRefresh.Load() {
+		c.recordDisconnect(time.Now())
+		if takenOver && c.stopsOnTakeover() {
+			// Not reconnecting: no grace period
+			if c.opts.OnConnectionLost != nil {
+				go c.opts.OnConnectionLost(c, reason)
+			}
+		} else {
+			c.notifyConnectionLost(reason)
+		}
 	}
 
 	// Signal reconnect loop
@@ -873,6 +1391,16 @@ func (c *Client) IsConnected() bool {
 // WithReason to specify the reason code. These options are ignored when
 // using MQTT v3.1.1.
 //
+// Outgoing messages still queued or awaiting acknowledgment are abandoned
+// unless WithDrain is given; their tokens complete with
+// ErrGracefulDisconnect, which also matches ErrClientDisconnected.
+//
+// Messages whose acknowledgment is deferred until their handlers return
+// (LimitPolicyBackpressure) or call Message.Ack (WithManualAck) are not
+// acknowledged if their handlers are still running, so the server can
+// redeliver them. Use WithShutdownAckTimeout to
+// give handlers time to finish first.
+//
 // Example:
 //
 //	// Normal disconnect (v3.1.1 or v5.0)
@@ -887,9 +1415,30 @@ func (c *Client) Disconnect(ctx context.Context, opts ...DisconnectOption) error
 	for _, opt := range opts {
 		opt(options)
 	}
+
+	if options.DrainTimeout > 0 && c.IsConnected() {
+		c.drainPending(ctx, options.DrainTimeout)
+	}
+	if c.opts.ShutdownAckTimeout > 0 && c.IsConnected() {
+		c.waitForDeferredAcks(ctx, c.opts.ShutdownAckTimeout)
+	}
+
+	c.disconnectRequested.Store(true)
+	// A user-initiated shutdown is not a lost connection
+	c.cancelConnectionLost()
 	return c.disconnectWithReason(ctx, uint8(options.ReasonCode), options.Properties)
 }
 
+// stopError returns the error for operations cancelled because the client
+// stopped: ErrGracefulDisconnect after Disconnect, ErrClientDisconnected
+// otherwise.
+func (c *Client) stopError() error {
+	if c.disconnectRequested.Load() {
+		return ErrGracefulDisconnect
+	}
+	return ErrClientDisconnected
+}
+
 // disconnectWithReason is an internal helper that sends a DISCONNECT packet
 // with a specific reason code (MQTT v5.0).
 func (c *Client) disconnectWithReason(ctx context.Context, reasonCode uint8, props *Properties) error {
@@ -900,6 +1449,9 @@ func (c *Client) disconnectWithReason(ctx context.Context, reasonCode uint8, pro
 		return nil // Already disconnected
 	}
 
+	// Handlers that are still running must not ack after the DISCONNECT
+	c.withholdAcks.Store(true)
+
 	// Send DISCONNECT packet
 	disconnectPkt := &packets.DisconnectPacket{
 		Version:    c.opts.ProtocolVersion,
@@ -917,6 +1469,7 @@ func (c *Client) disconnectWithReason(ctx context.Context, reasonCode uint8, pro
 
 	// Stop all goroutines
 	close(c.stop)
+	c.closeStateChanges()
 
 	// Close connection to unblock readLoop
 	c.connLock.Lock()
@@ -948,25 +1501,79 @@ func (c *Client) disconnectWithReason(ctx context.Context, reasonCode uint8, pro
 func (c *Client) reconnectLoop() {
 	defer c.wg.Done()
 
-	backoff := time.Second
-	maxBackoff := 2 * time.Minute
+	backoff := c.initialBackoff()
+	c.reconnectBackoff.Store(int64(backoff))
+	attempts := 0 // Consecutive failures
 
 	for {
 		select {
 		case <-c.disconnected:
-			// Wait before reconnecting
-			time.Sleep(backoff)
+			if c.sessionTakenOver.Load() && c.stopsOnTakeover() {
+				c.stopAfterTakeover()
+				return
+			}
+			c.setState(StateReconnecting)
+
+			// Wait before reconnecting (a will refresh or a server redirect
+			// reconnects at once)
+			refreshing := c.willRefresh.Load()
+			var delay time.Duration
+			if !refreshing && !c.hasRedirect() {
+				delay = c.jittered(backoff)
+				start := time.Now()
+				timer := time.NewTimer(delay)
+				select {
+				case <-timer.C:
+				case <-c.reconnectNow:
+					timer.Stop()
+					delay = time.Since(start)
+				case <-c.stop:
+					timer.Stop()
+					c.opts.Logger.Debug("reconnectLoop stopped")
+					return
+				}
+			}
 
 			c.reconnectCount.Add(1)
 
+			if c.opts.OnReconnecting != nil && !refreshing {
+				go c.opts.OnReconnecting(attempts+1, delay)
+			}
+
+			if c.opts.ClientIDOnTakeover != nil && c.sessionTakenOver.Swap(false) {
+				c.rotateClientID()
+			}
+
+			if c.opts.SuppressRetainedOnReconnect {
+				c.suppressRetainedOnResubscribe()
+			}
+
+			if c.opts.AutoServerRedirect {
+				c.chooseServer()
+			}
+
 			// Attempt to reconnect
 			ctx, cancel := context.WithTimeout(context.Background(), c.opts.ConnectTimeout)
 			err := c.connect(ctx)
 			cancel()
 
 			if err != nil {
+				c.noteRedirect(err)
+
+				if c.willRefresh.Swap(false) {
+					// The connection was not lost by accident, but it is lost now
+					c.notifyConnectionLost(err)
+				}
+
+				attempts++
+				if limit := c.opts.MaxReconnectAttempts; limit > 0 && attempts >= limit {
+					c.giveUpReconnecting(attempts, err)
+					return
+				}
+
 				// Exponential backoff
-				backoff = min(backoff*2, maxBackoff)
+				backoff = c.nextBackoff(backoff)
+				c.reconnectBackoff.Store(int64(backoff))
 
 				// Signal disconnected again to retry
 				select {
@@ -976,7 +1583,16 @@ func (c *Client) reconnectLoop() {
 				continue
 			}
 
-			backoff = time.Second
+			backoff = c.initialBackoff()
+			c.reconnectBackoff.Store(int64(backoff))
+			attempts = 0
+			c.redirects = 0
+
+			// Forget a ReconnectNow that arrived while connecting
+			select {
+			case <-c.reconnectNow:
+			default:
+			}
 
 			if c.opts.CleanSession {
 				c.internalResetState()
@@ -991,6 +1607,66 @@ func (c *Client) reconnectLoop() {
 	}
 }
 
+// giveUpReconnecting stops the client after MaxReconnectAttempts failed
+// attempts and reports it through OnConnectionLost.
+func (c *Client) giveUpReconnecting(attempts int, lastErr error) {
+	c.opts.Logger.Warn("giving up reconnecting", "attempts", attempts, "error", lastErr)
+
+	// Only reconnectLoop could mark the client connected again, so nothing
+	// else closes c.stop while it is disconnected
+	select {
+	case <-c.stop:
+		return // Stopped meanwhile
+	default:
+		close(c.stop)
+	}
+	c.closeStateChanges()
+
+	// Report now rather than after a pending grace period
+	c.cancelConnectionLost()
+	if c.opts.OnConnectionLost != nil {
+		go c.opts.OnConnectionLost(c, fmt.Errorf("%w after %d attempts: %w",
+			ErrReconnectAttemptsExhausted, attempts, lastErr))
+	}
+}
+
+// stopsOnTakeover reports whether the client stops instead of reconnecting
+// after a session takeover (SessionTakeoverStop).
+func (c *Client) stopsOnTakeover() bool {
+	return c.opts.AutoReconnect && c.opts.TakeoverPolicy == SessionTakeoverStop &&
+		c.opts.ClientIDOnTakeover == nil
+}
+
+// stopAfterTakeover stops the client after a session takeover instead of
+// reconnecting. handleDisconnect has already reported it.
+func (c *Client) stopAfterTakeover() {
+	c.opts.Logger.Warn("session taken over by another connection, not reconnecting",
+		"client_id", c.opts.ClientID)
+
+	// As in giveUpReconnecting, nothing else closes c.stop while the client
+	// is disconnected
+	select {
+	case <-c.stop:
+		return
+	default:
+		close(c.stop)
+	}
+	c.closeStateChanges()
+}
+
+// rotateClientID replaces the client ID using WithClientIDOnTakeover.
+func (c *Client) rotateClientID() {
+	current := c.opts.ClientID
+	next := c.opts.ClientIDOnTakeover(current)
+	if next == "" || next == current {
+		return
+	}
+	c.opts.Logger.Warn("session taken over, reconnecting with a new client ID",
+		"old_client_id", current, "new_client_id", next)
+	c.opts.ClientID = next
+	c.assignedClientID = ""
+}
+
 // AssignedClientID returns the client ID assigned by the server.
 //
 // When connecting with an empty client ID, the server may assign a unique
@@ -1059,9 +1735,10 @@ func (c *Client) ServerKeepAlive() uint16 {
 //   - Geographic routing: Direct clients to nearest server
 //   - Failover: Redirect to backup server
 //
-// IMPORTANT: The library does NOT automatically redirect to the referenced server.
-// Users must check this value and manually reconnect if desired or use the
-// WithOnServerRedirect option.
+// IMPORTANT: By default the library does NOT automatically redirect to the
+// referenced server. Users must check this value and manually reconnect if
+// desired, use the WithOnServerRedirect option, or enable
+// WithAutoServerRedirect.
 //
 // This method returns the server's reference URI if one was provided, or an
 // empty string if:
@@ -1122,6 +1799,31 @@ func (c *Client) SessionExpiryInterval() uint32 {
 	return c.sessionExpiryInterval
 }
 
+// SessionPresent reports whether the server resumed an existing session on
+// the most recent connection (the Session Present flag of the CONNACK).
+//
+// This is what the server actually gave the client, as opposed to what was
+// requested with WithCleanSession: a client asking to resume a session
+// (CleanSession=false) still gets a fresh one if the session expired or the
+// server lost it. Applications can use it to skip restoring state that the
+// server already has.
+//
+// Returns false before the first connection.
+//
+// Example:
+//
+//	client, _ := mq.Dial(uri,
+//	    mq.WithClientID("sensor-1"),
+//	    mq.WithCleanSession(false),
+//	    mq.WithOnConnect(func(c *mq.Client) {
+//	        if !c.SessionPresent() {
+//	            republishState(c)
+//	        }
+//	    }))
+func (c *Client) SessionPresent() bool {
+	return c.sessionPresent.Load()
+}
+
 // ResponseInformation returns the response information string provided by the server.
 //
 // In MQTT v5.0, the server can provide a ResponseInformation string in the CONNACK
@@ -1163,6 +1865,7 @@ func extractServerCapabilities(props *packets.Properties) serverCapabilities {
 		WildcardAvailable:           true,  // Default if not specified
 		SubscriptionIDAvailable:     true,  // Default if not specified
 		SharedSubscriptionAvailable: true,  // Default if not specified
+		fromConnack:                 true,
 	}
 
 	if props == nil {
@@ -1269,6 +1972,41 @@ func (c *Client) ConnectionUserProperties() map[string]string {
 	return props
 }
 
+// ConnectReasonString returns the Reason String the server sent in the
+// CONNACK of the current (most recent) successful connection.
+//
+// Some servers use it to pass operational information on connect, such as
+// "Welcome, rate limit: 1000 msg/s". Together with ConnectionUserProperties
+// it gives access to all informational CONNACK content. (For refused
+// connections the reason string is reported in ConnectRefusedError.)
+//
+// Returns an empty string for MQTT v3.1.1 connections or if the server did
+// not send one.
+func (c *Client) ConnectReasonString() string {
+	return c.connectReasonString
+}
+
+// ConnackProperties returns the properties the server sent in the CONNACK of
+// the current (most recent) successful connection, such as user properties,
+// the reason string, response information, the server reference or an
+// assigned client identifier. It gives access to server-provided metadata
+// that has no dedicated accessor.
+//
+// The result is a copy and may be modified freely. Returns nil for MQTT
+// v3.1.1 connections, before the first connection, or if the CONNACK carried
+// no properties. Server limits such as Receive Maximum are reported by
+// ServerCapabilities instead.
+//
+// Example:
+//
+//	if props := client.ConnackProperties(); props != nil {
+//	    tenant := props.GetUserProperty("tenant-shard")
+//	    ...
+//	}
+func (c *Client) ConnackProperties() *Properties {
+	return toPublicProperties(c.connackProperties.Load())
+}
+
 // ClientStats holds connection and throughput statistics.
 type ClientStats struct {
 	PacketsSent     uint64
@@ -1277,6 +2015,25 @@ type ClientStats struct {
 	BytesReceived   uint64
 	ReconnectCount  uint64
 	Connected       bool
+
+	// InFlightPublishes is the number of QoS 1/2 publishes sent and not yet
+	// fully acknowledged.
+	InFlightPublishes int
+
+	// PublishLatency is the moving average of QoS 1/2 publish round trips.
+	PublishLatency time.Duration
+	// SubscribeLatency is the moving average of SUBSCRIBE round trips.
+	SubscribeLatency time.Duration
+
+	// IncomingDropped is the number of incoming QoS 0 messages discarded
+	// because the incoming queue was full (see IncomingBackpressureDropNewest
+	// and IncomingBackpressureDropOldest).
+	IncomingDropped uint64
+
+	// Retransmissions is the number of PUBLISH, PUBREL, SUBSCRIBE and
+	// UNSUBSCRIBE packets sent again because they were not acknowledged in
+	// time or the connection was lost (see WithOnRetransmit).
+	Retransmissions uint64
 }
 
 // GetStats returns the current client statistics.
@@ -1288,6 +2045,24 @@ func (c *Client) GetStats() ClientStats {
 		BytesReceived:   c.bytesReceived.Load(),
 		ReconnectCount:  c.reconnectCount.Load(),
 		Connected:       c.IsConnected(),
+
+		InFlightPublishes: int(c.inFlightPublishes.Load()),
+
+		PublishLatency:   c.publishLatency.value(),
+		SubscribeLatency: c.subscribeLatency.value(),
+
+		IncomingDropped: c.incomingDropped.Load(),
+		Retransmissions: c.retransmissions.Load(),
+	}
+}
+
+// setInFlightCount updates inFlightCount and its copy for GetStats. Must be
+// called with sessionLock held.
+func (c *Client) setInFlightCount(n int) {
+	c.inFlightCount = n
+	c.inFlightPublishes.Store(int64(n))
+	if n == 0 {
+		c.signalPendingSettled()
 	}
 }
 
@@ -1362,8 +2137,17 @@ func (c *Client) performHandshake(ctx context.Context, r io.Reader, w io.Writer)
 	}
 }
 
-func (c *Client) processConnackProperties(connack *packets.ConnackPacket) {
+// processConnackProperties validates an accepted CONNACK and applies the
+// properties it carries.
+func (c *Client) processConnackProperties(connack *packets.ConnackPacket) error {
+	// MQTT v3.1.1 section 3.2.2.2 / v5.0 section 3.2.2.1.1: a clean start
+	// always yields a new session, so Session Present must be 0.
+	if c.opts.CleanSession && connack.SessionPresent {
+		return fmt.Errorf("%w: CONNACK has Session Present set after a clean start request", ErrProtocolViolation)
+	}
+
 	if c.opts.ProtocolVersion >= ProtocolV50 && connack.Properties != nil {
+		c.connackProperties.Store(connack.Properties)
 		c.serverCaps = extractServerCapabilities(connack.Properties)
 		c.opts.Logger.Debug("received server capabilities",
 			"max_packet_size", c.serverCaps.MaximumPacketSize,
@@ -1391,14 +2175,15 @@ func (c *Client) processConnackProperties(connack *packets.ConnackPacket) {
 			}
 		}
 
-		if c.opts.TopicAliasMaximum > 0 && connack.Properties.Presence&packets.PresTopicAliasMaximum != 0 {
+		aliasMax := c.topicAliasMaximum()
+		if aliasMax > 0 && connack.Properties.Presence&packets.PresTopicAliasMaximum != 0 {
 			serverLimit := connack.Properties.TopicAliasMaximum
 			if serverLimit > 0 {
-				c.maxAliases = min(serverLimit, c.opts.TopicAliasMaximum)
+				c.maxAliases = min(serverLimit, aliasMax)
 				c.topicAliases = make(map[string]uint16)
 				c.nextAliasID = 1
 				c.opts.Logger.Debug("topic aliases enabled",
-					"client_accepts", c.opts.TopicAliasMaximum,
+					"client_accepts", aliasMax,
 					"server_accepts", serverLimit,
 					"using", c.maxAliases)
 			}
@@ -1427,6 +2212,13 @@ func (c *Client) processConnackProperties(connack *packets.ConnackPacket) {
 				"interval", c.sessionExpiryInterval)
 		}
 
+		c.connectReasonString = ""
+		if connack.Properties.Presence&packets.PresReasonString != 0 {
+			c.connectReasonString = connack.Properties.ReasonString
+			c.opts.Logger.Debug("received connack reason string", "reason_string", c.connectReasonString)
+		}
+
+		c.connackUserProperties = nil
 		if len(connack.Properties.UserProperties) > 0 {
 			c.connackUserProperties = make(map[string]string)
 			for _, up := range connack.Properties.UserProperties {
@@ -1437,8 +2229,12 @@ func (c *Client) processConnackProperties(connack *packets.ConnackPacket) {
 	} else {
 		// Use default capabilities for older protocols or if no properties sent
 		c.serverCaps = extractServerCapabilities(nil)
+		c.connackProperties.Store(nil)
 		c.connackUserProperties = nil
+		c.connectReasonString = ""
 	}
+
+	return nil
 }
 
 type countingReader struct {
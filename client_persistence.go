@@ -23,13 +23,13 @@ func (c *Client) loadSessionState() error {
 	}
 
 	c.pending = make(map[uint16]*pendingOp)
-	c.inFlightCount = 0
+	c.setInFlightCount(0)
 	for id, pub := range pending {
 		op := c.convertFromPersistedPublish(pub)
 		if pkt, ok := op.packet.(*packets.PublishPacket); ok {
 			pkt.PacketID = id // Restore PacketID from map key
 			if pkt.QoS > 0 {
-				c.inFlightCount++
+				c.setInFlightCount(c.inFlightCount + 1)
 			}
 		}
 		c.pending[id] = op
@@ -88,4 +88,8 @@ func TestGetStats(t *testing.T) {
 	if newStats.BytesSent <= stats.BytesSent {
 		t.Errorf("BytesSent did not increase: %d -> %d", stats.BytesSent, newStats.BytesSent)
 	}
+	if stats.InFlightPublishes != 0 || newStats.InFlightPublishes != 1 {
+		t.Errorf("InFlightPublishes = %d -> %d, want 0 -> 1 (the server never acknowledges)",
+			stats.InFlightPublishes, newStats.InFlightPublishes)
+	}
 }
@@ -1,6 +1,7 @@
 package mq
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -19,6 +20,7 @@ func TestSubscribe(t *testing.T) {
 		stop:          make(chan struct{}),
 		nextPacketID:  1,
 	}
+	c.connected.Store(true)
 
 	topic := "test/topic"
 	handler := func(_ *Client, _ Message) {}
@@ -97,6 +99,60 @@ func TestUnsubscribe(t *testing.T) {
 
 }
 
+func TestUnsubscribeWithOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		version   uint8
+		wantProps bool
+	}{
+		{"MQTT v5.0", ProtocolV50, true},
+		{"MQTT v3.1.1", ProtocolV311, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultOptions("tcp://localhost:1883")
+			opts.ProtocolVersion = tt.version
+			opts.Logger = testLogger()
+			c := newTestClient(opts)
+			c.subscriptions["jobs/#"] = subscriptionEntry{}
+			c.subscriptions["status"] = subscriptionEntry{}
+			c.subscriptions["other"] = subscriptionEntry{}
+
+			topics := []string{"jobs/#", "status"}
+			c.UnsubscribeWithOptions(topics, WithUnsubscribeUserProperty("audit", "shutdown"))
+			topics[0] = "changed" // The packet must not share the caller's slice
+
+			pkt := (<-c.outgoing).(*packets.UnsubscribePacket)
+			if !reflect.DeepEqual(pkt.Topics, []string{"jobs/#", "status"}) {
+				t.Errorf("packet topics = %v", pkt.Topics)
+			}
+			hasProps := pkt.Properties != nil && len(pkt.Properties.UserProperties) == 1 &&
+				pkt.Properties.UserProperties[0] == packets.UserProperty{Key: "audit", Value: "shutdown"}
+			if hasProps != tt.wantProps || (!tt.wantProps && pkt.Properties != nil) {
+				t.Errorf("packet properties = %+v, want user properties: %v", pkt.Properties, tt.wantProps)
+			}
+			if _, ok := c.subscriptions["other"]; !ok || len(c.subscriptions) != 1 {
+				t.Errorf("remaining subscriptions = %v", c.subscriptions)
+			}
+		})
+	}
+}
+
+func TestUnsubscribeWithOptions_Invalid(t *testing.T) {
+	c := newTestClient(nil)
+	c.opts.Logger = testLogger()
+
+	for _, topics := range [][]string{nil, {"a", "b", "a"}} {
+		if err := c.UnsubscribeWithOptions(topics).Error(); err == nil {
+			t.Errorf("expected an error for %v", topics)
+		}
+	}
+	if len(c.outgoing) != 0 {
+		t.Error("invalid unsubscribe must not be sent")
+	}
+}
+
 func TestResubscribeAll(t *testing.T) {
 	c := &Client{
 		opts: &clientOptions{
@@ -138,6 +194,7 @@ func TestInternalSubscribe(t *testing.T) {
 		outgoing:      make(chan packets.Packet, 10),
 		nextPacketID:  1,
 	}
+	c.connected.Store(true)
 
 	topic := "test/topic"
 	handler := func(_ *Client, _ Message) {}
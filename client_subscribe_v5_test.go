@@ -1,6 +1,7 @@
 package mq
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -19,6 +20,7 @@ func TestSubscribeWithUserProperties(t *testing.T) {
 		stop:          make(chan struct{}),
 		nextPacketID:  1,
 	}
+	c.connected.Store(true)
 
 	topic := "test/topic"
 	handler := func(_ *Client, _ Message) {}
@@ -162,3 +164,53 @@ func TestResubscribeWithUserPropertiesGrouping(t *testing.T) {
 		t.Error("Did not see packets for both groups")
 	}
 }
+
+func TestSubscribeUnsupportedFeatures(t *testing.T) {
+	tests := []struct {
+		name    string
+		topic   string
+		opts    []SubscribeOption
+		disable func(*serverCapabilities)
+		want    error
+	}{
+		{"wildcard +", "sensors/+/temp", nil, func(s *serverCapabilities) { s.WildcardAvailable = false }, ErrWildcardNotSupported},
+		{"wildcard #", "sensors/#", nil, func(s *serverCapabilities) { s.WildcardAvailable = false }, ErrWildcardNotSupported},
+		{"shared", "$share/g/jobs", nil, func(s *serverCapabilities) { s.SharedSubscriptionAvailable = false }, ErrSharedSubNotSupported},
+		{"subscription ID", "jobs", []SubscribeOption{WithSubscriptionIdentifier(7)}, func(s *serverCapabilities) { s.SubscriptionIDAvailable = false }, ErrSubscriptionIDNotSupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, version := range []uint8{ProtocolV311, ProtocolV50} {
+				c := newTestClient(nil)
+				c.opts.Logger = testLogger()
+				c.opts.ProtocolVersion = version
+				c.serverCaps = extractServerCapabilities(nil)
+				c.connected.Store(true)
+
+				// Supported: sent
+				c.Subscribe(tt.topic, AtLeastOnce, nil, tt.opts...)
+				if len(c.outgoing) != 1 {
+					t.Fatalf("v%d: supported subscription was not sent", version)
+				}
+				<-c.outgoing
+
+				// Unsupported: refused locally on v5.0 only
+				tt.disable(&c.serverCaps)
+				tok := c.Subscribe(tt.topic, AtLeastOnce, nil, tt.opts...)
+				if version == ProtocolV311 {
+					if len(c.outgoing) != 1 {
+						t.Errorf("v3.1.1: subscription was not sent")
+					}
+					continue
+				}
+				if !errors.Is(tok.Error(), tt.want) {
+					t.Errorf("error = %v, want %v", tok.Error(), tt.want)
+				}
+				if len(c.outgoing) != 0 {
+					t.Error("refused subscription must not be sent")
+				}
+			}
+		})
+	}
+}
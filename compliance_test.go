@@ -86,7 +86,7 @@ func TestCompliance_PacketID_Reuse(t *testing.T) {
 
 	// Generate next ID - should be 11 (nextPacketID++)
 	// But since 11 is used, it should skip to 12 if compliant.
-	id := c.nextID()
+	id, _ := c.nextID()
 	switch id {
 	case 11:
 		t.Errorf("Compliance violation: nextID() returned 11 which is currently in use (MQTT-2.3.1-4)")
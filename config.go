@@ -0,0 +1,110 @@
+package mq
+
+import "time"
+
+// ClientConfig is a read-only snapshot of the effective client configuration,
+// intended for diagnostics (e.g. a status endpoint or a startup log line).
+//
+// Secrets are never included: the password is reduced to HasPassword, and
+// only the presence of a TLS configuration is reported, not its certificates
+// or keys.
+//
+// Where the server can override a requested value (MQTT v5.0), both the
+// requested and the negotiated values are reported. Negotiated values reflect
+// the most recent connection.
+type ClientConfig struct {
+	Server   string
+	ClientID string // Assigned by the server if none was requested (MQTT v5.0)
+	Username string
+
+	// HasPassword reports whether a password is configured.
+	HasPassword bool
+
+	// TLS reports whether a TLS configuration is set.
+	TLS bool
+
+	ProtocolVersion     uint8
+	AutoProtocolVersion bool
+
+	KeepAlive           time.Duration // Requested keepalive
+	NegotiatedKeepAlive time.Duration // Keepalive in use (may be overridden by the server)
+
+	CleanSession bool
+
+	SessionExpiryInterval           uint32 // Requested session expiry (seconds, MQTT v5.0)
+	NegotiatedSessionExpiryInterval uint32 // Session expiry in use (seconds, MQTT v5.0)
+
+	TopicAliasMaximum     uint16 // Inbound aliases this client accepts (MQTT v5.0)
+	ReceiveMaximum        uint16 // Inbound QoS 1/2 messages in flight (MQTT v5.0, 0 = 65535)
+	EagerAliasReestablish bool
+
+	AutoReconnect  bool
+	ConnectTimeout time.Duration
+
+	MaxTopicLength        int
+	MaxPayloadSize        int
+	MaxIncomingPacket     int
+	MaxHandlerConcurrency int
+	HandlerConcurrency    int
+	MaxSubscriptions      int
+
+	OutgoingQueueSize int
+	IncomingQueueSize int
+
+	HasWill          bool
+	HasSessionStore  bool
+	HasAuthenticator bool
+}
+
+// Config returns a snapshot of the effective client configuration.
+//
+// Use it to verify what configuration a running client actually has, for
+// example from a diagnostics endpoint. Credentials and TLS key material are
+// excluded.
+//
+// Example:
+//
+//	cfg := client.Config()
+//	log.Printf("mqtt: %s v%d keepalive=%s (negotiated %s)",
+//	    cfg.Server, cfg.ProtocolVersion, cfg.KeepAlive, cfg.NegotiatedKeepAlive)
+func (c *Client) Config() ClientConfig {
+	cfg := ClientConfig{
+		Server:                c.currentServer(),
+		ClientID:              c.opts.ClientID,
+		Username:              c.opts.Username,
+		HasPassword:           c.opts.Password != "",
+		TLS:                   c.tlsConfigured(),
+		ProtocolVersion:       c.opts.ProtocolVersion,
+		AutoProtocolVersion:   c.opts.AutoProtocolVersion,
+		KeepAlive:             c.requestedKeepAlive,
+		CleanSession:          c.opts.CleanSession,
+		SessionExpiryInterval: c.requestedSessionExpiry,
+		TopicAliasMaximum:     c.topicAliasMaximum(),
+		ReceiveMaximum:        c.opts.ReceiveMaximum,
+		EagerAliasReestablish: c.opts.EagerAliasReestablish,
+		AutoReconnect:         c.opts.AutoReconnect,
+		ConnectTimeout:        c.opts.ConnectTimeout,
+		MaxTopicLength:        c.opts.MaxTopicLength,
+		MaxPayloadSize:        c.opts.MaxPayloadSize,
+		MaxIncomingPacket:     c.opts.MaxIncomingPacket,
+		MaxHandlerConcurrency: c.opts.MaxHandlerConcurrency,
+		HandlerConcurrency:    c.opts.HandlerConcurrency,
+		MaxSubscriptions:      c.opts.MaxSubscriptions,
+		OutgoingQueueSize:     c.opts.OutgoingQueueSize,
+		IncomingQueueSize:     c.opts.IncomingQueueSize,
+		HasWill:               c.currentWill() != nil,
+		HasSessionStore:       c.opts.SessionStore != nil,
+		HasAuthenticator:      c.opts.Authenticator != nil,
+	}
+
+	if cfg.KeepAlive == 0 {
+		cfg.KeepAlive = c.opts.KeepAlive
+	}
+	if cfg.SessionExpiryInterval == 0 && c.opts.SessionExpirySet {
+		cfg.SessionExpiryInterval = c.opts.SessionExpiryInterval
+	}
+	cfg.NegotiatedKeepAlive = c.opts.KeepAlive
+	cfg.NegotiatedSessionExpiryInterval = c.sessionExpiryInterval
+
+	return cfg
+}
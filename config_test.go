@@ -0,0 +1,117 @@
+package mq
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestConfigSnapshot(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+			return
+		}
+		connack := &packets.ConnackPacket{
+			ReturnCode: 0,
+			Properties: &packets.Properties{
+				ServerKeepAlive:       20,
+				SessionExpiryInterval: 600,
+				Presence:              packets.PresServerKeepAlive | packets.PresSessionExpiryInterval,
+			},
+		}
+		if _, err := connack.WriteTo(conn); err != nil {
+			return
+		}
+		_, _ = packets.ReadPacket(conn, ProtocolV50, 0)
+	}()
+
+	const password = "s3cr3t-password"
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("config-client"),
+		WithCredentials("alice", password),
+		WithKeepAlive(45*time.Second),
+		WithCleanSession(false),
+		WithSessionExpiryInterval(3600),
+		WithTopicAliasMaximum(8),
+		WithReceiveMaximum(16, LimitPolicyIgnore),
+		WithAutoReconnect(false),
+		WithMaxSubscriptions(50),
+		WithConnectTimeout(3*time.Second),
+		WithWill("status/config-client", []byte("offline"), 1, true),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	cfg := client.Config()
+
+	checks := []struct {
+		name     string
+		got, exp any
+	}{
+		{"Server", cfg.Server, "tcp://" + ln.Addr().String()},
+		{"ClientID", cfg.ClientID, "config-client"},
+		{"Username", cfg.Username, "alice"},
+		{"HasPassword", cfg.HasPassword, true},
+		{"TLS", cfg.TLS, false},
+		{"ProtocolVersion", cfg.ProtocolVersion, ProtocolV50},
+		{"KeepAlive", cfg.KeepAlive, 45 * time.Second},
+		{"NegotiatedKeepAlive", cfg.NegotiatedKeepAlive, 20 * time.Second},
+		{"CleanSession", cfg.CleanSession, false},
+		{"SessionExpiryInterval", cfg.SessionExpiryInterval, uint32(3600)},
+		{"NegotiatedSessionExpiryInterval", cfg.NegotiatedSessionExpiryInterval, uint32(600)},
+		{"TopicAliasMaximum", cfg.TopicAliasMaximum, uint16(8)},
+		{"ReceiveMaximum", cfg.ReceiveMaximum, uint16(16)},
+		{"AutoReconnect", cfg.AutoReconnect, false},
+		{"ConnectTimeout", cfg.ConnectTimeout, 3 * time.Second},
+		{"MaxSubscriptions", cfg.MaxSubscriptions, 50},
+		{"HasWill", cfg.HasWill, true},
+		{"HasSessionStore", cfg.HasSessionStore, false},
+	}
+	for _, c := range checks {
+		if c.got != c.exp {
+			t.Errorf("%s: got %v, want %v", c.name, c.got, c.exp)
+		}
+	}
+
+	// The password must not leak through any field
+	if dump := fmt.Sprintf("%+v", cfg); strings.Contains(dump, password) {
+		t.Errorf("config snapshot contains the password: %s", dump)
+	}
+}
+
+func TestConfigExcludesTLSMaterial(t *testing.T) {
+	opts := defaultOptions("tls://localhost:8883")
+	WithTLS(&tls.Config{ServerName: "broker.example"})(opts)
+	c := newTestClient(opts)
+
+	cfg := c.Config()
+	if !cfg.TLS {
+		t.Error("expected TLS to be reported")
+	}
+	if cfg.KeepAlive != 60*time.Second {
+		t.Errorf("expected default keepalive before connecting, got %v", cfg.KeepAlive)
+	}
+	if cfg.NegotiatedKeepAlive != cfg.KeepAlive {
+		t.Errorf("expected negotiated keepalive to match requested before connecting, got %v", cfg.NegotiatedKeepAlive)
+	}
+}
@@ -0,0 +1,151 @@
+package mq
+
+import (
+	"fmt"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// ConnectRefusedError is returned by Dial and DialContext when the server
+// refuses the connection in its CONNACK.
+//
+// ReasonCode uses MQTT v5.0 values for both protocol versions: the MQTT v3.1.1
+// return codes are translated to their v5.0 equivalents (e.g. return code 5
+// "not authorized" becomes ReasonCodeNotAuthorized), so applications can handle
+// refusals uniformly.
+//
+// The error wraps ErrConnectionRefused and, where one exists, the matching
+// sentinel error (ErrBadUsernameOrPassword, ErrNotAuthorized,
+// ErrServerUnavailable, ErrIdentifierRejected, ErrUnacceptableProtocolVersion),
+// so existing errors.Is checks keep working. For MQTT v5.0 it also wraps an
+// *MqttError carrying the raw reason code.
+//
+// Example:
+//
+//	client, err := mq.Dial(uri)
+//	var refused *mq.ConnectRefusedError
+//	if errors.As(err, &refused) && !refused.RetryAdvised() {
+//	    log.Fatalf("permanent failure: %v", refused)
+//	}
+type ConnectRefusedError struct {
+	// ReasonCode is the (v5.0 equivalent) refusal reason.
+	ReasonCode ReasonCode
+
+	// Message is a human-readable description. It is the server's Reason
+	// String when one was sent (MQTT v5.0), otherwise the name of the code.
+	Message string
+
+	// ServerReference is the other server the client should use, sent with
+	// ReasonCodeUseAnotherServer or ReasonCodeServerMoved (MQTT v5.0).
+	// Empty if not set.
+	ServerReference string
+
+	sentinel error
+	mqttErr  *MqttError
+}
+
+func (e *ConnectRefusedError) Error() string {
+	return fmt.Sprintf("connection refused (0x%02X): %s", uint8(e.ReasonCode), e.Message)
+}
+
+// Unwrap returns ErrConnectionRefused, the matching sentinel error (if any),
+// and the underlying *MqttError for MQTT v5.0 refusals.
+func (e *ConnectRefusedError) Unwrap() []error {
+	errs := []error{ErrConnectionRefused}
+	if e.sentinel != nil {
+		errs = append(errs, e.sentinel)
+	}
+	if e.mqttErr != nil {
+		errs = append(errs, e.mqttErr)
+	}
+	return errs
+}
+
+// Is implements the errors.Is interface, allowing checks against ReasonCode constants.
+func (e *ConnectRefusedError) Is(target error) bool {
+	if rc, ok := target.(ReasonCode); ok {
+		return e.ReasonCode == rc
+	}
+	return false
+}
+
+// RetryAdvised reports whether retrying the connection later may succeed.
+//
+// It returns false for permanent failures that need a configuration change
+// (bad credentials, not authorized, banned, invalid client identifier,
+// unsupported protocol or features) and true for transient conditions
+// (server unavailable or busy, quota or connection rate exceeded, redirects,
+// and unspecified errors).
+func (e *ConnectRefusedError) RetryAdvised() bool {
+	switch e.ReasonCode {
+	case ReasonCodeUnspecifiedError,
+		ReasonCodeImplementationError,
+		ReasonCodeServerMovedConnack, // 0x88 Server unavailable
+		ReasonCodeServerBusy,
+		ReasonCodeQuotaExceeded,
+		ReasonCodeUseAnotherServer,
+		ReasonCodeServerMoved,
+		ReasonCodeConnectionRateExceed:
+		return true
+	default:
+		return false
+	}
+}
+
+// v311ConnackReasonCodes translates MQTT v3.1.1 CONNACK return codes to
+// their MQTT v5.0 equivalents.
+var v311ConnackReasonCodes = map[uint8]ReasonCode{
+	packets.ConnRefusedUnacceptableProtocol:  ReasonCodeUnsupportedProtocol,
+	packets.ConnRefusedIdentifierRejected:    ReasonCodeClientIdentifierInvalid,
+	packets.ConnRefusedServerUnavailable:     ReasonCodeServerMovedConnack,
+	packets.ConnRefusedBadUsernameOrPassword: ReasonCodeBadUsernameOrPassword,
+	packets.ConnRefusedNotAuthorized:         ReasonCodeNotAuthorized,
+}
+
+// connectRefusedSentinels maps refusal reasons to the legacy sentinel errors.
+var connectRefusedSentinels = map[ReasonCode]error{
+	ReasonCode(packets.ConnRefusedUnacceptableProtocol): ErrUnacceptableProtocolVersion, // v3 code sent by a v5 server
+	ReasonCodeUnsupportedProtocol:                       ErrUnacceptableProtocolVersion,
+	ReasonCodeClientIdentifierInvalid:                   ErrIdentifierRejected,
+	ReasonCodeServerMovedConnack:                        ErrServerUnavailable,
+	ReasonCodeBadUsernameOrPassword:                     ErrBadUsernameOrPassword,
+	ReasonCodeNotAuthorized:                             ErrNotAuthorized,
+}
+
+// newConnectRefusedError builds the error for a refused CONNACK.
+func newConnectRefusedError(connack *packets.ConnackPacket, version uint8) *ConnectRefusedError {
+	code := ReasonCode(connack.ReturnCode)
+	if version < ProtocolV50 {
+		if v5, ok := v311ConnackReasonCodes[connack.ReturnCode]; ok {
+			code = v5
+		}
+	}
+
+	e := &ConnectRefusedError{
+		ReasonCode: code,
+		sentinel:   connectRefusedSentinels[code],
+	}
+
+	if version >= ProtocolV50 {
+		e.mqttErr = &MqttError{ReasonCode: code, Parent: ErrConnectionRefused}
+		if connack.Properties != nil && connack.Properties.Presence&packets.PresReasonString != 0 {
+			e.Message = connack.Properties.ReasonString
+			e.mqttErr.Message = e.Message
+		}
+		if connack.Properties != nil && connack.Properties.Presence&packets.PresServerReference != 0 {
+			e.ServerReference = connack.Properties.ServerReference
+		}
+	}
+
+	if e.Message == "" {
+		if e.sentinel != nil {
+			e.Message = e.sentinel.Error()
+		} else if name, ok := disconnectReasonCodeNames[code]; ok {
+			e.Message = name
+		} else {
+			e.Message = fmt.Sprintf("code %d", connack.ReturnCode)
+		}
+	}
+
+	return e
+}
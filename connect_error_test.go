@@ -0,0 +1,110 @@
+package mq
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestConnectRefusedError(t *testing.T) {
+	tests := []struct {
+		name         string
+		version      uint8
+		returnCode   uint8
+		wantCode     ReasonCode
+		wantSentinel error
+		wantRetry    bool
+	}{
+		// MQTT v3.1.1 return codes
+		{"v3 unacceptable protocol", ProtocolV311, packets.ConnRefusedUnacceptableProtocol, ReasonCodeUnsupportedProtocol, ErrUnacceptableProtocolVersion, false},
+		{"v3 identifier rejected", ProtocolV311, packets.ConnRefusedIdentifierRejected, ReasonCodeClientIdentifierInvalid, ErrIdentifierRejected, false},
+		{"v3 server unavailable", ProtocolV311, packets.ConnRefusedServerUnavailable, ReasonCodeServerMovedConnack, ErrServerUnavailable, true},
+		{"v3 bad username or password", ProtocolV311, packets.ConnRefusedBadUsernameOrPassword, ReasonCodeBadUsernameOrPassword, ErrBadUsernameOrPassword, false},
+		{"v3 not authorized", ProtocolV311, packets.ConnRefusedNotAuthorized, ReasonCodeNotAuthorized, ErrNotAuthorized, false},
+		{"v3 unknown code", ProtocolV311, 42, ReasonCode(42), nil, false},
+
+		// MQTT v5.0 reason codes
+		{"v5 unspecified error", ProtocolV50, 0x80, ReasonCodeUnspecifiedError, nil, true},
+		{"v5 malformed packet", ProtocolV50, 0x81, ReasonCodeMalformedPacket, nil, false},
+		{"v5 protocol error", ProtocolV50, 0x82, ReasonCodeProtocolError, nil, false},
+		{"v5 implementation specific", ProtocolV50, 0x83, ReasonCodeImplementationError, nil, true},
+		{"v5 unsupported protocol", ProtocolV50, 0x84, ReasonCodeUnsupportedProtocol, ErrUnacceptableProtocolVersion, false},
+		{"v5 v3-style unacceptable protocol", ProtocolV50, 0x01, ReasonCode(0x01), ErrUnacceptableProtocolVersion, false},
+		{"v5 client identifier invalid", ProtocolV50, 0x85, ReasonCodeClientIdentifierInvalid, ErrIdentifierRejected, false},
+		{"v5 bad username or password", ProtocolV50, 0x86, ReasonCodeBadUsernameOrPassword, ErrBadUsernameOrPassword, false},
+		{"v5 not authorized", ProtocolV50, 0x87, ReasonCodeNotAuthorized, ErrNotAuthorized, false},
+		{"v5 server unavailable", ProtocolV50, 0x88, ReasonCodeServerMovedConnack, ErrServerUnavailable, true},
+		{"v5 server busy", ProtocolV50, 0x89, ReasonCodeServerBusy, nil, true},
+		{"v5 banned", ProtocolV50, 0x8A, ReasonCodeBanned, nil, false},
+		{"v5 bad authentication method", ProtocolV50, 0x8C, ReasonCodeBadAuthenticationMethod, nil, false},
+		{"v5 packet too large", ProtocolV50, 0x95, ReasonCodePacketTooLarge, nil, false},
+		{"v5 quota exceeded", ProtocolV50, 0x97, ReasonCodeQuotaExceeded, nil, true},
+		{"v5 QoS not supported", ProtocolV50, 0x9B, ReasonCodeQoSNotSupported, nil, false},
+		{"v5 use another server", ProtocolV50, 0x9C, ReasonCodeUseAnotherServer, nil, true},
+		{"v5 server moved", ProtocolV50, 0x9D, ReasonCodeServerMoved, nil, true},
+		{"v5 connection rate exceeded", ProtocolV50, 0x9F, ReasonCodeConnectionRateExceed, nil, true},
+	}
+
+	sentinels := []error{
+		ErrUnacceptableProtocolVersion,
+		ErrIdentifierRejected,
+		ErrServerUnavailable,
+		ErrBadUsernameOrPassword,
+		ErrNotAuthorized,
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error = newConnectRefusedError(&packets.ConnackPacket{ReturnCode: tt.returnCode}, tt.version)
+
+			var refused *ConnectRefusedError
+			if !errors.As(err, &refused) {
+				t.Fatalf("expected *ConnectRefusedError, got %T", err)
+			}
+			if refused.ReasonCode != tt.wantCode {
+				t.Errorf("expected reason code 0x%02X, got 0x%02X", uint8(tt.wantCode), uint8(refused.ReasonCode))
+			}
+			if refused.RetryAdvised() != tt.wantRetry {
+				t.Errorf("expected RetryAdvised=%v, got %v", tt.wantRetry, refused.RetryAdvised())
+			}
+			if refused.Message == "" {
+				t.Error("expected a human-readable message")
+			}
+
+			if !errors.Is(err, ErrConnectionRefused) {
+				t.Error("expected errors.Is(err, ErrConnectionRefused)")
+			}
+			if !errors.Is(err, tt.wantCode) {
+				t.Errorf("expected errors.Is(err, 0x%02X)", uint8(tt.wantCode))
+			}
+			for _, s := range sentinels {
+				if got := errors.Is(err, s); got != (s == tt.wantSentinel) {
+					t.Errorf("errors.Is(err, %q) = %v", s, got)
+				}
+			}
+
+			var mqErr *MqttError
+			if got := errors.As(err, &mqErr); got != (tt.version >= ProtocolV50) {
+				t.Errorf("errors.As(*MqttError) = %v for version %d", got, tt.version)
+			}
+		})
+	}
+}
+
+func TestConnectRefusedErrorReasonString(t *testing.T) {
+	err := newConnectRefusedError(&packets.ConnackPacket{
+		ReturnCode: 0x87,
+		Properties: &packets.Properties{
+			ReasonString: "tenant suspended",
+			Presence:     packets.PresReasonString,
+		},
+	}, ProtocolV50)
+
+	if err.Message != "tenant suspended" {
+		t.Errorf("expected server reason string, got %q", err.Message)
+	}
+	if want := "connection refused (0x87): tenant suspended"; err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+}
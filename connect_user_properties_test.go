@@ -1,8 +1,10 @@
 package mq
 
 import (
+	"context"
 	"io"
 	"log/slog"
+	"net"
 	"testing"
 	"time"
 
@@ -135,3 +137,127 @@ func TestConnackUserProperties(t *testing.T) {
 		t.Error("ConnectionUserProperties should be nil for v3.1.1")
 	}
 }
+
+func TestConnackReasonStringOnSuccess(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+			return
+		}
+		connack := &packets.ConnackPacket{
+			ReturnCode: 0,
+			Properties: &packets.Properties{
+				ReasonString: "Welcome, rate limit: 1000 msg/s",
+				Presence:     packets.PresReasonString,
+				UserProperties: []packets.UserProperty{
+					{Key: "tier", Value: "gold"},
+				},
+			},
+		}
+		if _, err := connack.WriteTo(conn); err != nil {
+			return
+		}
+		_, _ = packets.ReadPacket(conn, ProtocolV50, 0)
+	}()
+
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("connack-info"),
+		WithAutoReconnect(false),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	if got := client.ConnectReasonString(); got != "Welcome, rate limit: 1000 msg/s" {
+		t.Errorf("ConnectReasonString() = %q", got)
+	}
+	if got := client.ConnectionUserProperties()["tier"]; got != "gold" {
+		t.Errorf("ConnectionUserProperties()[tier] = %q, want gold", got)
+	}
+
+	// A later CONNACK without them (e.g. after reconnect) clears stale values
+	_ = client.processConnackProperties(&packets.ConnackPacket{Properties: &packets.Properties{}})
+	if got := client.ConnectReasonString(); got != "" {
+		t.Errorf("expected reason string to be cleared, got %q", got)
+	}
+	if got := client.ConnectionUserProperties(); got != nil {
+		t.Errorf("expected user properties to be cleared, got %v", got)
+	}
+}
+
+func TestConnackProperties(t *testing.T) {
+	c := &Client{
+		opts: &clientOptions{
+			ProtocolVersion: ProtocolV50,
+			Logger:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+	}
+	if props := c.ConnackProperties(); props != nil {
+		t.Fatalf("ConnackProperties() before connecting = %+v, want nil", props)
+	}
+
+	c.processConnackProperties(&packets.ConnackPacket{
+		Properties: &packets.Properties{
+			ReasonString:             "welcome",
+			ResponseInformation:      "responses/abc",
+			AssignedClientIdentifier: "auto-123",
+			Presence: packets.PresReasonString | packets.PresResponseInformation |
+				packets.PresAssignedClientIdentifier,
+			UserProperties: []packets.UserProperty{
+				{Key: "tenant-shard", Value: "7"},
+			},
+		},
+	})
+
+	props := c.ConnackProperties()
+	if props == nil {
+		t.Fatal("ConnackProperties() = nil")
+	}
+	if props.ReasonString != "welcome" {
+		t.Errorf("ReasonString = %q, want welcome", props.ReasonString)
+	}
+	if props.ResponseInformation != "responses/abc" {
+		t.Errorf("ResponseInformation = %q, want responses/abc", props.ResponseInformation)
+	}
+	if props.AssignedClientIdentifier != "auto-123" {
+		t.Errorf("AssignedClientIdentifier = %q, want auto-123", props.AssignedClientIdentifier)
+	}
+	if got := props.GetUserProperty("tenant-shard"); got != "7" {
+		t.Errorf("user property tenant-shard = %q, want 7", got)
+	}
+
+	// The result is a copy
+	props.SetUserProperty("tenant-shard", "changed")
+	if got := c.ConnackProperties().GetUserProperty("tenant-shard"); got != "7" {
+		t.Errorf("modifying the result changed the stored properties: %q", got)
+	}
+
+	// A reconnect replaces them
+	c.processConnackProperties(&packets.ConnackPacket{
+		Properties: &packets.Properties{
+			ServerReference: "tcp://other:1883",
+			Presence:        packets.PresServerReference,
+		},
+	})
+	props = c.ConnackProperties()
+	if props == nil || props.ServerReference != "tcp://other:1883" || props.ReasonString != "" {
+		t.Errorf("ConnackProperties() after reconnect = %+v", props)
+	}
+
+	c.opts.ProtocolVersion = ProtocolV311
+	c.processConnackProperties(&packets.ConnackPacket{})
+	if props := c.ConnackProperties(); props != nil {
+		t.Errorf("ConnackProperties() for v3.1.1 = %+v, want nil", props)
+	}
+}
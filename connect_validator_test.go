@@ -0,0 +1,136 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestConnectValidatorRejectsServer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	gotDisconnect := make(chan bool, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+			return
+		}
+
+		connack := &packets.ConnackPacket{
+			ReturnCode: 0,
+			Properties: &packets.Properties{
+				MaximumQoS: 1,
+				Presence:   packets.PresMaximumQoS,
+				UserProperties: []packets.UserProperty{
+					{Key: "tier", Value: "basic"},
+				},
+			},
+		}
+		if _, err := connack.WriteTo(conn); err != nil {
+			return
+		}
+
+		pkt, err := packets.ReadPacket(conn, ProtocolV50, 0)
+		_, ok := pkt.(*packets.DisconnectPacket)
+		gotDisconnect <- err == nil && ok
+	}()
+
+	errNeedQoS2 := errors.New("broker must support QoS 2")
+	var seenTier string
+
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("validator"),
+		WithConnectTimeout(2*time.Second),
+		WithAutoReconnect(false),
+		WithConnectValidator(func(caps ServerCapabilities, props *Properties) error {
+			seenTier = props.UserProperties["tier"]
+			if caps.MaximumQoS < 2 {
+				return errNeedQoS2
+			}
+			return nil
+		}),
+	)
+	if err == nil {
+		_ = client.Disconnect(context.Background())
+		t.Fatal("expected Dial to fail")
+	}
+	if !errors.Is(err, errNeedQoS2) {
+		t.Fatalf("expected validator error, got %v", err)
+	}
+	if seenTier != "basic" {
+		t.Errorf("expected validator to see CONNACK user property, got %q", seenTier)
+	}
+
+	select {
+	case ok := <-gotDisconnect:
+		if !ok {
+			t.Error("expected DISCONNECT before the connection was closed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("timeout waiting for DISCONNECT")
+	}
+}
+
+func TestConnectValidatorAccepts(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if _, err := packets.ReadPacket(conn, ProtocolV311, 0); err != nil {
+			return
+		}
+		connack := &packets.ConnackPacket{ReturnCode: 0}
+		_, _ = connack.WriteTo(conn)
+
+		// Keep the connection open until the client goes away
+		_, _ = packets.ReadPacket(conn, ProtocolV311, 0)
+	}()
+
+	called := false
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("validator"),
+		WithProtocolVersion(ProtocolV311),
+		WithConnectTimeout(2*time.Second),
+		WithAutoReconnect(false),
+		WithConnectValidator(func(_ ServerCapabilities, props *Properties) error {
+			called = true
+			if props == nil {
+				t.Error("expected non-nil properties")
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	if !called {
+		t.Error("validator was not called")
+	}
+	if !client.IsConnected() {
+		t.Error("expected client to be connected")
+	}
+}
@@ -0,0 +1,58 @@
+package mq
+
+import "time"
+
+// notifyConnectionLost calls OnConnectionLost, deferring it by the configured
+// grace period so that a quick reconnect can cancel it.
+func (c *Client) notifyConnectionLost(reason error) {
+	grace := c.opts.ConnectionLostGracePeriod
+	if grace <= 0 || !c.opts.AutoReconnect {
+		if c.opts.OnConnectionLost != nil {
+			go c.opts.OnConnectionLost(c, reason)
+		}
+		return
+	}
+
+	c.connLostLock.Lock()
+	defer c.connLostLock.Unlock()
+
+	if c.connLostTimer != nil {
+		return // Still within the grace period of an earlier loss
+	}
+
+	var t *time.Timer
+	t = time.AfterFunc(grace, func() {
+		c.connLostLock.Lock()
+		if c.connLostTimer != t {
+			c.connLostLock.Unlock()
+			return // Cancelled by a reconnect
+		}
+		c.connLostTimer = nil
+		c.connLostLock.Unlock()
+
+		if c.disconnectRequested.Load() {
+			return // Disconnect was called meanwhile
+		}
+		c.opts.Logger.Debug("connection still down after grace period", "grace_period", grace)
+		if c.opts.OnConnectionLost != nil {
+			c.opts.OnConnectionLost(c, reason)
+		}
+	})
+	c.connLostTimer = t
+}
+
+// cancelConnectionLost cancels a deferred OnConnectionLost notification.
+// It reports whether one was pending, i.e. whether the reconnect happened
+// within the grace period and should be transparent.
+func (c *Client) cancelConnectionLost() bool {
+	c.connLostLock.Lock()
+	defer c.connLostLock.Unlock()
+
+	t := c.connLostTimer
+	if t == nil {
+		return false
+	}
+	c.connLostTimer = nil
+	t.Stop()
+	return true
+}
@@ -0,0 +1,153 @@
+package mq
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// serveDroppingConnection accepts connections on ln and acknowledges them.
+// The first connection is closed shortly after CONNACK; later ones stay open.
+func serveDroppingConnection(ln net.Listener, accepted chan<- int) {
+	for n := 1; ; n++ {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn, n int) {
+			defer conn.Close()
+			if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+				return
+			}
+			connack := &packets.ConnackPacket{ReturnCode: 0}
+			if _, err := connack.WriteTo(conn); err != nil {
+				return
+			}
+			accepted <- n
+			if n == 1 {
+				time.Sleep(50 * time.Millisecond)
+				return
+			}
+			for {
+				if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+					return
+				}
+			}
+		}(conn, n)
+	}
+}
+
+func TestConnectionLostGracePeriod_TransparentReconnect(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the reconnect backoff")
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	accepted := make(chan int, 4)
+	go serveDroppingConnection(ln, accepted)
+
+	var lost, connects atomic.Int32
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("grace"),
+		WithConnectionLostGracePeriod(10*time.Second),
+		WithOnConnectionLost(func(_ *Client, _ error) { lost.Add(1) }),
+		WithOnConnect(func(_ *Client) { connects.Add(1) }),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	for want := 1; want <= 2; want++ {
+		select {
+		case n := <-accepted:
+			if n != want {
+				t.Fatalf("expected connection %d, got %d", want, n)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for connection %d", want)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !client.IsConnected() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !client.IsConnected() {
+		t.Fatal("client did not reconnect")
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if got := lost.Load(); got != 0 {
+		t.Errorf("expected OnConnectionLost to be suppressed, called %d times", got)
+	}
+	if got := connects.Load(); got != 1 {
+		t.Errorf("expected OnConnect only for the initial connection, called %d times", got)
+	}
+}
+
+func TestConnectionLostGracePeriod_FiresWhenStillDown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accepted := make(chan int, 4)
+	go serveDroppingConnection(ln, accepted)
+
+	const grace = 200 * time.Millisecond
+	lost := make(chan time.Time, 1)
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("grace-down"),
+		WithConnectionLostGracePeriod(grace),
+		WithOnConnectionLost(func(_ *Client, _ error) { lost <- time.Now() }),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	<-accepted
+	ln.Close() // Reconnects will fail
+
+	deadline := time.Now().Add(2 * time.Second)
+	for client.IsConnected() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	droppedAt := time.Now()
+
+	select {
+	case at := <-lost:
+		if elapsed := at.Sub(droppedAt); elapsed < grace-20*time.Millisecond {
+			t.Errorf("OnConnectionLost fired after %v, expected at least %v", elapsed, grace)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("expected OnConnectionLost after the grace period")
+	}
+}
+
+func TestConnectionLostGracePeriod_CancelledByDisconnect(t *testing.T) {
+	var lost atomic.Int32
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	opts.ConnectionLostGracePeriod = 50 * time.Millisecond
+	opts.OnConnectionLost = func(*Client, error) { lost.Add(1) }
+	c := newTestClient(opts)
+
+	c.notifyConnectionLost(ErrClientDisconnected)
+	_ = c.Disconnect(context.Background())
+
+	time.Sleep(150 * time.Millisecond)
+	if n := lost.Load(); n != 0 {
+		t.Errorf("OnConnectionLost called %d times after Disconnect, want 0", n)
+	}
+}
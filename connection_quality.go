@@ -0,0 +1,154 @@
+package mq
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// qualityDisconnectWindow is how far back disconnects count against
+	// the stability factor.
+	qualityDisconnectWindow = 10 * time.Minute
+
+	// qualityMaxDisconnects bounds the number of remembered disconnects.
+	qualityMaxDisconnects = 64
+
+	// qualityGoodLatency is the publish round trip at or below which the
+	// latency factor is perfect.
+	qualityGoodLatency = 100 * time.Millisecond
+)
+
+// ConnectionQualityWeights sets how much each factor contributes to the
+// ConnectionQuality score. Weights are relative: they are normalized by
+// their sum, so {2, 1, 1} and {0.5, 0.25, 0.25} are equivalent. A weight
+// of 0 excludes the factor.
+type ConnectionQualityWeights struct {
+	Stability   float64 // Recent disconnects
+	Latency     float64 // Publish round-trip latency
+	PingSuccess float64 // Keepalive pings answered in time
+}
+
+// DefaultConnectionQualityWeights are used unless overridden with
+// WithConnectionQualityWeights.
+var DefaultConnectionQualityWeights = ConnectionQualityWeights{
+	Stability:   0.4,
+	Latency:     0.3,
+	PingSuccess: 0.3,
+}
+
+// ConnectionQuality is a summary of connection health returned by
+// Client.ConnectionQuality, with the factors that produced it.
+type ConnectionQuality struct {
+	// Score is the overall quality, from 0 (unusable) to 100 (perfect).
+	// It is always 0 while disconnected.
+	Score int
+
+	Connected bool
+
+	// Raw inputs
+	ReconnectCount    uint64        // Reconnection attempts since Dial
+	RecentDisconnects int           // Disconnects in the last 10 minutes
+	PublishLatency    time.Duration // Moving average, 0 if no samples
+	PingsSent         uint64
+	PingsFailed       uint64 // Keepalive timeouts with a PINGREQ outstanding
+
+	// Factor scores in [0, 1], 1 being perfect
+	StabilityScore   float64
+	LatencyScore     float64
+	PingSuccessScore float64
+}
+
+// ConnectionQuality returns a 0-100 score summarizing connection health,
+// along with the factors that contributed to it.
+//
+// Applications can use it as a single signal to degrade gracefully, for
+// example reducing the publish rate or deferring bulk uploads while the
+// score is low.
+//
+// The score is a weighted average of three factors, each in [0, 1]:
+//
+//   - Stability: 1 / (1 + n), where n is the number of disconnects in the
+//     last 10 minutes. One recent drop halves it, three quarter it.
+//   - Latency: 1 while the publish latency average (see PublishLatency) is
+//     at or below 100ms, then 100ms / latency (0.5 at 200ms, 0.1 at 1s).
+//     1 if no QoS 1/2 publish has completed yet.
+//   - Ping success: the fraction of keepalive PINGREQs that did not end in
+//     a keepalive timeout. 1 if no ping has been sent yet.
+//
+// The weights default to DefaultConnectionQualityWeights and can be changed
+// with WithConnectionQualityWeights. While disconnected the score is 0,
+// regardless of the factors.
+//
+// Example:
+//
+//	if q := client.ConnectionQuality(); q.Score < 50 {
+//	    log.Printf("poor connection (%d): %d drops, latency %s",
+//	        q.Score, q.RecentDisconnects, q.PublishLatency)
+//	    rate.SetLimit(rate.Limit() / 2)
+//	}
+func (c *Client) ConnectionQuality() ConnectionQuality {
+	q := ConnectionQuality{
+		Connected:         c.IsConnected(),
+		ReconnectCount:    c.reconnectCount.Load(),
+		RecentDisconnects: c.recentDisconnectCount(time.Now()),
+		PublishLatency:    c.publishLatency.value(),
+		PingsSent:         c.pingsSent.Load(),
+		PingsFailed:       c.pingsFailed.Load(),
+	}
+
+	q.StabilityScore = 1 / float64(1+q.RecentDisconnects)
+
+	q.LatencyScore = 1
+	if q.PublishLatency > qualityGoodLatency {
+		q.LatencyScore = float64(qualityGoodLatency) / float64(q.PublishLatency)
+	}
+
+	q.PingSuccessScore = 1
+	if q.PingsSent > 0 {
+		failed := min(q.PingsFailed, q.PingsSent)
+		q.PingSuccessScore = float64(q.PingsSent-failed) / float64(q.PingsSent)
+	}
+
+	if !q.Connected {
+		return q
+	}
+
+	w := c.opts.QualityWeights
+	total := w.Stability + w.Latency + w.PingSuccess
+	if total <= 0 {
+		w = DefaultConnectionQualityWeights
+		total = w.Stability + w.Latency + w.PingSuccess
+	}
+	score := (w.Stability*q.StabilityScore +
+		w.Latency*q.LatencyScore +
+		w.PingSuccess*q.PingSuccessScore) / total
+	q.Score = int(math.Round(100 * score))
+
+	return q
+}
+
+// recordDisconnect remembers a connection loss for the stability factor.
+func (c *Client) recordDisconnect(at time.Time) {
+	c.qualityLock.Lock()
+	defer c.qualityLock.Unlock()
+
+	c.disconnectTimes = append(c.disconnectTimes, at)
+	if len(c.disconnectTimes) > qualityMaxDisconnects {
+		c.disconnectTimes = c.disconnectTimes[len(c.disconnectTimes)-qualityMaxDisconnects:]
+	}
+}
+
+// recentDisconnectCount returns the number of disconnects within
+// qualityDisconnectWindow of now, forgetting older ones.
+func (c *Client) recentDisconnectCount(now time.Time) int {
+	c.qualityLock.Lock()
+	defer c.qualityLock.Unlock()
+
+	cutoff := now.Add(-qualityDisconnectWindow)
+	i := 0
+	for i < len(c.disconnectTimes) && c.disconnectTimes[i].Before(cutoff) {
+		i++
+	}
+	c.disconnectTimes = c.disconnectTimes[i:]
+	return len(c.disconnectTimes)
+}
@@ -0,0 +1,94 @@
+package mq
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConnectionQuality(t *testing.T) {
+	c := newTestClient(nil)
+	c.connected.Store(true)
+
+	q := c.ConnectionQuality()
+	if q.Score != 100 {
+		t.Fatalf("expected perfect score for a fresh connection, got %d (%+v)", q.Score, q)
+	}
+
+	// A couple of recent drops hurt stability
+	now := time.Now()
+	c.recordDisconnect(now.Add(-time.Minute))
+	c.recordDisconnect(now.Add(-30 * time.Second))
+	c.reconnectCount.Add(2)
+	afterDrops := c.ConnectionQuality()
+	if afterDrops.RecentDisconnects != 2 || afterDrops.ReconnectCount != 2 {
+		t.Errorf("expected 2 recent disconnects and reconnects, got %+v", afterDrops)
+	}
+	if afterDrops.Score >= q.Score {
+		t.Errorf("expected disconnects to lower the score, got %d -> %d", q.Score, afterDrops.Score)
+	}
+
+	// High latency degrades it further
+	for range 20 {
+		c.publishLatency.observe(time.Second)
+	}
+	afterLatency := c.ConnectionQuality()
+	if afterLatency.LatencyScore > 0.11 {
+		t.Errorf("expected latency score ~0.1 at 1s, got %v", afterLatency.LatencyScore)
+	}
+	if afterLatency.Score >= afterDrops.Score {
+		t.Errorf("expected high latency to lower the score, got %d -> %d", afterDrops.Score, afterLatency.Score)
+	}
+
+	// As do failed pings
+	c.pingsSent.Add(4)
+	c.pingsFailed.Add(2)
+	afterPings := c.ConnectionQuality()
+	if afterPings.PingSuccessScore != 0.5 {
+		t.Errorf("expected ping success 0.5, got %v", afterPings.PingSuccessScore)
+	}
+	if afterPings.Score >= afterLatency.Score {
+		t.Errorf("expected failed pings to lower the score, got %d -> %d", afterLatency.Score, afterPings.Score)
+	}
+
+	// Old disconnects are forgotten
+	c.disconnectTimes = []time.Time{now.Add(-time.Hour)}
+	if got := c.ConnectionQuality().RecentDisconnects; got != 0 {
+		t.Errorf("expected disconnects outside the window to be dropped, got %d", got)
+	}
+
+	// Disconnected means 0, whatever the factors
+	c.connected.Store(false)
+	if got := c.ConnectionQuality().Score; got != 0 {
+		t.Errorf("expected score 0 while disconnected, got %d", got)
+	}
+}
+
+func TestConnectionQualityWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights ConnectionQualityWeights
+		want    int
+	}{
+		// Stability 1/(1+1) = 0.5, latency 100/200 = 0.5, pings 1.0
+		{"defaults", ConnectionQualityWeights{}, 65},
+		{"latency only", ConnectionQualityWeights{Latency: 1}, 50},
+		{"pings only", ConnectionQualityWeights{PingSuccess: 1}, 100},
+		{"relative", ConnectionQualityWeights{Stability: 2, PingSuccess: 2}, 75},
+		{"negative ignored", ConnectionQualityWeights{Stability: -1, PingSuccess: 1}, 65},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultOptions("tcp://localhost:1883")
+			WithConnectionQualityWeights(tt.weights)(opts)
+			c := newTestClient(opts)
+			c.connected.Store(true)
+			c.recordDisconnect(time.Now())
+			c.publishLatency.observe(200 * time.Millisecond)
+
+			if got := c.ConnectionQuality().Score; got != tt.want {
+				t.Errorf("Score = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
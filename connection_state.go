@@ -0,0 +1,112 @@
+package mq
+
+import "fmt"
+
+// stateChangesBuffer is the capacity of the StateChanges channel.
+const stateChangesBuffer = 16
+
+// ConnectionState is the connection state of a Client, as reported by
+// StateChanges.
+type ConnectionState int
+
+const (
+	// StateDisconnected means the client has no connection: it was lost and
+	// automatic reconnection is disabled or has given up, or the client was
+	// disconnected.
+	StateDisconnected ConnectionState = iota
+
+	// StateConnecting means the initial connection (Dial) is in progress.
+	StateConnecting
+
+	// StateConnected means the client is connected and the session is usable.
+	StateConnected
+
+	// StateReconnecting means the connection was lost and the client is
+	// trying to reconnect (automatic reconnection), including the backoff
+	// delay before each attempt.
+	StateReconnecting
+)
+
+// String returns the name of the state.
+func (s ConnectionState) String() string {
+	switch s {
+	case StateDisconnected:
+		return "disconnected"
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateReconnecting:
+		return "reconnecting"
+	}
+	return fmt.Sprintf("ConnectionState(%d)", int(s))
+}
+
+// StateChanges returns a channel that receives the client's connection state
+// every time it changes, so a single consumer can observe the connection
+// without combining OnConnect, OnConnectionLost and IsConnected.
+//
+// A typical sequence is StateConnecting and StateConnected during Dial (both
+// are delivered once the consumer starts reading), then StateDisconnected
+// when the connection is lost, StateReconnecting while automatic
+// reconnection retries, and StateConnected again once it succeeds. Repeated
+// failed attempts do not repeat StateReconnecting.
+//
+// The channel is buffered. If the consumer falls behind, the oldest pending
+// states are dropped in favor of newer ones, so the last value received is
+// always the current state. The channel is closed when the client stops,
+// after Disconnect or when reconnection gives up (see
+// WithMaxReconnectAttempts).
+//
+// Every call returns the same channel: with several readers, each state is
+// received by only one of them.
+//
+// Example:
+//
+//	go func() {
+//	    for state := range client.StateChanges() {
+//	        indicator.Set(state.String())
+//	    }
+//	    indicator.Set("stopped")
+//	}()
+func (c *Client) StateChanges() <-chan ConnectionState {
+	return c.stateCh
+}
+
+// setState records a connection state change and publishes it on the
+// StateChanges channel, dropping the oldest pending state if it is full.
+func (c *Client) setState(s ConnectionState) {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	if c.stateCh == nil || c.stateClosed || c.state == s {
+		return
+	}
+	c.state = s
+
+	for {
+		select {
+		case c.stateCh <- s:
+			return
+		default:
+		}
+		select {
+		case <-c.stateCh:
+		default:
+		}
+	}
+}
+
+// closeStateChanges reports the client as disconnected, then closes the
+// StateChanges channel.
+func (c *Client) closeStateChanges() {
+	c.setState(StateDisconnected)
+
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	if c.stateCh != nil && !c.stateClosed {
+		c.stateClosed = true
+		close(c.stateCh)
+	}
+}
@@ -0,0 +1,109 @@
+package mq
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStateChanges_Lifecycle(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// Connection 1 drops, 2 is refused, 3 stays up
+	accepted := make(chan int, 16)
+	go serveScripted(ln, func(n int) bool { return n != 2 }, 3, accepted)
+
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("state-changes"),
+		WithReconnectBackoff(10*time.Millisecond, 10*time.Millisecond, 1),
+		WithLogger(testLogger()),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+
+	states := client.StateChanges()
+	next := func() (ConnectionState, bool) {
+		t.Helper()
+		select {
+		case s, ok := <-states:
+			return s, ok
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for a state change")
+			return 0, false
+		}
+	}
+
+	want := []ConnectionState{
+		StateConnecting,
+		StateConnected,
+		StateDisconnected,
+		StateReconnecting, // Kept through the refused attempt
+		StateConnected,
+	}
+	for i, w := range want {
+		if got, _ := next(); got != w {
+			t.Fatalf("state %d = %v, want %v", i, got, w)
+		}
+	}
+
+	if err := client.Disconnect(context.Background()); err != nil {
+		t.Fatalf("Disconnect failed: %v", err)
+	}
+	if got, _ := next(); got != StateDisconnected {
+		t.Errorf("state after Disconnect = %v, want %v", got, StateDisconnected)
+	}
+	if _, ok := next(); ok {
+		t.Error("expected the channel to be closed after Disconnect")
+	}
+}
+
+func TestStateChanges_DropOldest(t *testing.T) {
+	c := &Client{stateCh: make(chan ConnectionState, 2)}
+
+	for _, s := range []ConnectionState{StateConnecting, StateConnected, StateDisconnected, StateReconnecting} {
+		c.setState(s)
+	}
+	c.setState(StateReconnecting) // Unchanged: not repeated
+
+	if got := len(c.stateCh); got != 2 {
+		t.Fatalf("expected 2 buffered states, got %d", got)
+	}
+	if got := <-c.stateCh; got != StateDisconnected {
+		t.Errorf("first buffered state = %v, want %v", got, StateDisconnected)
+	}
+	if got := <-c.stateCh; got != StateReconnecting {
+		t.Errorf("last buffered state = %v, want the current state %v", got, StateReconnecting)
+	}
+
+	c.closeStateChanges()
+	c.closeStateChanges()
+	c.setState(StateConnected) // Must not panic after close
+
+	if got := <-c.stateCh; got != StateDisconnected {
+		t.Errorf("final state = %v, want %v", got, StateDisconnected)
+	}
+	if _, ok := <-c.stateCh; ok {
+		t.Error("expected the channel to be closed")
+	}
+}
+
+func TestConnectionStateString(t *testing.T) {
+	tests := map[ConnectionState]string{
+		StateDisconnected:   "disconnected",
+		StateConnecting:     "connecting",
+		StateConnected:      "connected",
+		StateReconnecting:   "reconnecting",
+		ConnectionState(42): "ConnectionState(42)",
+	}
+	for s, want := range tests {
+		if got := s.String(); got != want {
+			t.Errorf("%d.String() = %q, want %q", int(s), got, want)
+		}
+	}
+}
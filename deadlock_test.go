@@ -15,6 +15,7 @@ func newTestClient(opts *clientOptions) *Client {
 	}
 	return &Client{
 		opts:            opts,
+		server:          opts.Server,
 		outgoing:        make(chan packets.Packet, opts.OutgoingQueueSize),
 		incoming:        make(chan packets.Packet, opts.IncomingQueueSize),
 		packetReceived:  make(chan struct{}, 1),
@@ -230,6 +231,32 @@ func TestCustomBufferSizes(t *testing.T) {
 	}
 }
 
+// TestDialBufferSizes verifies that the channels created by Dial use the
+// default and custom buffer sizes.
+func TestDialBufferSizes(t *testing.T) {
+	tests := []struct {
+		name               string
+		opts               []Option
+		outgoing, incoming int
+	}{
+		{"defaults", nil, 1000, 100},
+		{"custom", []Option{WithOutgoingQueueSize(5000), WithIncomingQueueSize(500)}, 5000, 500},
+		{"invalid ignored", []Option{WithOutgoingQueueSize(0), WithIncomingQueueSize(-1)}, 1000, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newClient("tcp://localhost:1883", tt.opts)
+			if cap(c.outgoing) != tt.outgoing {
+				t.Errorf("outgoing capacity = %d, want %d", cap(c.outgoing), tt.outgoing)
+			}
+			if cap(c.incoming) != tt.incoming {
+				t.Errorf("incoming capacity = %d, want %d", cap(c.incoming), tt.incoming)
+			}
+		})
+	}
+}
+
 // TestQoS0Blocking verifies that QoS 0 publishes block when the outgoing channel is full
 // if the QoS0LimitPolicyBlock policy is set.
 func TestQoS0Blocking(t *testing.T) {
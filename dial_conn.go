@@ -0,0 +1,65 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// errNoRedial is returned when a client created with DialConn needs a new
+// connection and no dialer was set with WithDialer.
+var errNoRedial = errors.New("connection given to DialConn cannot be redialed (no dialer set)")
+
+// unknownConnServer is the server string of a connection given to DialConn
+// that reports no remote address.
+const unknownConnServer = "conn://unknown"
+
+// DialConn runs the MQTT protocol over conn, an already established
+// connection to the server, and returns a Client. Use it when the
+// connection is set up outside of the library, e.g. a socket tunneled
+// through SSH or a SOCKS5 proxy.
+//
+// The CONNECT handshake is performed on conn, bounded by ctx, and the client
+// takes ownership of conn: it is closed on Disconnect, on connection loss,
+// and if DialConn fails.
+//
+// conn can be used only once. To reconnect, the client calls the dialer set
+// with WithDialer, passing conn.RemoteAddr() as a "network://address"
+// server string, or "conn://unknown" if conn has no remote address. Without
+// a dialer, automatic reconnection is disabled, and so are the v3.1.1
+// fallback of WithAutoProtocolVersion and server redirects, which also need
+// a new connection.
+//
+// Example:
+//
+//	sshConn, _ := sshClient.Dial("tcp", "broker.internal:1883")
+//	client, err := mq.DialConn(ctx, sshConn,
+//	    mq.WithClientID("tunneled-client"),
+//	    mq.WithDialer(mq.DialFunc(func(ctx context.Context, _, _ string) (net.Conn, error) {
+//	        return sshClient.DialContext(ctx, "tcp", "broker.internal:1883")
+//	    })))
+func DialConn(ctx context.Context, conn net.Conn, opts ...Option) (*Client, error) {
+	if conn == nil {
+		return nil, fmt.Errorf("conn is nil")
+	}
+
+	server := unknownConnServer
+	if addr := conn.RemoteAddr(); addr != nil {
+		server = addr.Network() + "://" + addr.String()
+	}
+	c := newClient(server, opts)
+	c.preDialed = conn
+	if c.opts.Dialer == nil {
+		c.opts.AutoReconnect = false
+		c.opts.Dialer = DialFunc(func(context.Context, string, string) (net.Conn, error) {
+			return nil, errNoRedial
+		})
+	}
+
+	if err := c.start(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,167 @@
+package mq
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// servePipe returns the client end of an in-memory connection whose server
+// end accepts the CONNECT and acknowledges every QoS 1 PUBLISH. Closing
+// the returned channel drops the connection.
+func servePipe(t *testing.T) (net.Conn, chan struct{}) {
+	t.Helper()
+	clientConn, serverConn := net.Pipe()
+	drop := make(chan struct{})
+	t.Cleanup(func() { serverConn.Close() })
+
+	go func() {
+		<-drop
+		serverConn.Close()
+	}()
+	go func() {
+		defer serverConn.Close()
+		if _, err := packets.ReadPacket(serverConn, ProtocolV50, 0); err != nil {
+			return
+		}
+		_, _ = (&packets.ConnackPacket{ReturnCode: 0}).WriteTo(serverConn)
+
+		for {
+			pkt, err := packets.ReadPacket(serverConn, ProtocolV50, 0)
+			if err != nil {
+				return
+			}
+			if p, ok := pkt.(*packets.PublishPacket); ok && p.QoS == 1 {
+				_, _ = (&packets.PubackPacket{PacketID: p.PacketID, Version: ProtocolV50}).WriteTo(serverConn)
+			}
+		}
+	}()
+	return clientConn, drop
+}
+
+func TestDialConn(t *testing.T) {
+	conn, drop := servePipe(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := DialConn(ctx, conn, WithClientID("piped-client"))
+	if err != nil {
+		t.Fatalf("DialConn failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	if err := client.PublishSync(ctx, "t", []byte("x"), WithQoS(AtLeastOnce)); err != nil {
+		t.Errorf("PublishSync failed: %v", err)
+	}
+
+	// Without a dialer, a lost connection is not redialed
+	if client.opts.AutoReconnect {
+		t.Error("AutoReconnect should be disabled without a dialer")
+	}
+	close(drop)
+	deadline := time.Now().Add(2 * time.Second)
+	for client.IsConnected() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if client.IsConnected() {
+		t.Fatal("client still connected after the connection was dropped")
+	}
+	if err := client.ReconnectNow(); err == nil {
+		t.Error("ReconnectNow should fail without a dialer")
+	}
+}
+
+func TestDialConn_Redial(t *testing.T) {
+	conn, drop := servePipe(t)
+
+	var dials atomic.Int32
+	dialer := DialFunc(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dials.Add(1)
+		if network != "pipe" || addr != "pipe://pipe" {
+			t.Errorf("dialer got (%q, %q), want (\"pipe\", \"pipe://pipe\")", network, addr)
+		}
+		redialed, _ := servePipe(t)
+		return redialed, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := DialConn(ctx, conn,
+		WithClientID("piped-client"),
+		WithDialer(dialer),
+		WithReconnectBackoff(10*time.Millisecond, 10*time.Millisecond, 1),
+	)
+	if err != nil {
+		t.Fatalf("DialConn failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	if n := dials.Load(); n != 0 {
+		t.Fatalf("dialer called %d times for the initial connection", n)
+	}
+
+	close(drop)
+	deadline := time.Now().Add(5 * time.Second)
+	for (dials.Load() == 0 || !client.IsConnected()) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if dials.Load() == 0 || !client.IsConnected() {
+		t.Fatalf("client not reconnected through the dialer (%d dials)", dials.Load())
+	}
+
+	if err := client.PublishSync(ctx, "t", []byte("x"), WithQoS(AtLeastOnce)); err != nil {
+		t.Errorf("PublishSync after redial failed: %v", err)
+	}
+}
+
+// noAddrConn is a connection that reports no remote address.
+type noAddrConn struct {
+	net.Conn
+}
+
+func (noAddrConn) RemoteAddr() net.Addr { return nil }
+
+func TestDialConn_NoRemoteAddr(t *testing.T) {
+	conn, _ := servePipe(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := DialConn(ctx, noAddrConn{conn}, WithClientID("piped-client"))
+	if err != nil {
+		t.Fatalf("DialConn failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	if got := client.Config().Server; got != unknownConnServer {
+		t.Errorf("Server = %q, want %q", got, unknownConnServer)
+	}
+}
+
+func TestDialConn_Failure(t *testing.T) {
+	if _, err := DialConn(context.Background(), nil); err == nil {
+		t.Error("DialConn(nil) should fail")
+	}
+
+	// The server end goes away without answering the CONNECT
+	clientConn, serverConn := net.Pipe()
+	go func() {
+		_, _ = packets.ReadPacket(serverConn, ProtocolV50, 0)
+		serverConn.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := DialConn(ctx, clientConn, WithClientID("piped-client")); err == nil {
+		t.Fatal("DialConn should fail when the handshake fails")
+	}
+	if _, err := clientConn.Write([]byte{0}); err == nil {
+		t.Error("conn should be closed after DialConn fails")
+	}
+}
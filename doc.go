@@ -67,6 +67,7 @@
 //   - WithOutgoingQueueSize(int) - Set internal outgoing buffer size
 //   - WithIncomingQueueSize(int) - Set internal incoming buffer size
 //   - WithQoS0LimitPolicy(policy) - Set reliability policy for QoS 0
+//   - WithQoS0TokenBehavior(behavior) - Choose when QoS 0 tokens complete
 //   - WithHandlerInterceptor(interceptor) - Add an interceptor for incoming messages
 //   - WithPublishInterceptor(interceptor) - Add an interceptor for outgoing messages
 //
@@ -223,6 +224,13 @@
 //	    log.Println("Timeout")
 //	}
 //
+//	// Connection refusals carry the reason code and a retry hint
+//	client, err := mq.Dial(uri)
+//	var refused *mq.ConnectRefusedError
+//	if errors.As(err, &refused) && !refused.RetryAdvised() {
+//	    log.Fatalf("giving up: %v", refused)
+//	}
+//
 //	// Connection can be closed with a specific reason code and properties (MQTT v5.0):
 //
 //	expiry := uint32(3600)
@@ -0,0 +1,40 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var errDrainTimeout = errors.New("drain timeout expired")
+
+// drainPending waits up to timeout for the publish queue to empty and every
+// pending QoS 1/2 operation to be acknowledged, so that Disconnect does not
+// abandon them (see WithDrain). It gives up early if the connection is lost.
+func (c *Client) drainPending(ctx context.Context, timeout time.Duration) {
+	c.connLock.RLock()
+	connDone := c.connDone
+	c.connLock.RUnlock()
+	if connDone == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeoutCause(ctx, timeout, errDrainTimeout)
+	defer cancel()
+	go func() {
+		// Nothing is acknowledged once the connection is gone
+		select {
+		case <-connDone:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if err := c.WaitForPending(ctx); err != nil && context.Cause(ctx) == errDrainTimeout {
+		c.sessionLock.Lock()
+		pending, queued := len(c.pending), len(c.publishQueue)
+		c.sessionLock.Unlock()
+		c.opts.Logger.Warn("drain timeout expired, disconnecting with outstanding operations",
+			"pending", pending, "queued", queued)
+	}
+}
@@ -0,0 +1,150 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// serveDrain accepts one connection with a Receive Maximum of 1 and acks
+// every PUBLISH after delay (never, if delay is negative). It reports the
+// packets received, up to and including DISCONNECT.
+func serveDrain(t *testing.T, delay time.Duration) (string, <-chan []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = packets.ReadPacket(conn, ProtocolV50, 0)
+		connack := &packets.ConnackPacket{ReturnCode: 0, Properties: &packets.Properties{
+			ReceiveMaximum: 1,
+			Presence:       packets.PresReceiveMaximum,
+		}}
+		_, _ = connack.WriteTo(conn)
+
+		var got []string
+		for {
+			pkt, err := packets.ReadPacket(conn, ProtocolV50, 0)
+			if err != nil {
+				received <- got
+				return
+			}
+			got = append(got, fmt.Sprintf("%T", pkt))
+			switch p := pkt.(type) {
+			case *packets.PublishPacket:
+				if delay >= 0 {
+					time.Sleep(delay)
+					_, _ = (&packets.PubackPacket{PacketID: p.PacketID}).WriteTo(conn)
+				}
+			case *packets.DisconnectPacket:
+				received <- got
+				return
+			}
+		}
+	}()
+	return "tcp://" + ln.Addr().String(), received
+}
+
+func TestDisconnect_Drain(t *testing.T) {
+	server, received := serveDrain(t, 50*time.Millisecond)
+	client, err := Dial(server, WithAutoReconnect(false), WithLogger(testLogger()))
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+
+	// The second and third wait in the publish queue for the Receive Maximum
+	var tokens []Token
+	for i := range 3 {
+		tokens = append(tokens, client.Publish("telemetry", []byte{byte(i)}, WithQoS(AtLeastOnce)))
+	}
+
+	if err := client.Disconnect(context.Background(), WithDrain(5*time.Second)); err != nil {
+		t.Fatalf("Disconnect failed: %v", err)
+	}
+	for i, tok := range tokens {
+		if err := waitToken(t, tok); err != nil {
+			t.Errorf("publish %d failed: %v", i, err)
+		}
+	}
+
+	want := []string{"*packets.PublishPacket", "*packets.PublishPacket", "*packets.PublishPacket", "*packets.DisconnectPacket"}
+	select {
+	case got := <-received:
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("server received %v, want %v", got, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for server")
+	}
+}
+
+func TestDisconnect_DrainLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		ctx     func() (context.Context, context.CancelFunc)
+	}{
+		{
+			name:    "timeout",
+			timeout: 100 * time.Millisecond,
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithCancel(context.Background())
+			},
+		},
+		{
+			name:    "context done",
+			timeout: time.Minute,
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 100*time.Millisecond)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, received := serveDrain(t, -1)
+			client, err := Dial(server, WithAutoReconnect(false), WithLogger(testLogger()))
+			if err != nil {
+				t.Fatalf("Dial failed: %v", err)
+			}
+			tok := client.Publish("telemetry", []byte("x"), WithQoS(AtLeastOnce))
+
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			start := time.Now()
+			err = client.Disconnect(ctx, WithDrain(tt.timeout))
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("Disconnect took %v", elapsed)
+			}
+			if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Disconnect failed: %v", err)
+			}
+
+			select {
+			case got := <-received:
+				if len(got) == 0 || got[len(got)-1] != "*packets.DisconnectPacket" {
+					t.Errorf("expected the DISCONNECT to be sent anyway, server received %v", got)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("timeout waiting for server")
+			}
+			if waitToken(t, tok) == nil {
+				t.Error("expected the unacknowledged publish to fail")
+			}
+		})
+	}
+}
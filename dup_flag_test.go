@@ -0,0 +1,164 @@
+package mq
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestPublishDupFlagOnRetransmission(t *testing.T) {
+	c := newTestClient(nil)
+	c.opts.Logger = testLogger()
+	c.serverCaps.MaximumQoS = 2
+
+	tok := newToken()
+	c.internalPublish(&publishRequest{
+		packet: &packets.PublishPacket{Topic: "dup/test", Payload: []byte("payload"), QoS: 1},
+		token:  tok,
+	})
+
+	var first *packets.PublishPacket
+	select {
+	case pkt := <-c.outgoing:
+		first = pkt.(*packets.PublishPacket)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for first transmission")
+	}
+	if first.Dup {
+		t.Fatal("DUP must not be set on first transmission")
+	}
+	firstID := first.PacketID
+
+	// Not acknowledged: age the pending operation to force a retransmission
+	c.sessionLock.Lock()
+	c.pending[firstID].timestamp = time.Now().Add(-time.Minute)
+	c.retryPending()
+	c.sessionLock.Unlock()
+
+	select {
+	case pkt := <-c.outgoing:
+		retry := pkt.(*packets.PublishPacket)
+		if !retry.Dup {
+			t.Error("DUP must be set on retransmission")
+		}
+		if retry.PacketID != firstID {
+			t.Errorf("retransmission must reuse packet ID %d, got %d", firstID, retry.PacketID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for retransmission")
+	}
+
+	select {
+	case <-tok.Done():
+		t.Fatal("token must not complete without PUBACK")
+	default:
+	}
+}
+
+func TestPublishDupFlagAfterReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	type seen struct {
+		dup bool
+		id  uint16
+	}
+	transmissions := make(chan seen, 2)
+
+	go func() {
+		// First connection: read the PUBLISH and drop the connection without PUBACK
+		conn1, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		if _, err := packets.ReadPacket(conn1, ProtocolV50, 0); err != nil {
+			return
+		}
+		connack := &packets.ConnackPacket{ReturnCode: 0}
+		if _, err := connack.WriteTo(conn1); err != nil {
+			return
+		}
+		for {
+			pkt, err := packets.ReadPacket(conn1, ProtocolV50, 0)
+			if err != nil {
+				return
+			}
+			if pub, ok := pkt.(*packets.PublishPacket); ok {
+				transmissions <- seen{pub.Dup, pub.PacketID}
+				break
+			}
+		}
+		conn1.Close()
+
+		// Second connection: session resumed, expect the redelivery
+		conn2, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn2.Close()
+		if _, err := packets.ReadPacket(conn2, ProtocolV50, 0); err != nil {
+			return
+		}
+		connack = &packets.ConnackPacket{ReturnCode: 0, SessionPresent: true}
+		if _, err := connack.WriteTo(conn2); err != nil {
+			return
+		}
+		for {
+			pkt, err := packets.ReadPacket(conn2, ProtocolV50, 0)
+			if err != nil {
+				return
+			}
+			if pub, ok := pkt.(*packets.PublishPacket); ok {
+				transmissions <- seen{pub.Dup, pub.PacketID}
+				puback := &packets.PubackPacket{PacketID: pub.PacketID, Version: ProtocolV50}
+				_, _ = puback.WriteTo(conn2)
+			}
+		}
+	}()
+
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("dup-reconnect"),
+		WithCleanSession(false),
+		WithSessionExpiryInterval(3600),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	tok := client.Publish("dup/test", []byte("payload"), WithQoS(AtLeastOnce))
+
+	var first, second seen
+	select {
+	case first = <-transmissions:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for first transmission")
+	}
+	select {
+	case second = <-transmissions:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for redelivery after reconnect")
+	}
+
+	if first.dup {
+		t.Error("DUP must not be set on first transmission")
+	}
+	if !second.dup {
+		t.Error("DUP must be set on redelivery after reconnect")
+	}
+	if first.id != second.id {
+		t.Errorf("redelivery must reuse packet ID %d, got %d", first.id, second.id)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := tok.Wait(ctx); err != nil {
+		t.Errorf("expected publish to complete after redelivery, got %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package mq
+
+import "sync"
+
+// dynamicSubscription routes messages of one wildcard subscription to
+// per-topic handlers created on demand.
+type dynamicSubscription struct {
+	factory func(topic string) MessageHandler
+
+	mu       sync.Mutex
+	handlers map[string]MessageHandler
+}
+
+// handlerFor returns the cached handler for topic, creating it on first use.
+// The factory runs without d.mu held, so that it may evict handlers and a
+// slow one does not hold up other topics; if two messages race to create
+// the handler of a topic, the first one cached wins.
+func (d *dynamicSubscription) handlerFor(topic string) MessageHandler {
+	d.mu.Lock()
+	h, ok := d.handlers[topic]
+	d.mu.Unlock()
+	if ok {
+		return h
+	}
+
+	h = d.factory(topic)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if cached, ok := d.handlers[topic]; ok {
+		return cached
+	}
+	d.handlers[topic] = h
+	return h
+}
+
+// SubscribeDynamic subscribes to a wildcard filter once, but dispatches each
+// concrete topic to its own handler.
+//
+// On the first message from each concrete topic matching filter,
+// handlerFactory is called with that topic to create a handler, which is
+// cached and used for all subsequent messages from the same topic. This gives
+// per-topic handler state (e.g. one state machine per device) without one
+// subscription per topic.
+//
+// handlerFactory is normally called once per topic. When messages of a new
+// topic are handled concurrently (WithHandlerConcurrency), it may be called
+// more than once for that topic, and only the first handler returned is
+// kept. It may return nil to ignore a topic; the nil result is cached as
+// well.
+//
+// Memory: one handler is kept per discovered topic for as long as the
+// subscription exists. For filters that can match an unbounded set of topics,
+// release handlers with EvictDynamicHandler when a topic goes away (e.g. a
+// device is decommissioned). Unsubscribe(filter) releases all of them.
+//
+// The returned token behaves exactly as the one returned by Subscribe.
+//
+// Example:
+//
+//	client.SubscribeDynamic("devices/+/data", mq.AtLeastOnce,
+//	    func(topic string) mq.MessageHandler {
+//	        dev := newDeviceTracker(topic)
+//	        return func(c *mq.Client, msg mq.Message) {
+//	            dev.update(msg.Payload)
+//	        }
+//	    })
+func (c *Client) SubscribeDynamic(filter string, qos QoS, handlerFactory func(topic string) MessageHandler, opts ...SubscribeOption) SubscribeToken {
+	d := &dynamicSubscription{
+		factory:  handlerFactory,
+		handlers: make(map[string]MessageHandler),
+	}
+
+	tok := c.Subscribe(filter, qos, func(client *Client, msg Message) {
+		if h := d.handlerFor(msg.Topic); h != nil {
+			h(client, msg)
+		}
+	}, opts...)
+
+	// Local validation failures complete the token immediately
+	select {
+	case <-tok.Done():
+		if tok.Error() != nil {
+			return tok
+		}
+	default:
+	}
+
+	c.dynamicLock.Lock()
+	if c.dynamicSubs == nil {
+		c.dynamicSubs = make(map[string]*dynamicSubscription)
+	}
+	c.dynamicSubs[filter] = d
+	c.dynamicLock.Unlock()
+
+	return tok
+}
+
+// EvictDynamicHandler drops the cached handler for topic from the dynamic
+// subscription registered for filter (see SubscribeDynamic). The next message
+// from topic creates a new handler through the factory.
+//
+// Returns false if filter is not a dynamic subscription or no handler was
+// cached for topic.
+func (c *Client) EvictDynamicHandler(filter, topic string) bool {
+	c.dynamicLock.Lock()
+	d, ok := c.dynamicSubs[filter]
+	c.dynamicLock.Unlock()
+	if !ok {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.handlers[topic]; !ok {
+		return false
+	}
+	delete(d.handlers, topic)
+	return true
+}
+
+// removeDynamicSubscription forgets the handler cache of filter, if any.
+func (c *Client) removeDynamicSubscription(filter string) {
+	c.dynamicLock.Lock()
+	delete(c.dynamicSubs, filter)
+	c.dynamicLock.Unlock()
+}
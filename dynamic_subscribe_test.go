@@ -0,0 +1,112 @@
+package mq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestSubscribeDynamic(t *testing.T) {
+	c := newTestClient(nil)
+	c.connected.Store(true)
+	c.opts.Logger = testLogger()
+
+	type deviceHandler struct {
+		topic    string
+		messages int
+	}
+	created := make(map[string][]*deviceHandler)
+	delivered := make(chan *deviceHandler, 10)
+
+	tok := c.SubscribeDynamic("devices/+/data", AtMostOnce, func(topic string) MessageHandler {
+		h := &deviceHandler{topic: topic}
+		created[topic] = append(created[topic], h)
+		return func(_ *Client, msg Message) {
+			if msg.Topic != h.topic {
+				t.Errorf("handler for %q received message for %q", h.topic, msg.Topic)
+			}
+			h.messages++
+			delivered <- h
+		}
+	})
+	select {
+	case <-tok.Done():
+		t.Fatalf("unexpected completion: %v", tok.Error())
+	default:
+	}
+	if sub, ok := (<-c.outgoing).(*packets.SubscribePacket); !ok || len(sub.Topics) != 1 {
+		t.Fatalf("expected a single SUBSCRIBE for the wildcard, got %#v", sub)
+	}
+
+	deliver := func(topic string) *deviceHandler {
+		t.Helper()
+		c.sessionLock.Lock()
+		c.handleIncoming(&packets.PublishPacket{Topic: topic, Payload: []byte("x")})
+		c.sessionLock.Unlock()
+		return <-delivered
+	}
+
+	a1 := deliver("devices/a/data")
+	b1 := deliver("devices/b/data")
+	a2 := deliver("devices/a/data")
+
+	if a1 == b1 {
+		t.Error("expected distinct handlers per concrete topic")
+	}
+	if a1 != a2 {
+		t.Error("expected the cached handler to be reused for the same topic")
+	}
+	if len(created["devices/a/data"]) != 1 || len(created["devices/b/data"]) != 1 {
+		t.Errorf("expected one factory call per topic, got %d and %d",
+			len(created["devices/a/data"]), len(created["devices/b/data"]))
+	}
+
+	// Eviction forces a new handler on the next message
+	if !c.EvictDynamicHandler("devices/+/data", "devices/a/data") {
+		t.Fatal("expected eviction to succeed")
+	}
+	if c.EvictDynamicHandler("devices/+/data", "devices/a/data") {
+		t.Error("expected second eviction to report no cached handler")
+	}
+	if a3 := deliver("devices/a/data"); a3 == a1 {
+		t.Error("expected a new handler after eviction")
+	}
+	if n := len(created["devices/a/data"]); n != 2 {
+		t.Errorf("expected 2 factory calls after eviction, got %d", n)
+	}
+
+	// Unsubscribe releases the cache
+	c.Unsubscribe("devices/+/data")
+	if c.EvictDynamicHandler("devices/+/data", "devices/b/data") {
+		t.Error("expected cache to be released on unsubscribe")
+	}
+}
+
+func TestSubscribeDynamic_FactoryMayEvict(t *testing.T) {
+	c := newTestClient(nil)
+	c.connected.Store(true)
+	c.opts.Logger = testLogger()
+
+	delivered := make(chan string, 1)
+	c.SubscribeDynamic("devices/+/data", AtMostOnce, func(topic string) MessageHandler {
+		// A new device replaces the previous one
+		c.EvictDynamicHandler("devices/+/data", "devices/old/data")
+		return func(_ *Client, msg Message) { delivered <- msg.Topic }
+	})
+	<-c.outgoing
+
+	go func() {
+		c.sessionLock.Lock()
+		defer c.sessionLock.Unlock()
+		c.handleIncoming(&packets.PublishPacket{Topic: "devices/new/data"})
+	}()
+	select {
+	case topic := <-delivered:
+		if topic != "devices/new/data" {
+			t.Errorf("delivered %q, want devices/new/data", topic)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("factory calling EvictDynamicHandler deadlocked")
+	}
+}
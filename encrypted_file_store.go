@@ -0,0 +1,117 @@
+package mq
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+)
+
+// encryptedExtension is the file name extension of encrypted entries.
+const encryptedExtension = "enc"
+
+// NewEncryptedFileStore creates a FileStore that encrypts every entry with
+// AES-GCM before writing it to disk, so pending payloads and subscriptions
+// are not stored in plaintext.
+//
+// The key must be 16, 24 or 32 bytes long, selecting AES-128, AES-192 or
+// AES-256. Each entry is written as a random 12-byte nonce followed by the
+// sealed record, and is bound to clientID and to its file: a file copied
+// from another client's store, or renamed to another entry, fails to
+// decrypt. Loading an entry that cannot be
+// decrypted (wrong key, tampering, corruption) returns an error wrapping
+// ErrStoreDecryption, which makes the connection fail rather than silently
+// dropping session state.
+//
+// Entries use the ".enc" extension. Records are serialized with JSONCodec
+// before encryption unless another codec is selected with WithStoreCodec.
+// The store is a drop-in replacement for NewFileStore, but does not read
+// plaintext entries: migrate with an empty store (or call Clear first).
+//
+// The key must be kept outside the store directory, e.g. in a secrets
+// manager or a hardware-backed keystore.
+//
+// Example:
+//
+//	key, _ := hex.DecodeString(os.Getenv("MQTT_STORE_KEY")) // 32 bytes
+//	store, err := mq.NewEncryptedFileStore("/var/lib/mqtt", "gateway-1", key)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//
+//	client, err := mq.Dial("tls://broker:8883",
+//	    mq.WithClientID("gateway-1"),
+//	    mq.WithCleanSession(false),
+//	    mq.WithSessionStore(store))
+func NewEncryptedFileStore(baseDir, clientID string, key []byte, opts ...FileStoreOption) (*FileStore, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	store, err := NewFileStore(baseDir, clientID, opts...)
+	if err != nil {
+		return nil, err
+	}
+	store.config.codec = &encryptedCodec{
+		codec:    store.config.codec,
+		aead:     aead,
+		clientID: clientID,
+	}
+	return store, nil
+}
+
+// encryptedCodec seals the output of another codec with an AEAD. The
+// additional data binds each record to the client ID and to the name of its
+// entry, so a record moved to another file or store fails to decrypt.
+type encryptedCodec struct {
+	codec    StoreCodec
+	aead     cipher.AEAD
+	clientID string
+}
+
+// additionalData returns the data authenticated with the named entry.
+func (e *encryptedCodec) additionalData(name string) []byte {
+	// NUL cannot appear in the client ID, which names a directory
+	return []byte(e.clientID + "\x00" + name)
+}
+
+func (e *encryptedCodec) Marshal(v any) ([]byte, error) {
+	return e.marshalEntry("", v)
+}
+
+func (e *encryptedCodec) Unmarshal(data []byte, v any) error {
+	return e.unmarshalEntry("", data, v)
+}
+
+func (e *encryptedCodec) marshalEntry(name string, v any) ([]byte, error) {
+	plain, err := e.codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, e.aead.NonceSize(), e.aead.NonceSize()+len(plain)+e.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return e.aead.Seal(nonce, nonce, plain, e.additionalData(name)), nil
+}
+
+func (e *encryptedCodec) unmarshalEntry(name string, data []byte, v any) error {
+	n := e.aead.NonceSize()
+	if len(data) < n+e.aead.Overhead() {
+		return fmt.Errorf("%w: entry too short", ErrStoreDecryption)
+	}
+
+	plain, err := e.aead.Open(nil, data[:n], data[n:], e.additionalData(name))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrStoreDecryption, err)
+	}
+	return e.codec.Unmarshal(plain, v)
+}
+
+func (e *encryptedCodec) Extension() string { return encryptedExtension }
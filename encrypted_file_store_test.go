@@ -0,0 +1,188 @@
+package mq
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testStoreKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestEncryptedFileStore_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewEncryptedFileStore(dir, "gateway", testStoreKey(1))
+	if err != nil {
+		t.Fatalf("NewEncryptedFileStore failed: %v", err)
+	}
+
+	pub := &PersistedPublish{Topic: "patients/42/vitals", Payload: []byte("heart-rate=71"), QoS: 1}
+	if err := store.SavePendingPublish(1, pub); err != nil {
+		t.Fatalf("SavePendingPublish failed: %v", err)
+	}
+	if err := store.SaveSubscription("patients/+/alerts", &PersistedSubscription{QoS: 2}); err != nil {
+		t.Fatalf("SaveSubscription failed: %v", err)
+	}
+	if err := store.SaveReceivedQoS2(5); err != nil {
+		t.Fatalf("SaveReceivedQoS2 failed: %v", err)
+	}
+
+	// Nothing readable on disk
+	entries, _ := os.ReadDir(store.dir)
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	for _, e := range entries {
+		if filepath.Ext(e.Name()) != ".enc" {
+			t.Errorf("unexpected file name %s", e.Name())
+		}
+		data, _ := os.ReadFile(filepath.Join(store.dir, e.Name()))
+		for _, plain := range []string{"patients", "heart-rate", "Topic"} {
+			if bytes.Contains(data, []byte(plain)) {
+				t.Errorf("%s contains plaintext %q", e.Name(), plain)
+			}
+		}
+	}
+
+	// Same payload saved twice gets a different nonce
+	first, _ := os.ReadFile(store.pendingPath(1))
+	_ = store.SavePendingPublish(1, pub)
+	second, _ := os.ReadFile(store.pendingPath(1))
+	if bytes.Equal(first[:12], second[:12]) {
+		t.Error("expected a fresh nonce per record")
+	}
+
+	reopened, err := NewEncryptedFileStore(dir, "gateway", testStoreKey(1))
+	if err != nil {
+		t.Fatalf("NewEncryptedFileStore failed: %v", err)
+	}
+	pending, err := reopened.LoadPendingPublishes()
+	if err != nil {
+		t.Fatalf("LoadPendingPublishes failed: %v", err)
+	}
+	if !reflect.DeepEqual(pending, map[uint16]*PersistedPublish{1: pub}) {
+		t.Errorf("LoadPendingPublishes() = %#v", pending)
+	}
+	subs, err := reopened.LoadSubscriptions()
+	if err != nil || len(subs) != 1 || subs["patients/+/alerts"].QoS != 2 {
+		t.Errorf("LoadSubscriptions() = %#v, %v", subs, err)
+	}
+	qos2, err := reopened.LoadReceivedQoS2()
+	if err != nil || !reflect.DeepEqual(qos2, map[uint16]struct{}{5: {}}) {
+		t.Errorf("LoadReceivedQoS2() = %v, %v", qos2, err)
+	}
+
+	if err := reopened.Clear(); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if entries, _ := os.ReadDir(store.dir); len(entries) != 0 {
+		t.Errorf("expected empty directory after Clear, got %d entries", len(entries))
+	}
+}
+
+func TestEncryptedFileStore_InvalidKey(t *testing.T) {
+	for _, n := range []int{0, 8, 31, 64} {
+		if _, err := NewEncryptedFileStore(t.TempDir(), "c", make([]byte, n)); err == nil {
+			t.Errorf("expected an error for a %d-byte key", n)
+		}
+	}
+	for _, n := range []int{16, 24, 32} {
+		if _, err := NewEncryptedFileStore(t.TempDir(), "c", make([]byte, n)); err != nil {
+			t.Errorf("unexpected error for a %d-byte key: %v", n, err)
+		}
+	}
+}
+
+func TestEncryptedFileStore_DecryptionFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		reopen func(t *testing.T, dir string) *FileStore
+		tamper func(t *testing.T, store *FileStore)
+	}{
+		{
+			name: "wrong key",
+			reopen: func(t *testing.T, dir string) *FileStore {
+				s, _ := NewEncryptedFileStore(dir, "gateway", testStoreKey(2))
+				return s
+			},
+		},
+		{
+			name: "tampered files",
+			tamper: func(t *testing.T, store *FileStore) {
+				for _, name := range []string{"pending_1", "subscriptions", "qos2_received"} {
+					path := store.path(name)
+					data, err := os.ReadFile(path)
+					if err != nil {
+						t.Fatal(err)
+					}
+					data[len(data)-1] ^= 0xff
+					if err := os.WriteFile(path, data, 0600); err != nil {
+						t.Fatal(err)
+					}
+				}
+			},
+		},
+		{
+			name: "entries swapped between files",
+			tamper: func(t *testing.T, store *FileStore) {
+				names := []string{"pending_1", "subscriptions", "qos2_received"}
+				data := make([][]byte, len(names))
+				for i, name := range names {
+					var err error
+					if data[i], err = os.ReadFile(store.path(name)); err != nil {
+						t.Fatal(err)
+					}
+				}
+				for i, name := range names {
+					if err := os.WriteFile(store.path(name), data[(i+1)%len(names)], 0600); err != nil {
+						t.Fatal(err)
+					}
+				}
+			},
+		},
+		{
+			name: "entries moved to another client",
+			tamper: func(t *testing.T, store *FileStore) {
+				other := filepath.Join(filepath.Dir(store.dir), "intruder")
+				if err := os.Rename(store.dir, other); err != nil {
+					t.Fatal(err)
+				}
+			},
+			reopen: func(t *testing.T, dir string) *FileStore {
+				s, _ := NewEncryptedFileStore(dir, "intruder", testStoreKey(1))
+				return s
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store, _ := NewEncryptedFileStore(dir, "gateway", testStoreKey(1))
+			_ = store.SavePendingPublish(1, &PersistedPublish{Topic: "a", QoS: 1})
+			_ = store.SaveSubscription("a/#", &PersistedSubscription{QoS: 1})
+			_ = store.SaveReceivedQoS2(3)
+
+			if tt.tamper != nil {
+				tt.tamper(t, store)
+			}
+			if tt.reopen != nil {
+				store = tt.reopen(t, dir)
+			}
+
+			if _, err := store.LoadPendingPublishes(); !errors.Is(err, ErrStoreDecryption) {
+				t.Errorf("LoadPendingPublishes: expected ErrStoreDecryption, got %v", err)
+			}
+			if _, err := store.LoadSubscriptions(); !errors.Is(err, ErrStoreDecryption) {
+				t.Errorf("LoadSubscriptions: expected ErrStoreDecryption, got %v", err)
+			}
+			if _, err := store.LoadReceivedQoS2(); !errors.Is(err, ErrStoreDecryption) {
+				t.Errorf("LoadReceivedQoS2: expected ErrStoreDecryption, got %v", err)
+			}
+		})
+	}
+}
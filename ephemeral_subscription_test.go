@@ -50,6 +50,7 @@ func TestEphemeralSubscription(t *testing.T) {
 		outgoing:      make(chan packets.Packet, 10),
 		stop:          make(chan struct{}),
 	}
+	c.connected.Store(true)
 	c.opts.SessionStore = store
 
 	// 1. Subscribe with Persistence=false (Ephemeral)
@@ -21,9 +21,98 @@ var (
 	// ErrSubscriptionFailed is returned when the server rejects a subscription.
 	ErrSubscriptionFailed = errors.New("subscription failed")
 
+	// ErrSubscriptionLimitExceeded is returned when a subscription would exceed
+	// the limit configured with WithMaxSubscriptions.
+	ErrSubscriptionLimitExceeded = errors.New("subscription limit exceeded")
+
+	// ErrInvalidTopic is returned when a topic name is not valid for
+	// publishing (see ValidateTopicName). The message is rejected locally
+	// and never sent.
+	ErrInvalidTopic = errors.New("invalid topic")
+
+	// ErrInvalidTopicFilter is returned when a topic filter is not valid for
+	// subscribing (see ValidateTopicFilter). Nothing is sent.
+	ErrInvalidTopicFilter = errors.New("invalid topic filter")
+
+	// ErrPacketTooLarge is returned when an outgoing packet's encoded size
+	// exceeds the Maximum Packet Size advertised by the server (MQTT v5.0).
+	// The packet is rejected locally and never sent.
+	ErrPacketTooLarge = errors.New("packet too large")
+
+	// ErrPayloadTooLarge is returned when a publish payload exceeds the
+	// client-side limit set with WithMaxPayloadSize or SetTopicPayloadLimit.
+	// The message is rejected locally and never sent.
+	ErrPayloadTooLarge = errors.New("payload too large")
+
+	// ErrPayloadFormatInvalid is returned when a publish declares a UTF-8
+	// payload with WithPayloadFormat(PayloadFormatUTF8) but the payload is
+	// not valid UTF-8. The message is rejected locally and never sent, since
+	// the server may answer it with reason code 0x99 and disconnect.
+	ErrPayloadFormatInvalid = errors.New("payload format invalid")
+
+	// ErrRetainNotSupported is returned when a retained message is published
+	// (WithRetain, PublishRetained, ClearRetained) but the server reported in
+	// CONNACK that it does not support retained messages (MQTT v5.0). The
+	// message is rejected locally and never sent.
+	ErrRetainNotSupported = errors.New("retained messages not supported by server")
+
+	// ErrWildcardNotSupported is returned when a subscription uses a
+	// wildcard (+ or #) but the server reported in CONNACK that it does not
+	// support wildcard subscriptions (MQTT v5.0). Nothing is sent.
+	ErrWildcardNotSupported = errors.New("wildcard subscriptions not supported by server")
+
+	// ErrSharedSubNotSupported is returned when a subscription uses a
+	// "$share/" topic filter but the server reported in CONNACK that it does
+	// not support shared subscriptions (MQTT v5.0). Nothing is sent.
+	ErrSharedSubNotSupported = errors.New("shared subscriptions not supported by server")
+
+	// ErrSubscriptionIDNotSupported is returned when a subscription sets
+	// WithSubscriptionIdentifier but the server reported in CONNACK that it
+	// does not support subscription identifiers (MQTT v5.0). Nothing is sent.
+	ErrSubscriptionIDNotSupported = errors.New("subscription identifiers not supported by server")
+
+	// ErrPacketIDsExhausted is returned when a SUBSCRIBE or UNSUBSCRIBE
+	// cannot be sent because every packet identifier (see WithPacketIDRange)
+	// is in use by a pending operation. Nothing is sent. Publishes wait for
+	// an identifier instead.
+	ErrPacketIDsExhausted = errors.New("no packet identifier available")
+
+	// ErrTopicAliasInvalid is returned when a publish requests a topic alias
+	// with WithAliasID above the number of aliases allowed on the connection,
+	// or on an MQTT v3.1.1 connection. The message is rejected locally and
+	// never sent.
+	ErrTopicAliasInvalid = errors.New("topic alias invalid")
+
+	// ErrProtocolViolation is returned when the server sends a packet that
+	// violates the MQTT specification, such as a CONNACK reporting a present
+	// session after a clean start was requested.
+	ErrProtocolViolation = errors.New("server protocol violation")
+
+	// ErrMalformedPacket is reported through OnConnectionLost when the client
+	// closed the connection because the server sent a packet that could not
+	// be decoded. For MQTT v5.0, the client sends a DISCONNECT with Reason
+	// Code 0x81 (Malformed Packet) first.
+	ErrMalformedPacket = errors.New("malformed packet")
+
+	// ErrReconnectAttemptsExhausted is reported through OnConnectionLost when
+	// the client stopped after WithMaxReconnectAttempts consecutive failed
+	// reconnection attempts.
+	ErrReconnectAttemptsExhausted = errors.New("reconnect attempts exhausted")
+
+	// ErrStoreDecryption is returned when an entry of an encrypted FileStore
+	// (NewEncryptedFileStore) cannot be decrypted, because the key is wrong
+	// or the file was modified or corrupted.
+	ErrStoreDecryption = errors.New("session store decryption failed")
+
 	// ErrClientDisconnected is returned when an operation is cancelled because
 	// the client was disconnected or stopped.
 	ErrClientDisconnected = errors.New("client disconnected")
+
+	// ErrGracefulDisconnect is returned instead of ErrClientDisconnected when
+	// an operation is cancelled because Disconnect was called, so callers can
+	// tell a requested shutdown from a lost connection. It wraps
+	// ErrClientDisconnected, so errors.Is matches both.
+	ErrGracefulDisconnect = fmt.Errorf("%w: Disconnect called", ErrClientDisconnected)
 )
 
 // MqttError represents an error returned by the MQTT server, including
@@ -58,6 +147,20 @@ func (e *MqttError) Is(target error) bool {
 
 // DisconnectError represents a DISCONNECT packet received from the server,
 // containing potential MQTT v5.0 properties.
+//
+// It matches its reason code with errors.Is, also when wrapped, so the
+// connection lost handler can tell causes apart.
+//
+// Example:
+//
+//	mq.WithOnConnectionLost(func(c *mq.Client, err error) {
+//	    switch {
+//	    case errors.Is(err, mq.ReasonCodeServerShuttingDown):
+//	        // Try another server
+//	    case errors.Is(err, mq.ReasonCodeSessionTakenOver):
+//	        // Another client uses our ID; stop retrying
+//	    }
+//	})
 type DisconnectError struct {
 	ReasonCode            ReasonCode
 	ReasonString          string
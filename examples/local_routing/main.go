@@ -1,7 +1,7 @@
 //go:build ignore_test
 
 // This example demonstrates how to implement client-side routing for messages
-// using mq.WithDefaultPublishHandler and mq.MatchTopic.
+// using mq.WithDefaultPublishHandler and mq.Router.
 //
 // This pattern is useful for:
 //   - Centralized message dispatching
@@ -25,28 +25,25 @@ import (
 )
 
 func main() {
-	// 1. Define our custom routes map
-	// These are filters we want to handle locally.
+	// 1. Define our custom routes
+	// These are filters we want to handle locally. mq.Router matches a topic
+	// in time proportional to its levels, however many routes there are.
 	// Note: We are NOT calling client.Subscribe() with these handlers directly.
-	routes := map[string]mq.MessageHandler{
-		"system/alerts/#": handleSystemAlert,
-		"logs/error":      handleErrorLog,
-	}
+	var routes mq.Router
+	_ = routes.Add("system/alerts/#", handleSystemAlert)
+	_ = routes.Add("logs/error", handleErrorLog)
 
 	// 2. Create the central router (DefaultPublishHandler)
 	// This function receives ALL messages that don't match a specific
-	// callback registered via client.Subscribe().
+	// callback registered via client.Subscribe(). Without the UNMATCHED
+	// report, routes.Handle could be registered directly.
 	router := func(c *mq.Client, msg mq.Message) {
-		matched := false
-		// Check the message topic against our route filters
-		for filter, handler := range routes {
-			if mq.MatchTopic(filter, msg.Topic) {
-				handler(c, msg)
-				matched = true
-			}
+		handlers := routes.Match(msg.Topic)
+		for _, handler := range handlers {
+			handler(c, msg)
 		}
 
-		if !matched {
+		if len(handlers) == 0 {
 			fmt.Printf("[UNMATCHED] Topic: %s, Payload: %s\n", msg.Topic, string(msg.Payload))
 		}
 	}
@@ -108,6 +108,14 @@ func (s *ScramAuthenticator) Complete() error {
 	return nil
 }
 
+// Reset clears the exchange state so a reconnect starts a fresh handshake
+// (it implements mq.AuthenticatorResetter).
+func (s *ScramAuthenticator) Reset() {
+	s.clientNonce = ""
+	s.serverNonce = ""
+	s.authMsg = ""
+}
+
 // Helper: Compute HMAC-SHA256
 func computeHMAC(key, data []byte) []byte {
 	h := hmac.New(sha256.New, key)
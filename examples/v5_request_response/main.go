@@ -172,5 +172,17 @@ func main() {
 	// Wait for all responses
 	time.Sleep(2 * time.Second)
 
+	// The same request with client.Request, which manages the response
+	// topic and correlation data itself
+	fmt.Println("\n📤 Sending a request with client.Request")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := client.Request(ctx, requestTopic, []byte("get-temperature"), mq.WithQoS(1))
+	if err != nil {
+		fmt.Printf("   ⚠️  Request failed: %v\n", err)
+	} else {
+		fmt.Printf("   ✅ Received response: %s\n", resp.Payload)
+	}
+
 	fmt.Println("\n✅ Request-response pattern example completed!")
 }
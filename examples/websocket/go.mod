@@ -2,9 +2,14 @@ module github.com/gonzalop/mq/examples/websocket
 
 go 1.25.5
 
-replace github.com/gonzalop/mq => ../../
+replace (
+	github.com/gonzalop/mq => ../../
+	github.com/gonzalop/mq/wstransport => ../../wstransport
+)
 
 require (
 	github.com/gonzalop/mq v0.0.0-00010101000000-000000000000
-	nhooyr.io/websocket v1.8.17
+	github.com/gonzalop/mq/wstransport v0.0.0-00010101000000-000000000000
 )
+
+require github.com/coder/websocket v1.8.15 // indirect
@@ -5,14 +5,13 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"net"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/gonzalop/mq"
-	"nhooyr.io/websocket"
+	"github.com/gonzalop/mq/wstransport"
 )
 
 func main() {
@@ -27,24 +26,7 @@ func main() {
 		password = os.Args[3]
 	}
 
-	// 1. Create a custom dialer for WebSockets
-	wsDialer := mq.DialFunc(func(ctx context.Context, _ string, addr string) (net.Conn, error) {
-		// Note: 'addr' here is the full URL string like "ws://server.hivemq.com:8000/mqtt"
-		// If you passed "wss://..." it would be encrypted.
-
-		// Connect using nhooyr.io/websocket
-		c, _, err := websocket.Dial(ctx, addr, &websocket.DialOptions{
-			Subprotocols: []string{"mqtt"}, // Crucial: MQTT over Websocket subprotocol
-		})
-		if err != nil {
-			return nil, err
-		}
-
-		// Wrap the websocket connection to look like a net.Conn
-		return websocket.NetConn(ctx, c, websocket.MessageBinary), nil
-	})
-
-	// 2. Connect using the custom dialer
+	// 1. Connect using the WebSocket dialer
 	// Local Mosquitto with WebSockets enabled on port 9001
 	server := "ws://localhost:9001/"
 	if len(os.Args) > 1 {
@@ -56,7 +38,7 @@ func main() {
 
 	opts := []mq.Option{
 		mq.WithClientID(clientID),
-		mq.WithDialer(wsDialer), // Inject our custom dialer
+		mq.WithDialer(wstransport.Dialer()), // MQTT over WebSocket ("mqtt" subprotocol)
 		mq.WithCleanSession(true),
 	}
 
@@ -72,7 +54,7 @@ func main() {
 
 	fmt.Println("Connected!")
 
-	// 3. Subscribe
+	// 2. Subscribe
 	topic := "mq-test/websocket"
 	subReady := make(chan struct{})
 
@@ -87,7 +69,7 @@ func main() {
 	}
 	fmt.Printf("Subscribed to %s\n", topic)
 
-	// 4. Publish
+	// 3. Publish
 	fmt.Println("Publishing message...")
 	pubToken := client.Publish(topic, []byte("Hello from WebSockets!"), mq.WithQoS(1))
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -1,7 +1,7 @@
 package mq
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,7 +11,7 @@ import (
 // Compile-time check that FileStore implements SessionStore
 var _ SessionStore = (*FileStore)(nil)
 
-// FileStore implements SessionStore using JSON files on disk.
+// FileStore implements SessionStore using files on disk.
 // Each client ID gets its own directory containing separate files for
 // pending publishes, subscriptions, and received QoS 2 packet IDs.
 //
@@ -24,6 +24,10 @@ var _ SessionStore = (*FileStore)(nil)
 //	    subscriptions.json
 //	    qos2_received.json
 //
+// Files are encoded with JSONCodec unless another codec is selected with
+// WithStoreCodec; the extension follows the codec (e.g. ".bin" for
+// BinaryCodec).
+//
 // This implementation is synchronous - all operations block until complete.
 // For async/batched writes, users can implement a custom SessionStore.
 type FileStore struct {
@@ -34,6 +38,7 @@ type FileStore struct {
 
 type fileStoreConfig struct {
 	permissions os.FileMode
+	codec       StoreCodec
 }
 
 // FileStoreOption configures a FileStore.
@@ -52,6 +57,25 @@ func WithPermissions(perm os.FileMode) FileStoreOption {
 	}
 }
 
+// WithStoreCodec sets the serialization format of stored files.
+// Default is JSONCodec, which is human-readable and editable; BinaryCodec
+// is more compact. A nil codec is ignored.
+//
+// Files written with one codec are not read by another: switch codecs only
+// with an empty store (or after Clear).
+//
+// Example:
+//
+//	store, _ := mq.NewFileStore("/var/lib/mqtt", "sensor-1",
+//	    mq.WithStoreCodec(mq.BinaryCodec))
+func WithStoreCodec(codec StoreCodec) FileStoreOption {
+	return func(c *fileStoreConfig) {
+		if codec != nil {
+			c.codec = codec
+		}
+	}
+}
+
 // NewFileStore creates a file-based session store for the specified client ID.
 //
 // The baseDir will contain a subdirectory for each client ID, allowing
@@ -79,6 +103,7 @@ func NewFileStore(baseDir, clientID string, opts ...FileStoreOption) (*FileStore
 
 	cfg := &fileStoreConfig{
 		permissions: 0600,
+		codec:       JSONCodec,
 	}
 
 	for _, opt := range opts {
@@ -103,14 +128,53 @@ func (f *FileStore) ClientID() string {
 	return f.clientID
 }
 
+// path returns the path of the named entry, with the codec's extension.
+func (f *FileStore) path(name string) string {
+	return filepath.Join(f.dir, name+"."+f.config.codec.Extension())
+}
+
+// pendingName returns the entry name of the pending publish with packetID.
+func pendingName(packetID uint16) string {
+	return fmt.Sprintf("pending_%d", packetID)
+}
+
+// pendingPath returns the path of the pending publish with packetID.
+func (f *FileStore) pendingPath(packetID uint16) string {
+	return f.path(pendingName(packetID))
+}
+
+// entryCodec is implemented by codecs that bind each record to the name of
+// its entry, such as the encrypting codec of NewEncryptedFileStore, so that
+// records cannot be swapped between files.
+type entryCodec interface {
+	marshalEntry(name string, v any) ([]byte, error)
+	unmarshalEntry(name string, data []byte, v any) error
+}
+
+// marshal encodes v for the named entry.
+func (f *FileStore) marshal(name string, v any) ([]byte, error) {
+	if ec, ok := f.config.codec.(entryCodec); ok {
+		return ec.marshalEntry(name, v)
+	}
+	return f.config.codec.Marshal(v)
+}
+
+// unmarshal decodes the data of the named entry into v.
+func (f *FileStore) unmarshal(name string, data []byte, v any) error {
+	if ec, ok := f.config.codec.(entryCodec); ok {
+		return ec.unmarshalEntry(name, data, v)
+	}
+	return f.config.codec.Unmarshal(data, v)
+}
+
 // SavePendingPublish stores a pending publish to disk.
 func (f *FileStore) SavePendingPublish(packetID uint16, pub *PersistedPublish) error {
-	data, err := json.Marshal(pub)
+	data, err := f.marshal(pendingName(packetID), pub)
 	if err != nil {
 		return fmt.Errorf("failed to marshal publish: %w", err)
 	}
 
-	path := filepath.Join(f.dir, fmt.Sprintf("pending_%d.json", packetID))
+	path := f.pendingPath(packetID)
 	if err := os.WriteFile(path, data, f.config.permissions); err != nil {
 		return fmt.Errorf("failed to write pending publish: %w", err)
 	}
@@ -120,7 +184,7 @@ func (f *FileStore) SavePendingPublish(packetID uint16, pub *PersistedPublish) e
 
 // DeletePendingPublish removes a pending publish from disk.
 func (f *FileStore) DeletePendingPublish(packetID uint16) error {
-	path := filepath.Join(f.dir, fmt.Sprintf("pending_%d.json", packetID))
+	path := f.pendingPath(packetID)
 	err := os.Remove(path)
 	if os.IsNotExist(err) {
 		return nil // Already deleted
@@ -135,7 +199,7 @@ func (f *FileStore) DeletePendingPublish(packetID uint16) error {
 func (f *FileStore) LoadPendingPublishes() (map[uint16]*PersistedPublish, error) {
 	result := make(map[uint16]*PersistedPublish)
 
-	files, err := filepath.Glob(filepath.Join(f.dir, "pending_*.json"))
+	files, err := filepath.Glob(f.path("pending_*"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to list pending publishes: %w", err)
 	}
@@ -143,7 +207,7 @@ func (f *FileStore) LoadPendingPublishes() (map[uint16]*PersistedPublish, error)
 	for _, file := range files {
 		var packetID uint16
 		base := filepath.Base(file)
-		if _, err := fmt.Sscanf(base, "pending_%d.json", &packetID); err != nil {
+		if _, err := fmt.Sscanf(base, "pending_%d."+f.config.codec.Extension(), &packetID); err != nil {
 			continue // Skip malformed filenames
 		}
 
@@ -153,7 +217,10 @@ func (f *FileStore) LoadPendingPublishes() (map[uint16]*PersistedPublish, error)
 		}
 
 		var pub PersistedPublish
-		if err := json.Unmarshal(data, &pub); err != nil {
+		if err := f.unmarshal(pendingName(packetID), data, &pub); err != nil {
+			if errors.Is(err, ErrStoreDecryption) {
+				return nil, fmt.Errorf("failed to load pending publish %d: %w", packetID, err)
+			}
 			continue // Skip corrupted files
 		}
 
@@ -165,7 +232,7 @@ func (f *FileStore) LoadPendingPublishes() (map[uint16]*PersistedPublish, error)
 
 // ClearPendingPublishes removes all pending publishes from disk.
 func (f *FileStore) ClearPendingPublishes() error {
-	files, err := filepath.Glob(filepath.Join(f.dir, "pending_*.json"))
+	files, err := filepath.Glob(f.path("pending_*"))
 	if err != nil {
 		return fmt.Errorf("failed to list pending publishes: %w", err)
 	}
@@ -186,12 +253,12 @@ func (f *FileStore) SaveSubscription(topic string, sub *PersistedSubscription) e
 
 	subs[topic] = sub
 
-	data, err := json.Marshal(subs)
+	data, err := f.marshal("subscriptions", subs)
 	if err != nil {
 		return fmt.Errorf("failed to marshal subscriptions: %w", err)
 	}
 
-	path := filepath.Join(f.dir, "subscriptions.json")
+	path := f.path("subscriptions")
 	if err := os.WriteFile(path, data, f.config.permissions); err != nil {
 		return fmt.Errorf("failed to write subscriptions: %w", err)
 	}
@@ -209,17 +276,17 @@ func (f *FileStore) DeleteSubscription(topic string) error {
 	delete(subs, topic)
 
 	if len(subs) == 0 {
-		path := filepath.Join(f.dir, "subscriptions.json")
+		path := f.path("subscriptions")
 		os.Remove(path)
 		return nil
 	}
 
-	data, err := json.Marshal(subs)
+	data, err := f.marshal("subscriptions", subs)
 	if err != nil {
 		return fmt.Errorf("failed to marshal subscriptions: %w", err)
 	}
 
-	path := filepath.Join(f.dir, "subscriptions.json")
+	path := f.path("subscriptions")
 	if err := os.WriteFile(path, data, f.config.permissions); err != nil {
 		return fmt.Errorf("failed to write subscriptions: %w", err)
 	}
@@ -229,7 +296,7 @@ func (f *FileStore) DeleteSubscription(topic string) error {
 
 // LoadSubscriptions loads all subscriptions from disk.
 func (f *FileStore) LoadSubscriptions() (map[string]*PersistedSubscription, error) {
-	path := filepath.Join(f.dir, "subscriptions.json")
+	path := f.path("subscriptions")
 
 	data, err := os.ReadFile(path)
 	if os.IsNotExist(err) {
@@ -240,7 +307,7 @@ func (f *FileStore) LoadSubscriptions() (map[string]*PersistedSubscription, erro
 	}
 
 	var subs map[string]*PersistedSubscription
-	if err := json.Unmarshal(data, &subs); err != nil {
+	if err := f.unmarshal("subscriptions", data, &subs); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal subscriptions: %w", err)
 	}
 
@@ -261,12 +328,12 @@ func (f *FileStore) SaveReceivedQoS2(packetID uint16) error {
 		ids = append(ids, id)
 	}
 
-	data, err := json.Marshal(ids)
+	data, err := f.marshal("qos2_received", ids)
 	if err != nil {
 		return fmt.Errorf("failed to marshal QoS2 IDs: %w", err)
 	}
 
-	path := filepath.Join(f.dir, "qos2_received.json")
+	path := f.path("qos2_received")
 	if err := os.WriteFile(path, data, f.config.permissions); err != nil {
 		return fmt.Errorf("failed to write QoS2 IDs: %w", err)
 	}
@@ -284,7 +351,7 @@ func (f *FileStore) DeleteReceivedQoS2(packetID uint16) error {
 	delete(qos2, packetID)
 
 	if len(qos2) == 0 {
-		path := filepath.Join(f.dir, "qos2_received.json")
+		path := f.path("qos2_received")
 		os.Remove(path)
 		return nil
 	}
@@ -294,12 +361,12 @@ func (f *FileStore) DeleteReceivedQoS2(packetID uint16) error {
 		ids = append(ids, id)
 	}
 
-	data, err := json.Marshal(ids)
+	data, err := f.marshal("qos2_received", ids)
 	if err != nil {
 		return fmt.Errorf("failed to marshal QoS2 IDs: %w", err)
 	}
 
-	path := filepath.Join(f.dir, "qos2_received.json")
+	path := f.path("qos2_received")
 	if err := os.WriteFile(path, data, f.config.permissions); err != nil {
 		return fmt.Errorf("failed to write QoS2 IDs: %w", err)
 	}
@@ -309,7 +376,7 @@ func (f *FileStore) DeleteReceivedQoS2(packetID uint16) error {
 
 // LoadReceivedQoS2 loads all received QoS 2 packet IDs.
 func (f *FileStore) LoadReceivedQoS2() (map[uint16]struct{}, error) {
-	path := filepath.Join(f.dir, "qos2_received.json")
+	path := f.path("qos2_received")
 
 	data, err := os.ReadFile(path)
 	if os.IsNotExist(err) {
@@ -320,7 +387,7 @@ func (f *FileStore) LoadReceivedQoS2() (map[uint16]struct{}, error) {
 	}
 
 	var ids []uint16
-	if err := json.Unmarshal(data, &ids); err != nil {
+	if err := f.unmarshal("qos2_received", data, &ids); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal QoS2 IDs: %w", err)
 	}
 
@@ -334,7 +401,7 @@ func (f *FileStore) LoadReceivedQoS2() (map[uint16]struct{}, error) {
 
 // ClearReceivedQoS2 removes all received QoS 2 packet IDs.
 func (f *FileStore) ClearReceivedQoS2() error {
-	path := filepath.Join(f.dir, "qos2_received.json")
+	path := f.path("qos2_received")
 	err := os.Remove(path)
 	if os.IsNotExist(err) {
 		return nil
@@ -354,9 +421,11 @@ func (f *FileStore) Clear() error {
 			continue
 		}
 		name := entry.Name()
+		// Match any extension so entries left by a previous codec go too
+		base := strings.TrimSuffix(name, filepath.Ext(name))
 		if strings.HasPrefix(name, "pending_") ||
-			name == "subscriptions.json" ||
-			name == "qos2_received.json" {
+			base == "subscriptions" ||
+			base == "qos2_received" {
 			_ = os.Remove(filepath.Join(f.dir, name))
 		}
 	}
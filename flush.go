@@ -0,0 +1,76 @@
+package mq
+
+import (
+	"context"
+	"io"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// flushMarker is queued by Flush. It writes nothing: the write loop flushes
+// the batch it is part of, which completes the token of its
+// writeNotifyPacket.
+type flushMarker struct{}
+
+func (flushMarker) Type() uint8                      { return 0 }
+func (flushMarker) WriteTo(io.Writer) (int64, error) { return 0, nil }
+
+// isFlushMarker reports whether pkt was queued by Flush and must not be
+// counted as a sent packet.
+func isFlushMarker(pkt packets.Packet) bool {
+	_, ok := unwrapPacket(pkt).(flushMarker)
+	return ok
+}
+
+// Flush waits until every packet queued for sending before the call has
+// been written to the network, flushing the write buffer.
+//
+// The write loop already flushes as soon as it runs out of queued packets,
+// so Flush is mostly useful as a barrier: when it returns nil, a preceding
+// Publish, including a QoS 0 one, has left the client, and nothing is held
+// back waiting for more traffic. It does not wait for acknowledgments; use
+// the token (or PublishSync) for that.
+//
+// It returns ErrClientDisconnected if the client is not connected or the
+// connection is lost before the flush (ErrGracefulDisconnect after
+// Disconnect), and ctx.Err() if ctx is done first.
+//
+// Example:
+//
+//	client.Publish("robot/arm/stop", nil)
+//	if err := client.Flush(ctx); err != nil {
+//	    log.Printf("stop command not sent: %v", err)
+//	}
+func (c *Client) Flush(ctx context.Context) error {
+	c.connLock.RLock()
+	connDone := c.connDone
+	c.connLock.RUnlock()
+
+	if !c.IsConnected() {
+		return c.stopError()
+	}
+
+	tok := newToken()
+	pkt := &writeNotifyPacket{Packet: flushMarker{}, token: tok}
+
+	select {
+	case c.outgoing <- pkt:
+	case <-connDone:
+		return c.stopError()
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.stop:
+		return c.stopError()
+	}
+
+	select {
+	case <-tok.Done():
+		return tok.Error()
+	case <-connDone:
+		return c.stopError()
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.stop:
+		return c.stopError()
+	}
+}
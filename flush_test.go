@@ -0,0 +1,82 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestFlush(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan *packets.PublishPacket, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = packets.ReadPacket(conn, ProtocolV50, 0)
+		_, _ = (&packets.ConnackPacket{ReturnCode: 0}).WriteTo(conn)
+		for {
+			pkt, err := packets.ReadPacket(conn, ProtocolV50, 0)
+			if err != nil {
+				return
+			}
+			if p, ok := pkt.(*packets.PublishPacket); ok {
+				received <- p
+			}
+		}
+	}()
+
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("flush-client"),
+		WithAutoReconnect(false),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sent := client.GetStats().PacketsSent
+	client.Publish("robot/stop", []byte("now"))
+	if err := client.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	// The publish was on the wire when Flush returned
+	select {
+	case p := <-received:
+		if p.Topic != "robot/stop" {
+			t.Errorf("received topic %q, want robot/stop", p.Topic)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("publish not received after Flush")
+	}
+
+	if got := client.GetStats().PacketsSent - sent; got != 1 {
+		t.Errorf("PacketsSent grew by %d, want 1 (Flush itself sends nothing)", got)
+	}
+
+	// Done context
+	canceled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	if err := client.Flush(canceled); err != nil && !errors.Is(err, context.Canceled) {
+		t.Errorf("Flush with canceled context = %v, want nil or context.Canceled", err)
+	}
+
+	_ = client.Disconnect(context.Background())
+	if err := client.Flush(ctx); !errors.Is(err, ErrGracefulDisconnect) {
+		t.Errorf("Flush after Disconnect = %v, want ErrGracefulDisconnect", err)
+	}
+}
@@ -0,0 +1,51 @@
+package mq
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestStopErrorGraceful verifies that pending and queued operations
+// complete with ErrGracefulDisconnect after Disconnect and with
+// ErrClientDisconnected on any other stop.
+func TestStopErrorGraceful(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested bool
+		want      error
+	}{
+		{"Disconnect", true, ErrGracefulDisconnect},
+		{"abnormal stop", false, ErrClientDisconnected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultOptions("tcp://localhost:1883")
+			opts.Logger = testLogger()
+			c := newTestClient(opts)
+			c.disconnectRequested.Store(tt.requested)
+
+			pending := newToken()
+			queued := newToken()
+			c.pending[1] = &pendingOp{token: pending}
+			c.publishQueue = append(c.publishQueue, &publishRequest{token: queued})
+
+			c.wg.Add(1)
+			go c.logicLoop()
+			close(c.stop)
+			c.wg.Wait()
+
+			for name, tok := range map[string]*token{"pending": pending, "queued": queued} {
+				err := tok.Error()
+				if err != tt.want {
+					t.Errorf("%s token error = %v, want %v", name, err, tt.want)
+				}
+				if !errors.Is(err, ErrClientDisconnected) {
+					t.Errorf("%s token error %v does not match ErrClientDisconnected", name, err)
+				}
+				if got := errors.Is(err, ErrGracefulDisconnect); got != tt.requested {
+					t.Errorf("errors.Is(%v, ErrGracefulDisconnect) = %v, want %v", err, got, tt.requested)
+				}
+			}
+		})
+	}
+}
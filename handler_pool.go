@@ -0,0 +1,39 @@
+package mq
+
+// handlerPool runs message handlers on a fixed set of worker goroutines
+// (WithHandlerConcurrency), instead of one goroutine per invocation.
+type handlerPool struct {
+	jobs chan func()
+}
+
+// newHandlerPool starts n workers that run queued handlers until stop is
+// closed. Handlers still queued at that point are discarded.
+func newHandlerPool(n int, stop <-chan struct{}) *handlerPool {
+	p := &handlerPool{jobs: make(chan func(), n)}
+	for range n {
+		go p.work(stop)
+	}
+	return p
+}
+
+func (p *handlerPool) work(stop <-chan struct{}) {
+	for {
+		select {
+		case fn := <-p.jobs:
+			fn()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// submit queues fn, blocking while every worker is busy and the queue is
+// full. It reports false if stop was closed first.
+func (p *handlerPool) submit(fn func(), stop <-chan struct{}) bool {
+	select {
+	case p.jobs <- fn:
+		return true
+	case <-stop:
+		return false
+	}
+}
@@ -0,0 +1,151 @@
+package mq
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func newHandlerPoolClient(n int, handler MessageHandler) *Client {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	opts.HandlerConcurrency = n
+	c := newTestClient(opts)
+	c.handlerPool = newHandlerPool(n, c.stop)
+	c.subscriptions["jobs/#"] = subscriptionEntry{handler: handler}
+	return c
+}
+
+func TestWithHandlerConcurrency(t *testing.T) {
+	tests := []struct {
+		n    int
+		want int
+	}{
+		{8, 8},
+		{0, 0},
+		{-1, 0},
+	}
+	for _, tt := range tests {
+		opts := defaultOptions("tcp://localhost:1883")
+		WithHandlerConcurrency(tt.n)(opts)
+		if opts.HandlerConcurrency != tt.want {
+			t.Errorf("WithHandlerConcurrency(%d): got %d, want %d", tt.n, opts.HandlerConcurrency, tt.want)
+		}
+	}
+}
+
+func TestHandlerPool_BoundsConcurrency(t *testing.T) {
+	const workers, messages = 3, 50
+
+	var running, peak, done atomic.Int32
+	release := make(chan struct{})
+	c := newHandlerPoolClient(workers, func(*Client, Message) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		running.Add(-1)
+		done.Add(1)
+	})
+	defer close(c.stop)
+
+	dispatched := make(chan struct{})
+	go func() {
+		defer close(dispatched)
+		for i := range messages {
+			c.handlePublish(&packets.PublishPacket{Topic: fmt.Sprintf("jobs/%d", i)})
+		}
+	}()
+
+	// Workers busy and queue full: the dispatch must be held back
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-dispatched:
+		t.Fatal("dispatch did not block while every worker was busy")
+	default:
+	}
+	if got := running.Load(); got != workers {
+		t.Errorf("%d handlers running, want %d", got, workers)
+	}
+
+	close(release)
+	select {
+	case <-dispatched:
+	case <-time.After(2 * time.Second):
+		t.Fatal("dispatch did not resume")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for done.Load() < messages && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := done.Load(); got != messages {
+		t.Fatalf("%d of %d handlers ran", got, messages)
+	}
+	if got := peak.Load(); got > workers {
+		t.Errorf("peak concurrency %d exceeds the pool size %d", got, workers)
+	}
+}
+
+func TestHandlerPool_SingleWorkerOrder(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	finished := make(chan struct{}, 100)
+	c := newHandlerPoolClient(1, func(_ *Client, msg Message) {
+		mu.Lock()
+		got = append(got, msg.Topic)
+		mu.Unlock()
+		finished <- struct{}{}
+	})
+	defer close(c.stop)
+
+	var want []string
+	for i := range 20 {
+		topic := fmt.Sprintf("jobs/%d", i)
+		want = append(want, topic)
+		c.handlePublish(&packets.PublishPacket{Topic: topic})
+	}
+	for range want {
+		select {
+		case <-finished:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for handlers")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(got, want) {
+		t.Errorf("delivered %v, want %v", got, want)
+	}
+}
+
+func TestHandlerPool_Stop(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	c := newHandlerPoolClient(1, func(*Client, Message) { <-block })
+
+	dispatched := make(chan struct{})
+	go func() {
+		defer close(dispatched)
+		for range 5 { // One running, one queued, the rest waiting
+			c.handlePublish(&packets.PublishPacket{Topic: "jobs/x"})
+		}
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	close(c.stop)
+	select {
+	case <-dispatched:
+	case <-time.After(2 * time.Second):
+		t.Fatal("dispatch still blocked after stop")
+	}
+}
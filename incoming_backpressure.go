@@ -0,0 +1,120 @@
+package mq
+
+import (
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// queueOrOverflow is used by readLoop with a non-blocking
+// IncomingBackpressureStrategy. It queues pkt if there is room and otherwise
+// applies the strategy. It returns false if pkt must still be queued with the
+// usual blocking send. connDone is closed when the connection pkt was read
+// from is torn down.
+func (c *Client) queueOrOverflow(pkt packets.Packet, connDone <-chan struct{}) bool {
+	// PINGRESP only needs the writeLoop notified and involves no session
+	// state. Handle it here so keepalive never waits behind slow handlers,
+	// even when it would fit in the queue.
+	if _, ok := pkt.(*packets.PingrespPacket); ok {
+		select {
+		case c.pingPendingCh <- struct{}{}:
+		default:
+		}
+		return true
+	}
+
+	// Some publishes may skip the queue, so inbound topic aliases are
+	// resolved here, in stream order, rather than in handlePublish. A
+	// dropped message may carry an alias mapping that later messages rely on.
+	resolved, droppable := true, false
+	if p, ok := pkt.(*packets.PublishPacket); ok {
+		resolved = c.resolveIncomingAlias(p) // Else handlePublish reports it
+		droppable = resolved && p.QoS == 0
+	}
+
+	if c.opts.IncomingStrategy == IncomingBackpressureDropOldest {
+		// Everything goes through the overflow buffer, so nothing overtakes
+		// the packets waiting there
+		c.overflow.push(pkt, droppable, c.stop, connDone, c.noteIncomingDropped)
+		return true
+	}
+	if !resolved {
+		return false
+	}
+
+	select {
+	case c.incoming <- pkt:
+		return true
+	default:
+		return c.handleIncomingOverflow(pkt)
+	}
+}
+
+// handleIncomingOverflow applies the IncomingBackpressureStrategy to a packet
+// that does not fit in the incoming queue.
+func (c *Client) handleIncomingOverflow(pkt packets.Packet) bool {
+	switch p := pkt.(type) {
+	case *packets.PublishPacket:
+		if p.QoS > 0 && c.opts.IncomingStrategy != IncomingBackpressureShed {
+			return false
+		}
+		if p.QoS > 0 {
+			// Not acknowledged: the server keeps it in flight and redelivers it
+			c.opts.Logger.Debug("incoming queue full, shedding message",
+				"topic", p.Topic, "qos", p.QoS, "packet_id", p.PacketID)
+			return true
+		}
+		if c.opts.IncomingStrategy == IncomingBackpressureDropNewest {
+			c.noteIncomingDropped(pkt)
+			return true
+		}
+
+		c.opts.Logger.Debug("incoming queue full, spilling QoS 0 message", "topic", p.Topic)
+		if c.opts.OnIncomingSpill != nil {
+			c.opts.OnIncomingSpill(c, Message{
+				Topic:      p.Topic,
+				Payload:    p.Payload,
+				QoS:        AtMostOnce,
+				Retained:   p.Retain,
+				Duplicate:  p.Dup,
+				Properties: toPublicProperties(p.Properties),
+				ReceivedAt: time.Now(),
+			})
+		}
+		return true
+	}
+
+	return false
+}
+
+// resolveIncomingAlias registers or resolves the inbound topic alias of p.
+// Alias-only messages get their topic filled in; handlePublish then sees a
+// regular topic+alias message and registers the same mapping again. It
+// returns false if the alias is invalid or unknown.
+func (c *Client) resolveIncomingAlias(p *packets.PublishPacket) bool {
+	if c.opts.ProtocolVersion < ProtocolV50 || p.Properties == nil ||
+		p.Properties.Presence&packets.PresTopicAlias == 0 {
+		return true
+	}
+
+	aliasID := p.Properties.TopicAlias
+	if aliasMax := c.topicAliasMaximum(); aliasID == 0 || (aliasMax > 0 && aliasID > aliasMax) {
+		return false
+	}
+
+	if p.Topic == "" {
+		c.receivedAliasesLock.RLock()
+		topic, exists := c.receivedAliases[aliasID]
+		c.receivedAliasesLock.RUnlock()
+		if !exists {
+			return false
+		}
+		p.Topic = topic
+		return true
+	}
+
+	c.receivedAliasesLock.Lock()
+	c.receivedAliases[aliasID] = p.Topic
+	c.receivedAliasesLock.Unlock()
+	return true
+}
@@ -0,0 +1,141 @@
+package mq
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// runSlowConsumer connects a client with a single, blocked handler to a
+// server that floods it with messages and then answers keepalive pings.
+// It returns whether the client stayed connected past the keepalive timeout,
+// plus the number of spilled messages and PUBACKs seen by the server.
+func runSlowConsumer(t *testing.T, strategy IncomingBackpressureStrategy, qos uint8) (alive bool, spilled, acks int32) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	const flood = 50
+	var pubacks atomic.Int32
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+			return
+		}
+		connack := &packets.ConnackPacket{ReturnCode: 0}
+		if _, err := connack.WriteTo(conn); err != nil {
+			return
+		}
+
+		go func() {
+			for i := 1; i <= flood; i++ {
+				pub := &packets.PublishPacket{
+					Topic:    "flood",
+					QoS:      qos,
+					Payload:  []byte("x"),
+					Version:  ProtocolV50,
+					PacketID: uint16(i),
+				}
+				if _, err := pub.WriteTo(conn); err != nil {
+					return
+				}
+			}
+		}()
+
+		for {
+			pkt, err := packets.ReadPacket(conn, ProtocolV50, 0)
+			if err != nil {
+				return
+			}
+			switch pkt.(type) {
+			case *packets.PingreqPacket:
+				if _, err := (&packets.PingrespPacket{}).WriteTo(conn); err != nil {
+					return
+				}
+			case *packets.PubackPacket:
+				pubacks.Add(1)
+			}
+		}
+	}()
+
+	release := make(chan struct{})
+	var once sync.Once
+	defer once.Do(func() { close(release) })
+
+	var spills atomic.Int32
+	lost := make(chan struct{})
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("slow-consumer"),
+		WithAutoReconnect(false),
+		WithKeepAlive(time.Second),
+		WithIncomingQueueSize(1),
+		WithMaxHandlerConcurrency(1),
+		WithIncomingBackpressureStrategy(strategy),
+		WithIncomingSpillHandler(func(_ *Client, _ Message) { spills.Add(1) }),
+		WithDefaultPublishHandler(func(_ *Client, _ Message) { <-release }),
+		WithOnConnectionLost(func(_ *Client, _ error) { close(lost) }),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	// Keepalive timeout is 1.5x the interval
+	select {
+	case <-lost:
+		alive = false
+	case <-time.After(2500 * time.Millisecond):
+		alive = client.IsConnected()
+	}
+	once.Do(func() { close(release) })
+
+	return alive, spills.Load(), pubacks.Load()
+}
+
+func TestIncomingBackpressureStrategy(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for keepalive timeouts")
+	}
+
+	tests := []struct {
+		name       string
+		strategy   IncomingBackpressureStrategy
+		qos        uint8
+		wantAlive  bool
+		wantSpills bool
+	}{
+		{"block disconnects", IncomingBackpressureBlock, 0, false, false},
+		{"spill QoS 0", IncomingBackpressureSpillQoS0, 0, true, true},
+		{"shed QoS 1", IncomingBackpressureShed, 1, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			alive, spilled, acks := runSlowConsumer(t, tt.strategy, tt.qos)
+			if alive != tt.wantAlive {
+				t.Errorf("connection alive = %v, want %v", alive, tt.wantAlive)
+			}
+			if (spilled > 0) != tt.wantSpills {
+				t.Errorf("spilled %d messages, want spills = %v", spilled, tt.wantSpills)
+			}
+			if tt.strategy == IncomingBackpressureShed && acks >= 50 {
+				t.Errorf("expected shed messages to stay unacknowledged, got %d PUBACKs", acks)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package mq
+
+import (
+	"sync"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// noteIncomingDropped records a QoS 0 message discarded by
+// IncomingBackpressureDropNewest or IncomingBackpressureDropOldest.
+func (c *Client) noteIncomingDropped(pkt packets.Packet) {
+	c.incomingDropped.Add(1)
+	if p, ok := pkt.(*packets.PublishPacket); ok {
+		c.opts.Logger.Debug("incoming queue full, dropping QoS 0 message", "topic", p.Topic)
+	}
+}
+
+// overflowBuffer holds the packets that did not fit in the incoming queue
+// with IncomingBackpressureDropOldest. Packets go through it in order, so QoS 1/2
+// messages and acknowledgments are never overtaken; only QoS 0 messages
+// still waiting in it can be discarded.
+type overflowBuffer struct {
+	mu        sync.Mutex
+	items     []packets.Packet
+	droppable []bool
+	max       int
+
+	ready chan struct{} // Signaled after a push
+	space chan struct{} // Signaled after a pop
+}
+
+func newOverflowBuffer(size int) *overflowBuffer {
+	return &overflowBuffer{
+		max:   size,
+		ready: make(chan struct{}, 1),
+		space: make(chan struct{}, 1),
+	}
+}
+
+// push appends pkt. When the buffer is full, the oldest droppable packet is
+// discarded to make room for a droppable pkt, or pkt itself if there is
+// none; other packets wait for room. It returns false if stop or connDone
+// was closed first.
+func (b *overflowBuffer) push(pkt packets.Packet, droppable bool, stop, connDone <-chan struct{}, dropped func(packets.Packet)) bool {
+	for {
+		b.mu.Lock()
+		if len(b.items) < b.max {
+			b.appendLocked(pkt, droppable)
+			b.mu.Unlock()
+			return true
+		}
+		if droppable {
+			i := 0
+			for i < len(b.items) && !b.droppable[i] {
+				i++
+			}
+			if i == len(b.items) {
+				b.mu.Unlock()
+				dropped(pkt)
+				return true
+			}
+			old := b.items[i]
+			b.items = append(b.items[:i], b.items[i+1:]...)
+			b.droppable = append(b.droppable[:i], b.droppable[i+1:]...)
+			b.appendLocked(pkt, droppable)
+			b.mu.Unlock()
+			dropped(old)
+			return true
+		}
+		b.mu.Unlock()
+
+		select {
+		case <-b.space:
+		case <-stop:
+			return false
+		case <-connDone:
+			return false
+		}
+	}
+}
+
+func (b *overflowBuffer) appendLocked(pkt packets.Packet, droppable bool) {
+	b.items = append(b.items, pkt)
+	b.droppable = append(b.droppable, droppable)
+	select {
+	case b.ready <- struct{}{}:
+	default:
+	}
+}
+
+// clear discards the buffered packets, which belong to a connection that is
+// gone: acknowledgments would refer to its packet IDs, and the server
+// redelivers QoS 1 and QoS 2 messages on the next one.
+func (b *overflowBuffer) clear() {
+	b.mu.Lock()
+	b.items, b.droppable = nil, nil
+	b.mu.Unlock()
+	select {
+	case b.space <- struct{}{}:
+	default:
+	}
+}
+
+// pop removes and returns the oldest packet, waiting for one until stop is
+// closed.
+func (b *overflowBuffer) pop(stop <-chan struct{}) (packets.Packet, bool) {
+	for {
+		b.mu.Lock()
+		if len(b.items) > 0 {
+			pkt := b.items[0]
+			b.items[0] = nil
+			b.items = b.items[1:]
+			b.droppable = b.droppable[1:]
+			if len(b.items) == 0 {
+				b.items, b.droppable = nil, nil // Release the backing arrays after a burst
+			}
+			b.mu.Unlock()
+			select {
+			case b.space <- struct{}{}:
+			default:
+			}
+			return pkt, true
+		}
+		b.mu.Unlock()
+
+		select {
+		case <-b.ready:
+		case <-stop:
+			return nil, false
+		}
+	}
+}
+
+// forwardOverflow moves packets from the overflow buffer to the incoming
+// queue, blocking while it is full, until the client stops.
+func (c *Client) forwardOverflow() {
+	defer c.wg.Done()
+	for {
+		pkt, ok := c.overflow.pop(c.stop)
+		if !ok {
+			return
+		}
+		select {
+		case c.incoming <- pkt:
+		case <-c.stop:
+			return
+		}
+	}
+}
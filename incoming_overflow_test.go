@@ -0,0 +1,206 @@
+package mq
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func newOverflowClient(policy IncomingOverflowPolicy, size int) *Client {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	opts.IncomingQueueSize = size
+	WithIncomingOverflowPolicy(policy)(opts)
+	c := newTestClient(opts)
+	if policy == IncomingOverflowDropOldest {
+		c.overflow = newOverflowBuffer(size)
+	}
+	return c
+}
+
+func qos0(topic string) *packets.PublishPacket {
+	return &packets.PublishPacket{Topic: topic}
+}
+
+func qos1(topic string, id uint16) *packets.PublishPacket {
+	return &packets.PublishPacket{Topic: topic, QoS: 1, PacketID: id}
+}
+
+func topicsOf(pkts []packets.Packet) []string {
+	var topics []string
+	for _, pkt := range pkts {
+		topics = append(topics, pkt.(*packets.PublishPacket).Topic)
+	}
+	return topics
+}
+
+func drainIncoming(c *Client) []packets.Packet {
+	var pkts []packets.Packet
+	for len(c.incoming) > 0 {
+		pkts = append(pkts, <-c.incoming)
+	}
+	return pkts
+}
+
+func TestWithIncomingOverflowPolicy(t *testing.T) {
+	tests := []struct {
+		policy IncomingOverflowPolicy
+		want   IncomingBackpressureStrategy
+	}{
+		{IncomingOverflowBlock, IncomingBackpressureBlock},
+		{IncomingOverflowDropNewest, IncomingBackpressureDropNewest},
+		{IncomingOverflowDropOldest, IncomingBackpressureDropOldest},
+	}
+	for _, tt := range tests {
+		opts := defaultOptions("tcp://localhost:1883")
+		WithIncomingBackpressureStrategy(IncomingBackpressureShed)(opts)
+		WithIncomingOverflowPolicy(tt.policy)(opts)
+		if opts.IncomingStrategy != tt.want {
+			t.Errorf("policy %d: strategy = %d, want %d", tt.policy, opts.IncomingStrategy, tt.want)
+		}
+	}
+
+	c := newClient("tcp://localhost:1883", []Option{WithIncomingOverflowPolicy(IncomingOverflowDropOldest)})
+	if c.overflow == nil {
+		t.Error("expected IncomingOverflowDropOldest to create the overflow buffer")
+	}
+}
+
+func TestIncomingOverflowDropNewest(t *testing.T) {
+	c := newOverflowClient(IncomingOverflowDropNewest, 2)
+
+	for _, topic := range []string{"a", "b", "c", "d"} {
+		if !c.queueOrOverflow(qos0(topic), nil) {
+			t.Fatal("QoS 0 message left to the blocking send")
+		}
+	}
+	if got := c.GetStats().IncomingDropped; got != 2 {
+		t.Errorf("IncomingDropped = %d, want 2", got)
+	}
+
+	// PINGRESP bypasses the full queue
+	c.queueOrOverflow(&packets.PingrespPacket{}, nil)
+	select {
+	case <-c.pingPendingCh:
+	default:
+		t.Error("PINGRESP was not processed directly")
+	}
+
+	// QoS 1 waits for room instead of being dropped
+	if c.queueOrOverflow(qos1("q1", 1), nil) {
+		t.Fatal("QoS 1 message was not left to the blocking send")
+	}
+	if want := []string{"a", "b"}; !slices.Equal(topicsOf(drainIncoming(c)), want) {
+		t.Errorf("queued messages differ, want %v", want)
+	}
+	if got := c.GetStats().IncomingDropped; got != 2 {
+		t.Errorf("IncomingDropped = %d after QoS 1, want 2", got)
+	}
+}
+
+func TestIncomingOverflowDropOldest(t *testing.T) {
+	c := newOverflowClient(IncomingOverflowDropOldest, 2)
+
+	for _, topic := range []string{"a", "b", "c"} {
+		c.queueOrOverflow(qos0(topic), nil) // a is dropped
+	}
+
+	// A QoS 1 message waits while the buffer is full...
+	queued := make(chan struct{})
+	go func() {
+		c.queueOrOverflow(qos1("q1", 1), nil)
+		close(queued)
+	}()
+	select {
+	case <-queued:
+		t.Fatal("QoS 1 message was not held back by the full buffer")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if pkt, _ := c.overflow.pop(c.stop); pkt.(*packets.PublishPacket).Topic != "b" {
+		t.Fatalf("popped %v, want b", pkt)
+	}
+	<-queued
+
+	// ...and is never discarded to make room: the oldest QoS 0 message is
+	c.queueOrOverflow(qos0("d"), nil) // Drops c
+	c.queueOrOverflow(qos0("e"), nil) // Drops d, q1 stays
+
+	var got []packets.Packet
+	for len(got) < 2 {
+		pkt, _ := c.overflow.pop(c.stop)
+		got = append(got, pkt)
+	}
+	if want := []string{"q1", "e"}; !slices.Equal(topicsOf(got), want) {
+		t.Errorf("buffered %v, want %v", topicsOf(got), want)
+	}
+	if got := c.GetStats().IncomingDropped; got != 3 {
+		t.Errorf("IncomingDropped = %d, want 3 (a, c, d)", got)
+	}
+}
+
+func TestIncomingOverflowDropOldest_OnlyNonDroppable(t *testing.T) {
+	c := newOverflowClient(IncomingOverflowDropOldest, 1)
+
+	c.queueOrOverflow(qos1("q1", 1), nil)
+	c.queueOrOverflow(qos0("late"), nil) // Nothing older to discard
+
+	if got := c.GetStats().IncomingDropped; got != 1 {
+		t.Errorf("IncomingDropped = %d, want 1", got)
+	}
+	if pkt, _ := c.overflow.pop(c.stop); pkt.(*packets.PublishPacket).Topic != "q1" {
+		t.Errorf("popped %v, want q1", pkt)
+	}
+}
+
+func TestForwardOverflow(t *testing.T) {
+	c := newOverflowClient(IncomingOverflowDropOldest, 2)
+	c.wg.Add(1)
+	go c.forwardOverflow()
+	defer func() {
+		close(c.stop)
+		c.wg.Wait()
+	}()
+
+	for _, topic := range []string{"a", "b"} {
+		c.queueOrOverflow(qos0(topic), nil)
+	}
+	var got []packets.Packet
+	for len(got) < 2 {
+		select {
+		case pkt := <-c.incoming:
+			got = append(got, pkt)
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for forwarded packets")
+		}
+	}
+	if want := []string{"a", "b"}; !slices.Equal(topicsOf(got), want) {
+		t.Errorf("forwarded %v, want %v", topicsOf(got), want)
+	}
+}
+
+func TestIncomingOverflowDropOldest_ClearedOnDisconnect(t *testing.T) {
+	c := newOverflowClient(IncomingOverflowDropOldest, 1)
+	c.connected.Store(true)
+
+	c.queueOrOverflow(qos1("q1", 1), nil)
+
+	// Waiting for room when the connection goes away
+	connDone := make(chan struct{})
+	queued := make(chan bool)
+	go func() {
+		queued <- c.overflow.push(qos1("q2", 2), false, c.stop, connDone, c.noteIncomingDropped)
+	}()
+	close(connDone)
+	if <-queued {
+		t.Error("push succeeded after the connection was torn down")
+	}
+
+	c.handleDisconnect()
+	c.overflow.mu.Lock()
+	defer c.overflow.mu.Unlock()
+	if len(c.overflow.items) != 0 {
+		t.Errorf("%d packets of the lost connection still buffered", len(c.overflow.items))
+	}
+}
@@ -0,0 +1,46 @@
+package mq
+
+// reserveInflightBytes accounts size payload bytes of a new QoS 1/2 publish,
+// waiting until they fit within MaxInflightBytes. A publish is always
+// admitted when nothing else is outstanding, so a single oversized message
+// cannot block forever.
+//
+// Must be called with sessionLock held; the lock is released while waiting.
+// Returns false if the client was stopped, or done was closed, while waiting.
+func (c *Client) reserveInflightBytes(size int, done <-chan struct{}) bool {
+	limit := c.opts.MaxInflightBytes
+	for limit > 0 && c.inFlightBytes > 0 && c.inFlightBytes+size > limit {
+		if c.inFlightBytesFreed == nil {
+			c.inFlightBytesFreed = make(chan struct{})
+		}
+		freed := c.inFlightBytesFreed
+
+		c.sessionLock.Unlock()
+		select {
+		case <-freed:
+		case <-c.stop:
+			c.sessionLock.Lock()
+			return false
+		case <-done:
+			c.sessionLock.Lock()
+			return false
+		}
+		c.sessionLock.Lock()
+	}
+
+	c.inFlightBytes += size
+	return true
+}
+
+// releaseInflightBytes returns size bytes to the budget and wakes blocked
+// publishers. Must be called with sessionLock held.
+func (c *Client) releaseInflightBytes(size int) {
+	if size == 0 {
+		return
+	}
+	c.inFlightBytes -= size
+	if c.inFlightBytesFreed != nil {
+		close(c.inFlightBytesFreed)
+		c.inFlightBytesFreed = nil
+	}
+}
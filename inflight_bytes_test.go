@@ -0,0 +1,118 @@
+package mq
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestMaxInflightBytes(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	opts.MaxInflightBytes = 100
+	c := newTestClient(opts)
+	c.serverCaps.MaximumQoS = 2
+
+	publish := func(size int) (*token, <-chan struct{}) {
+		tok := newToken()
+		returned := make(chan struct{})
+		go func() {
+			defer close(returned)
+			c.internalPublish(&publishRequest{
+				packet: &packets.PublishPacket{Topic: "big", QoS: 1, Payload: make([]byte, size)},
+				token:  tok,
+			})
+		}()
+		return tok, returned
+	}
+
+	expectSent := func(what string) *packets.PublishPacket {
+		t.Helper()
+		select {
+		case pkt := <-c.outgoing:
+			return pkt.(*packets.PublishPacket)
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for %s", what)
+			return nil
+		}
+	}
+
+	// A single message larger than the limit is accepted when nothing is outstanding
+	_, returned := publish(150)
+	big := expectSent("oversized publish")
+	<-returned
+
+	// Nothing fits until the oversized message is acknowledged
+	_, returned = publish(60)
+	select {
+	case pkt := <-c.outgoing:
+		t.Fatalf("expected publish to wait for capacity, got %T", pkt)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	c.sessionLock.Lock()
+	c.handlePuback(&packets.PubackPacket{PacketID: big.PacketID})
+	c.sessionLock.Unlock()
+
+	first := expectSent("publish after PUBACK freed capacity")
+	<-returned
+
+	// 60 + 30 fits, 60 + 30 + 20 does not
+	_, returned = publish(30)
+	expectSent("publish within the byte limit")
+	<-returned
+
+	_, returned = publish(20)
+	select {
+	case <-returned:
+		t.Fatal("expected publish to block at the byte limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	c.sessionLock.Lock()
+	c.handlePuback(&packets.PubackPacket{PacketID: first.PacketID})
+	c.sessionLock.Unlock()
+
+	expectSent("publish after second PUBACK")
+	<-returned
+
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+	if c.inFlightBytes != 30+20 {
+		t.Errorf("expected 50 in-flight bytes, got %d", c.inFlightBytes)
+	}
+}
+
+func TestMaxInflightBytesStop(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	opts.MaxInflightBytes = 10
+	c := newTestClient(opts)
+	c.serverCaps.MaximumQoS = 2
+
+	c.internalPublish(&publishRequest{
+		packet: &packets.PublishPacket{Topic: "t", QoS: 1, Payload: make([]byte, 10)},
+		token:  newToken(),
+	})
+	<-c.outgoing
+
+	tok := newToken()
+	go c.internalPublish(&publishRequest{
+		packet: &packets.PublishPacket{Topic: "t", QoS: 1, Payload: make([]byte, 10)},
+		token:  tok,
+	})
+
+	time.Sleep(50 * time.Millisecond)
+	close(c.stop)
+
+	select {
+	case <-tok.Done():
+		if !errors.Is(tok.Error(), ErrClientDisconnected) {
+			t.Errorf("expected ErrClientDisconnected, got %v", tok.Error())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("blocked publish was not released on stop")
+	}
+}
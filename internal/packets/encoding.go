@@ -65,5 +65,8 @@ func decodeBinary(buf []byte) ([]byte, int, error) {
 		return nil, 0, fmt.Errorf("buffer too short for binary data: need %d, have %d", 2+length, len(buf))
 	}
 
-	return buf[2 : 2+length], 2 + length, nil
+	// Copied: buf may be a pooled read buffer that is reused after decoding
+	data := make([]byte, length)
+	copy(data, buf[2:2+length])
+	return data, 2 + length, nil
 }
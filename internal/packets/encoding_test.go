@@ -212,6 +212,14 @@ func TestDecodeBinary(t *testing.T) {
 			if n != tt.wantBytes {
 				t.Errorf("decodeBinary() bytes consumed = %v, want %v", n, tt.wantBytes)
 			}
+
+			// The result must not alias the (possibly pooled) input buffer
+			if len(got) > 0 {
+				tt.input[2] ^= 0xFF
+				if !bytes.Equal(got, tt.want) {
+					t.Error("decodeBinary() result changed with its input buffer")
+				}
+			}
 		})
 	}
 }
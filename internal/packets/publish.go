@@ -28,6 +28,10 @@ type PublishPacket struct {
 	// UseAlias indicates whether to use topic alias for this publish.
 	// This is set by WithAlias() and processed by the client.
 	UseAlias bool
+
+	// AliasID is the topic alias forced by WithAliasID(), or 0 to let the
+	// client choose one. It is not encoded.
+	AliasID uint16
 }
 
 // Type returns the packet type.
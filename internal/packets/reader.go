@@ -1,6 +1,7 @@
 package packets
 
 import (
+	"errors"
 	"fmt"
 	"io"
 )
@@ -139,5 +140,21 @@ func ReadPacket(r io.Reader, version uint8, maxIncomingPacket int) (Packet, erro
 		putBuffer(bufPtr)
 	}
 
-	return pkt, err
+	if err != nil {
+		return nil, asDecodeError(header.PacketType, err)
+	}
+	return pkt, nil
+}
+
+// asDecodeError classifies an error from a packet decoder. The whole body has
+// been read at this point, so any failure is an encoding problem, not an I/O
+// error: errors that are not already a ProtocolError or MalformedPacketError
+// are wrapped in a MalformedPacketError.
+func asDecodeError(packetType uint8, err error) error {
+	var protoErr *ProtocolError
+	var malformed *MalformedPacketError
+	if errors.As(err, &protoErr) || errors.As(err, &malformed) {
+		return err
+	}
+	return &MalformedPacketError{Message: fmt.Sprintf("malformed %s packet: %v", PacketNames[packetType], err)}
 }
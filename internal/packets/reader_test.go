@@ -0,0 +1,50 @@
+package packets
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestReadPacket_ErrorClassification(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  string // "io", "malformed" or "protocol"
+	}{
+		{"empty stream", nil, "io"},
+		{"truncated remaining length", []byte{0x30, 0x80}, "io"},
+		{"truncated body", []byte{0x30, 0x05, 0x00}, "io"},
+		{"remaining length too long", []byte{0x30, 0xFF, 0xFF, 0xFF, 0xFF, 0x7F}, "malformed"},
+		{"PUBLISH body too short for topic", []byte{0x30, 0x01, 0x00}, "malformed"},
+		{"PUBACK body too short", []byte{0x40, 0x01, 0x00}, "malformed"},
+		{"invalid flags", []byte{0xD1, 0x00}, "protocol"},
+		{"unknown packet type", []byte{0x00, 0x00}, "protocol"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadPacket(bytes.NewReader(tt.input), 5, 0)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			var malformed *MalformedPacketError
+			var protoErr *ProtocolError
+			got := "io"
+			switch {
+			case errors.As(err, &malformed):
+				got = "malformed"
+			case errors.As(err, &protoErr):
+				got = "protocol"
+			}
+			if got != tt.want {
+				t.Errorf("error %q classified as %s, want %s", err, got, tt.want)
+			}
+			if tt.want == "io" && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("expected I/O error to wrap EOF, got %v", err)
+			}
+		})
+	}
+}
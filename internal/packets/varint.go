@@ -47,17 +47,22 @@ func decodeVarInt(r io.Reader) (int, error) {
 		br = &byteReader{r: r}
 	}
 
-	val, err := binary.ReadUvarint(br)
-	if err != nil {
-		return 0, err
-	}
-
-	// MQTT v5.0 Spec: Variable Byte Integer MUST NOT exceed 268,435,455
-	if val > 268435455 {
-		return 0, fmt.Errorf("variable byte integer exceeds limit")
+	// MQTT v5.0 Spec: Variable Byte Integer MUST NOT exceed 4 bytes
+	// (268,435,455). Read errors are returned as-is, so callers can tell a
+	// broken connection from a malformed encoding.
+	val := 0
+	for i := range 4 {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		val |= int(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			return val, nil
+		}
 	}
 
-	return int(val), nil
+	return 0, &MalformedPacketError{Message: "malformed packet: variable byte integer exceeds limit"}
 }
 
 // byteReader wraps an io.Reader to implement io.ByteReader
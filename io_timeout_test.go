@@ -0,0 +1,68 @@
+package mq
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestWithIOTimeout(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	if opts.IOTimeout != 0 {
+		t.Errorf("default IOTimeout = %v, want 0", opts.IOTimeout)
+	}
+	WithIOTimeout(10 * time.Second)(opts)
+	if opts.IOTimeout != 10*time.Second {
+		t.Errorf("IOTimeout = %v, want 10s", opts.IOTimeout)
+	}
+	WithIOTimeout(-time.Second)(opts)
+	if opts.IOTimeout != 10*time.Second {
+		t.Errorf("negative timeout changed IOTimeout to %v", opts.IOTimeout)
+	}
+}
+
+// TestIOTimeoutWedgedWrite verifies that a write the peer never reads
+// disconnects the client after the I/O timeout.
+func TestIOTimeoutWedgedWrite(t *testing.T) {
+	// Nobody reads from serverConn, so every write blocks
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	client := &Client{
+		opts: &clientOptions{
+			IOTimeout:       100 * time.Millisecond,
+			Server:          "tcp://test:1883",
+			Logger:          testLogger(),
+			ProtocolVersion: ProtocolV311,
+		},
+		conn:           clientConn,
+		outgoing:       make(chan packets.Packet, 10),
+		packetReceived: make(chan struct{}, 1),
+		stop:           make(chan struct{}),
+		disconnected:   make(chan struct{}, 1),
+	}
+	client.connected.Store(true)
+
+	done := make(chan struct{})
+	client.wg.Add(1)
+	go func() {
+		client.writeLoop()
+		close(done)
+	}()
+
+	client.outgoing <- &packets.PublishPacket{Topic: "test", Payload: []byte("data")}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		close(client.stop)
+		clientConn.Close()
+		t.Fatal("writeLoop still blocked after the I/O timeout")
+	}
+	if client.IsConnected() {
+		t.Error("client should be marked as disconnected")
+	}
+}
@@ -0,0 +1,65 @@
+package mq
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// latencyAlpha is the smoothing factor of the latency moving averages.
+// Each new sample contributes 20% to the average, so the value follows
+// changes within a handful of round trips without being overly noisy.
+const latencyAlpha = 0.2
+
+// latencyEMA is a lock-free exponential moving average of durations.
+// The zero value is ready to use and reports 0 until the first sample.
+type latencyEMA struct {
+	nanos atomic.Int64
+}
+
+// observe feeds a new sample into the average.
+func (e *latencyEMA) observe(d time.Duration) {
+	// Keep samples strictly positive so 0 can mean "no samples yet".
+	sample := max(int64(d), 1)
+	for {
+		old := e.nanos.Load()
+		next := sample
+		if old != 0 {
+			next = old + int64(latencyAlpha*float64(sample-old))
+		}
+		if e.nanos.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+// observeSince feeds the time elapsed since start, if start is set.
+func (e *latencyEMA) observeSince(start time.Time) {
+	if !start.IsZero() {
+		e.observe(time.Since(start))
+	}
+}
+
+// value returns the current average.
+func (e *latencyEMA) value() time.Duration {
+	return time.Duration(e.nanos.Load())
+}
+
+// PublishLatency returns the exponential moving average of the round-trip
+// time of QoS 1 and QoS 2 publishes, measured from the moment the PUBLISH is
+// queued for the network until the final acknowledgment (PUBACK or PUBCOMP)
+// is processed.
+//
+// Returns 0 until the first acknowledgment has been received. This is a
+// cheap, always-on health signal for broker responsiveness; it is also
+// reported in ClientStats.
+func (c *Client) PublishLatency() time.Duration {
+	return c.publishLatency.value()
+}
+
+// SubscribeLatency returns the exponential moving average of the round-trip
+// time of SUBSCRIBE requests (SUBSCRIBE sent to SUBACK processed).
+//
+// Returns 0 until the first SUBACK has been received.
+func (c *Client) SubscribeLatency() time.Duration {
+	return c.subscribeLatency.value()
+}
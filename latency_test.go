@@ -0,0 +1,105 @@
+package mq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestLatencyEMA(t *testing.T) {
+	var e latencyEMA
+
+	if got := e.value(); got != 0 {
+		t.Fatalf("expected 0 before samples, got %v", got)
+	}
+
+	// First sample seeds the average
+	e.observe(100 * time.Millisecond)
+	if got := e.value(); got != 100*time.Millisecond {
+		t.Fatalf("expected 100ms after first sample, got %v", got)
+	}
+
+	// Subsequent samples move the average by alpha
+	e.observe(200 * time.Millisecond)
+	want := 100*time.Millisecond + time.Duration(latencyAlpha*float64(100*time.Millisecond))
+	if got := e.value(); got != want {
+		t.Fatalf("expected %v after second sample, got %v", want, got)
+	}
+
+	// Repeated identical samples converge on that value
+	for range 100 {
+		e.observe(50 * time.Millisecond)
+	}
+	if got := e.value(); got < 49*time.Millisecond || got > 51*time.Millisecond {
+		t.Errorf("expected average to converge to ~50ms, got %v", got)
+	}
+
+	// Zero-length samples never reset the tracker to "no samples"
+	var z latencyEMA
+	z.observe(0)
+	if z.value() == 0 {
+		t.Error("expected non-zero value after a zero-length sample")
+	}
+}
+
+func TestPublishAndSubscribeLatencyFromAcks(t *testing.T) {
+	c := newTestClient(nil)
+	c.opts.Logger = testLogger()
+
+	now := time.Now()
+	c.pending[1] = &pendingOp{
+		packet:  &packets.PublishPacket{PacketID: 1, QoS: 1},
+		token:   newToken(),
+		qos:     1,
+		created: now.Add(-40 * time.Millisecond),
+	}
+	c.pending[2] = &pendingOp{
+		packet:  &packets.PubrelPacket{PacketID: 2},
+		token:   newToken(),
+		qos:     2,
+		created: now.Add(-80 * time.Millisecond),
+	}
+	c.pending[3] = &pendingOp{
+		packet:  &packets.SubscribePacket{PacketID: 3, Topics: []string{"a"}, QoS: []uint8{1}},
+		token:   newToken(),
+		created: now.Add(-30 * time.Millisecond),
+	}
+	// Restored operations have no creation time and must not skew the average
+	c.pending[4] = &pendingOp{
+		packet: &packets.PublishPacket{PacketID: 4, QoS: 1},
+		token:  newToken(),
+		qos:    1,
+	}
+	c.inFlightCount = 3
+
+	c.handlePuback(&packets.PubackPacket{PacketID: 1})
+	first := c.PublishLatency()
+	if first < 40*time.Millisecond || first > time.Second {
+		t.Fatalf("expected publish latency ~40ms after PUBACK, got %v", first)
+	}
+
+	c.handlePubcomp(&packets.PubcompPacket{PacketID: 2})
+	second := c.PublishLatency()
+	if second <= first {
+		t.Errorf("expected PUBCOMP with higher RTT to raise the average, got %v -> %v", first, second)
+	}
+
+	c.handlePuback(&packets.PubackPacket{PacketID: 4})
+	if got := c.PublishLatency(); got != second {
+		t.Errorf("expected restored op to be ignored, average changed %v -> %v", second, got)
+	}
+
+	c.handleSuback(&packets.SubackPacket{PacketID: 3, ReturnCodes: []uint8{1}})
+	if got := c.SubscribeLatency(); got < 30*time.Millisecond || got > time.Second {
+		t.Errorf("expected subscribe latency ~30ms, got %v", got)
+	}
+
+	stats := c.GetStats()
+	if stats.PublishLatency != c.PublishLatency() {
+		t.Errorf("stats publish latency %v != %v", stats.PublishLatency, c.PublishLatency())
+	}
+	if stats.SubscribeLatency != c.SubscribeLatency() {
+		t.Errorf("stats subscribe latency %v != %v", stats.SubscribeLatency, c.SubscribeLatency())
+	}
+}
@@ -0,0 +1,95 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestWithLocalAddr_Dialer(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 0}
+
+	opts := defaultOptions("tcp://localhost:1883")
+	WithLocalAddr(addr)(opts)
+	c := newTestClient(opts)
+
+	if got := c.netDialer().LocalAddr; got != addr {
+		t.Errorf("LocalAddr = %v, want %v", got, addr)
+	}
+
+	// Unset by default
+	if got := newTestClient(nil).netDialer().LocalAddr; got != nil {
+		t.Errorf("expected no LocalAddr by default, got %v", got)
+	}
+}
+
+func TestWithLocalAddr_Binds(t *testing.T) {
+	// A second loopback address makes the binding observable. It is
+	// available on Linux but not configured by default everywhere.
+	probe, err := net.Listen("tcp", "127.0.0.2:0")
+	if err != nil {
+		t.Skipf("127.0.0.2 not available: %v", err)
+	}
+	probe.Close()
+
+	ln, err := net.Listen("tcp", "0.0.0.0:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	accepted := make(chan net.Addr, 4)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn.RemoteAddr()
+			conn.Close()
+		}
+	}()
+
+	tests := []struct {
+		name   string
+		scheme string
+	}{
+		{"tcp", "tcp"},
+		{"tls", "tls"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fmt.Sprintf("%s://127.0.0.1:%d", tt.scheme, port)
+			opts := defaultOptions(server)
+			WithLocalAddr(&net.TCPAddr{IP: net.ParseIP("127.0.0.2")})(opts)
+			c := newTestClient(opts)
+
+			// Every connect, including reconnects, uses the address
+			for range 2 {
+				ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+				conn, err := c.dialServer(ctx)
+				cancel()
+				if err == nil {
+					conn.Close()
+				} else if tt.scheme == "tcp" {
+					t.Fatalf("dialServer failed: %v", err)
+				}
+				// The TLS handshake fails against the plain listener,
+				// but the TCP connection was made from the bound address.
+
+				select {
+				case remote := <-accepted:
+					if ip := remote.(*net.TCPAddr).IP.String(); ip != "127.0.0.2" {
+						t.Errorf("connection from %s, want 127.0.0.2", ip)
+					}
+				case <-time.After(2 * time.Second):
+					t.Fatal("server did not accept a connection")
+				}
+			}
+		})
+	}
+}
@@ -2,7 +2,12 @@ package mq
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gonzalop/mq/internal/packets"
 )
@@ -12,7 +17,7 @@ import (
 func (c *Client) logicLoop() {
 	defer c.wg.Done()
 
-	retryTicker := time.NewTicker(5 * time.Second)
+	retryTicker := time.NewTicker(c.retryTickPeriod())
 	defer retryTicker.Stop()
 
 	for {
@@ -20,23 +25,26 @@ func (c *Client) logicLoop() {
 		case pkt := <-c.incoming:
 			c.sessionLock.Lock()
 			c.handleIncoming(pkt)
+			c.signalPendingSettled()
 			c.sessionLock.Unlock()
 
 		case <-retryTicker.C:
 			c.sessionLock.Lock()
 			c.retryPending()
 			c.processPublishQueue()
+			c.signalPendingSettled()
 			c.sessionLock.Unlock()
 
 		case <-c.stop:
 			c.opts.Logger.Debug("logicLoop stopped")
+			err := c.stopError()
 			c.sessionLock.Lock()
 			for _, op := range c.pending {
-				op.token.complete(ErrClientDisconnected)
+				op.token.complete(err)
 			}
 			// Complete tokens for queued publish requests
 			for _, req := range c.publishQueue {
-				req.token.complete(ErrClientDisconnected)
+				req.token.complete(err)
 			}
 			c.publishQueue = nil
 			c.sessionLock.Unlock()
@@ -112,10 +120,10 @@ func (c *Client) handlePublish(p *packets.PublishPacket) {
 		}
 
 		// Check if server violated our declared maximum
-		if c.opts.TopicAliasMaximum > 0 && aliasID > c.opts.TopicAliasMaximum {
+		if aliasMax := c.topicAliasMaximum(); aliasMax > 0 && aliasID > aliasMax {
 			c.opts.Logger.Error("server exceeded topic alias maximum",
 				"alias", aliasID,
-				"max", c.opts.TopicAliasMaximum)
+				"max", aliasMax)
 			// Protocol error - disconnect
 			if c.opts.ProtocolVersion >= ProtocolV50 {
 				_ = c.disconnectWithReason(context.Background(), uint8(ReasonCodeTopicAliasInvalid), nil)
@@ -155,6 +163,10 @@ func (c *Client) handlePublish(p *packets.PublishPacket) {
 		}
 	}
 
+	if c.opts.TopicAliasAutoTune {
+		c.observeIncomingTopic(p.Topic)
+	}
+
 	// Check receive maximum (MQTT v5.0) for QoS 1 and 2
 	if c.opts.ProtocolVersion >= ProtocolV50 && p.QoS > 0 {
 		if _, exists := c.inboundUnacked[p.PacketID]; !exists {
@@ -180,7 +192,9 @@ func (c *Client) handlePublish(p *packets.PublishPacket) {
 		}
 	}
 
-	// For QoS 2, check if we've already received this packet
+	// For QoS 2, check if we've already received this packet. IDs are
+	// recorded when the PUBREC is sent, so a message whose deferred ack was
+	// never sent is delivered again.
 	if p.QoS == 2 {
 		if _, exists := c.receivedQoS2[p.PacketID]; exists {
 			// Duplicate QoS 2 message - send PUBREC but don't deliver again
@@ -191,32 +205,71 @@ func (c *Client) handlePublish(p *packets.PublishPacket) {
 			}
 			return
 		}
-		c.receivedQoS2[p.PacketID] = struct{}{}
+	}
 
-		// Persist QoS 2 ID
-		if c.opts.SessionStore != nil {
-			if err := c.opts.SessionStore.SaveReceivedQoS2(p.PacketID); err != nil {
-				c.opts.Logger.Warn("failed to persist QoS2 ID", "packet_id", p.PacketID, "error", err)
-			}
-		}
+	if c.opts.Metrics != nil {
+		c.opts.Metrics.ObserveReceiveSize(len(p.Payload))
 	}
 
-	// Find matching handlers
+	// A payload declared as UTF-8 that is not is the server's (or the
+	// publisher's) fault: report it but deliver the message anyway
+	if p.Properties != nil && p.Properties.Presence&packets.PresPayloadFormatIndicator != 0 &&
+		p.Properties.PayloadFormatIndicator == PayloadFormatUTF8 && !utf8.Valid(p.Payload) {
+		c.opts.Logger.Warn("received message declared as UTF-8 is not valid UTF-8", "topic", p.Topic)
+	}
+
+	// Find matching handlers, and the subscriptions they belong to. Handlers
+	// registered by subscription identifier replace those of the
+	// subscriptions with that identifier.
 	var handlers []MessageHandler
+	var filters []string
+	var ordered []bool
+	routedIDs := c.routeBySubscriptionID(p)
+	suppressed := false
 	for filter, entry := range c.subscriptions {
-		if MatchTopic(filter, p.Topic) {
-			if entry.handler != nil {
-				handlers = append(handlers, entry.handler)
+		if !MatchTopic(filter, p.Topic) {
+			continue
+		}
+		id := entry.options.SubscriptionID
+		routed := id != 0 && slices.Contains(routedIDs, id)
+		if p.Retain && entry.suppressRetained {
+			suppressed = true
+			if routed {
+				routedIDs = slices.DeleteFunc(routedIDs, func(r int) bool { return r == id })
 			}
+			continue
+		}
+		if c.opts.TopicStats {
+			c.countTopicMessage(filter, len(p.Payload))
 		}
+		if routed {
+			continue // Delivered by identifier
+		}
+		if h := c.messageHandler(filter, entry); h != nil {
+			handlers = append(handlers, h)
+			filters = append(filters, filter)
+			ordered = append(ordered, c.opts.OrderedDelivery || entry.options.Ordered)
+		}
+	}
+	for _, id := range routedIDs {
+		handlers = append(handlers, c.idHandlers[id])
+		filters = append(filters, subscriptionIDQueue(id))
+		ordered = append(ordered, c.opts.OrderedDelivery)
+	}
+	if suppressed && len(handlers) == 0 {
+		c.opts.Logger.Debug("suppressed retained message after reconnect", "topic", p.Topic)
 	}
 
 	// Use default handler if no matches found
-	if len(handlers) == 0 {
+	if len(handlers) == 0 && !suppressed {
 		if c.defaultHandler != nil {
 			handlers = append(handlers, c.defaultHandler)
+			filters = append(filters, defaultHandlerQueue)
+			ordered = append(ordered, c.opts.OrderedDelivery)
 		} else if c.opts != nil && c.opts.DefaultPublishHandler != nil {
 			handlers = append(handlers, c.opts.DefaultPublishHandler)
+			filters = append(filters, defaultHandlerQueue)
+			ordered = append(ordered, c.opts.OrderedDelivery)
 		}
 	}
 
@@ -227,12 +280,62 @@ func (c *Client) handlePublish(p *packets.PublishPacket) {
 		Retained:   p.Retain,
 		Duplicate:  p.Dup,
 		Properties: toPublicProperties(p.Properties),
+		ReceivedAt: time.Now(),
+	}
+
+	// With LimitPolicyBackpressure, QoS 1/2 acknowledgments are deferred until
+	// every handler has returned, so ReceiveMaximum bounds the number of
+	// messages being processed and readLoop pauses when it is reached.
+	// With WithManualAck, they are deferred until a handler calls msg.Ack.
+	var remaining *atomic.Int32
+	gen := c.inboundGen
+	manualAck := c.opts.ManualAck && p.QoS > 0 && len(handlers) > 0
+	if manualAck {
+		msg.ack = &messageAck{c: c, packetID: p.PacketID, qos: p.QoS, gen: gen}
+		c.inboundProcessing++
+	} else if c.opts.ProtocolVersion >= ProtocolV50 && p.QoS > 0 &&
+		c.opts.ReceiveMaximumPolicy == LimitPolicyBackpressure && len(handlers) > 0 {
+		remaining = new(atomic.Int32)
+		remaining.Store(int32(len(handlers)))
+		c.inboundProcessing++
+	}
+
+	// Record QoS 2 ID. A deferred PUBREC records it when it is sent, so a
+	// message that is never acked is not mistaken for a duplicate later.
+	if p.QoS == 2 && remaining == nil && !manualAck {
+		c.receivedQoS2[p.PacketID] = struct{}{}
+		c.persistReceivedQoS2(p.PacketID)
 	}
 
 	// Call handlers in separate goroutines (don't block logicLoop)
-	for _, handler := range handlers {
+	for i, handler := range handlers {
 		h := handler // Capture for goroutine
 
+		// Ordered delivery: one queue per subscription, run sequentially
+		if ordered[i] {
+			c.orderedQueue(filters[i]).push(func() {
+				h(c, msg)
+				if remaining != nil && remaining.Add(-1) == 0 {
+					c.sendDeferredAck(p.PacketID, p.QoS, gen)
+				}
+			})
+			continue
+		}
+
+		// Worker pool: bounded goroutines, see WithHandlerConcurrency
+		if c.handlerPool != nil {
+			ok := c.handlerPool.submit(func() {
+				h(c, msg)
+				if remaining != nil && remaining.Add(-1) == 0 {
+					c.sendDeferredAck(p.PacketID, p.QoS, gen)
+				}
+			}, c.stop)
+			if !ok {
+				return
+			}
+			continue
+		}
+
 		// Acquire semaphore if configured
 		if c.handlerSem != nil {
 			select {
@@ -247,9 +350,16 @@ func (c *Client) handlePublish(p *packets.PublishPacket) {
 				defer func() { <-c.handlerSem }()
 			}
 			h(c, msg)
+			if remaining != nil && remaining.Add(-1) == 0 {
+				c.sendDeferredAck(p.PacketID, p.QoS, gen)
+			}
 		}()
 	}
 
+	if remaining != nil || manualAck {
+		return
+	}
+
 	switch p.QoS {
 	case 1:
 		select {
@@ -284,6 +394,7 @@ func (c *Client) handlePuback(p *packets.PubackPacket) {
 		}
 		op.token.complete(err)
 		delete(c.pending, p.PacketID)
+		c.publishLatency.observeSince(op.created)
 
 		if c.opts.SessionStore != nil {
 			if err := c.opts.SessionStore.DeletePendingPublish(p.PacketID); err != nil {
@@ -291,7 +402,8 @@ func (c *Client) handlePuback(p *packets.PubackPacket) {
 			}
 		}
 
-		c.inFlightCount--
+		c.setInFlightCount(c.inFlightCount - 1)
+		c.releaseInflightBytes(op.size)
 		c.processPublishQueue()
 	}
 }
@@ -304,17 +416,22 @@ func (c *Client) handlePubrec(p *packets.PubrecPacket) {
 			if p.ReasonCode >= 0x80 {
 				op.token.complete(&MqttError{ReasonCode: ReasonCode(p.ReasonCode)})
 				delete(c.pending, p.PacketID)
+				c.setInFlightCount(c.inFlightCount - 1)
+				c.releaseInflightBytes(op.size)
 				c.processPublishQueue()
 				return
 			}
 		}
 
+		// Track PUBREL for retransmission even if it cannot be queued right
+		// now: once PUBREC arrives the PUBLISH must not be sent again, only
+		// the PUBREL (MQTT section 4.3.3). retryPending and resendPending
+		// deliver it later if the queue is full or the connection drops.
 		pubrel := &packets.PubrelPacket{PacketID: p.PacketID, Version: c.opts.ProtocolVersion}
+		op.packet = pubrel
+		op.timestamp = time.Now()
 		select {
 		case c.outgoing <- pubrel:
-			// Update pending operation to track PUBREL for retransmission
-			op.packet = pubrel
-			op.timestamp = time.Now()
 		case <-c.stop:
 		default:
 		}
@@ -353,6 +470,7 @@ func (c *Client) handlePubcomp(p *packets.PubcompPacket) {
 		}
 		op.token.complete(err)
 		delete(c.pending, p.PacketID)
+		c.publishLatency.observeSince(op.created)
 
 		if c.opts.SessionStore != nil {
 			if err := c.opts.SessionStore.DeletePendingPublish(p.PacketID); err != nil {
@@ -360,7 +478,8 @@ func (c *Client) handlePubcomp(p *packets.PubcompPacket) {
 			}
 		}
 
-		c.inFlightCount--
+		c.setInFlightCount(c.inFlightCount - 1)
+		c.releaseInflightBytes(op.size)
 		c.processPublishQueue()
 	}
 }
@@ -389,6 +508,13 @@ func (c *Client) handleSuback(p *packets.SubackPacket) {
 			op.token.reasonCode = ReasonCode(p.ReturnCodes[0])
 		}
 
+		// Record per-filter results so callers can see exactly which filters failed
+		if subPkt, ok := op.packet.(*packets.SubscribePacket); ok {
+			op.token.results = buildSubscribeResults(subPkt.Topics, p.ReturnCodes)
+			c.recordGrantedQoS(subPkt.Topics, p.ReturnCodes)
+			c.completeDeferredSubscribes(op.token.results)
+		}
+
 		// Save subscriptions if successful
 		if c.opts.SessionStore != nil && err == nil { // Global error (e.g. timeout) check
 			if subPkt, ok := op.packet.(*packets.SubscribePacket); ok {
@@ -416,6 +542,7 @@ func (c *Client) handleSuback(p *packets.SubackPacket) {
 
 		op.token.complete(err)
 		delete(c.pending, p.PacketID)
+		c.subscribeLatency.observeSince(op.created)
 	}
 }
 
@@ -424,7 +551,7 @@ func (c *Client) handleUnsuback(p *packets.UnsubackPacket) {
 	if op, ok := c.pending[p.PacketID]; ok {
 		var err error
 		if c.opts.ProtocolVersion >= ProtocolV50 {
-			// Set reason code from the first reason code (Unsubscribe operates on a single topic)
+			// Set reason code from the first reason code (the first topic)
 			if len(p.ReasonCodes) > 0 {
 				op.token.reasonCode = ReasonCode(p.ReasonCodes[0])
 			}
@@ -453,20 +580,45 @@ func (c *Client) handleUnsuback(p *packets.UnsubackPacket) {
 	}
 }
 
-// retryPending retransmits packets that haven't been acknowledged.
+// retryInterval returns the retransmission threshold set by
+// WithRetryInterval.
+func (c *Client) retryInterval() time.Duration {
+	if c.opts.RetryInterval <= 0 {
+		return defaultRetryInterval
+	}
+	return c.opts.RetryInterval
+}
+
+// retryTickPeriod returns how often the logic loop checks for packets to
+// retransmit: half the retry interval.
+func (c *Client) retryTickPeriod() time.Duration {
+	return max(c.retryInterval()/2, time.Millisecond)
+}
+
+// retryPending retransmits packets that haven't been acknowledged within the
+// retry interval (WithRetryInterval).
 func (c *Client) retryPending() {
 	now := time.Now()
-
-	for _, op := range c.pending {
-		if now.Sub(op.timestamp) > 10*time.Second {
-			// Resend with DUP flag if it's a PUBLISH
-			if pub, ok := op.packet.(*packets.PublishPacket); ok {
-				pub.Dup = true
+	interval := c.retryInterval()
+
+	for id, op := range c.pending {
+		if op.withheld {
+			continue // Not sent yet, see sendWithheldLocked
+		}
+		if now.Sub(op.timestamp) > interval {
+			// Resend with DUP flag if it's a PUBLISH. The previous
+			// transmission may still be in the writeLoop, so flag a copy
+			// rather than the packet being written.
+			if pub, ok := op.packet.(*packets.PublishPacket); ok && !pub.Dup {
+				dup := *pub
+				dup.Dup = true
+				op.packet = &dup
 			}
 
 			select {
 			case c.outgoing <- op.packet:
 				op.timestamp = now
+				c.noteRetransmit(id, op)
 			case <-c.stop:
 				return
 			default:
@@ -478,21 +630,209 @@ func (c *Client) retryPending() {
 	}
 }
 
-// nextID generates the next packet ID (1-65535, cycling).
-func (c *Client) nextID() uint16 {
-	for range 65535 {
-		c.nextPacketID++
-		if c.nextPacketID == 0 {
-			c.nextPacketID = 1
+// resendPending retransmits unacknowledged PUBLISH (with the DUP flag set)
+// and PUBREL packets right after a (re)connect, as required for session
+// resumption (MQTT v3.1.1 and v5.0, section 4.4). Packets that are still
+// waiting in the outgoing queue are left alone since they will be sent anyway.
+// Publishes beyond the server's ReceiveMaximum are withheld until
+// acknowledgments free their slots (see sendWithheldLocked).
+// It must be called before the write loop of the new connection starts, and
+// acquires the session lock.
+func (c *Client) resendPending() {
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+
+	for _, op := range c.withheld {
+		op.withheld = false
+	}
+	c.withheld = nil
+
+	if len(c.pending) == 0 {
+		return
+	}
+
+	// Find packets that are already queued
+	queued := make(map[packets.Packet]struct{})
+	c.requeueOutgoing(func(pkt packets.Packet) {
+		queued[pkt] = struct{}{}
+	})
+
+	// QoS 2 publishes awaiting PUBCOMP and queued publishes use up slots of
+	// the server's ReceiveMaximum
+	var ops []*pendingOp
+	inFlight := 0
+	for _, op := range c.pending {
+		switch op.packet.(type) {
+		case *packets.PublishPacket:
+		case *packets.PubrelPacket:
+			inFlight++
+		default:
+			continue
+		}
+		if _, ok := queued[op.packet]; ok {
+			if _, ok := op.packet.(*packets.PublishPacket); ok {
+				inFlight++
+			}
+			continue
+		}
+		ops = append(ops, op)
+	}
+	limit := int(c.serverCaps.ReceiveMaximum)
+
+	// Preserve the original transmission order as far as it is known
+	sort.Slice(ops, func(i, j int) bool {
+		if !ops[i].created.Equal(ops[j].created) {
+			return ops[i].created.Before(ops[j].created)
+		}
+		return packetID(ops[i].packet) < packetID(ops[j].packet)
+	})
+
+	now := time.Now()
+	for _, op := range ops {
+		pub, ok := op.packet.(*packets.PublishPacket)
+		if ok {
+			// The server may accept smaller packets than when the publish
+			// was first sent
+			if err := c.checkPublishSize(pub); err != nil {
+				c.opts.Logger.Warn("dropping pending publish",
+					"packet_id", pub.PacketID, "topic", pub.Topic, "error", err)
+				c.failPendingPublishLocked(op, err)
+				continue
+			}
+			if limit > 0 && inFlight >= limit {
+				op.withheld = true
+				c.withheld = append(c.withheld, op)
+				continue
+			}
+			pub.Dup = true
+			inFlight++
+		}
+		select {
+		case c.outgoing <- op.packet:
+			op.timestamp = now
+			c.noteRetransmit(packetID(op.packet), op)
+		default:
+			// Outgoing queue is full; retryPending will pick up the rest.
+			return
+		}
+	}
+
+	c.opts.Logger.Debug("resent pending packets after connect",
+		"count", len(ops)-len(c.withheld),
+		"withheld", len(c.withheld))
+}
+
+// failPendingPublishLocked completes a pending QoS 1/2 publish with err
+// without sending it again. Must be called with sessionLock held.
+func (c *Client) failPendingPublishLocked(op *pendingOp, err error) {
+	id := packetID(op.packet)
+	op.token.complete(err)
+	delete(c.pending, id)
+
+	if c.opts.SessionStore != nil {
+		if err := c.opts.SessionStore.DeletePendingPublish(id); err != nil {
+			c.opts.Logger.Warn("failed to delete pending publish", "packet_id", id, "error", err)
 		}
-		if _, used := c.pending[c.nextPacketID]; !used {
-			return c.nextPacketID
+	}
+
+	c.setInFlightCount(c.inFlightCount - 1)
+	c.releaseInflightBytes(op.size)
+	c.signalPendingSettled()
+}
+
+// sendWithheldLocked sends the publishes withheld by resendPending, in
+// order, while the server's ReceiveMaximum allows it. It reports whether
+// all of them were sent. Must be called with sessionLock held.
+func (c *Client) sendWithheldLocked() bool {
+	limit := int(c.serverCaps.ReceiveMaximum)
+	for len(c.withheld) > 0 {
+		op := c.withheld[0]
+		pub := op.packet.(*packets.PublishPacket)
+		if c.pending[pub.PacketID] == op {
+			// Withheld publishes are counted in inFlightCount but not sent
+			if limit > 0 && c.inFlightCount-len(c.withheld) >= limit {
+				return false
+			}
+			pub.Dup = true
+			select {
+			case c.outgoing <- pub:
+				op.timestamp = time.Now()
+				c.noteRetransmit(pub.PacketID, op)
+			default:
+				return false
+			}
 		}
+		op.withheld = false
+		c.withheld = c.withheld[1:]
+	}
+	return true
+}
+
+// noteRetransmit counts a retransmission of op and reports it to the
+// WithOnRetransmit handler.
+func (c *Client) noteRetransmit(id uint16, op *pendingOp) {
+	op.resent++
+	c.retransmissions.Add(1)
+	if c.opts.OnRetransmit != nil {
+		go c.opts.OnRetransmit(id, packets.PacketNames[op.packet.Type()], op.resent)
 	}
-	// This should only happen if we have 65535 pending packets.
-	// In that case, we return the next ID anyway as a fallback,
-	// though it will cause a collision.
-	return c.nextPacketID
+}
+
+// packetID returns the packet identifier of a PUBLISH or PUBREL packet.
+func packetID(pkt packets.Packet) uint16 {
+	switch p := pkt.(type) {
+	case *packets.PublishPacket:
+		return p.PacketID
+	case *packets.PubrelPacket:
+		return p.PacketID
+	}
+	return 0
+}
+
+// packetIDAllocator chooses packet identifiers.
+type packetIDAllocator interface {
+	// next returns the ID to use after last, skipping IDs for which inUse
+	// reports true, or false if every ID is in use.
+	next(last uint16, inUse func(uint16) bool) (uint16, bool)
+}
+
+// packetIDRange allocates IDs in increasing order within min..max,
+// wrapping around (WithPacketIDRange).
+type packetIDRange struct {
+	min, max uint16
+}
+
+func (r packetIDRange) next(last uint16, inUse func(uint16) bool) (uint16, bool) {
+	id := last
+	for range int(r.max-r.min) + 1 {
+		if id < r.min || id >= r.max {
+			id = r.min
+		} else {
+			id++
+		}
+		if !inUse(id) {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// nextID generates the next packet ID (1-65535 or WithPacketIDRange,
+// cycling). It returns false, and no ID, if every ID is pending: reusing
+// one would mix up the acknowledgments (MQTT-2.3.1-4).
+func (c *Client) nextID() (uint16, bool) {
+	var alloc packetIDAllocator = packetIDRange{min: 1, max: 65535}
+	if c.opts != nil && c.opts.PacketIDs != nil {
+		alloc = c.opts.PacketIDs
+	}
+	id, ok := alloc.next(c.nextPacketID, func(id uint16) bool {
+		_, used := c.pending[id]
+		return used
+	})
+	if ok {
+		c.nextPacketID = id
+	}
+	return id, ok
 }
 
 // handleDisconnectPacket processes a DISCONNECT packet from the server.
@@ -535,6 +875,9 @@ func (c *Client) handleDisconnectPacket(p *packets.DisconnectPacket) {
 		}
 	}
 
+	// A DISCONNECT during re-authentication is how the server rejects it
+	c.finishReauth(fmt.Errorf("re-authentication rejected: %w", err))
+
 	// Store for handleDisconnect to pick up
 	c.connLock.Lock()
 	c.lastDisconnectReason = err
@@ -1,6 +1,10 @@
 package mq
 
 func (c *Client) processPublishQueue() {
+	// Publishes withheld after a reconnect were accepted first
+	if !c.sendWithheldLocked() {
+		return
+	}
 	if len(c.publishQueue) == 0 {
 		return
 	}
@@ -20,6 +24,7 @@ func (c *Client) processPublishQueue() {
 
 			// Success, remove from queue
 			c.publishQueue = c.publishQueue[1:]
+			req.dequeued()
 		}
 	} else {
 		// No limit? Flush everything.
@@ -31,6 +36,7 @@ func (c *Client) processPublishQueue() {
 			}
 
 			c.publishQueue = c.publishQueue[1:]
+			req.dequeued()
 		}
 	}
 }
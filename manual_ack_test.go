@@ -0,0 +1,221 @@
+package mq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// newManualAckClient returns a test client using WithManualAck whose
+// subscription to "t" hands received messages to the returned channel.
+func newManualAckClient(t *testing.T) (*Client, <-chan Message) {
+	t.Helper()
+
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	opts.ReceiveMaximum = 1
+	WithManualAck()(opts)
+	c := newTestClient(opts)
+	c.inboundAcked = make(chan struct{}, 1)
+
+	received := make(chan Message, 4)
+	c.subscriptions["t"] = subscriptionEntry{
+		handler: func(_ *Client, msg Message) { received <- msg },
+	}
+	return c, received
+}
+
+func TestManualAck(t *testing.T) {
+	tests := []struct {
+		name string
+		qos  uint8
+		want func(packets.Packet) bool
+	}{
+		{"QoS 1", 1, func(p packets.Packet) bool {
+			ack, ok := p.(*packets.PubackPacket)
+			return ok && ack.PacketID == 7
+		}},
+		{"QoS 2", 2, func(p packets.Packet) bool {
+			rec, ok := p.(*packets.PubrecPacket)
+			return ok && rec.PacketID == 7
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, received := newManualAckClient(t)
+
+			c.sessionLock.Lock()
+			c.handleIncoming(&packets.PublishPacket{Topic: "t", QoS: tt.qos, PacketID: 7})
+			c.sessionLock.Unlock()
+
+			var msg Message
+			select {
+			case msg = <-received:
+			case <-time.After(time.Second):
+				t.Fatal("timeout waiting for the handler")
+			}
+
+			// The handler returned without acking
+			select {
+			case pkt := <-c.outgoing:
+				t.Fatalf("expected ack to wait for msg.Ack, got %T", pkt)
+			case <-time.After(50 * time.Millisecond):
+			}
+
+			// Unacked messages count against ReceiveMaximum
+			waitDone := make(chan bool, 1)
+			go func() { waitDone <- c.waitForReceiveCapacity(nil) }()
+			select {
+			case <-waitDone:
+				t.Fatal("expected waitForReceiveCapacity to block at capacity")
+			case <-time.After(50 * time.Millisecond):
+			}
+
+			msg.Ack()
+			msg.Ack() // Only the first call acks
+
+			select {
+			case pkt := <-c.outgoing:
+				if !tt.want(pkt) {
+					t.Fatalf("unexpected ack %#v", pkt)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("timeout waiting for the ack")
+			}
+			if len(c.outgoing) != 0 {
+				t.Errorf("expected a single ack, %d more queued", len(c.outgoing))
+			}
+
+			select {
+			case ok := <-waitDone:
+				if !ok {
+					t.Error("expected waitForReceiveCapacity to report capacity")
+				}
+			case <-time.After(time.Second):
+				t.Fatal("waitForReceiveCapacity did not resume after Ack")
+			}
+
+			c.sessionLock.Lock()
+			defer c.sessionLock.Unlock()
+			if c.inboundProcessing != 0 {
+				t.Errorf("expected 0 messages processing, got %d", c.inboundProcessing)
+			}
+		})
+	}
+}
+
+func TestManualAck_NotRequired(t *testing.T) {
+	t.Run("QoS 0", func(t *testing.T) {
+		c, received := newManualAckClient(t)
+
+		c.sessionLock.Lock()
+		c.handleIncoming(&packets.PublishPacket{Topic: "t", QoS: 0})
+		c.sessionLock.Unlock()
+
+		msg := <-received
+		msg.Ack() // No-op
+
+		if len(c.outgoing) != 0 || c.inboundProcessing != 0 {
+			t.Errorf("expected nothing to ack, got %d queued, %d processing",
+				len(c.outgoing), c.inboundProcessing)
+		}
+	})
+
+	t.Run("no matching handler", func(t *testing.T) {
+		c, _ := newManualAckClient(t)
+
+		c.sessionLock.Lock()
+		c.handleIncoming(&packets.PublishPacket{Topic: "other", QoS: 1, PacketID: 3})
+		c.sessionLock.Unlock()
+
+		select {
+		case pkt := <-c.outgoing:
+			if ack, ok := pkt.(*packets.PubackPacket); !ok || ack.PacketID != 3 {
+				t.Fatalf("expected immediate PUBACK for packet 3, got %#v", pkt)
+			}
+		default:
+			t.Fatal("expected an unhandled message to be acked immediately")
+		}
+		if c.inboundProcessing != 0 {
+			t.Errorf("expected 0 messages processing, got %d", c.inboundProcessing)
+		}
+	})
+
+	t.Run("without WithManualAck", func(t *testing.T) {
+		var msg Message
+		msg.Ack() // No-op, must not panic
+	})
+}
+
+func TestManualAck_WithheldAfterDisconnect(t *testing.T) {
+	c, received := newManualAckClient(t)
+
+	c.sessionLock.Lock()
+	c.handleIncoming(&packets.PublishPacket{Topic: "t", QoS: 2, PacketID: 9})
+	c.sessionLock.Unlock()
+
+	msg := <-received
+	c.withholdAcks.Store(true)
+	msg.Ack()
+
+	if len(c.outgoing) != 0 {
+		t.Errorf("expected no ack after Disconnect, got %d queued", len(c.outgoing))
+	}
+
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+	if c.inboundProcessing != 0 {
+		t.Errorf("expected 0 messages processing, got %d", c.inboundProcessing)
+	}
+	if _, ok := c.receivedQoS2[9]; ok {
+		t.Error("expected the QoS 2 ID to be forgotten so the redelivery is not a duplicate")
+	}
+}
+
+func TestManualAck_QoS2RedeliveredAfterReconnect(t *testing.T) {
+	c, received := newManualAckClient(t)
+
+	c.sessionLock.Lock()
+	c.handleIncoming(&packets.PublishPacket{Topic: "t", QoS: 2, PacketID: 7})
+	c.sessionLock.Unlock()
+
+	select {
+	case <-received: // Not acked
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the handler")
+	}
+
+	// After a reconnect the server redelivers the message: since no PUBREC
+	// was sent, it is not a duplicate and must reach the handler again
+	c.resetInbound()
+	c.sessionLock.Lock()
+	c.handleIncoming(&packets.PublishPacket{Topic: "t", QoS: 2, PacketID: 7, Dup: true})
+	c.sessionLock.Unlock()
+
+	var msg Message
+	select {
+	case msg = <-received:
+	case <-time.After(time.Second):
+		t.Fatal("redelivered message was discarded as a duplicate")
+	}
+	if len(c.outgoing) != 0 {
+		t.Fatalf("expected no PUBREC before Ack, got %d packets", len(c.outgoing))
+	}
+
+	msg.Ack()
+	if rec, ok := (<-c.outgoing).(*packets.PubrecPacket); !ok || rec.PacketID != 7 {
+		t.Fatalf("expected PUBREC for packet 7, got %#v", rec)
+	}
+
+	// Now it is a duplicate
+	c.sessionLock.Lock()
+	c.handleIncoming(&packets.PublishPacket{Topic: "t", QoS: 2, PacketID: 7, Dup: true})
+	c.sessionLock.Unlock()
+	select {
+	case <-received:
+		t.Error("acked message delivered again")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,163 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// serveScripted accepts connections on ln. Connection n is acknowledged if
+// accept(n) is true and closed 20ms later, unless it is the last one
+// (n == keep), which stays open; otherwise it is closed before CONNACK.
+func serveScripted(ln net.Listener, accept func(n int) bool, keep int, accepted chan<- int) {
+	for n := 1; ; n++ {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn, n int) {
+			defer conn.Close()
+			if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+				return
+			}
+			accepted <- n
+			if !accept(n) {
+				return
+			}
+			if _, err := (&packets.ConnackPacket{ReturnCode: 0}).WriteTo(conn); err != nil {
+				return
+			}
+			if n != keep {
+				time.Sleep(20 * time.Millisecond)
+				return
+			}
+			for {
+				if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+					return
+				}
+			}
+		}(conn, n)
+	}
+}
+
+func TestMaxReconnectAttempts_GivesUp(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	accepted := make(chan int, 16)
+	go serveScripted(ln, func(n int) bool { return n == 1 }, 0, accepted)
+
+	lost := make(chan error, 4)
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("max-attempts"),
+		WithReconnectBackoff(10*time.Millisecond, 10*time.Millisecond, 1),
+		WithMaxReconnectAttempts(3),
+		WithOnConnectionLost(func(_ *Client, err error) { lost <- err }),
+		WithLogger(testLogger()),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	var final error
+	timeout := time.After(5 * time.Second)
+	for final == nil {
+		select {
+		case err := <-lost:
+			if errors.Is(err, ErrReconnectAttemptsExhausted) {
+				final = err
+			}
+		case <-timeout:
+			t.Fatal("client did not give up reconnecting")
+		}
+	}
+
+	// The client is stopped: no further attempts
+	select {
+	case <-client.stop:
+	default:
+		t.Error("expected the client to be stopped")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := client.reconnectCount.Load(); got != 3 {
+		t.Errorf("reconnect attempts = %d, want 3", got)
+	}
+	if got := len(accepted); got != 4 {
+		t.Errorf("server saw %d connections, want 4 (1 + 3 attempts)", got)
+	}
+	if client.IsConnected() {
+		t.Error("expected the client to be disconnected")
+	}
+	if err := client.ReconnectNow(); !errors.Is(err, ErrClientDisconnected) {
+		t.Errorf("ReconnectNow after giving up = %v, want ErrClientDisconnected", err)
+	}
+}
+
+func TestMaxReconnectAttempts_ResetOnSuccess(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// Two outages of two failed attempts each (connections 2-3 and 5-6),
+	// four failures in total but never three in a row
+	accepted := make(chan int, 16)
+	go serveScripted(ln, func(n int) bool { return n == 1 || n == 4 || n == 7 }, 7, accepted)
+
+	lost := make(chan error, 8)
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("max-attempts-reset"),
+		WithReconnectBackoff(10*time.Millisecond, 10*time.Millisecond, 1),
+		WithMaxReconnectAttempts(3),
+		WithOnConnectionLost(func(_ *Client, err error) { lost <- err }),
+		WithLogger(testLogger()),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	timeout := time.After(5 * time.Second)
+	for n := 0; n != 7; {
+		select {
+		case n = <-accepted:
+		case <-timeout:
+			t.Fatal("timeout waiting for connection 7")
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !client.IsConnected() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !client.IsConnected() {
+		t.Fatal("client did not reconnect")
+	}
+
+	for len(lost) > 0 {
+		if err := <-lost; errors.Is(err, ErrReconnectAttemptsExhausted) {
+			t.Errorf("client gave up although the counter should reset: %v", err)
+		}
+	}
+}
+
+func TestWithMaxReconnectAttempts(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	if opts.MaxReconnectAttempts != 0 {
+		t.Errorf("expected unlimited by default, got %d", opts.MaxReconnectAttempts)
+	}
+	WithMaxReconnectAttempts(5)(opts)
+	WithMaxReconnectAttempts(-1)(opts)
+	if opts.MaxReconnectAttempts != 5 {
+		t.Errorf("MaxReconnectAttempts = %d, want 5 (negative ignored)", opts.MaxReconnectAttempts)
+	}
+}
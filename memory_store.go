@@ -0,0 +1,198 @@
+package mq
+
+import (
+	"maps"
+	"sync"
+)
+
+// Compile-time check that MemoryStore implements SessionStore
+var _ SessionStore = (*MemoryStore)(nil)
+
+// MemoryStore implements SessionStore in memory.
+//
+// State survives reconnections and is shared by every client using the same
+// store, but is lost when the process exits. It is intended for tests, where
+// it exercises the same persistence code paths as FileStore without disk
+// I/O, and for ephemeral deployments that want a session store without a
+// durable one.
+//
+// MemoryStore is safe for concurrent use, so tests can inspect it (see
+// Snapshot) while a client is running.
+type MemoryStore struct {
+	mu            sync.Mutex
+	pending       map[uint16]*PersistedPublish
+	subscriptions map[string]*PersistedSubscription
+	receivedQoS2  map[uint16]struct{}
+}
+
+// MemoryStoreSnapshot is a copy of the contents of a MemoryStore.
+type MemoryStoreSnapshot struct {
+	PendingPublishes map[uint16]*PersistedPublish
+	Subscriptions    map[string]*PersistedSubscription
+	ReceivedQoS2     map[uint16]struct{}
+}
+
+// NewMemoryStore creates an empty in-memory session store.
+//
+// Example:
+//
+//	store := mq.NewMemoryStore()
+//	client, err := mq.Dial("tcp://localhost:1883",
+//	    mq.WithClientID("test-client"),
+//	    mq.WithCleanSession(false),
+//	    mq.WithSessionStore(store))
+//	// ...
+//	if n := len(store.Snapshot().PendingPublishes); n != 0 {
+//	    t.Errorf("%d publishes still pending", n)
+//	}
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		pending:       make(map[uint16]*PersistedPublish),
+		subscriptions: make(map[string]*PersistedSubscription),
+		receivedQoS2:  make(map[uint16]struct{}),
+	}
+}
+
+// Snapshot returns a copy of the store contents. The maps are never nil and
+// are not affected by later changes to the store.
+func (m *MemoryStore) Snapshot() MemoryStoreSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return MemoryStoreSnapshot{
+		PendingPublishes: copyPending(m.pending),
+		Subscriptions:    copySubscriptions(m.subscriptions),
+		ReceivedQoS2:     maps.Clone(m.receivedQoS2),
+	}
+}
+
+// SavePendingPublish stores a pending publish.
+func (m *MemoryStore) SavePendingPublish(packetID uint16, pub *PersistedPublish) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := *pub
+	m.pending[packetID] = &p
+	return nil
+}
+
+// DeletePendingPublish removes a pending publish.
+func (m *MemoryStore) DeletePendingPublish(packetID uint16) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.pending, packetID)
+	return nil
+}
+
+// LoadPendingPublishes returns a copy of all pending publishes.
+func (m *MemoryStore) LoadPendingPublishes() (map[uint16]*PersistedPublish, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return copyPending(m.pending), nil
+}
+
+// ClearPendingPublishes removes all pending publishes.
+func (m *MemoryStore) ClearPendingPublishes() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	clear(m.pending)
+	return nil
+}
+
+// SaveSubscription stores a subscription.
+func (m *MemoryStore) SaveSubscription(topic string, sub *PersistedSubscription) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := *sub
+	m.subscriptions[topic] = &s
+	return nil
+}
+
+// DeleteSubscription removes a subscription.
+func (m *MemoryStore) DeleteSubscription(topic string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.subscriptions, topic)
+	return nil
+}
+
+// LoadSubscriptions returns a copy of all subscriptions.
+func (m *MemoryStore) LoadSubscriptions() (map[string]*PersistedSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return copySubscriptions(m.subscriptions), nil
+}
+
+// SaveReceivedQoS2 marks a QoS 2 packet ID as received.
+func (m *MemoryStore) SaveReceivedQoS2(packetID uint16) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.receivedQoS2[packetID] = struct{}{}
+	return nil
+}
+
+// DeleteReceivedQoS2 removes a received QoS 2 packet ID.
+func (m *MemoryStore) DeleteReceivedQoS2(packetID uint16) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.receivedQoS2, packetID)
+	return nil
+}
+
+// LoadReceivedQoS2 returns a copy of all received QoS 2 packet IDs.
+func (m *MemoryStore) LoadReceivedQoS2() (map[uint16]struct{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return maps.Clone(m.receivedQoS2), nil
+}
+
+// ClearReceivedQoS2 removes all received QoS 2 packet IDs.
+func (m *MemoryStore) ClearReceivedQoS2() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	clear(m.receivedQoS2)
+	return nil
+}
+
+// Clear removes all session state.
+func (m *MemoryStore) Clear() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	clear(m.pending)
+	clear(m.subscriptions)
+	clear(m.receivedQoS2)
+	return nil
+}
+
+// copyPending copies the entries of a pending publish map, so callers cannot
+// modify the stored values.
+func copyPending(src map[uint16]*PersistedPublish) map[uint16]*PersistedPublish {
+	dst := make(map[uint16]*PersistedPublish, len(src))
+	for id, pub := range src {
+		p := *pub
+		dst[id] = &p
+	}
+	return dst
+}
+
+// copySubscriptions copies the entries of a subscription map, so callers
+// cannot modify the stored values.
+func copySubscriptions(src map[string]*PersistedSubscription) map[string]*PersistedSubscription {
+	dst := make(map[string]*PersistedSubscription, len(src))
+	for topic, sub := range src {
+		s := *sub
+		dst[topic] = &s
+	}
+	return dst
+}
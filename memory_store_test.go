@@ -0,0 +1,117 @@
+package mq
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestMemoryStore_RoundTrip(t *testing.T) {
+	store := NewMemoryStore()
+
+	pub := &PersistedPublish{Topic: "a/b", Payload: []byte("x"), QoS: 1}
+	sub := &PersistedSubscription{QoS: 2, Options: &PersistedSubscriptionOptions{NoLocal: true}}
+
+	if err := store.SavePendingPublish(1, pub); err != nil {
+		t.Fatalf("SavePendingPublish failed: %v", err)
+	}
+	if err := store.SavePendingPublish(2, &PersistedPublish{Topic: "c", QoS: 2}); err != nil {
+		t.Fatalf("SavePendingPublish failed: %v", err)
+	}
+	if err := store.SaveSubscription("a/#", sub); err != nil {
+		t.Fatalf("SaveSubscription failed: %v", err)
+	}
+	if err := store.SaveReceivedQoS2(7); err != nil {
+		t.Fatalf("SaveReceivedQoS2 failed: %v", err)
+	}
+
+	pending, _ := store.LoadPendingPublishes()
+	if len(pending) != 2 || !reflect.DeepEqual(pending[1], pub) {
+		t.Errorf("LoadPendingPublishes() = %#v", pending)
+	}
+	subs, _ := store.LoadSubscriptions()
+	if len(subs) != 1 || !reflect.DeepEqual(subs["a/#"], sub) {
+		t.Errorf("LoadSubscriptions() = %#v", subs)
+	}
+	qos2, _ := store.LoadReceivedQoS2()
+	if !reflect.DeepEqual(qos2, map[uint16]struct{}{7: {}}) {
+		t.Errorf("LoadReceivedQoS2() = %v", qos2)
+	}
+
+	// Loaded values are copies
+	pending[1].Topic = "modified"
+	delete(subs, "a/#")
+	if snap := store.Snapshot(); snap.PendingPublishes[1].Topic != "a/b" || len(snap.Subscriptions) != 1 {
+		t.Error("modifying loaded state changed the store")
+	}
+
+	_ = store.DeletePendingPublish(2)
+	_ = store.DeleteSubscription("a/#")
+	_ = store.DeleteReceivedQoS2(7)
+	snap := store.Snapshot()
+	if len(snap.PendingPublishes) != 1 || len(snap.Subscriptions) != 0 || len(snap.ReceivedQoS2) != 0 {
+		t.Errorf("unexpected contents after deletes: %+v", snap)
+	}
+
+	_ = store.SaveReceivedQoS2(8)
+	_ = store.ClearPendingPublishes()
+	_ = store.ClearReceivedQoS2()
+	snap = store.Snapshot()
+	if len(snap.PendingPublishes) != 0 || len(snap.ReceivedQoS2) != 0 {
+		t.Errorf("unexpected contents after clears: %+v", snap)
+	}
+
+	_ = store.SaveSubscription("x", &PersistedSubscription{})
+	_ = store.Clear()
+	snap = store.Snapshot()
+	if snap.PendingPublishes == nil || snap.Subscriptions == nil || snap.ReceivedQoS2 == nil {
+		t.Fatal("expected non-nil snapshot maps")
+	}
+	if len(snap.Subscriptions) != 0 {
+		t.Errorf("expected an empty store after Clear, got %+v", snap)
+	}
+}
+
+func TestMemoryStore_ClientPersistence(t *testing.T) {
+	store := NewMemoryStore()
+
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.SessionStore = store
+	c := newPublishContextClient(t, opts)
+	c.connected.Store(true)
+
+	tok := c.Publish("sensors/temp", []byte("21"), WithQoS(AtLeastOnce))
+	pub := (<-c.outgoing).(*packets.PublishPacket)
+
+	snap := store.Snapshot()
+	if got := snap.PendingPublishes[pub.PacketID]; got == nil || got.Topic != "sensors/temp" {
+		t.Fatalf("expected the publish to be persisted, got %+v", snap.PendingPublishes)
+	}
+
+	c.sessionLock.Lock()
+	c.handleIncoming(&packets.PubackPacket{PacketID: pub.PacketID})
+	c.sessionLock.Unlock()
+
+	if err := waitToken(t, tok); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := len(store.Snapshot().PendingPublishes); n != 0 {
+		t.Errorf("expected the acknowledged publish to be deleted, %d left", n)
+	}
+
+	// A new client restores the state saved by the first one
+	_ = store.SavePendingPublish(9, &PersistedPublish{Topic: "restored", QoS: 1})
+	_ = store.SaveSubscription("restored/#", &PersistedSubscription{QoS: 1})
+
+	restored := &Client{opts: opts}
+	if err := restored.loadSessionState(); err != nil {
+		t.Fatalf("loadSessionState failed: %v", err)
+	}
+	if op := restored.pending[9]; op == nil {
+		t.Error("expected the pending publish to be restored")
+	}
+	if _, ok := restored.subscriptions["restored/#"]; !ok {
+		t.Error("expected the subscription to be restored")
+	}
+}
@@ -1,5 +1,10 @@
 package mq
 
+import (
+	"sync"
+	"time"
+)
+
 // Message represents an MQTT message received on a subscribed topic.
 //
 // This struct is designed to be compatible with both MQTT v3.1.1 and v5.0.
@@ -26,4 +31,68 @@ type Message struct {
 	// MQTT v5.0 properties.
 	// This field is nil for MQTT v3.1.1 connections or when no properties are present.
 	Properties *Properties
+
+	// ReceivedAt is the local time at which the client processed the message.
+	ReceivedAt time.Time
+
+	// ack sends the deferred acknowledgment (WithManualAck), nil otherwise.
+	ack *messageAck
+
+	// subscription is the context passed to WithSubscriptionContext
+	// handlers, nil for other handlers.
+	subscription *SubscriptionContext
+}
+
+// Ack acknowledges the message to the server when the client was created
+// with WithManualAck, sending its PUBACK (QoS 1) or PUBREC (QoS 2).
+//
+// Only the first call has an effect; later calls, and calls on QoS 0
+// messages or without WithManualAck, do nothing. Ack may be called from any
+// goroutine, and may block briefly if the outgoing queue is full.
+//
+// Example:
+//
+//	func handle(c *mq.Client, msg mq.Message) {
+//	    if err := store(msg.Payload); err != nil {
+//	        log.Printf("not acknowledging %s: %v", msg.Topic, err)
+//	        return
+//	    }
+//	    msg.Ack()
+//	}
+func (m Message) Ack() {
+	if m.ack != nil {
+		m.ack.once.Do(func() {
+			m.ack.c.sendDeferredAck(m.ack.packetID, m.ack.qos, m.ack.gen)
+		})
+	}
+}
+
+// messageAck is the deferred acknowledgment of a received message.
+type messageAck struct {
+	once     sync.Once
+	c        *Client
+	packetID uint16
+	qos      uint8
+	gen      uint64 // Client.inboundGen when the message was received
+}
+
+// ExpiresAt returns the local deadline after which the message should be
+// considered stale, computed as ReceivedAt plus the remaining Message Expiry
+// Interval sent by the server (MQTT v5.0).
+//
+// The server decrements the expiry interval by the time it held the message,
+// so the result does not depend on the publisher's or the server's clock.
+// The second return value is false if the message has no expiry (always the
+// case for MQTT v3.1.1) or ReceivedAt is not set.
+//
+// Example:
+//
+//	if deadline, ok := msg.ExpiresAt(); ok && time.Now().After(deadline) {
+//	    return // too old to act on
+//	}
+func (m Message) ExpiresAt() (time.Time, bool) {
+	if m.Properties == nil || m.Properties.MessageExpiry == nil || m.ReceivedAt.IsZero() {
+		return time.Time{}, false
+	}
+	return m.ReceivedAt.Add(time.Duration(*m.Properties.MessageExpiry) * time.Second), true
 }
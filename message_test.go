@@ -0,0 +1,108 @@
+package mq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestMessageExpiresAt(t *testing.T) {
+	receivedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	expiry := uint32(30)
+	zero := uint32(0)
+
+	tests := []struct {
+		name   string
+		msg    Message
+		want   time.Time
+		wantOK bool
+	}{
+		{
+			name: "remaining expiry",
+			msg: Message{
+				ReceivedAt: receivedAt,
+				Properties: &Properties{MessageExpiry: &expiry},
+			},
+			want:   receivedAt.Add(30 * time.Second),
+			wantOK: true,
+		},
+		{
+			name: "zero remaining expiry",
+			msg: Message{
+				ReceivedAt: receivedAt,
+				Properties: &Properties{MessageExpiry: &zero},
+			},
+			want:   receivedAt,
+			wantOK: true,
+		},
+		{
+			name:   "no properties (v3.1.1)",
+			msg:    Message{ReceivedAt: receivedAt},
+			wantOK: false,
+		},
+		{
+			name: "no expiry property",
+			msg: Message{
+				ReceivedAt: receivedAt,
+				Properties: &Properties{ContentType: "text/plain"},
+			},
+			wantOK: false,
+		},
+		{
+			name: "no receive time",
+			msg: Message{
+				Properties: &Properties{MessageExpiry: &expiry},
+			},
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.msg.ExpiresAt()
+			if ok != tt.wantOK {
+				t.Fatalf("expected ok=%v, got %v", tt.wantOK, ok)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHandlePublishSetsReceivedAt(t *testing.T) {
+	c := newTestClient(nil)
+	c.opts.Logger = testLogger()
+
+	msgs := make(chan Message, 1)
+	c.subscriptions["sensors/temp"] = subscriptionEntry{
+		handler: func(_ *Client, m Message) { msgs <- m },
+	}
+
+	before := time.Now()
+	c.handlePublish(&packets.PublishPacket{
+		Topic: "sensors/temp",
+		Properties: &packets.Properties{
+			MessageExpiryInterval: 10,
+			Presence:              packets.PresMessageExpiryInterval,
+		},
+	})
+	after := time.Now()
+
+	select {
+	case m := <-msgs:
+		if m.ReceivedAt.Before(before) || m.ReceivedAt.After(after) {
+			t.Errorf("ReceivedAt %v not within [%v, %v]", m.ReceivedAt, before, after)
+		}
+		deadline, ok := m.ExpiresAt()
+		if !ok {
+			t.Fatal("expected message to have an expiry deadline")
+		}
+		if want := m.ReceivedAt.Add(10 * time.Second); !deadline.Equal(want) {
+			t.Errorf("expected deadline %v, got %v", want, deadline)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler not called")
+	}
+}
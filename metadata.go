@@ -0,0 +1,38 @@
+package mq
+
+// SetMetadata attaches an arbitrary value to the client under the given key.
+//
+// Metadata lets applications that manage many clients keep per-client state
+// (a device record, a scoped logger, etc.) on the client itself. Handlers
+// receive the *Client, so they can look the value up without a side map
+// keyed by client pointer.
+//
+// Keys follow the same rules as map keys: they must be comparable. To avoid
+// collisions between packages, use an unexported key type, as with
+// context.WithValue. Setting a nil value removes the key.
+//
+// It is safe to call SetMetadata and Metadata concurrently from any goroutine.
+//
+// Example:
+//
+//	type deviceKey struct{}
+//
+//	client.SetMetadata(deviceKey{}, device)
+//	client.Subscribe("commands/#", mq.AtLeastOnce, func(c *mq.Client, msg mq.Message) {
+//	    dev := c.Metadata(deviceKey{}).(*Device)
+//	    dev.Handle(msg)
+//	})
+func (c *Client) SetMetadata(key, value any) {
+	if value == nil {
+		c.metadata.Delete(key)
+		return
+	}
+	c.metadata.Store(key, value)
+}
+
+// Metadata returns the value stored with SetMetadata (or WithMetadata) for
+// key, or nil if there is none.
+func (c *Client) Metadata(key any) any {
+	v, _ := c.metadata.Load(key)
+	return v
+}
@@ -0,0 +1,126 @@
+package mq
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+type testMetadataKey struct{}
+
+type testDevice struct {
+	Name string
+}
+
+func TestMetadataFromHandler(t *testing.T) {
+	c := newTestClient(nil)
+	c.opts.Logger = testLogger()
+
+	dev := &testDevice{Name: "thermostat-1"}
+	c.SetMetadata(testMetadataKey{}, dev)
+
+	got := make(chan any, 1)
+	c.subscriptions["commands/#"] = subscriptionEntry{
+		handler: func(client *Client, _ Message) {
+			got <- client.Metadata(testMetadataKey{})
+		},
+	}
+
+	c.handlePublish(&packets.PublishPacket{Topic: "commands/reboot"})
+
+	select {
+	case v := <-got:
+		if v != dev {
+			t.Errorf("expected handler to see %v, got %v", dev, v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler not called")
+	}
+}
+
+func TestMetadataSetAndDelete(t *testing.T) {
+	c := newTestClient(nil)
+
+	if v := c.Metadata("missing"); v != nil {
+		t.Errorf("expected nil for missing key, got %v", v)
+	}
+
+	c.SetMetadata("k", 1)
+	c.SetMetadata("k", 2)
+	if v := c.Metadata("k"); v != 2 {
+		t.Errorf("expected 2, got %v", v)
+	}
+
+	c.SetMetadata("k", nil)
+	if v := c.Metadata("k"); v != nil {
+		t.Errorf("expected key to be removed, got %v", v)
+	}
+
+	// Concurrent access
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.SetMetadata(i, i)
+			_ = c.Metadata(i)
+		}()
+	}
+	wg.Wait()
+	for i := range 50 {
+		if v := c.Metadata(i); v != i {
+			t.Errorf("key %d: expected %d, got %v", i, i, v)
+		}
+	}
+}
+
+func TestWithMetadataAvailableInOnConnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+			return
+		}
+		connack := &packets.ConnackPacket{ReturnCode: 0}
+		_, _ = connack.WriteTo(conn)
+		_, _ = packets.ReadPacket(conn, ProtocolV50, 0)
+	}()
+
+	dev := &testDevice{Name: "sensor-7"}
+	seen := make(chan any, 1)
+
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("metadata"),
+		WithAutoReconnect(false),
+		WithMetadata(testMetadataKey{}, dev),
+		WithOnConnect(func(c *Client) {
+			seen <- c.Metadata(testMetadataKey{})
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	select {
+	case v := <-seen:
+		if v != dev {
+			t.Errorf("expected OnConnect to see %v, got %v", dev, v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnConnect not called")
+	}
+}
@@ -0,0 +1,31 @@
+package mq
+
+// Metrics receives observations for exporting to a metrics system such as
+// Prometheus. It complements the counters reported by GetStats with
+// distribution data.
+//
+// Methods are called synchronously from the publish and receive paths, so
+// they must be fast and safe for concurrent use. Histogram and summary types
+// of common metrics libraries meet both requirements.
+//
+// Example:
+//
+//	type promMetrics struct {
+//	    published, received prometheus.Histogram
+//	}
+//
+//	func (m *promMetrics) ObservePublishSize(n int) { m.published.Observe(float64(n)) }
+//	func (m *promMetrics) ObserveReceiveSize(n int) { m.received.Observe(float64(n)) }
+type Metrics interface {
+	// ObservePublishSize is called with the payload size, in bytes, of
+	// each message Publish queues for the network (after outgoing
+	// transforms). Publishes rejected by validation or by the server's
+	// limits, and QoS 0 messages dropped because the outgoing queue is
+	// full, are not observed.
+	ObservePublishSize(n int)
+
+	// ObserveReceiveSize is called with the payload size, in bytes, of
+	// each message received from the server. Duplicate QoS 2 deliveries
+	// that are not passed to handlers are not observed.
+	ObserveReceiveSize(n int)
+}
@@ -0,0 +1,136 @@
+package mq
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+type recordingMetrics struct {
+	mu        sync.Mutex
+	published []int
+	received  []int
+}
+
+func (m *recordingMetrics) ObservePublishSize(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.published = append(m.published, n)
+}
+
+func (m *recordingMetrics) ObserveReceiveSize(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.received = append(m.received, n)
+}
+
+func TestMetricsObservePublishSize(t *testing.T) {
+	m := &recordingMetrics{}
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	opts.MaxPayloadSize = 100
+	WithMetrics(m)(opts)
+	WithOutgoingPublishTransform(func(v *PublishView) {
+		if v.Topic == "compressed" {
+			v.Payload = v.Payload[:1]
+		}
+	})(opts)
+	c := newTestClient(opts)
+	c.serverCaps = extractServerCapabilities(nil)
+	c.connected.Store(true)
+
+	c.Publish("a", nil)
+	c.Publish("b", []byte("hello"))
+	c.Publish("c", make([]byte, 100), WithQoS(AtLeastOnce))
+	c.Publish("compressed", []byte("abcdef"))
+
+	// Rejected publishes are not observed
+	c.Publish("too/big", make([]byte, 101))
+	c.Publish("bad/#", []byte("x"))
+
+	want := []int{0, 5, 100, 1}
+	if !reflect.DeepEqual(m.published, want) {
+		t.Errorf("published sizes = %v, want %v", m.published, want)
+	}
+	if len(m.received) != 0 {
+		t.Errorf("expected no receive observations, got %v", m.received)
+	}
+}
+
+func TestMetricsRejectedPublishNotObserved(t *testing.T) {
+	m := &recordingMetrics{}
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	opts.OutgoingQueueSize = 1
+	WithMetrics(m)(opts)
+	c := newTestClient(opts)
+	c.connected.Store(true)
+	c.serverCaps = extractServerCapabilities(nil)
+	c.serverCaps.RetainAvailable = false
+	c.serverCaps.MaximumQoS = 1
+	c.serverCaps.MaximumPacketSize = 64
+
+	// Rejected by the server limits
+	for _, tok := range []Token{
+		c.Publish("retained", []byte("x"), WithRetain(true)),
+		c.Publish("qos2", []byte("x"), WithQoS(ExactlyOnce)),
+		c.Publish("big", make([]byte, 100)),
+	} {
+		if tok.Error() == nil {
+			t.Fatal("expected the publish to be rejected")
+		}
+	}
+	if len(m.published) != 0 {
+		t.Fatalf("rejected publishes observed: %v", m.published)
+	}
+
+	// Queued, then dropped because the outgoing queue is full
+	c.Publish("queued", []byte("abc"))
+	if tok := c.Publish("dropped", []byte("x")); !tok.Dropped() {
+		t.Fatal("expected the second QoS 0 publish to be dropped")
+	}
+	if want := []int{3}; !reflect.DeepEqual(m.published, want) {
+		t.Errorf("published sizes = %v, want %v", m.published, want)
+	}
+}
+
+func TestMetricsObserveReceiveSize(t *testing.T) {
+	m := &recordingMetrics{}
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	WithMetrics(m)(opts)
+	c := newTestClient(opts)
+	c.subscriptions["#"] = subscriptionEntry{handler: func(*Client, Message) {}}
+
+	c.handlePublish(&packets.PublishPacket{Topic: "a", Payload: []byte("hi")})
+	c.handlePublish(&packets.PublishPacket{Topic: "b", Payload: []byte(strings.Repeat("x", 1000)), QoS: 1, PacketID: 1})
+	c.handlePublish(&packets.PublishPacket{Topic: "c", QoS: 2, PacketID: 2})
+	// Duplicate QoS 2 delivery is not passed to handlers, nor observed
+	c.handlePublish(&packets.PublishPacket{Topic: "c", QoS: 2, PacketID: 2, Dup: true})
+	// Messages without a matching handler are still observed
+	delete(c.subscriptions, "#")
+	c.handlePublish(&packets.PublishPacket{Topic: "d", Payload: []byte("abc")})
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	want := []int{2, 1000, 0, 3}
+	if !reflect.DeepEqual(m.received, want) {
+		t.Errorf("received sizes = %v, want %v", m.received, want)
+	}
+	if len(m.published) != 0 {
+		t.Errorf("expected no publish observations, got %v", m.published)
+	}
+}
+
+func TestMetricsOptional(t *testing.T) {
+	c := newTestClient(nil)
+	c.opts.Logger = testLogger()
+	c.connected.Store(true)
+
+	// No Metrics configured: both paths must work without observing
+	c.Publish("a", []byte("x"))
+	c.handlePublish(&packets.PublishPacket{Topic: "a", Payload: []byte("x")})
+}
@@ -0,0 +1,101 @@
+package metricsmq
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gonzalop/mq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type fakeStats struct {
+	stats mq.ClientStats
+}
+
+func (f *fakeStats) GetStats() mq.ClientStats {
+	return f.stats
+}
+
+func TestCollector(t *testing.T) {
+	source := &fakeStats{stats: mq.ClientStats{
+		PacketsSent:       12,
+		PacketsReceived:   9,
+		BytesSent:         2048,
+		BytesReceived:     1024,
+		ReconnectCount:    2,
+		Retransmissions:   4,
+		Connected:         true,
+		InFlightPublishes: 3,
+	}}
+	collector := NewCollector(source)
+
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP mqtt_client_bytes_received_total Total number of bytes read from the network.
+# TYPE mqtt_client_bytes_received_total counter
+mqtt_client_bytes_received_total 1024
+# HELP mqtt_client_bytes_sent_total Total number of bytes written to the network.
+# TYPE mqtt_client_bytes_sent_total counter
+mqtt_client_bytes_sent_total 2048
+# HELP mqtt_client_connected Whether the client is connected (1) or not (0).
+# TYPE mqtt_client_connected gauge
+mqtt_client_connected 1
+# HELP mqtt_client_inflight_publishes Number of QoS 1/2 publishes sent and not yet acknowledged.
+# TYPE mqtt_client_inflight_publishes gauge
+mqtt_client_inflight_publishes 3
+# HELP mqtt_client_packets_received_total Total number of MQTT packets received.
+# TYPE mqtt_client_packets_received_total counter
+mqtt_client_packets_received_total 9
+# HELP mqtt_client_packets_sent_total Total number of MQTT packets sent.
+# TYPE mqtt_client_packets_sent_total counter
+mqtt_client_packets_sent_total 12
+# HELP mqtt_client_reconnect_attempts_total Total number of reconnection attempts, including failed ones.
+# TYPE mqtt_client_reconnect_attempts_total counter
+mqtt_client_reconnect_attempts_total 2
+# HELP mqtt_client_retransmissions_total Total number of packets resent for lack of acknowledgment.
+# TYPE mqtt_client_retransmissions_total counter
+mqtt_client_retransmissions_total 4
+`)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every scrape reads a fresh snapshot
+	source.stats.Connected = false
+	source.stats.InFlightPublishes = 0
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP mqtt_client_connected Whether the client is connected (1) or not (0).
+# TYPE mqtt_client_connected gauge
+mqtt_client_connected 0
+# HELP mqtt_client_inflight_publishes Number of QoS 1/2 publishes sent and not yet acknowledged.
+# TYPE mqtt_client_inflight_publishes gauge
+mqtt_client_inflight_publishes 0
+`), "mqtt_client_connected", "mqtt_client_inflight_publishes"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCollector_Options(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	for _, id := range []string{"hub-1", "hub-2"} {
+		collector := NewCollector(&fakeStats{stats: mq.ClientStats{Connected: id == "hub-1"}},
+			WithNamespace("acme"),
+			WithSubsystem("mqtt"),
+			WithConstLabels(prometheus.Labels{"client_id": id}))
+		if err := reg.Register(collector); err != nil {
+			t.Fatalf("Register(%s) failed: %v", id, err)
+		}
+	}
+
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP acme_mqtt_connected Whether the client is connected (1) or not (0).
+# TYPE acme_mqtt_connected gauge
+acme_mqtt_connected{client_id="hub-1"} 1
+acme_mqtt_connected{client_id="hub-2"} 0
+`), "acme_mqtt_connected"); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := testutil.GatherAndCount(reg); err != nil || n != 16 {
+		t.Errorf("GatherAndCount() = %d, %v, want 16 series", n, err)
+	}
+}
@@ -30,6 +30,37 @@ type PublishFunc func(topic string, payload []byte, opts ...PublishOption) Token
 //	}
 type PublishInterceptor func(PublishFunc) PublishFunc
 
+// PublishView is the mutable form of an outgoing publish passed to an
+// OutgoingPublishTransform. All fields may be changed; Properties may be
+// nil, and a transform may set it to add MQTT v5.0 properties.
+type PublishView struct {
+	Topic      string
+	Payload    []byte
+	QoS        QoS
+	Retain     bool
+	Properties *Properties
+}
+
+// OutgoingPublishTransform modifies an outgoing publish after its options
+// have been applied and before it is validated and enqueued.
+//
+// Unlike a PublishInterceptor, which wraps the Publish call, a transform
+// sees the final topic, payload, QoS, retain flag and properties, so it can
+// enforce policy uniformly (stamp a sequence number, add a checksum
+// property, rewrite topic prefixes) regardless of call site.
+//
+// Example:
+//
+//	func stampSequence(seq *atomic.Uint64) mq.OutgoingPublishTransform {
+//	    return func(p *mq.PublishView) {
+//	        if p.Properties == nil {
+//	            p.Properties = mq.NewProperties()
+//	        }
+//	        p.Properties.SetUserProperty("seq", strconv.FormatUint(seq.Add(1), 10))
+//	    }
+//	}
+type OutgoingPublishTransform func(*PublishView)
+
 // applyHandlerInterceptors wraps a MessageHandler with multiple interceptors.
 func applyHandlerInterceptors(handler MessageHandler, interceptors []HandlerInterceptor) MessageHandler {
 	for i := len(interceptors) - 1; i >= 0; i-- {
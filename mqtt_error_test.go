@@ -2,6 +2,7 @@ package mq
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/gonzalop/mq/internal/packets"
@@ -165,3 +166,28 @@ func TestMqttError_v5_v3_Compatibility(t *testing.T) {
 		}
 	})
 }
+
+func TestDisconnectError_Is(t *testing.T) {
+	for code, name := range disconnectReasonCodeNames {
+		t.Run(name, func(t *testing.T) {
+			opts := defaultOptions("tcp://localhost:1883")
+			opts.Logger = testLogger()
+			c := newTestClient(opts)
+			c.handleDisconnectPacket(&packets.DisconnectPacket{ReasonCode: uint8(code), Version: 5})
+
+			err := c.lastDisconnectReason
+			if !errors.Is(err, code) {
+				t.Errorf("errors.Is(%v, 0x%02X) = false, want true", err, uint8(code))
+			}
+			if wrapped := fmt.Errorf("reconnect: %w", err); !errors.Is(wrapped, code) {
+				t.Errorf("errors.Is on wrapped error = false for 0x%02X", uint8(code))
+			}
+
+			for other := range disconnectReasonCodeNames {
+				if other != code && errors.Is(err, other) {
+					t.Errorf("errors.Is(%v, 0x%02X) = true, want false", err, uint8(other))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package mq
+
+import (
+	"context"
+	"net"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestOnReconnecting(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// Connection 1 drops, attempts 2 and 3 fail, 4 succeeds
+	accepted := make(chan int, 16)
+	go serveScripted(ln, func(n int) bool { return n == 1 || n == 4 }, 4, accepted)
+
+	type call struct {
+		attempt int
+		delay   time.Duration
+	}
+	calls := make(chan call, 8)
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("on-reconnecting"),
+		WithReconnectBackoff(10*time.Millisecond, time.Second, 2),
+		WithOnReconnecting(func(attempt int, delay time.Duration) {
+			calls <- call{attempt, delay}
+		}),
+		WithLogger(testLogger()),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	var got []call
+	timeout := time.After(5 * time.Second)
+	for len(got) < 3 {
+		select {
+		case c := <-calls:
+			got = append(got, c)
+		case <-timeout:
+			t.Fatalf("expected 3 calls, got %v", got)
+		}
+	}
+
+	// Calls are asynchronous, so they may be observed out of order
+	sort.Slice(got, func(i, j int) bool { return got[i].attempt < got[j].attempt })
+	want := []call{
+		{1, 10 * time.Millisecond},
+		{2, 20 * time.Millisecond},
+		{3, 40 * time.Millisecond},
+	}
+	for i, w := range want {
+		if got[i] != w {
+			t.Errorf("call %d = %+v, want %+v", i, got[i], w)
+		}
+	}
+
+	// The successful attempt ends the cycle
+	deadline := time.Now().Add(2 * time.Second)
+	for !client.IsConnected() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if len(calls) != 0 {
+		t.Errorf("unexpected extra calls: %d", len(calls))
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"maps"
 	"net"
+	"slices"
 	"time"
 )
 
@@ -24,6 +25,15 @@ type clientOptions struct {
 	// Client identifier
 	ClientID string
 
+	// ClientIDOnTakeover derives a new client ID after a session takeover
+	// (optional).
+	ClientIDOnTakeover func(current string) string
+
+	// TakeoverPolicy determines whether the client reconnects after a
+	// session takeover; OnSessionTakenOver is notified of it
+	TakeoverPolicy     SessionTakeoverPolicy
+	OnSessionTakenOver func(*Client, error)
+
 	// Username for authentication (optional)
 	Username string
 
@@ -33,18 +43,58 @@ type clientOptions struct {
 	// Keep alive interval
 	KeepAlive time.Duration
 
+	// AdaptiveKeepAlive sends PINGREQ only when nothing has been sent for
+	// 3/4 of the keepalive interval, whatever was received.
+	AdaptiveKeepAlive bool
+
+	// ReadTimeout is how long the connection may stay silent before it is
+	// considered lost. Zero means 1.5x KeepAlive.
+	ReadTimeout time.Duration
+
+	// IOTimeout bounds each batch of writes to the connection. Zero means
+	// no bound.
+	IOTimeout time.Duration
+
+	// PingTimeout is how long a PINGREQ may wait for its PINGRESP before
+	// the connection is considered lost. Zero disables the check.
+	PingTimeout time.Duration
+
 	// Clean session flag
 	CleanSession bool
 
 	// Auto-reconnect on connection loss
 	AutoReconnect bool
 
+	// Reconnect backoff: the first delay, its upper bound, and the factor
+	// applied after each failed attempt (default: 1s, 2m, 2)
+	ReconnectBackoffInitial    time.Duration
+	ReconnectBackoffMax        time.Duration
+	ReconnectBackoffMultiplier float64
+
+	// ReconnectJitter randomizes each reconnect delay by up to
+	// ±ReconnectJitter of its value. Default is 0 (deterministic).
+	ReconnectJitter float64
+
+	// MaxReconnectAttempts is the number of consecutive failed reconnection
+	// attempts after which the client gives up. Default is 0 (unlimited).
+	MaxReconnectAttempts int
+
 	// Connection timeout
 	ConnectTimeout time.Duration
 
+	// RetryInterval is how long an unacknowledged packet waits before it is
+	// retransmitted. Default is 10s.
+	RetryInterval time.Duration
+
 	// TLS configuration (optional)
 	TLSConfig *tls.Config
 
+	// TLS server name (SNI), ALPN protocols and client certificate getter
+	// applied on top of TLSConfig
+	TLSServerName        string
+	TLSNextProtos        []string
+	GetClientCertificate func(*tls.CertificateRequestInfo) (*tls.Certificate, error)
+
 	// Logger for client events (optional, defaults to discarding logs)
 	Logger *slog.Logger
 
@@ -58,18 +108,77 @@ type clientOptions struct {
 	// Default is 100. Set to 0 for unlimited (not recommended for production).
 	MaxHandlerConcurrency int
 
+	// HandlerConcurrency is the size of the handler worker pool. Default is
+	// 0 (one goroutine per handler invocation).
+	HandlerConcurrency int
+
 	// MaxAuthExchanges limits the number of AUTH packet exchanges per connection.
 	// Default is 10.
 	MaxAuthExchanges uint16
 
+	// MaxInflightBytes limits the payload bytes of outstanding QoS 1/2 publishes.
+	// Default is 0 (unlimited).
+	MaxInflightBytes int
+
+	// MaxSubscriptions limits the number of simultaneously active subscriptions.
+	// Default is 0 (unlimited).
+	MaxSubscriptions int
+
 	// Will message (optional)
 	will *willMessage
 
+	// WillDelay is the Will Delay Interval in seconds (MQTT v5.0), sent with
+	// any will if WillDelaySet. It overrides the will's WillDelayInterval.
+	WillDelay    uint32
+	WillDelaySet bool
+
 	// Lifecycle hooks (optional)
 	OnConnect        func(*Client)
 	OnConnectionLost func(*Client, error)
+	OnReconnecting   func(attempt int, delay time.Duration)
 	OnServerRedirect func(serverURI string) // MQTT v5.0: Called when server provides redirection reference
 
+	// SuppressRetainedOnReconnect drops retained messages resent after
+	// a reconnect. Default is false.
+	SuppressRetainedOnReconnect bool
+
+	// ConnectionLostGracePeriod delays OnConnectionLost; reconnects within
+	// it are transparent. Default is 0 (notify immediately).
+	ConnectionLostGracePeriod time.Duration
+
+	// SubscribeWhileDisconnected determines what Subscribe does while the
+	// client is disconnected. Default is SubscribeWhileDisconnectedFail.
+	SubscribeWhileDisconnected SubscribeWhileDisconnectedPolicy
+
+	// OrderedDelivery invokes the handlers of each subscription
+	// sequentially, in the order messages were received. Default is false.
+	OrderedDelivery bool
+
+	// ShutdownAckTimeout is how long Disconnect waits for handlers whose
+	// acknowledgment is deferred. Default is 0 (don't wait).
+	ShutdownAckTimeout time.Duration
+
+	// ManualAck defers the acknowledgment of QoS 1/2 messages until the
+	// application calls Message.Ack. Default is false.
+	ManualAck bool
+
+	// TopicStats counts received messages and bytes per subscription
+	// filter (see Client.TopicStats). Default is false.
+	TopicStats bool
+
+	// QualityWeights weighs the ConnectionQuality factors.
+	// Zero value means DefaultConnectionQualityWeights.
+	QualityWeights ConnectionQualityWeights
+
+	// Metrics receives message size observations (optional).
+	Metrics Metrics
+
+	// ConnectValidator is called after CONNACK and may veto the connection.
+	ConnectValidator func(ServerCapabilities, *Properties) error
+
+	// Metadata is copied into the client at Dial time (see SetMetadata).
+	Metadata map[any]any
+
 	// Initial subscriptions (optional)
 	InitialSubscriptions map[string]MessageHandler
 
@@ -80,6 +189,10 @@ type clientOptions struct {
 	// If true, the client will first try v5.0 and fall back to v3.1.1 if refused.
 	AutoProtocolVersion bool
 
+	// AutoServerRedirect reconnects to the server named by a Server
+	// Reference (MQTT v5.0). Default is false.
+	AutoServerRedirect bool
+
 	// MQTT v5.0 request flags
 	RequestProblemInformation  bool
 	RequestResponseInformation bool
@@ -89,6 +202,15 @@ type clientOptions struct {
 	// 0 = disabled (default). Server may override to a lower value.
 	TopicAliasMaximum uint16
 
+	// TopicAliasAutoTune sizes TopicAliasMaximum from observed traffic.
+	TopicAliasAutoTune bool
+
+	// EagerAliasReestablish reuses outgoing topic aliases across reconnects.
+	EagerAliasReestablish bool
+
+	// TopicAliasPolicy chooses what happens when all outgoing aliases are used.
+	TopicAliasPolicy TopicAliasPolicy
+
 	// MQTT v5.0 receive maximum (client side flow control)
 	// Maximum number of QoS 1 and QoS 2 publications the client is willing to process concurrently.
 	// 0 = 65535 (default)
@@ -108,10 +230,19 @@ type clientOptions struct {
 	// Called when a PUBLISH packet doesn't match any registered subscription.
 	DefaultPublishHandler MessageHandler
 
+	// SubscriptionIDHandlers route messages by subscription identifier.
+	SubscriptionIDHandlers map[int]MessageHandler
+
 	// Custom dialer (optional)
 	// If set, this is used to establish the connection instead of net.Dialer.
 	Dialer ContextDialer
 
+	// TCP keepalive settings (optional); nil leaves the Go defaults
+	TCPKeepAlive *net.KeepAliveConfig
+
+	// Local address to bind outgoing connections to (optional)
+	LocalAddr net.Addr
+
 	// Session store for persistence (optional)
 	// If set, session state will be persisted across process restarts.
 	SessionStore SessionStore
@@ -120,6 +251,13 @@ type clientOptions struct {
 	// If set, enables challenge/response authentication via AUTH packet flow.
 	Authenticator Authenticator
 
+	// OnRetransmit is called whenever a packet is sent again
+	OnRetransmit func(packetID uint16, packetType string, attempt int)
+
+	// PacketIDs chooses the packet identifiers of outgoing packets (nil uses
+	// the full range, 1-65535)
+	PacketIDs packetIDAllocator
+
 	// Buffer sizes for internal packet processing.
 
 	// OutgoingQueueSize is the capacity of the outgoing packet channel.
@@ -129,13 +267,23 @@ type clientOptions struct {
 	// IncomingQueueSize is the capacity of the incoming packet channel.
 	IncomingQueueSize int
 
+	// IncomingStrategy determines what happens when the incoming channel is full.
+	IncomingStrategy IncomingBackpressureStrategy
+	OnIncomingSpill  MessageHandler
+
 	// QoS0Policy determines how the client handles QoS 0 messages when the
 	// OutgoingQueueSize is reached.
 	QoS0Policy QoS0LimitPolicy
 
+	// QoS0TokenBehavior determines when the token of a QoS 0 publish completes.
+	QoS0TokenBehavior QoS0TokenBehavior
+
 	// Interceptors for message handling and publishing.
 	HandlerInterceptors []HandlerInterceptor
 	PublishInterceptors []PublishInterceptor
+
+	// PublishTransforms modify outgoing publishes before they are enqueued.
+	PublishTransforms []OutgoingPublishTransform
 }
 
 const (
@@ -172,6 +320,95 @@ func WithClientID(id string) Option {
 	}
 }
 
+// WithClientIDOnTakeover sets a function that derives a new client ID when
+// the server disconnects the client because another connection took over
+// its session (MQTT v5.0 reason code 0x8E, Session taken over).
+//
+// Two instances sharing a client ID and both reconnecting automatically
+// will keep knocking each other off the server. With this option, the
+// client that was taken over reconnects with derive(current) instead,
+// breaking the loop. Returning an empty string or the current ID keeps the
+// ID unchanged. Requires AutoReconnect.
+//
+// Changing the client ID abandons the old session: the server starts a new
+// one for the new ID, so subscriptions are re-established by the client but
+// messages queued for the old session are not delivered to it. A session
+// store bound to the old ID (NewFileStore takes a client ID) keeps being
+// used; pending publishes are resent on the new session.
+//
+// Example:
+//
+//	client, _ := mq.Dial(uri,
+//	    mq.WithClientID("worker"),
+//	    mq.WithClientIDOnTakeover(func(current string) string {
+//	        return fmt.Sprintf("worker-%d", os.Getpid())
+//	    }))
+func WithClientIDOnTakeover(derive func(current string) string) Option {
+	return func(o *clientOptions) {
+		o.ClientIDOnTakeover = derive
+	}
+}
+
+// SessionTakeoverPolicy determines what the client does after the server
+// disconnected it because another connection took over its session (MQTT
+// v5.0 reason code 0x8E, Session taken over).
+type SessionTakeoverPolicy int
+
+const (
+	// SessionTakeoverStop stops the client instead of reconnecting
+	// (default). Two instances sharing a client ID would otherwise keep
+	// knocking each other off the server.
+	SessionTakeoverStop SessionTakeoverPolicy = iota
+
+	// SessionTakeoverReconnect reconnects as after any other connection
+	// loss, for topologies where another instance intentionally takes over
+	// and this one should take the session back later.
+	SessionTakeoverReconnect
+)
+
+// WithSessionTakeoverPolicy sets what the client does after a session
+// takeover (default: SessionTakeoverStop).
+//
+// With SessionTakeoverStop and AutoReconnect, the client stops as if
+// Disconnect had been called, without sending DISCONNECT: OnConnectionLost
+// reports the *DisconnectError (matching ReasonCodeSessionTakenOver with
+// errors.Is) at once, ignoring WithConnectionLostGracePeriod, and Dial must
+// be called to connect again. WithClientIDOnTakeover resolves the conflict
+// by changing the client ID instead, so the client always reconnects when
+// it is set.
+//
+// Example:
+//
+//	client, _ := mq.Dial(uri,
+//	    mq.WithClientID("gateway"),
+//	    mq.WithSessionTakeoverPolicy(mq.SessionTakeoverReconnect))
+func WithSessionTakeoverPolicy(policy SessionTakeoverPolicy) Option {
+	return func(o *clientOptions) {
+		o.TakeoverPolicy = policy
+	}
+}
+
+// WithOnSessionTakenOver sets a handler called when the server disconnects
+// the client because another connection took over its session (MQTT v5.0
+// reason code 0x8E). err is the *DisconnectError sent by the server.
+//
+// The handler runs in its own goroutine, before the client reconnects or
+// stops according to WithSessionTakeoverPolicy, and can be used to alert an
+// operator or to start a replacement client.
+//
+// Example:
+//
+//	client, _ := mq.Dial(uri,
+//	    mq.WithClientID("gateway"),
+//	    mq.WithOnSessionTakenOver(func(c *mq.Client, err error) {
+//	        log.Printf("client ID %q in use elsewhere: %v", c.Config().ClientID, err)
+//	    }))
+func WithOnSessionTakenOver(handler func(*Client, error)) Option {
+	return func(o *clientOptions) {
+		o.OnSessionTakenOver = handler
+	}
+}
+
 // WithCredentials sets the username and password for authentication.
 func WithCredentials(username, password string) Option {
 	return func(o *clientOptions) {
@@ -181,12 +418,113 @@ func WithCredentials(username, password string) Option {
 }
 
 // WithKeepAlive sets the MQTT keep alive interval (default: 60s).
+//
+// A duration of 0 disables keepalive: the CONNECT packet asks the server not
+// to time out the client, and the client never sends PINGREQ. Dead
+// connections are then only detected by WithReadTimeout, if set.
 func WithKeepAlive(duration time.Duration) Option {
 	return func(o *clientOptions) {
 		o.KeepAlive = duration
 	}
 }
 
+// WithAdaptiveKeepAlive makes the client skip PINGREQs while traffic is
+// flowing.
+//
+// By default a PINGREQ is sent when nothing has been sent OR nothing has
+// been received for 3/4 of the keepalive interval, so a client publishing
+// every second still pings regularly. In adaptive mode a PINGREQ is only
+// sent when nothing has been sent for that long, which the server must
+// never see for more than the keepalive interval.
+//
+// The 1.5x keepalive receive timeout is unchanged, so the mode suits
+// connections where the server regularly sends something back, such as
+// PUBACKs for QoS 1 publishes or subscribed messages. A client that only
+// publishes QoS 0 messages receives nothing and would be disconnected.
+//
+// Example:
+//
+//	client, _ := mq.Dial(uri, mq.WithAdaptiveKeepAlive())
+func WithAdaptiveKeepAlive() Option {
+	return func(o *clientOptions) {
+		o.AdaptiveKeepAlive = true
+	}
+}
+
+// WithReadTimeout sets how long the client waits without receiving any
+// packet before it considers the connection lost (default: 0, meaning 1.5x
+// the keepalive interval).
+//
+// The timeout is independent of PINGREQ sending. Combined with
+// WithKeepAlive(0), the client sends no PINGREQ but still disconnects a
+// silent connection, e.g. behind a proxy that injects its own keepalive
+// traffic. With a non-zero keepalive, PINGREQs are still sent every 3/4 of
+// the interval and d replaces the 1.5x keepalive rule; choose d longer than
+// the keepalive interval so that a PINGRESP can arrive in time.
+//
+// Example:
+//
+//	// No PINGREQ over the cellular link; drop after 5 minutes of silence
+//	client, _ := mq.Dial(uri,
+//	    mq.WithKeepAlive(0),
+//	    mq.WithReadTimeout(5*time.Minute))
+func WithReadTimeout(d time.Duration) Option {
+	return func(o *clientOptions) {
+		if d < 0 {
+			return
+		}
+		o.ReadTimeout = d
+	}
+}
+
+// WithIOTimeout sets the maximum time a write to the network connection may
+// take, including flushing a batch of queued packets (default: 0, no
+// limit). When it is exceeded, the connection is considered lost.
+//
+// Keepalive only notices a dead connection after 1.5x the keepalive
+// interval, and a write on a wedged TCP or TLS session can block for far
+// longer than that, holding up every later packet. WithIOTimeout puts a hard
+// bound on each write, independent of keepalive. Choose d well above the
+// time needed to send the largest expected message on the slowest link.
+//
+// Example:
+//
+//	client, _ := mq.Dial(uri, mq.WithIOTimeout(10*time.Second))
+func WithIOTimeout(d time.Duration) Option {
+	return func(o *clientOptions) {
+		if d < 0 {
+			return
+		}
+		o.IOTimeout = d
+	}
+}
+
+// WithPingTimeout sets how long the client waits for a PINGRESP after
+// sending a PINGREQ before it considers the connection lost (default: 0,
+// disabled).
+//
+// Without it, a dead connection is only detected once nothing has been
+// received for 1.5x the keepalive interval. A half-open TCP connection (the
+// server vanished without closing it) can then go unnoticed for most of that
+// window. With a ping timeout, the client disconnects, and reconnects if
+// WithAutoReconnect is enabled, as soon as a PINGREQ goes unanswered for d.
+// The 1.5x keepalive check still applies.
+//
+// Example:
+//
+//	// Detect a dead server within ~45s + 5s instead of 90s
+//	client, _ := mq.Dial(uri,
+//	    mq.WithKeepAlive(60*time.Second),
+//	    mq.WithPingTimeout(5*time.Second))
+func WithPingTimeout(d time.Duration) Option {
+	return func(o *clientOptions) {
+		if d < 0 {
+			return
+		}
+		o.PingTimeout = d
+	}
+}
+
 // WithCleanSession sets the clean session flag.
 //
 // When set to true (default), the server will discard any previous session state
@@ -228,6 +566,123 @@ func WithAutoReconnect(enable bool) Option {
 	}
 }
 
+// WithReconnectBackoff configures the delay between automatic reconnection
+// attempts. The first attempt waits initial; each failed attempt multiplies
+// the delay by multiplier, up to max. A successful connection resets the
+// delay to initial.
+//
+// The default is WithReconnectBackoff(time.Second, 2*time.Minute, 2).
+// A multiplier of 1 retries at a constant interval. Invalid values (initial
+// not positive, max below initial, or multiplier below 1) are ignored and
+// the defaults kept.
+//
+// The delay that will be used before the next attempt is reported by
+// Client.ReconnectBackoff.
+//
+// Example (flaky cellular link: retry fast, back off gently):
+//
+//	client, err := mq.Dial("tcp://broker:1883",
+//	    mq.WithReconnectBackoff(500*time.Millisecond, 30*time.Second, 1.5))
+func WithReconnectBackoff(initial, max time.Duration, multiplier float64) Option {
+	return func(o *clientOptions) {
+		if initial <= 0 || max < initial || !(multiplier >= 1) {
+			return
+		}
+		o.ReconnectBackoffInitial = initial
+		o.ReconnectBackoffMax = max
+		o.ReconnectBackoffMultiplier = multiplier
+	}
+}
+
+// WithReconnectJitter randomizes each reconnect delay by up to ±fraction of
+// its value, so a fleet of clients that lost their connection at the same
+// time (e.g. on a broker restart) spreads its reconnection attempts instead
+// of retrying in synchronized waves.
+//
+// With a fraction of 0.2, a 10s backoff becomes a delay anywhere between 8s
+// and 12s. The fraction must be between 0 and 1, so the delay never goes
+// negative; other values are ignored. The default of 0 keeps the schedule
+// deterministic. Each client uses its own random source, so clients started
+// together still diverge.
+//
+// The jitter applies to the sleep only: the backoff schedule itself, as set
+// with WithReconnectBackoff and reported by Client.ReconnectBackoff, is
+// unaffected.
+//
+// Example:
+//
+//	client, err := mq.Dial("tcp://broker:1883",
+//	    mq.WithReconnectJitter(0.3))
+func WithReconnectJitter(fraction float64) Option {
+	return func(o *clientOptions) {
+		if !(fraction >= 0 && fraction <= 1) {
+			return
+		}
+		o.ReconnectJitter = fraction
+	}
+}
+
+// WithMaxReconnectAttempts makes the client give up automatic reconnection
+// after n consecutive failed attempts, instead of retrying forever. This suits
+// short-lived batch jobs that should fail rather than hang when the server is
+// gone.
+//
+// When the limit is reached the client stops, as if Disconnect had been
+// called, and OnConnectionLost is invoked with an error wrapping
+// ErrReconnectAttemptsExhausted (and the error of the last attempt). The
+// count resets after every successful reconnection, so the limit applies to
+// each outage separately.
+//
+// The default of 0 means unlimited; negative values are ignored.
+//
+// Example:
+//
+//	client, err := mq.Dial("tcp://broker:1883",
+//	    mq.WithMaxReconnectAttempts(5),
+//	    mq.WithOnConnectionLost(func(c *mq.Client, err error) {
+//	        if errors.Is(err, mq.ErrReconnectAttemptsExhausted) {
+//	            log.Fatalf("broker unreachable: %v", err)
+//	        }
+//	    }))
+func WithMaxReconnectAttempts(n int) Option {
+	return func(o *clientOptions) {
+		if n < 0 {
+			return
+		}
+		o.MaxReconnectAttempts = n
+	}
+}
+
+// defaultRetryInterval is the default of WithRetryInterval.
+const defaultRetryInterval = 10 * time.Second
+
+// WithRetryInterval sets how long the client waits for the acknowledgment of
+// an in-flight packet before retransmitting it while the connection stays up
+// (default: 10s). Retransmitted PUBLISH packets carry the DUP flag; PUBREL,
+// SUBSCRIBE and UNSUBSCRIBE packets are sent again unchanged.
+//
+// Pending packets are checked every half interval, so a packet is resent
+// between one and one and a half intervals after its last transmission.
+// Raise the interval on high-latency links (e.g. satellite) to avoid
+// needless duplicates, or lower it on a LAN for faster recovery from lost
+// packets. Values that are not positive are ignored and the default kept.
+//
+// Unacknowledged packets are always resent right after a reconnect,
+// independently of this interval.
+//
+// Example:
+//
+//	client, err := mq.Dial("tcp://broker:1883",
+//	    mq.WithRetryInterval(45*time.Second))
+func WithRetryInterval(d time.Duration) Option {
+	return func(o *clientOptions) {
+		if d <= 0 {
+			return
+		}
+		o.RetryInterval = d
+	}
+}
+
 // WithConnectTimeout sets the connection timeout (default: 30s).
 func WithConnectTimeout(duration time.Duration) Option {
 	return func(o *clientOptions) {
@@ -245,6 +700,65 @@ func WithTLS(config *tls.Config) Option {
 	}
 }
 
+// WithTLSServerName sets the server name sent for SNI and checked against
+// the server certificate, and enables TLS. Use it when connecting through
+// an IP address or a load balancer whose address differs from the name in
+// the certificate.
+//
+// It composes with WithTLS in either order: the name is set on a copy of
+// the WithTLS configuration, overriding its ServerName.
+//
+// Example:
+//
+//	client, _ := mq.Dial("tls://10.0.0.5:8883",
+//	    mq.WithTLSServerName("broker.example.com"))
+func WithTLSServerName(name string) Option {
+	return func(o *clientOptions) {
+		o.TLSServerName = name
+	}
+}
+
+// WithTLSNextProtos sets the ALPN protocols offered during the TLS
+// handshake, in order of preference, and enables TLS. Some servers, such as
+// AWS IoT Core on port 443, select MQTT through ALPN.
+//
+// Like WithTLSServerName, it composes with WithTLS in either order,
+// overriding its NextProtos.
+//
+// Example:
+//
+//	client, _ := mq.Dial("tls://iot.example.com:443",
+//	    mq.WithTLS(tlsConfig),
+//	    mq.WithTLSNextProtos("x-amzn-mqtt-ca"))
+func WithTLSNextProtos(protos ...string) Option {
+	return func(o *clientOptions) {
+		o.TLSNextProtos = slices.Clone(protos)
+	}
+}
+
+// WithClientCertificateGetter sets a function that provides the client
+// certificate for mutual TLS when the server requests one, and enables TLS.
+// It is called during every handshake, including on reconnect, so the
+// certificate can be loaded on demand, e.g. from a TPM or HSM, or rotated
+// without recreating the client.
+//
+// The getter is set as GetClientCertificate on a copy of the WithTLS
+// configuration (in either order) and overrides any static Certificates
+// there. Returning a certificate with no Certificate chain sends none;
+// returning an error aborts the handshake.
+//
+// Example:
+//
+//	client, _ := mq.Dial("tls://broker.example.com:8883",
+//	    mq.WithClientCertificateGetter(func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+//	        return device.LoadCertificate()
+//	    }))
+func WithClientCertificateGetter(getter func(*tls.CertificateRequestInfo) (*tls.Certificate, error)) Option {
+	return func(o *clientOptions) {
+		o.GetClientCertificate = getter
+	}
+}
+
 // WithProtocolVersion sets the MQTT protocol version to use.
 // Use ProtocolV50 (default) for MQTT v5.0 or ProtocolV311 for MQTT v3.1.1.
 //
@@ -366,6 +880,92 @@ func WithTopicAliasMaximum(limit uint16) Option {
 	}
 }
 
+// WithTopicAliasAutoTune enables topic alias auto-tuning (MQTT v5.0).
+//
+// Receive side: the client counts the distinct topics of incoming messages
+// and, on each reconnect, declares that number as its TopicAliasMaximum (see
+// RecommendedReceiveAliasMaximum). The receive alias maximum is sent in
+// CONNECT and cannot change during a connection, so tuning only takes effect
+// on the next reconnect. The first connection uses WithTopicAliasMaximum, or
+// 16 if none was set.
+//
+// Send side: for messages published with WithAlias, an alias is only assigned
+// once a topic is published a second time. One-off topics are sent in full
+// and do not use up the server's alias slots.
+//
+// Tracking keeps one entry per distinct incoming topic, up to 65535 (the
+// largest alias maximum MQTT allows).
+//
+// Example:
+//
+//	client, _ := mq.Dial(uri, mq.WithTopicAliasAutoTune(true))
+func WithTopicAliasAutoTune(enabled bool) Option {
+	return func(o *clientOptions) {
+		o.TopicAliasAutoTune = enabled
+	}
+}
+
+// WithEagerAliasReestablish keeps outgoing topic aliases (see WithAlias) stable
+// across reconnects.
+//
+// Topic aliases only live as long as a network connection, so by default all
+// aliases are forgotten on reconnect and re-assigned lazily, in publish
+// order. With this option enabled the client remembers which topics were
+// aliased before the disconnect and, once the new CONNACK arrives, reserves
+// the same alias IDs for them (as far as the new server limit allows). Aliased
+// publishes already queued while offline are re-aliased immediately, and the
+// next PUBLISH for each remembered topic carries the full topic plus its alias
+// so that every following publish is alias-only again.
+//
+// MQTT has no way to announce an alias without sending a PUBLISH, so the
+// re-establishment always rides on the first real message for a topic.
+// Reserved IDs take precedence over new topics when the server lowers its
+// TopicAliasMaximum.
+//
+// Only applicable for MQTT v5.0.
+func WithEagerAliasReestablish(enabled bool) Option {
+	return func(o *clientOptions) {
+		o.EagerAliasReestablish = enabled
+	}
+}
+
+// TopicAliasPolicy determines how outgoing topic aliases (see WithAlias) are
+// handed out once all the aliases allowed on the connection are in use.
+type TopicAliasPolicy int
+
+const (
+	// TopicAliasFirstCome keeps the aliases of the first topics published on
+	// the connection. Once they are all used, other topics are sent in full.
+	TopicAliasFirstCome TopicAliasPolicy = iota
+
+	// TopicAliasLRU reassigns the alias of the least recently published
+	// topic to the new topic. The publish that remaps the alias carries the
+	// full topic, as MQTT requires; the evicted topic gets an alias again
+	// (evicting another one) the next time it is published.
+	TopicAliasLRU
+)
+
+// WithTopicAliasPolicy sets how outgoing topic aliases are handed out once the
+// alias pool of the connection is exhausted. The default, TopicAliasFirstCome,
+// keeps the first topics aliased for the whole connection; TopicAliasLRU
+// favors the topics published most recently, so a changing set of hot topics
+// keeps benefiting from aliases.
+//
+// Aliases assigned with WithAliasID are never evicted.
+//
+// Only applicable for MQTT v5.0.
+//
+// Example:
+//
+//	client, _ := mq.Dial(uri,
+//	    mq.WithTopicAliasMaximum(50),
+//	    mq.WithTopicAliasPolicy(mq.TopicAliasLRU))
+func WithTopicAliasPolicy(policy TopicAliasPolicy) Option {
+	return func(o *clientOptions) {
+		o.TopicAliasPolicy = policy
+	}
+}
+
 // LimitPolicy determines how the client enforces limits (like ReceiveMaximum).
 type LimitPolicy int
 
@@ -379,6 +979,18 @@ const (
 	// Note: Auto-reconnect should be disabled or carefully managed when using this policy,
 	// as a misbehaving server could cause an infinite loop of connect -> overflow -> disconnect.
 	LimitPolicyStrict
+
+	// LimitPolicyBackpressure applies flow control instead of enforcing the limit
+	// after the fact. QoS 1 and QoS 2 messages are acknowledged (PUBACK/PUBREC)
+	// only after all their handlers have returned, and the client stops reading
+	// from the socket while ReceiveMaximum messages are still being processed.
+	// Reading resumes as soon as an acknowledgment is sent, so a slow consumer
+	// pushes back on the server through TCP without disconnecting.
+	//
+	// Note: while reading is paused no packets (including PINGRESP) are
+	// processed. Handlers should complete well within the keepalive timeout
+	// (1.5x the keepalive interval), otherwise the connection is considered dead.
+	LimitPolicyBackpressure
 )
 
 // QoS0LimitPolicy determines how the client handles QoS 0 messages when the internal buffer is full.
@@ -412,6 +1024,9 @@ const (
 //     processing messages (potentially unbounded).
 //   - LimitPolicyStrict: Disconnect with Reason Code 0x93.
 //     Use this if strict flow control compliance is required.
+//   - LimitPolicyBackpressure: Acknowledge messages only after their handlers
+//     return and pause reading from the socket while the limit is reached.
+//     Use this to slow the server down to the pace of the handlers.
 //
 // This option is ignored when using MQTT v3.1.1.
 func WithReceiveMaximum(limit uint16, policy LimitPolicy) Option {
@@ -513,6 +1128,38 @@ func WithDefaultPublishHandler(handler MessageHandler) Option {
 	}
 }
 
+// WithSubscriptionIDHandler routes incoming messages that carry the
+// subscription identifier id (MQTT v5.0) straight to handler.
+//
+// When a subscription is made with WithSubscriptionIdentifier, the server
+// echoes the identifier in every PUBLISH it delivers for it, even when
+// several overlapping filters match the topic. Messages carrying id are
+// dispatched to handler, which replaces the one given to Subscribe for the
+// subscriptions with that identifier. Subscriptions without an identifier,
+// or whose identifier has no handler registered, are matched by topic as
+// usual, and WithSuppressRetainedOnReconnect and WithTopicStats apply to all of
+// them.
+//
+// It may be given several times, once per identifier. The handler runs
+// through the subscribe interceptors like any other handler.
+//
+// Example:
+//
+//	client, _ := mq.Dial(uri,
+//	    mq.WithSubscriptionIDHandler(1, alarms),
+//	    mq.WithSubscriptionIDHandler(2, telemetry))
+//
+//	client.Subscribe("plant/+/alarm", mq.AtLeastOnce, nil, mq.WithSubscriptionIdentifier(1))
+//	client.Subscribe("plant/#", mq.AtMostOnce, nil, mq.WithSubscriptionIdentifier(2))
+func WithSubscriptionIDHandler(id int, handler MessageHandler) Option {
+	return func(o *clientOptions) {
+		if o.SubscriptionIDHandlers == nil {
+			o.SubscriptionIDHandlers = make(map[int]MessageHandler)
+		}
+		o.SubscriptionIDHandlers[id] = handler
+	}
+}
+
 // WithLogger sets a custom logger for the client.
 // If not provided, the client will use a logger that discards all output.
 // Use this to integrate with your application's logging system.
@@ -559,6 +1206,70 @@ func WithDialer(dialer ContextDialer) Option {
 	}
 }
 
+// WithTCPKeepAlive enables TCP-level keepalive probes with the given
+// parameters on the connection to the server.
+//
+// MQTT keepalive detects a dead connection after 1.5x the keepalive
+// interval. TCP keepalive complements it with OS-level dead-peer detection,
+// which also notices a half-open connection (the peer vanished without
+// closing it) while the client is blocked writing, and keeps NAT or
+// firewall state alive on idle links. After idle without traffic, the OS
+// sends a probe every interval and drops the connection after count
+// unanswered probes. A zero value selects Go's default for that parameter
+// (15s, 15s and 9 respectively).
+//
+// The settings apply to the TCP socket beneath TLS. With a custom dialer
+// (WithDialer) they are applied only if the returned connection is a
+// *net.TCPConn, or wraps one and exposes it through a NetConn method (as
+// *tls.Conn does); other transports are left untouched.
+//
+// Platform differences: Linux, macOS, the BSDs and Windows 10 (1709) or
+// later support all three parameters. Older Windows versions cannot change
+// count or set idle and interval independently, and Solaris derivatives
+// require interval and count to be set together; there the unsupported
+// parameters keep their system defaults. Failures to apply the settings are
+// logged, not fatal.
+//
+// Example:
+//
+//	// Detect a vanished peer within ~30s + 3*5s
+//	client, _ := mq.Dial(uri, mq.WithTCPKeepAlive(30*time.Second, 5*time.Second, 3))
+func WithTCPKeepAlive(idle, interval time.Duration, count int) Option {
+	return func(o *clientOptions) {
+		if idle < 0 || interval < 0 || count < 0 {
+			return
+		}
+		o.TCPKeepAlive = &net.KeepAliveConfig{
+			Enable:   true,
+			Idle:     idle,
+			Interval: interval,
+			Count:    count,
+		}
+	}
+}
+
+// WithLocalAddr binds the connection to the server to the given local
+// address, typically a *net.TCPAddr. Use it on multi-homed hosts, or with
+// source-IP based firewall rules, to make MQTT traffic leave through a
+// specific interface.
+//
+// The address applies to plain TCP and TLS connections, on every connect
+// including automatic reconnects. A zero port lets the OS pick one; a fixed
+// port may fail to bind again for a while after a disconnect (TIME_WAIT). It
+// is ignored when a custom dialer is set with WithDialer, which is
+// responsible for its own binding.
+//
+// Example:
+//
+//	// Egress through the plant-network NIC
+//	client, _ := mq.Dial("tcp://10.0.5.1:1883",
+//	    mq.WithLocalAddr(&net.TCPAddr{IP: net.ParseIP("10.0.5.20")}))
+func WithLocalAddr(addr net.Addr) Option {
+	return func(o *clientOptions) {
+		o.LocalAddr = addr
+	}
+}
+
 // DialFunc is a helper to convert a function to the ContextDialer interface.
 type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
 
@@ -613,6 +1324,33 @@ func WithWill(topic string, payload []byte, qos uint8, retained bool, properties
 	}
 }
 
+// WithWillDelay sets the Will Delay Interval (MQTT v5.0): the server waits
+// this many seconds after the connection is lost before it publishes the
+// will, and discards it if the client reconnects in the meantime. A brief
+// network outage then does not announce the client as offline.
+//
+// It applies to the will set with WithWill or SetWill, without building a
+// Properties value, and takes precedence over WillDelayInterval in its
+// properties. It is ignored with MQTT v3.1.1.
+//
+// The server publishes the will when the session ends, even if the delay
+// has not elapsed, so set WithSessionExpiryInterval to at least the delay:
+// with the default session expiry of 0, the will is sent immediately.
+//
+// Example:
+//
+//	client, _ := mq.Dial(uri,
+//	    mq.WithClientID("sensor-1"),
+//	    mq.WithSessionExpiryInterval(300),
+//	    mq.WithWill("devices/sensor-1/status", []byte("offline"), 1, true),
+//	    mq.WithWillDelay(30))
+func WithWillDelay(seconds uint32) Option {
+	return func(o *clientOptions) {
+		o.WillDelay = seconds
+		o.WillDelaySet = true
+	}
+}
+
 // WithOnConnect sets the handler to be called when the client connects.
 // This is called for the initial connection and every successful reconnection.
 //
@@ -636,6 +1374,340 @@ func WithOnConnectionLost(onConnectionLost func(*Client, error)) Option {
 	}
 }
 
+// WithOnReconnecting sets a handler called before each automatic
+// reconnection attempt, with the attempt number within the current outage
+// (starting at 1, reset by every successful reconnection) and the delay the
+// client waited before it (shorter than the backoff if ReconnectNow cut it
+// short).
+//
+// Use it to surface the retry cycle, which OnConnectionLost and OnConnect
+// don't show, e.g. on a dashboard. It is not called for the transparent
+// reconnect of RefreshWill.
+//
+// The handler is invoked asynchronously in a separate goroutine, so it does
+// not delay the attempt.
+//
+// Example:
+//
+//	mq.WithOnReconnecting(func(attempt int, delay time.Duration) {
+//	    slog.Info("reconnecting", "attempt", attempt, "waited", delay)
+//	})
+func WithOnReconnecting(onReconnecting func(attempt int, delay time.Duration)) Option {
+	return func(o *clientOptions) {
+		o.OnReconnecting = onReconnecting
+	}
+}
+
+// WithSuppressRetainedOnReconnect drops the retained messages the server
+// sends again when the client resubscribes after a reconnect.
+//
+// On every new subscription the server delivers the retained message of
+// each matching topic. After a reconnect the client resubscribes, so an
+// application that already processed the retained state on the initial
+// connection would receive (and reprocess) all of it again. With this
+// option enabled, messages with the Retained flag set are dropped for
+// subscriptions that were re-established by a reconnect; they are still
+// acknowledged. Messages published while connected are unaffected, since
+// servers clear the Retained flag when forwarding them.
+//
+// Tradeoff: retained values that changed during the outage are lost too,
+// as the server signals them the same way. Use it only when the live
+// stream (or another mechanism) will bring the application up to date.
+//
+// Subscriptions using RetainAsPublished are exempt, because the flag does
+// not distinguish stored from live messages there. Calling Subscribe again
+// for a filter restores normal delivery for it. Where the server honours it,
+// prefer WithRetainHandling(1) (send retained messages only for new
+// subscriptions), which avoids the transfer altogether; this option covers
+// servers that send them anyway and MQTT v3.1.1.
+//
+// Example:
+//
+//	client, _ := mq.Dial(uri,
+//	    mq.WithCleanSession(false),
+//	    mq.WithSuppressRetainedOnReconnect(true))
+func WithSuppressRetainedOnReconnect(enabled bool) Option {
+	return func(o *clientOptions) {
+		o.SuppressRetainedOnReconnect = enabled
+	}
+}
+
+// WithConnectionLostGracePeriod delays the OnConnectionLost notification by d.
+//
+// If automatic reconnection succeeds within the grace period, the reconnect
+// is transparent: neither OnConnectionLost nor OnConnect is called for it.
+// If the connection is still down when the period ends, OnConnectionLost is
+// called with the original reason (and OnConnect follows on the eventual
+// reconnect, as usual). This smooths over short network blips, e.g. to avoid
+// flashing a "disconnected" indicator in a UI.
+//
+// Note that this delays disconnect notification by up to d; use IsConnected
+// for the immediate state. The reconnect loop waits at least one second
+// before its first attempt, so periods shorter than that have no effect
+// beyond the delay. Ignored unless AutoReconnect is enabled. Default is 0
+// (notify immediately).
+//
+// Example:
+//
+//	client, _ := mq.Dial(uri,
+//	    mq.WithConnectionLostGracePeriod(5*time.Second),
+//	    mq.WithOnConnectionLost(func(c *mq.Client, err error) {
+//	        ui.ShowOffline(err)
+//	    }),
+//	    mq.WithOnConnect(func(c *mq.Client) {
+//	        ui.ShowOnline()
+//	    }))
+func WithConnectionLostGracePeriod(d time.Duration) Option {
+	return func(o *clientOptions) {
+		if d >= 0 {
+			o.ConnectionLostGracePeriod = d
+		}
+	}
+}
+
+// WithOrderedDelivery delivers the messages of each subscription to its
+// handler one at a time, in the order they were received from the server.
+//
+// By default every handler call runs in its own goroutine, so two messages on
+// the same subscription may be processed concurrently and finish (or even
+// start) in any order. In ordered mode each subscription (and the default
+// handler) gets a queue instead: its handler is never called concurrently
+// with itself, and sees messages in wire order. Different subscriptions are
+// still processed in parallel, and a message matching several subscriptions
+// is queued on each. To order only some subscriptions, use the WithOrdered
+// subscribe option instead.
+//
+// The order is kept across reconnects: the queues belong to the client, not
+// the connection, so messages received before a disconnect are handled before
+// any received after it, including messages the server redelivers when a
+// persistent session is resumed (which MQTT requires it to resend first, in
+// their original order). The library does not reorder messages itself; the
+// order between different topics is up to the server.
+//
+// Tradeoff: a slow message delays every later message of its subscription
+// (head-of-line blocking). The logic loop never blocks on a queue, so queued
+// messages are buffered in memory; use WithReceiveMaximum with
+// LimitPolicyBackpressure to bound how many QoS 1/2 messages can pile up.
+// Ordered handlers do not count against WithMaxHandlerConcurrency.
+//
+// Example:
+//
+//	client, _ := mq.Dial(uri,
+//	    mq.WithOrderedDelivery(),
+//	    mq.WithSubscription("machines/+/events", stateMachine.Apply))
+func WithOrderedDelivery() Option {
+	return func(o *clientOptions) {
+		o.OrderedDelivery = true
+	}
+}
+
+// WithTopicStats makes the client count the messages and payload bytes it
+// receives for each subscription filter, reported by Client.TopicStats.
+//
+// A message matching several subscriptions counts once for each of them.
+// Retained messages suppressed after a reconnect, and messages only seen
+// by the default handler, are not counted. Counting is off by default so
+// the receive path does no extra work.
+//
+// Example:
+//
+//	client, _ := mq.Dial(uri, mq.WithTopicStats())
+//	...
+//	for filter, s := range client.TopicStats() {
+//	    log.Printf("%s: %d messages, %d bytes", filter, s.Messages, s.Bytes)
+//	}
+func WithTopicStats() Option {
+	return func(o *clientOptions) {
+		o.TopicStats = true
+	}
+}
+
+// WithManualAck makes the application responsible for acknowledging QoS 1
+// and QoS 2 messages: the PUBACK (QoS 1) or PUBREC (QoS 2) is only sent
+// when a handler calls Message.Ack, instead of as soon as the message is
+// dispatched.
+//
+// Use it for at-least-once processing, where a message must not be
+// acknowledged before it has been durably handled (e.g. written to a
+// database). If the process crashes before Ack, the server still has the
+// message and redelivers it on the next connection; the client never
+// redelivers messages itself. Redelivery requires the session to survive,
+// as described in WithShutdownAckTimeout.
+//
+// Unacknowledged messages count against ReceiveMaximum: once
+// WithReceiveMaximum messages (65535 by default) are waiting for Ack, the
+// client stops reading from the connection until one is acknowledged.
+// Forgetting to call Ack therefore stalls delivery of every message,
+// including QoS 0 ones and acknowledgments of the client's own publishes.
+//
+// Ack may be called from any goroutine, after the handler has returned.
+// If a message matches several subscriptions, the first Ack from any of
+// their handlers acknowledges it. Messages with no matching handler, and
+// QoS 0 messages, need no Ack. Acks made after Disconnect are not sent.
+//
+// Example:
+//
+//	client, _ := mq.Dial(uri,
+//	    mq.WithCleanSession(false),
+//	    mq.WithSessionExpiryInterval(3600),
+//	    mq.WithManualAck(),
+//	    mq.WithReceiveMaximum(100, mq.LimitPolicyBackpressure))
+//
+//	client.Subscribe("orders/#", mq.AtLeastOnce, func(c *mq.Client, msg mq.Message) {
+//	    if err := db.Insert(msg.Payload); err != nil {
+//	        return // not acked: redelivered after a reconnect
+//	    }
+//	    msg.Ack()
+//	})
+func WithManualAck() Option {
+	return func(o *clientOptions) {
+		o.ManualAck = true
+	}
+}
+
+// WithShutdownAckTimeout makes Disconnect wait up to d for in-progress
+// handlers to finish, so that their acknowledgments reach the server before
+// the DISCONNECT.
+//
+// This applies to QoS 1 and QoS 2 messages whose PUBACK/PUBREC is deferred
+// until their handlers return (see LimitPolicyBackpressure). Messages whose
+// handlers are still running when the timeout expires (or ctx is done) are
+// never acknowledged, even if the handler returns later: the server keeps
+// them and redelivers them on the next connection, so a message that was
+// only partly processed is neither lost nor falsely acknowledged. Handlers
+// must therefore be idempotent, or deduplicate redelivered messages.
+//
+// Redelivery requires the session to survive the disconnect: use
+// WithCleanSession(false) and, on MQTT v5.0, a non-zero
+// WithSessionExpiryInterval long enough to cover the downtime. With a clean
+// session, or once the session expires, the server discards unacknowledged
+// messages and they are lost.
+//
+// Default is 0: Disconnect does not wait, and still withholds the
+// acknowledgments of messages whose handlers have not returned.
+//
+// Example:
+//
+//	client, _ := mq.Dial(uri,
+//	    mq.WithCleanSession(false),
+//	    mq.WithSessionExpiryInterval(3600),
+//	    mq.WithReceiveMaximum(10, mq.LimitPolicyBackpressure),
+//	    mq.WithShutdownAckTimeout(5*time.Second))
+func WithShutdownAckTimeout(d time.Duration) Option {
+	return func(o *clientOptions) {
+		if d >= 0 {
+			o.ShutdownAckTimeout = d
+		}
+	}
+}
+
+// WithConnectionQualityWeights sets the relative weight of each factor in
+// the ConnectionQuality score. Weights are normalized by their sum; set a
+// weight to 0 to ignore that factor. Negative weights, or all weights 0,
+// are ignored and the defaults (DefaultConnectionQualityWeights) are kept.
+//
+// Example:
+//
+//	// Latency-sensitive application: latency dominates the score
+//	mq.WithConnectionQualityWeights(mq.ConnectionQualityWeights{
+//	    Stability:   1,
+//	    Latency:     3,
+//	    PingSuccess: 1,
+//	})
+func WithConnectionQualityWeights(weights ConnectionQualityWeights) Option {
+	return func(o *clientOptions) {
+		if weights.Stability < 0 || weights.Latency < 0 || weights.PingSuccess < 0 {
+			return
+		}
+		if weights.Stability+weights.Latency+weights.PingSuccess > 0 {
+			o.QualityWeights = weights
+		}
+	}
+}
+
+// WithMetrics sets a Metrics implementation that observes the payload size
+// of every message published and received, so a histogram can track the
+// size distribution (p50/p99) of the actual traffic.
+//
+// This is useful for capacity planning and for checking whether the buffer
+// sizes and packet limits (WithMaxPayloadSize, WithMaxIncomingPacket) suit
+// the messages being exchanged. Totals are available from GetStats.
+//
+// Example:
+//
+//	client, err := mq.Dial("tcp://localhost:1883",
+//	    mq.WithMetrics(&promMetrics{
+//	        published: prometheus.NewHistogram(prometheus.HistogramOpts{
+//	            Name:    "mqtt_publish_payload_bytes",
+//	            Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+//	        }),
+//	        received: prometheus.NewHistogram(prometheus.HistogramOpts{
+//	            Name:    "mqtt_receive_payload_bytes",
+//	            Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+//	        }),
+//	    }))
+func WithMetrics(m Metrics) Option {
+	return func(o *clientOptions) {
+		o.Metrics = m
+	}
+}
+
+// WithConnectValidator sets a function that inspects the server's advertised
+// capabilities and CONNACK properties before the connection goes live.
+//
+// The validator is called after the CONNACK has been processed but before the
+// client is marked as connected, session state is restored, or OnConnect runs.
+// If it returns an error, the client sends a normal DISCONNECT, closes the
+// connection, and Dial/DialContext returns that error unchanged. During
+// automatic reconnection a rejected connection is treated like any other
+// failed attempt and retried with backoff.
+//
+// The Properties argument is never nil. For MQTT v3.1.1 connections the
+// capabilities reflect protocol defaults and the properties are empty.
+//
+// Example (require QoS 2 and shared subscriptions):
+//
+//	client, err := mq.Dial("tcp://localhost:1883",
+//	    mq.WithConnectValidator(func(caps mq.ServerCapabilities, _ *mq.Properties) error {
+//	        if caps.MaximumQoS < 2 {
+//	            return errors.New("broker must support QoS 2")
+//	        }
+//	        if !caps.SharedSubscriptionAvailable {
+//	            return errors.New("broker must support shared subscriptions")
+//	        }
+//	        return nil
+//	    }))
+func WithConnectValidator(validator func(ServerCapabilities, *Properties) error) Option {
+	return func(o *clientOptions) {
+		o.ConnectValidator = validator
+	}
+}
+
+// WithMetadata attaches a value to the client at Dial time, as if
+// SetMetadata had been called before the connection was established.
+// This makes the value available to handlers and callbacks (such as
+// OnConnect) from the very first invocation.
+//
+// The option can be given multiple times with different keys.
+//
+// Example:
+//
+//	type deviceKey struct{}
+//
+//	client, _ := mq.Dial("tcp://localhost:1883",
+//	    mq.WithMetadata(deviceKey{}, device),
+//	    mq.WithOnConnect(func(c *mq.Client) {
+//	        log.Printf("device %s online", c.Metadata(deviceKey{}).(*Device).Name)
+//	    }))
+func WithMetadata(key, value any) Option {
+	return func(o *clientOptions) {
+		if o.Metadata == nil {
+			o.Metadata = make(map[any]any)
+		}
+		o.Metadata[key] = value
+	}
+}
+
 // WithOnServerRedirect sets the handler to be called when the server provides
 // a redirection reference (MQTT v5.0 only).
 //
@@ -646,12 +1718,13 @@ func WithOnConnectionLost(onConnectionLost func(*Client, error)) Option {
 //   - Geographic routing: Direct clients to nearest server
 //   - Failover: Redirect to backup server
 //
-// The handler receives the server URI as provided by the server. The client
-// does NOT automatically redirect - the handler should decide whether to accept
-// the redirect and manually reconnect if desired.
+// The handler receives the server URI as provided by the server. Unless
+// WithAutoServerRedirect is enabled, the client does NOT automatically
+// redirect - the handler should decide whether to accept the redirect and
+// manually reconnect if desired.
 //
 // The handler is invoked asynchronously in a separate goroutine to prevent
-// blocking the processing of the CONNACK packet.
+// blocking the processing of the CONNACK or DISCONNECT packet.
 //
 // The server reference is also available via the ServerReference() method for
 // polling/checking later.
@@ -672,6 +1745,40 @@ func WithOnServerRedirect(onServerRedirect func(serverURI string)) Option {
 	}
 }
 
+// WithAutoServerRedirect makes the client follow server redirects (MQTT v5.0)
+// instead of only reporting them through WithOnServerRedirect.
+//
+// When the server refuses a connection, or ends one with DISCONNECT, with
+// reason code 0x9C (Use another server) or 0x9D (Server moved) and a Server
+// Reference, the client connects to the referenced server at once, without
+// waiting for the reconnect backoff. This applies to Dial as well as to
+// automatic reconnection.
+//
+// The reference may be a full URI ("tls://b.example.com:8883") or only a
+// host, optionally with a port ("b.example.com:1884"), in which case the
+// scheme of the current server is kept. If it lists several servers,
+// separated by spaces, the first one is used.
+//
+// A "Use another server" redirect is temporary: once that connection is
+// lost, the client goes back to the original server given to Dial. A
+// "Server moved" redirect is followed until the next redirect. After
+// more than 3 consecutive redirects without an established connection, the
+// client ignores the last one and returns to the original server, so
+// misconfigured servers cannot redirect it in a loop.
+//
+// Example:
+//
+//	client, err := mq.Dial("tcp://mqtt.example.com:1883",
+//	    mq.WithAutoServerRedirect(true),
+//	    mq.WithOnServerRedirect(func(server string) {
+//	        log.Printf("redirected to %s", server)
+//	    }))
+func WithAutoServerRedirect(enabled bool) Option {
+	return func(o *clientOptions) {
+		o.AutoServerRedirect = enabled
+	}
+}
+
 // WithAuthenticator sets the authenticator for enhanced authentication (MQTT v5.0).
 //
 // Enhanced authentication allows challenge/response authentication mechanisms
@@ -702,6 +1809,10 @@ func WithAuthenticator(auth Authenticator) Option {
 type DisconnectOptions struct {
 	ReasonCode ReasonCode
 	Properties *Properties
+
+	// DrainTimeout bounds the wait for outstanding publishes before the
+	// DISCONNECT is sent (WithDrain); 0 disconnects immediately.
+	DrainTimeout time.Duration
 }
 
 // DisconnectOption is a functional option for configuring a disconnection.
@@ -733,6 +1844,28 @@ func WithDisconnectProperties(props *Properties) DisconnectOption {
 	}
 }
 
+// WithDrain makes Disconnect wait, before sending DISCONNECT, until the
+// outgoing publishes have settled: the messages held back by the server's
+// Receive Maximum (or WithMaxInflightBytes) have been sent and every QoS 1/2
+// publish, subscribe and unsubscribe has been acknowledged.
+//
+// The wait ends after timeout even if operations are still outstanding,
+// and ends early if the Disconnect context is done, the connection is lost
+// or the client stops; Disconnect then proceeds as without WithDrain.
+// Persisted QoS 1/2 messages that were not acknowledged are resent by the
+// next session, as usual.
+//
+// Example (flushing telemetry on shutdown):
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//	client.Disconnect(ctx, mq.WithDrain(5*time.Second))
+func WithDrain(timeout time.Duration) DisconnectOption {
+	return func(o *DisconnectOptions) {
+		o.DrainTimeout = timeout
+	}
+}
+
 // WithSubscription defines a subscription that the client should maintain.
 //
 // This serves two purposes:
@@ -780,6 +1913,57 @@ func WithSessionStore(store SessionStore) Option {
 	}
 }
 
+// WithOnRetransmit sets a handler called whenever the client sends a packet
+// again: a PUBLISH (with the DUP flag set), PUBREL, SUBSCRIBE or UNSUBSCRIBE
+// that was not acknowledged within the retry interval (WithRetryInterval),
+// or that is resent after a reconnect. attempt counts the retransmissions
+// of that packet, starting at 1; packetType is the MQTT packet name, such as
+// "PUBLISH".
+//
+// Retransmitted QoS 1 publishes may be delivered twice, so this helps tell
+// whether duplicates seen downstream come from the client on a flaky link.
+// The total is also reported as ClientStats.Retransmissions.
+//
+// The handler is invoked asynchronously in a separate goroutine, so it does
+// not delay the client.
+//
+// Example:
+//
+//	mq.WithOnRetransmit(func(id uint16, packetType string, attempt int) {
+//	    slog.Warn("retransmitting", "packet_id", id, "type", packetType, "attempt", attempt)
+//	})
+func WithOnRetransmit(handler func(packetID uint16, packetType string, attempt int)) Option {
+	return func(o *clientOptions) {
+		o.OnRetransmit = handler
+	}
+}
+
+// WithPacketIDRange restricts the packet identifiers of outgoing PUBLISH,
+// SUBSCRIBE and UNSUBSCRIBE packets to min..max (default: 1-65535). IDs are
+// still assigned in increasing order and wrap around within the range.
+//
+// This lets a gateway multiplexing several logical sessions over one
+// connection give each a disjoint part of the ID space, and makes IDs
+// predictable in tests. The range also bounds the number of packets in
+// flight: with every ID pending, new QoS 1/2 publishes wait for one to be
+// released, like when the server's Receive Maximum is reached, and
+// subscribes and unsubscribes fail with ErrPacketIDsExhausted. Invalid
+// ranges (min of 0 or max below min) are ignored.
+//
+// Example:
+//
+//	// Second of four sessions sharing the connection
+//	client, err := mq.Dial("tcp://broker:1883",
+//	    mq.WithPacketIDRange(16385, 32768))
+func WithPacketIDRange(min, max uint16) Option {
+	return func(o *clientOptions) {
+		if min == 0 || max < min {
+			return
+		}
+		o.PacketIDs = packetIDRange{min: min, max: max}
+	}
+}
+
 // WithOutgoingQueueSize sets the size of the internal outgoing packet buffer (default: 1000).
 func WithOutgoingQueueSize(size int) Option {
 	return func(o *clientOptions) {
@@ -798,6 +1982,134 @@ func WithIncomingQueueSize(size int) Option {
 	}
 }
 
+// IncomingBackpressureStrategy determines what the network reader does when
+// the incoming packet queue (WithIncomingQueueSize) is full because message
+// handlers cannot keep up.
+type IncomingBackpressureStrategy int
+
+const (
+	// IncomingBackpressureBlock stops reading from the network until the
+	// queue has room (default). Nothing is lost, but while the reader is
+	// blocked PINGRESPs are not read either, so a handler that stays slow for
+	// longer than 1.5x the keepalive interval causes a keepalive timeout and a
+	// reconnect.
+	IncomingBackpressureBlock IncomingBackpressureStrategy = iota
+
+	// IncomingBackpressureSpillQoS0 keeps reading when the queue is full.
+	// Overflowing QoS 0 messages are passed to the handler registered with
+	// WithIncomingSpillHandler (or dropped if none is set), and PINGRESPs are
+	// always processed directly, so keepalive keeps working. QoS 1 and QoS 2
+	// messages and acknowledgments still wait for room in the queue.
+	IncomingBackpressureSpillQoS0
+
+	// IncomingBackpressureShed behaves like IncomingBackpressureSpillQoS0,
+	// and additionally sheds overflowing QoS 1 and QoS 2 messages without
+	// acknowledging them. The server keeps them in flight and redelivers them
+	// (with the DUP flag) on the next connection; with MQTT v5.0 they also
+	// count against the ReceiveMaximum, which throttles the server. Use this
+	// when staying connected matters more than timely redelivery.
+	IncomingBackpressureShed
+
+	// IncomingBackpressureDropNewest keeps reading when the queue is full
+	// and discards the QoS 0 message that does not fit. PINGRESPs are always
+	// processed directly, and QoS 1 and QoS 2 messages and acknowledgments
+	// still wait for room in the queue: they are never dropped silently.
+	IncomingBackpressureDropNewest
+
+	// IncomingBackpressureDropOldest keeps the newest QoS 0 messages:
+	// messages that do not fit in the queue wait in an overflow buffer of
+	// the same size, and when that is full, the oldest QoS 0 message waiting
+	// there is discarded to make room. QoS 1 and QoS 2 messages and
+	// acknowledgments wait for room instead, and PINGRESPs are processed
+	// directly. Use it when only recent values matter, e.g. for sensor
+	// readings. The buffer is emptied when the connection is lost.
+	IncomingBackpressureDropOldest
+)
+
+// WithIncomingBackpressureStrategy sets what happens when message handlers
+// cannot keep up and the incoming queue fills (default:
+// IncomingBackpressureBlock).
+//
+// The non-blocking strategies keep the network reader running so a slow
+// consumer does not trigger a self-inflicted keepalive disconnect. QoS 0
+// messages discarded by IncomingBackpressureDropNewest and
+// IncomingBackpressureDropOldest are counted in ClientStats.IncomingDropped.
+//
+// Example:
+//
+//	client, _ := mq.Dial(uri,
+//	    mq.WithIncomingBackpressureStrategy(mq.IncomingBackpressureSpillQoS0),
+//	    mq.WithIncomingSpillHandler(func(_ *mq.Client, msg mq.Message) {
+//	        spilled.Add(1)
+//	    }))
+func WithIncomingBackpressureStrategy(strategy IncomingBackpressureStrategy) Option {
+	return func(o *clientOptions) {
+		o.IncomingStrategy = strategy
+	}
+}
+
+// IncomingOverflowPolicy determines what happens to incoming QoS 0 messages
+// when the incoming packet queue (WithIncomingQueueSize) is full.
+type IncomingOverflowPolicy int
+
+const (
+	// IncomingOverflowBlock stops reading from the network until the queue
+	// has room (default), like IncomingBackpressureBlock.
+	IncomingOverflowBlock IncomingOverflowPolicy = iota
+
+	// IncomingOverflowDropNewest discards the QoS 0 message that does not
+	// fit in the queue, like IncomingBackpressureDropNewest.
+	IncomingOverflowDropNewest
+
+	// IncomingOverflowDropOldest keeps the newest QoS 0 messages, like
+	// IncomingBackpressureDropOldest. Use it when only recent values matter,
+	// e.g. for sensor readings.
+	IncomingOverflowDropOldest
+)
+
+// WithIncomingOverflowPolicy sets which QoS 0 messages are discarded, instead
+// of blocking the network reader, when message handlers cannot keep up and
+// the incoming queue fills (default: IncomingOverflowBlock).
+//
+// Only QoS 0 messages are ever discarded. QoS 1 and QoS 2 messages and
+// acknowledgments are never dropped silently: they wait for room in the
+// queue, as with IncomingOverflowBlock. PINGRESPs are processed directly, so
+// the QoS 0 firehose cannot cause a keepalive timeout.
+//
+// Discarded messages are counted in ClientStats.IncomingDropped.
+//
+// The policy selects the matching IncomingBackpressureStrategy, so it
+// replaces any strategy set with WithIncomingBackpressureStrategy, and the
+// other way around: the last option wins.
+//
+// Example:
+//
+//	client, _ := mq.Dial(uri,
+//	    mq.WithIncomingOverflowPolicy(mq.IncomingOverflowDropOldest))
+func WithIncomingOverflowPolicy(policy IncomingOverflowPolicy) Option {
+	return func(o *clientOptions) {
+		switch policy {
+		case IncomingOverflowDropNewest:
+			o.IncomingStrategy = IncomingBackpressureDropNewest
+		case IncomingOverflowDropOldest:
+			o.IncomingStrategy = IncomingBackpressureDropOldest
+		default:
+			o.IncomingStrategy = IncomingBackpressureBlock
+		}
+	}
+}
+
+// WithIncomingSpillHandler sets the handler that receives QoS 0 messages
+// spilled by IncomingBackpressureSpillQoS0 or IncomingBackpressureShed.
+//
+// The handler runs synchronously on the network reader goroutine, so it must
+// return quickly (e.g. count the message, or copy it to a bounded buffer).
+func WithIncomingSpillHandler(handler MessageHandler) Option {
+	return func(o *clientOptions) {
+		o.OnIncomingSpill = handler
+	}
+}
+
 // WithQoS0LimitPolicy sets the policy for handling QoS 0 messages when the buffer is full.
 //
 // The default policy is QoS0LimitPolicyDrop, which ensures the client remains non-blocking
@@ -808,6 +2120,94 @@ func WithQoS0LimitPolicy(policy QoS0LimitPolicy) Option {
 	}
 }
 
+// SubscribeWhileDisconnectedPolicy determines what Subscribe does when it is
+// called while the client is disconnected (e.g. while automatic reconnection
+// is in progress).
+type SubscribeWhileDisconnectedPolicy int
+
+const (
+	// SubscribeWhileDisconnectedFail completes the token immediately with an
+	// error wrapping ErrClientDisconnected. Nothing is registered, so the
+	// caller can retry once connected (e.g. from OnConnect).
+	SubscribeWhileDisconnectedFail SubscribeWhileDisconnectedPolicy = iota
+
+	// SubscribeWhileDisconnectedQueue registers the subscription (and its
+	// handler) locally right away. It is sent with the other subscriptions
+	// when the client reconnects, and the token completes when its SUBACK
+	// arrives. Requires AutoReconnect; otherwise it behaves like
+	// SubscribeWhileDisconnectedFail.
+	SubscribeWhileDisconnectedQueue
+)
+
+// WithSubscribeWhileDisconnected sets what Subscribe does while the client is
+// disconnected.
+//
+// The default, SubscribeWhileDisconnectedFail, fails fast: the caller learns
+// right away that the subscription was not made. SubscribeWhileDisconnectedQueue
+// is convenient for fire-and-configure code that subscribes whenever it likes
+// and relies on automatic reconnection: the token simply completes later, once
+// the server has acknowledged the subscription on the new connection. Wait on
+// it with a context deadline if the outage may be long.
+//
+// Example:
+//
+//	client, _ := mq.Dial(uri,
+//	    mq.WithSubscribeWhileDisconnected(mq.SubscribeWhileDisconnectedQueue))
+//
+//	// Safe to call at any time; completes after the next SUBACK.
+//	tok := client.Subscribe("alerts/#", mq.AtLeastOnce, handleAlert)
+func WithSubscribeWhileDisconnected(policy SubscribeWhileDisconnectedPolicy) Option {
+	return func(o *clientOptions) {
+		o.SubscribeWhileDisconnected = policy
+	}
+}
+
+// QoS0TokenBehavior determines when the token returned by a QoS 0 Publish
+// completes. QoS 0 messages are never acknowledged by the server, so the token
+// can only report a local milestone.
+type QoS0TokenBehavior int
+
+const (
+	// QoS0TokenCompleteOnEnqueue completes the token as soon as the message
+	// has been placed in the outgoing queue (or dropped, see QoS0LimitPolicy).
+	// This is the default and is the cheapest option.
+	QoS0TokenCompleteOnEnqueue QoS0TokenBehavior = iota
+
+	// QoS0TokenCompleteOnWrite completes the token once the message has been
+	// written and flushed to the network connection, i.e. once it has left
+	// the process. If the connection fails first, the token completes with
+	// ErrClientDisconnected.
+	//
+	// A message still queued while the client is reconnecting is written on
+	// the next connection; use Wait with a context to bound how long to wait.
+	QoS0TokenCompleteOnWrite
+
+	// QoS0TokenNeverComplete does not track the message at all: the token is
+	// returned already completed, so Wait returns nil immediately and Dropped
+	// always reports false. Validation errors (e.g. an invalid topic) are
+	// still reported. Use it for fire-and-forget producers that never inspect
+	// QoS 0 tokens.
+	QoS0TokenNeverComplete
+)
+
+// WithQoS0TokenBehavior sets when the token of a QoS 0 publish completes.
+//
+// The default is QoS0TokenCompleteOnEnqueue. QoS0TokenCompleteOnWrite gives
+// producers a meaningful milestone to synchronize on (the bytes were handed
+// to the operating system), at the cost of a small amount of bookkeeping in
+// the write path.
+//
+// Example:
+//
+//	client, _ := mq.Dial(uri, mq.WithQoS0TokenBehavior(mq.QoS0TokenCompleteOnWrite))
+//	tok := client.Publish("metrics/cpu", data)
+//	_ = tok.Wait(ctx) // returns once the PUBLISH has been written to the socket
+func WithQoS0TokenBehavior(behavior QoS0TokenBehavior) Option {
+	return func(o *clientOptions) {
+		o.QoS0TokenBehavior = behavior
+	}
+}
+
 // WithHandlerInterceptor adds an interceptor to the incoming message handler chain.
 // Interceptors are called in the order they are added.
 //
@@ -846,6 +2246,39 @@ func WithPublishInterceptor(interceptor PublishInterceptor) Option {
 	}
 }
 
+// WithOutgoingPublishTransform adds a transform applied to every outgoing
+// publish. Transforms run in the order they are added.
+//
+// Ordering within a Publish call:
+//
+//  1. Publish interceptors (WithPublishInterceptor) wrap the call
+//  2. Publish options (WithQoS, WithRetain, ...) are applied
+//  3. Transforms run on the resulting PublishView
+//  4. Topic, payload size and payload format are validated
+//  5. A topic alias is assigned (if WithAlias was used)
+//  6. The packet ID is assigned and the packet is enqueued
+//
+// Because validation follows the transforms, a transform that produces an
+// invalid topic or payload fails the publish with the usual error. Messages
+// already persisted in a session store are resent as stored, without being
+// transformed again.
+//
+// Example:
+//
+//	// Enforce a tenant prefix on every topic
+//	mq.WithOutgoingPublishTransform(func(p *mq.PublishView) {
+//	    if !strings.HasPrefix(p.Topic, "tenant-a/") {
+//	        p.Topic = "tenant-a/" + p.Topic
+//	    }
+//	})
+func WithOutgoingPublishTransform(transform OutgoingPublishTransform) Option {
+	return func(o *clientOptions) {
+		if transform != nil {
+			o.PublishTransforms = append(o.PublishTransforms, transform)
+		}
+	}
+}
+
 // defaultOptions returns the default client options.
 func defaultOptions(server string) *clientOptions {
 	return &clientOptions{
@@ -857,10 +2290,16 @@ func defaultOptions(server string) *clientOptions {
 		AutoProtocolVersion: true,
 		AutoReconnect:       true,
 		ConnectTimeout:      30 * time.Second,
-		OutgoingQueueSize:   1000,
-		IncomingQueueSize:   100,
-		QoS0Policy:          QoS0LimitPolicyDrop,
-		Logger:              slog.New(slog.NewTextHandler(io.Discard, nil)),
+		RetryInterval:       defaultRetryInterval,
+
+		ReconnectBackoffInitial:    time.Second,
+		ReconnectBackoffMax:        2 * time.Minute,
+		ReconnectBackoffMultiplier: 2,
+
+		OutgoingQueueSize: 1000,
+		IncomingQueueSize: 100,
+		QoS0Policy:        QoS0LimitPolicyDrop,
+		Logger:            slog.New(slog.NewTextHandler(io.Discard, nil)),
 
 		// Use MQTT spec defaults (0 = use defaults in validation functions)
 		MaxTopicLength:    0,
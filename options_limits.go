@@ -42,6 +42,38 @@ func WithMaxHandlerConcurrency(concurrency int) Option {
 	}
 }
 
+// WithHandlerConcurrency runs message handlers on a fixed pool of n worker
+// goroutines instead of starting a goroutine for every handler invocation.
+// Under a burst of messages with slow handlers, this bounds the number of
+// goroutines (and their memory) to n, however large the burst.
+//
+// Messages are handed to the workers in the order they arrive, and each
+// worker runs one handler at a time. Ordering is therefore only preserved
+// within a single worker: with n > 1, handlers for consecutive messages may
+// run in parallel and finish in any order. Use WithOrdered (or
+// WithOrderedDelivery) for subscriptions that need in-order processing;
+// ordered handlers keep their own queues and do not use the pool.
+//
+// When every worker is busy and n more invocations are queued, the internal
+// processing loop blocks until a worker is free, as with
+// WithMaxHandlerConcurrency, which the pool replaces when both are set.
+//
+// The default of 0 keeps one goroutine per invocation; negative values are
+// ignored.
+//
+// Example:
+//
+//	client, err := mq.Dial("tcp://broker:1883",
+//	    mq.WithHandlerConcurrency(runtime.NumCPU()))
+func WithHandlerConcurrency(n int) Option {
+	return func(o *clientOptions) {
+		if n < 0 {
+			return
+		}
+		o.HandlerConcurrency = n
+	}
+}
+
 // WithMaxAuthExchanges limits the number of AUTH packet exchanges per connection.
 // This prevents infinite authentication loops with a malicious or misconfigured server.
 // Default is 10.
@@ -50,3 +82,42 @@ func WithMaxAuthExchanges(limit uint16) Option {
 		o.MaxAuthExchanges = limit
 	}
 }
+
+// WithMaxSubscriptions limits the number of simultaneously active subscriptions.
+//
+// Subscribing to a new topic filter while n filters are already active fails
+// locally with ErrSubscriptionLimitExceeded, without contacting the server.
+// Re-subscribing to an existing filter does not count against the limit, and
+// Unsubscribe frees a slot immediately.
+//
+// This gives predictable client-side quota enforcement instead of relying on
+// the server's quota (reason code 0x97, which may also disconnect the client),
+// and helps catch subscription leaks early.
+//
+// Default is 0 (unlimited).
+func WithMaxSubscriptions(n int) Option {
+	return func(o *clientOptions) {
+		o.MaxSubscriptions = n
+	}
+}
+
+// WithMaxInflightBytes limits the total payload bytes of outstanding QoS 1
+// and QoS 2 publishes: those waiting for acknowledgment plus those waiting in
+// the publish queue for flow-control capacity (see ServerCapabilities
+// ReceiveMaximum).
+//
+// When a new publish would exceed the limit, Publish blocks until enough
+// acknowledgments free capacity (or the client is disconnected, in which case
+// the token completes with ErrClientDisconnected). A single message larger
+// than the limit is still accepted when nothing else is outstanding, so large
+// messages cannot deadlock the client.
+//
+// This complements the message-count limits: a few large messages can use a
+// lot of memory even when the count is low. QoS 0 messages are not counted.
+//
+// Default is 0 (unlimited).
+func WithMaxInflightBytes(n int) Option {
+	return func(o *clientOptions) {
+		o.MaxInflightBytes = n
+	}
+}
@@ -0,0 +1,62 @@
+package mq
+
+import "sync"
+
+// defaultHandlerQueue is the ordered queue key used for the default handler.
+// It cannot collide with a subscription, as topic filters are never empty.
+const defaultHandlerQueue = ""
+
+// orderedQueue runs functions one at a time, in the order they were pushed.
+// Pushing never blocks: the queue grows as needed, and a goroutine drains it
+// only while it is not empty.
+type orderedQueue struct {
+	mu      sync.Mutex
+	items   []func()
+	running bool
+}
+
+// push queues fn, starting the drain goroutine if it is not running.
+func (q *orderedQueue) push(fn func()) {
+	q.mu.Lock()
+	q.items = append(q.items, fn)
+	if q.running {
+		q.mu.Unlock()
+		return
+	}
+	q.running = true
+	q.mu.Unlock()
+
+	go q.run()
+}
+
+func (q *orderedQueue) run() {
+	for {
+		q.mu.Lock()
+		if len(q.items) == 0 {
+			q.running = false
+			q.items = nil // Release the backing array after a burst
+			q.mu.Unlock()
+			return
+		}
+		fn := q.items[0]
+		q.items[0] = nil
+		q.items = q.items[1:]
+		q.mu.Unlock()
+
+		fn()
+	}
+}
+
+// orderedQueue returns the delivery queue of a subscription, creating it if
+// needed. Must be called with sessionLock held.
+func (c *Client) orderedQueue(filter string) *orderedQueue {
+	q, ok := c.orderedQueues[filter]
+	if !ok {
+		if c.orderedQueues == nil {
+			c.orderedQueues = make(map[string]*orderedQueue)
+		}
+		q = &orderedQueue{}
+		c.orderedQueues[filter] = q
+	}
+	return q
+}
@@ -0,0 +1,244 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestOrderedDelivery_Sequential(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	WithOrderedDelivery()(opts)
+	c := newTestClient(opts)
+
+	const n = 200
+	var (
+		mu      sync.Mutex
+		got     []int
+		active  atomic.Int32
+		overlap atomic.Bool
+		done    = make(chan struct{})
+	)
+	c.subscriptions["seq/+"] = subscriptionEntry{
+		handler: func(_ *Client, msg Message) {
+			if active.Add(1) > 1 {
+				overlap.Store(true)
+			}
+			i, _ := strconv.Atoi(string(msg.Payload))
+			if i%3 == 0 {
+				time.Sleep(100 * time.Microsecond) // Uneven handler durations
+			}
+			active.Add(-1)
+
+			mu.Lock()
+			got = append(got, i)
+			if len(got) == n {
+				close(done)
+			}
+			mu.Unlock()
+		},
+	}
+
+	for i := range n {
+		c.sessionLock.Lock()
+		c.handleIncoming(&packets.PublishPacket{Topic: "seq/a", Payload: []byte(strconv.Itoa(i))})
+		c.sessionLock.Unlock()
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for handlers")
+	}
+
+	if overlap.Load() {
+		t.Error("handler was called concurrently with itself")
+	}
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("message %d delivered at position %d: %v", v, i, got)
+		}
+	}
+}
+
+func TestOrderedDelivery_PerSubscription(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	c := newTestClient(opts)
+	c.connected.Store(true)
+
+	const n = 200
+	var (
+		mu        sync.Mutex
+		got       []int
+		active    atomic.Int32
+		overlap   atomic.Bool
+		done      = make(chan struct{})
+		unordered sync.WaitGroup
+	)
+	c.Subscribe("seq/+", AtMostOnce, func(_ *Client, msg Message) {
+		if active.Add(1) > 1 {
+			overlap.Store(true)
+		}
+		i, _ := strconv.Atoi(string(msg.Payload))
+		if i%3 == 0 {
+			time.Sleep(100 * time.Microsecond) // Uneven handler durations
+		}
+		active.Add(-1)
+
+		mu.Lock()
+		got = append(got, i)
+		if len(got) == n {
+			close(done)
+		}
+		mu.Unlock()
+	}, WithOrdered())
+	c.Subscribe("seq/#", AtMostOnce, func(*Client, Message) { unordered.Done() })
+	for len(c.outgoing) > 0 {
+		<-c.outgoing
+	}
+
+	if !c.subscriptions["seq/+"].options.Ordered || c.subscriptions["seq/#"].options.Ordered {
+		t.Fatal("expected only seq/+ to use ordered delivery")
+	}
+
+	unordered.Add(n)
+	for i := range n {
+		c.sessionLock.Lock()
+		c.handleIncoming(&packets.PublishPacket{Topic: "seq/a", Payload: []byte(strconv.Itoa(i))})
+		c.sessionLock.Unlock()
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for handlers")
+	}
+	unordered.Wait()
+
+	if overlap.Load() {
+		t.Error("ordered handler was called concurrently with itself")
+	}
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("message %d delivered at position %d: %v", v, i, got)
+		}
+	}
+	if _, ok := c.orderedQueues["seq/#"]; ok {
+		t.Error("expected no queue for the unordered subscription")
+	}
+}
+
+func TestOrderedDelivery_AcrossReconnect(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping reconnect test in short mode")
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	const perConn = 40
+	stopServer := make(chan struct{})
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- func() error {
+			for i := range 2 {
+				conn, err := ln.Accept()
+				if err != nil {
+					return err
+				}
+				if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+					return err
+				}
+				connack := &packets.ConnackPacket{ReturnCode: 0, SessionPresent: i > 0}
+				if _, err := connack.WriteTo(conn); err != nil {
+					return err
+				}
+				for j := range perConn {
+					seq := i*perConn + j
+					pub := &packets.PublishPacket{
+						Topic:    "machine/1/events",
+						QoS:      1,
+						PacketID: uint16(seq + 1),
+						Payload:  []byte(strconv.Itoa(seq)),
+						Version:  ProtocolV50,
+					}
+					if _, err := pub.WriteTo(conn); err != nil {
+						return fmt.Errorf("conn %d: %w", i, err)
+					}
+				}
+				if i == 0 {
+					// Drop the connection while the handler is still busy
+					// with the first batch
+					time.Sleep(50 * time.Millisecond)
+					conn.Close()
+					continue
+				}
+				<-stopServer
+				conn.Close()
+			}
+			return nil
+		}()
+	}()
+
+	var (
+		mu   sync.Mutex
+		got  []int
+		done = make(chan struct{})
+	)
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("ordered-reconnect"),
+		WithCleanSession(false),
+		WithSessionExpiryInterval(3600),
+		WithOrderedDelivery(),
+		WithSubscription("machine/+/events", func(_ *Client, msg Message) {
+			time.Sleep(30 * time.Millisecond) // 40 messages outlast the reconnect backoff
+			i, _ := strconv.Atoi(string(msg.Payload))
+
+			mu.Lock()
+			got = append(got, i)
+			if len(got) == 2*perConn {
+				close(done)
+			}
+			mu.Unlock()
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		mu.Lock()
+		t.Fatalf("timeout, got %d of %d messages", len(got), 2*perConn)
+	}
+	close(stopServer)
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("message %d delivered at position %d: %v", v, i, got)
+		}
+	}
+	if client.reconnectCount.Load() == 0 {
+		t.Error("expected the client to have reconnected")
+	}
+
+	if err := <-serverErr; err != nil {
+		t.Fatal(err)
+	}
+}
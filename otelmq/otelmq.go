@@ -0,0 +1,194 @@
+// Package otelmq provides OpenTelemetry tracing for the mq MQTT client.
+//
+// PublishInterceptor starts a producer span for every outgoing message and
+// injects its context into the message's MQTT v5.0 User Properties, as a
+// W3C Trace Context "traceparent" (and "tracestate") by default.
+// HandlerInterceptor extracts that context from incoming messages and starts
+// a consumer span, a child of the publisher's span, around the handler.
+// Spans are named after the topic and carry the QoS, payload size and
+// retain flag as attributes.
+//
+// The package lives in its own module so that the core mq module stays free
+// of external dependencies.
+//
+// Example:
+//
+//	client, err := mq.Dial("tcp://localhost:1883",
+//	    mq.WithPublishInterceptor(otelmq.PublishInterceptor()),
+//	    mq.WithHandlerInterceptor(otelmq.HandlerInterceptor()),
+//	)
+//
+//	// The span becomes a child of the span in ctx.
+//	client.PublishContext(ctx, "orders/new", payload, mq.WithQoS(mq.AtLeastOnce))
+//
+// User Properties only exist in MQTT v5.0: with v3.1.1 spans are still
+// recorded, but the trace context does not reach the subscriber.
+package otelmq
+
+import (
+	"context"
+
+	"github.com/gonzalop/mq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope name of the tracer.
+const ScopeName = "github.com/gonzalop/mq/otelmq"
+
+// Attribute keys set on every span.
+const (
+	// AttrMessagingSystem is always "mqtt".
+	AttrMessagingSystem = attribute.Key("messaging.system")
+	// AttrDestination is the topic.
+	AttrDestination = attribute.Key("messaging.destination.name")
+	// AttrOperation is "publish" or "process".
+	AttrOperation = attribute.Key("messaging.operation.type")
+	// AttrPayloadSize is the payload size in bytes.
+	AttrPayloadSize = attribute.Key("messaging.message.body.size")
+	// AttrQoS is the QoS level of the message.
+	AttrQoS = attribute.Key("mqtt.qos")
+	// AttrRetained is the retain flag of the message.
+	AttrRetained = attribute.Key("mqtt.retained")
+)
+
+// Option configures the interceptors.
+type Option func(*config)
+
+type config struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+}
+
+// WithTracerProvider sets the TracerProvider used to create spans.
+//
+// Default: the global provider (otel.GetTracerProvider).
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.provider = provider
+	}
+}
+
+// WithPropagator sets the propagator that injects and extracts the trace
+// context in User Properties.
+//
+// Default: propagation.TraceContext (W3C traceparent and tracestate). The
+// global propagator is not used, because its default propagates nothing.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagator = propagator
+	}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{propagator: propagation.TraceContext{}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.provider == nil {
+		c.provider = otel.GetTracerProvider()
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return c.provider.Tracer(ScopeName)
+}
+
+// messageAttributes returns the span attributes of a message.
+func messageAttributes(operation, topic string, payload []byte, qos mq.QoS, retained bool) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		AttrMessagingSystem.String("mqtt"),
+		AttrDestination.String(topic),
+		AttrOperation.String(operation),
+		AttrPayloadSize.Int(len(payload)),
+		AttrQoS.Int(int(qos)),
+		AttrRetained.Bool(retained),
+	}
+}
+
+// PublishInterceptor returns a publish interceptor that starts a producer
+// span named after the topic for every message and injects its context into
+// the message's User Properties.
+//
+// The parent span is taken from the context given to PublishContext (none
+// for Publish). The span ends when the publish token completes: at once for
+// QoS 0, on the server's acknowledgment for QoS 1 and 2. A failed publish
+// records the error on the span.
+//
+// Add it after interceptors that change the topic, QoS or retain flag, so
+// the span describes the message that is sent.
+func PublishInterceptor(opts ...Option) mq.PublishInterceptor {
+	cfg := newConfig(opts)
+	tracer := cfg.tracer()
+
+	return func(next mq.PublishFunc) mq.PublishFunc {
+		return func(topic string, payload []byte, opts ...mq.PublishOption) mq.Token {
+			var pubOpts mq.PublishOptions
+			for _, opt := range opts {
+				opt(&pubOpts)
+			}
+
+			ctx, span := tracer.Start(pubOpts.Context(), topic,
+				trace.WithSpanKind(trace.SpanKindProducer),
+				trace.WithAttributes(messageAttributes("publish", topic, payload, mq.QoS(pubOpts.QoS), pubOpts.Retain)...),
+			)
+
+			carrier := propagation.MapCarrier{}
+			cfg.propagator.Inject(ctx, carrier)
+			opts = opts[:len(opts):len(opts)]
+			for key, value := range carrier {
+				opts = append(opts, mq.WithUserProperty(key, value))
+			}
+
+			tok := next(topic, payload, opts...)
+			select {
+			case <-tok.Done():
+				endPublishSpan(span, tok)
+			default:
+				go func() {
+					<-tok.Done()
+					endPublishSpan(span, tok)
+				}()
+			}
+			return tok
+		}
+	}
+}
+
+func endPublishSpan(span trace.Span, tok mq.Token) {
+	if err := tok.Error(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// HandlerInterceptor returns a handler interceptor that starts a consumer
+// span named after the topic around every handler call. If the message
+// carries a trace context in its User Properties (see PublishInterceptor),
+// the span is a child of the publisher's span.
+func HandlerInterceptor(opts ...Option) mq.HandlerInterceptor {
+	cfg := newConfig(opts)
+	tracer := cfg.tracer()
+
+	return func(next mq.MessageHandler) mq.MessageHandler {
+		return func(client *mq.Client, msg mq.Message) {
+			ctx := context.Background()
+			if msg.Properties != nil {
+				ctx = cfg.propagator.Extract(ctx, propagation.MapCarrier(msg.Properties.UserProperties))
+			}
+
+			_, span := tracer.Start(ctx, msg.Topic,
+				trace.WithSpanKind(trace.SpanKindConsumer),
+				trace.WithAttributes(messageAttributes("process", msg.Topic, msg.Payload, msg.QoS, msg.Retained)...),
+			)
+			defer span.End()
+
+			next(client, msg)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package otelmq
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fakeToken is a Token completed by the test.
+type fakeToken struct {
+	done chan struct{}
+	err  error
+}
+
+func newFakeToken() *fakeToken {
+	return &fakeToken{done: make(chan struct{})}
+}
+
+func (t *fakeToken) complete(err error) {
+	t.err = err
+	close(t.done)
+}
+
+func (t *fakeToken) Wait(ctx context.Context) error {
+	select {
+	case <-t.done:
+		return t.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+func (t *fakeToken) Done() <-chan struct{}     { return t.done }
+func (t *fakeToken) Error() error              { return t.err }
+func (t *fakeToken) ReasonCode() mq.ReasonCode { return mq.ReasonCodeSuccess }
+func (t *fakeToken) Dropped() bool             { return false }
+
+func waitEnded(t *testing.T, recorder *tracetest.SpanRecorder, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(recorder.Ended()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d ended spans, got %d", n, len(recorder.Ended()))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func newRecorder() (*tracetest.SpanRecorder, trace.TracerProvider) {
+	recorder := tracetest.NewSpanRecorder()
+	return recorder, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+}
+
+func attrs(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+// capturePublish returns a PublishFunc that records the applied options and
+// completes with the given token.
+func capturePublish(got *mq.PublishOptions, tok mq.Token) mq.PublishFunc {
+	return func(_ string, _ []byte, opts ...mq.PublishOption) mq.Token {
+		for _, opt := range opts {
+			opt(got)
+		}
+		return tok
+	}
+}
+
+func TestPublishInterceptor(t *testing.T) {
+	recorder, provider := newRecorder()
+
+	var got mq.PublishOptions
+	tok := newFakeToken()
+	publish := PublishInterceptor(WithTracerProvider(provider))(capturePublish(&got, tok))
+
+	// An options slice with spare capacity must not be written to
+	opts := make([]mq.PublishOption, 2, 4)
+	opts[0] = mq.WithQoS(mq.AtLeastOnce)
+	opts[1] = mq.WithRetain(true)
+	publish("orders/new", []byte("12345"), opts...)
+	if extra := opts[:3][2]; extra != nil {
+		t.Error("interceptor wrote into the caller's options slice")
+	}
+
+	traceparent := got.Properties.GetUserProperty("traceparent")
+	if traceparent == "" {
+		t.Fatal("expected a traceparent user property")
+	}
+	if len(recorder.Ended()) != 0 {
+		t.Fatal("span ended before the token completed")
+	}
+
+	tok.complete(nil)
+	waitEnded(t, recorder, 1)
+
+	span := recorder.Ended()[0]
+	if span.Name() != "orders/new" {
+		t.Errorf("span name = %q, want %q", span.Name(), "orders/new")
+	}
+	if span.SpanKind() != trace.SpanKindProducer {
+		t.Errorf("span kind = %v, want producer", span.SpanKind())
+	}
+	if span.Parent().IsValid() {
+		t.Error("expected a root span without a parent context")
+	}
+	if want := "00-" + span.SpanContext().TraceID().String() + "-" + span.SpanContext().SpanID().String() + "-01"; traceparent != want {
+		t.Errorf("traceparent = %q, want %q", traceparent, want)
+	}
+
+	a := attrs(span)
+	if a[AttrQoS].AsInt64() != 1 || !a[AttrRetained].AsBool() || a[AttrPayloadSize].AsInt64() != 5 {
+		t.Errorf("unexpected attributes %v", span.Attributes())
+	}
+	if a[AttrDestination].AsString() != "orders/new" || a[AttrMessagingSystem].AsString() != "mqtt" {
+		t.Errorf("unexpected attributes %v", span.Attributes())
+	}
+}
+
+// pipeDialer returns a dialer whose server side accepts the connection and
+// discards everything the client sends.
+func pipeDialer() mq.DialFunc {
+	return func(context.Context, string, string) (net.Conn, error) {
+		client, server := net.Pipe()
+		go func() { _, _ = io.Copy(io.Discard, server) }()
+		go func() { _, _ = server.Write([]byte{0x20, 0x02, 0x00, 0x00}) }() // CONNACK
+		return client, nil
+	}
+}
+
+func TestPublishInterceptor_ContextParent(t *testing.T) {
+	recorder, provider := newRecorder()
+
+	var traceparent string
+	client, err := mq.Dial("tcp://broker:1883",
+		mq.WithDialer(pipeDialer()),
+		mq.WithAutoReconnect(false),
+		mq.WithPublishInterceptor(PublishInterceptor(WithTracerProvider(provider))),
+		mq.WithPublishInterceptor(func(next mq.PublishFunc) mq.PublishFunc {
+			return func(topic string, payload []byte, opts ...mq.PublishOption) mq.Token {
+				var o mq.PublishOptions
+				for _, opt := range opts {
+					opt(&o)
+				}
+				traceparent = o.Properties.GetUserProperty("traceparent")
+				return next(topic, payload, opts...)
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer client.Disconnect(context.Background())
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	if err := client.PublishContext(ctx, "orders/new", []byte("x")).Wait(context.Background()); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	parent.End()
+	waitEnded(t, recorder, 2)
+
+	span := recorder.Ended()[0]
+	if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("expected the span to be a child of the span in the PublishContext context")
+	}
+	if traceparent == "" || traceparent[3:35] != parent.SpanContext().TraceID().String() {
+		t.Errorf("traceparent %q does not carry the parent's trace ID", traceparent)
+	}
+}
+
+func TestPublishInterceptor_Error(t *testing.T) {
+	recorder, provider := newRecorder()
+
+	tok := newFakeToken()
+	tok.complete(errors.New("not authorized"))
+	var got mq.PublishOptions
+	publish := PublishInterceptor(WithTracerProvider(provider))(capturePublish(&got, tok))
+
+	publish("orders/new", nil)
+
+	// Already completed: the span ends synchronously
+	if len(recorder.Ended()) != 1 {
+		t.Fatalf("expected 1 ended span, got %d", len(recorder.Ended()))
+	}
+	span := recorder.Ended()[0]
+	if span.Status().Code != codes.Error || span.Status().Description != "not authorized" {
+		t.Errorf("status = %+v, want error", span.Status())
+	}
+	if span.Parent().IsValid() {
+		t.Error("expected a root span without a context")
+	}
+}
+
+func TestHandlerInterceptor(t *testing.T) {
+	recorder, provider := newRecorder()
+
+	// Publish side produces the user properties
+	var got mq.PublishOptions
+	tok := newFakeToken()
+	tok.complete(nil)
+	PublishInterceptor(WithTracerProvider(provider))(capturePublish(&got, tok))("sensors/temp", []byte("21.5"))
+
+	called := false
+	handler := HandlerInterceptor(WithTracerProvider(provider))(func(*mq.Client, mq.Message) {
+		called = true
+		if len(recorder.Ended()) != 1 {
+			t.Error("consumer span ended before the handler returned")
+		}
+	})
+	handler(nil, mq.Message{
+		Topic:      "sensors/temp",
+		Payload:    []byte("21.5"),
+		QoS:        mq.AtMostOnce,
+		Properties: got.Properties,
+	})
+	if !called {
+		t.Fatal("handler not called")
+	}
+
+	ended := recorder.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("expected 2 ended spans, got %d", len(ended))
+	}
+	producer, consumer := ended[0], ended[1]
+	if consumer.Name() != "sensors/temp" || consumer.SpanKind() != trace.SpanKindConsumer {
+		t.Errorf("consumer span = %q (%v)", consumer.Name(), consumer.SpanKind())
+	}
+	if consumer.Parent().SpanID() != producer.SpanContext().SpanID() || !consumer.Parent().IsRemote() {
+		t.Error("expected the consumer span to be a remote child of the producer span")
+	}
+	if a := attrs(consumer); a[AttrPayloadSize].AsInt64() != 4 || a[AttrOperation].AsString() != "process" {
+		t.Errorf("unexpected attributes %v", consumer.Attributes())
+	}
+}
+
+func TestHandlerInterceptor_NoTraceContext(t *testing.T) {
+	recorder, provider := newRecorder()
+
+	handler := HandlerInterceptor(WithTracerProvider(provider))(func(*mq.Client, mq.Message) {})
+	handler(nil, mq.Message{Topic: "legacy/v3", Retained: true})
+
+	if len(recorder.Ended()) != 1 {
+		t.Fatalf("expected 1 ended span, got %d", len(recorder.Ended()))
+	}
+	span := recorder.Ended()[0]
+	if span.Parent().IsValid() {
+		t.Error("expected a root span")
+	}
+	if !attrs(span)[AttrRetained].AsBool() {
+		t.Error("expected the retained attribute")
+	}
+}
@@ -0,0 +1,161 @@
+package mq
+
+import (
+	"errors"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+var _ PublishToken = (*token)(nil)
+
+func TestPublishTokenPacketID(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	c := newTestClient(opts)
+	c.serverCaps.MaximumQoS = 2
+	c.serverCaps.ReceiveMaximum = 1
+
+	packetID := func(tok Token) uint16 {
+		t.Helper()
+		pt, ok := tok.(PublishToken)
+		if !ok {
+			t.Fatalf("token %T does not implement PublishToken", tok)
+		}
+		return pt.PacketID()
+	}
+
+	// QoS 0 has no packet ID
+	qos0 := c.Publish("a", nil)
+	<-c.outgoing
+	if id := packetID(qos0); id != 0 {
+		t.Errorf("QoS 0 PacketID() = %d, want 0", id)
+	}
+
+	// Rejected before sending: no ID
+	if id := packetID(c.Publish("bad/#", nil, WithQoS(AtLeastOnce))); id != 0 {
+		t.Errorf("rejected publish PacketID() = %d, want 0", id)
+	}
+
+	first := c.Publish("a", nil, WithQoS(AtLeastOnce))
+	sent := (<-c.outgoing).(*packets.PublishPacket)
+	if id := packetID(first); id == 0 || id != sent.PacketID {
+		t.Errorf("PacketID() = %d, want %d", id, sent.PacketID)
+	}
+
+	// Waiting for Receive Maximum: assigned once sent
+	queued := c.Publish("a", nil, WithQoS(ExactlyOnce))
+	done := make(chan uint16)
+	go func() {
+		// Concurrent reads are safe while the logic loop assigns the ID
+		for packetID(queued) == 0 {
+			runtime.Gosched()
+		}
+		done <- packetID(queued)
+	}()
+
+	c.sessionLock.Lock()
+	c.handlePuback(&packets.PubackPacket{PacketID: sent.PacketID})
+	c.sessionLock.Unlock()
+
+	next := (<-c.outgoing).(*packets.PublishPacket)
+	if id := <-done; id != next.PacketID {
+		t.Errorf("queued PacketID() = %d, want %d", id, next.PacketID)
+	}
+
+	// PublishBatch tokens expose it as well
+	c.sessionLock.Lock()
+	c.serverCaps.ReceiveMaximum = 0
+	c.sessionLock.Unlock()
+	tokens := c.PublishBatch([]PublishRequest{{Topic: "b", Options: []PublishOption{WithQoS(AtLeastOnce)}}})
+	batched := (<-c.outgoing).(*packets.PublishPacket)
+	if id := packetID(tokens[0]); id != batched.PacketID {
+		t.Errorf("batch PacketID() = %d, want %d", id, batched.PacketID)
+	}
+}
+
+func TestPacketIDRange(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	WithPacketIDRange(10, 12)(opts)
+	WithPacketIDRange(0, 5)(opts)   // Ignored
+	WithPacketIDRange(20, 19)(opts) // Ignored
+	c := newTestClient(opts)
+
+	next := func() uint16 {
+		t.Helper()
+		id, ok := c.nextID()
+		if !ok {
+			t.Fatal("no packet ID available")
+		}
+		return id
+	}
+
+	var got []uint16
+	for range 4 {
+		got = append(got, next())
+	}
+	if want := []uint16{10, 11, 12, 10}; !slices.Equal(got, want) {
+		t.Errorf("IDs = %v, want %v", got, want)
+	}
+
+	// IDs in use are skipped, wrapping within the range
+	c.pending[11] = &pendingOp{}
+	c.pending[12] = &pendingOp{}
+	if id := next(); id != 10 {
+		t.Errorf("next ID = %d, want 10 (11 and 12 pending)", id)
+	}
+
+	// Never reused while pending
+	c.pending[10] = &pendingOp{}
+	if id, ok := c.nextID(); ok {
+		t.Errorf("next ID = %d with every ID pending", id)
+	}
+
+	// Full range by default, wrapping from 65535 to 1
+	c = newTestClient(nil)
+	c.nextPacketID = 65535
+	if id := next(); id != 1 {
+		t.Errorf("next ID after 65535 = %d, want 1", id)
+	}
+}
+
+func TestPacketIDRange_Exhausted(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	WithPacketIDRange(1, 2)(opts)
+	c := newTestClient(opts)
+	c.connected.Store(true)
+	c.serverCaps.MaximumQoS = 2
+
+	// Two publishes take the whole range; the third waits for an ID
+	var sent []*packets.PublishPacket
+	for range 2 {
+		c.Publish("a", nil, WithQoS(AtLeastOnce))
+		sent = append(sent, (<-c.outgoing).(*packets.PublishPacket))
+	}
+	queued := c.Publish("a", nil, WithQoS(AtLeastOnce))
+	select {
+	case pkt := <-c.outgoing:
+		t.Fatalf("sent %T with every packet ID in use", pkt)
+	default:
+	}
+
+	// Subscribing fails instead of reusing an ID
+	if err := c.Subscribe("b", AtLeastOnce, nil).Error(); !errors.Is(err, ErrPacketIDsExhausted) {
+		t.Errorf("Subscribe error = %v, want ErrPacketIDsExhausted", err)
+	}
+
+	c.sessionLock.Lock()
+	c.handlePuback(&packets.PubackPacket{PacketID: sent[0].PacketID})
+	c.sessionLock.Unlock()
+
+	next := (<-c.outgoing).(*packets.PublishPacket)
+	if next.PacketID != sent[0].PacketID {
+		t.Errorf("queued publish got packet ID %d, want %d", next.PacketID, sent[0].PacketID)
+	}
+	if id := queued.(PublishToken).PacketID(); id != next.PacketID {
+		t.Errorf("queued PacketID() = %d, want %d", id, next.PacketID)
+	}
+}
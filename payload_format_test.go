@@ -1,7 +1,14 @@
 package mq
 
 import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
 )
 
 func TestValidatePayloadFormat(t *testing.T) {
@@ -55,10 +62,67 @@ func TestValidatePayloadFormat(t *testing.T) {
 			if (err != nil) != tt.wantErr {
 				t.Errorf("validatePayloadFormat() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			if err != nil && !errors.Is(err, ErrPayloadFormatInvalid) {
+				t.Errorf("expected ErrPayloadFormatInvalid, got %v", err)
+			}
 		})
 	}
 }
 
+func TestPublish_InvalidUTF8Payload(t *testing.T) {
+	c := newTestClient(nil)
+	c.opts.Logger = testLogger()
+	c.serverCaps.MaximumQoS = 2
+
+	tok := c.Publish("text/out", []byte{0xFF, 0xFE}, WithQoS(AtLeastOnce), WithPayloadFormat(PayloadFormatUTF8))
+	select {
+	case <-tok.Done():
+	default:
+		t.Fatal("expected the token to fail at once")
+	}
+	if !errors.Is(tok.Error(), ErrPayloadFormatInvalid) {
+		t.Fatalf("expected ErrPayloadFormatInvalid, got %v", tok.Error())
+	}
+	if len(c.outgoing) != 0 || len(c.pending) != 0 {
+		t.Error("invalid payload must not be sent")
+	}
+
+	// The same bytes are fine when not declared as UTF-8
+	if err := c.Publish("text/out", []byte{0xFF, 0xFE}).Error(); err != nil {
+		t.Errorf("unexpected error for a bytes payload: %v", err)
+	}
+}
+
+func TestHandlePublish_InvalidUTF8PayloadWarns(t *testing.T) {
+	var logs bytes.Buffer
+	c := newTestClient(nil)
+	c.opts.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+
+	got := make(chan Message, 2)
+	c.subscriptions["text/#"] = subscriptionEntry{
+		handler: func(_ *Client, msg Message) { got <- msg },
+	}
+
+	props := &packets.Properties{PayloadFormatIndicator: PayloadFormatUTF8, Presence: packets.PresPayloadFormatIndicator}
+	c.handlePublish(&packets.PublishPacket{Topic: "text/valid", Payload: []byte("hello"), Properties: props})
+	if strings.Contains(logs.String(), "not valid UTF-8") {
+		t.Errorf("unexpected warning for a valid payload: %s", logs.String())
+	}
+	c.handlePublish(&packets.PublishPacket{Topic: "text/invalid", Payload: []byte{0xFF}, Properties: props})
+	if !strings.Contains(logs.String(), "not valid UTF-8") {
+		t.Errorf("expected a warning for an invalid payload, got: %s", logs.String())
+	}
+
+	// Both are delivered
+	for range 2 {
+		select {
+		case <-got:
+		case <-time.After(time.Second):
+			t.Fatal("message not delivered")
+		}
+	}
+}
+
 func ptr(v uint8) *uint8 {
 	return &v
 }
@@ -0,0 +1,158 @@
+package mq
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// newPingTimeoutClient returns a connected client for writeLoop tests whose
+// server side is returned for reading. onPing is called for each PINGREQ.
+func newPingTimeoutClient(t *testing.T, keepalive, pingTimeout time.Duration, onPing func(*Client)) *Client {
+	t.Helper()
+	serverConn, clientConn := net.Pipe()
+	t.Cleanup(func() {
+		serverConn.Close()
+		clientConn.Close()
+	})
+
+	client := &Client{
+		opts: &clientOptions{
+			KeepAlive:       keepalive,
+			PingTimeout:     pingTimeout,
+			Server:          "tcp://test:1883",
+			Logger:          testLogger(),
+			ProtocolVersion: ProtocolV311,
+		},
+		conn:           clientConn,
+		outgoing:       make(chan packets.Packet, 10),
+		packetReceived: make(chan struct{}, 1),
+		pingPendingCh:  make(chan struct{}, 1),
+		stop:           make(chan struct{}),
+		disconnected:   make(chan struct{}, 1),
+	}
+	client.connected.Store(true)
+
+	go func() {
+		buf := make([]byte, 2)
+		for {
+			n, err := serverConn.Read(buf)
+			if err != nil {
+				return
+			}
+			if n == 2 && buf[0] == 0xc0 && buf[1] == 0x00 && onPing != nil {
+				onPing(client)
+			}
+		}
+	}()
+	return client
+}
+
+// keepReceiving signals received packets until stop is closed, so that only
+// the ping timeout can end the connection.
+func keepReceiving(c *Client, stop <-chan struct{}) {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			select {
+			case c.packetReceived <- struct{}{}:
+			default:
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+func TestWithPingTimeout(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	if opts.PingTimeout != 0 {
+		t.Errorf("default PingTimeout = %v, want 0", opts.PingTimeout)
+	}
+	WithPingTimeout(5 * time.Second)(opts)
+	if opts.PingTimeout != 5*time.Second {
+		t.Errorf("PingTimeout = %v, want 5s", opts.PingTimeout)
+	}
+	WithPingTimeout(-time.Second)(opts)
+	if opts.PingTimeout != 5*time.Second {
+		t.Errorf("negative timeout changed PingTimeout to %v", opts.PingTimeout)
+	}
+}
+
+// TestPingTimeout verifies that an unanswered PINGREQ disconnects the client
+// well before the 1.5x keepalive receive timeout.
+func TestPingTimeout(t *testing.T) {
+	client := newPingTimeoutClient(t, time.Second, 100*time.Millisecond, nil)
+
+	stopReceiving := make(chan struct{})
+	defer close(stopReceiving)
+	go keepReceiving(client, stopReceiving)
+
+	done := make(chan struct{})
+	start := time.Now()
+	client.wg.Add(1)
+	go func() {
+		client.writeLoop()
+		close(done)
+	}()
+
+	// PINGREQ after 3/4 keepalive (750ms), timeout 100ms later
+	select {
+	case <-done:
+	case <-time.After(1400 * time.Millisecond):
+		close(client.stop)
+		t.Fatal("writeLoop did not exit after the ping timeout")
+	}
+
+	if elapsed := time.Since(start); elapsed < 800*time.Millisecond {
+		t.Errorf("disconnected after %v, before the ping timeout could expire", elapsed)
+	}
+	if client.IsConnected() {
+		t.Error("client should be marked as disconnected")
+	}
+	if got := client.pingsFailed.Load(); got != 1 {
+		t.Errorf("pingsFailed = %d, want 1", got)
+	}
+}
+
+// TestPingTimeoutAnswered verifies that a PINGRESP within the ping timeout
+// keeps the connection alive.
+func TestPingTimeoutAnswered(t *testing.T) {
+	client := newPingTimeoutClient(t, 400*time.Millisecond, 100*time.Millisecond, func(c *Client) {
+		select {
+		case c.pingPendingCh <- struct{}{}:
+		default:
+		}
+	})
+
+	stopReceiving := make(chan struct{})
+	defer close(stopReceiving)
+	go keepReceiving(client, stopReceiving)
+
+	done := make(chan struct{})
+	client.wg.Add(1)
+	go func() {
+		client.writeLoop()
+		close(done)
+	}()
+
+	// Several ping rounds
+	select {
+	case <-done:
+		t.Fatal("writeLoop exited although every PINGREQ was answered")
+	case <-time.After(time.Second):
+	}
+	close(client.stop)
+	<-done
+
+	if got := client.pingsSent.Load(); got < 2 {
+		t.Errorf("pingsSent = %d, want at least 2", got)
+	}
+	if got := client.pingsFailed.Load(); got != 0 {
+		t.Errorf("pingsFailed = %d, want 0", got)
+	}
+}
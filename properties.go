@@ -53,6 +53,20 @@ type Properties struct {
 	// ignored and not sent to the server.
 	ReasonString string
 
+	// ResponseInformation is the basis for response topics provided by the
+	// server in CONNACK when requested with WithRequestResponseInformation.
+	// This is a receive-only property.
+	ResponseInformation string
+
+	// ServerReference identifies another server to use, sent in CONNACK or
+	// DISCONNECT. This is a receive-only property.
+	ServerReference string
+
+	// AssignedClientIdentifier is the client ID assigned by the server in
+	// CONNACK when the client connected with an empty one. This is a
+	// receive-only property.
+	AssignedClientIdentifier string
+
 	// WillDelayInterval specifies the delay in seconds before the Will Message is sent.
 	// If the connection is re-established before this time, the Will Message is not sent.
 	WillDelayInterval *uint32
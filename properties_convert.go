@@ -59,6 +59,17 @@ func toPublicProperties(internal *packets.Properties) *Properties {
 		props.ReasonString = internal.ReasonString
 	}
 
+	// Convert CONNACK/DISCONNECT information (receive-only)
+	if internal.Presence&packets.PresResponseInformation != 0 {
+		props.ResponseInformation = internal.ResponseInformation
+	}
+	if internal.Presence&packets.PresServerReference != 0 {
+		props.ServerReference = internal.ServerReference
+	}
+	if internal.Presence&packets.PresAssignedClientIdentifier != 0 {
+		props.AssignedClientIdentifier = internal.AssignedClientIdentifier
+	}
+
 	// Convert user properties
 	for _, up := range internal.UserProperties {
 		props.UserProperties[up.Key] = up.Value
@@ -1,6 +1,7 @@
 package mq
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/gonzalop/mq/internal/packets"
@@ -12,6 +13,32 @@ type PublishOptions struct {
 	Retain     bool
 	Properties *Properties
 	UseAlias   bool
+	AliasID    uint16 // Set by WithAliasID; 0 lets the client choose
+	NoAlias    bool   // Set by WithoutAlias; overrides UseAlias and AliasID
+
+	ctx context.Context // Set by PublishContext
+}
+
+// Context returns the context passed to PublishContext, or
+// context.Background() for Publish. It lets a PublishInterceptor, which
+// only sees the options, reach request-scoped values such as the caller's
+// trace span:
+//
+//	func(next mq.PublishFunc) mq.PublishFunc {
+//	    return func(topic string, payload []byte, opts ...mq.PublishOption) mq.Token {
+//	        var o mq.PublishOptions
+//	        for _, opt := range opts {
+//	            opt(&o)
+//	        }
+//	        log.Printf("publish %s for request %v", topic, o.Context().Value(requestIDKey))
+//	        return next(topic, payload, opts...)
+//	    }
+//	}
+func (o *PublishOptions) Context() context.Context {
+	if o.ctx == nil {
+		return context.Background()
+	}
+	return o.ctx
 }
 
 // PublishOption is a functional option for configuring a PUBLISH packet.
@@ -137,7 +164,9 @@ func WithMessageExpiry(seconds uint32) PublishOption {
 //   - mq.PayloadFormatUTF8 (1): UTF-8 encoded character data
 //
 // If PayloadFormatUTF8 is used, the client will validate that the payload
-// is valid UTF-8 before sending.
+// is valid UTF-8 before sending; otherwise the token fails with
+// ErrPayloadFormatInvalid. Received messages declaring UTF-8 that are not
+// valid are still delivered, with a warning logged.
 //
 // Only used when protocol version is 5.0, ignored for v3.1.1.
 //
@@ -172,8 +201,10 @@ func WithProperties(props *Properties) PublishOption {
 // Publish publishes a message to the specified topic.
 //
 // The function returns a Token that can be used to wait for completion.
-// For QoS 0, the token completes immediately after sending. For QoS 1 and 2,
-// the token completes after receiving the appropriate acknowledgment from the server.
+// For QoS 0, the token completes once the message has been queued for sending
+// (see WithQoS0TokenBehavior to complete it after the network write instead).
+// For QoS 1 and 2, the token completes after receiving the appropriate
+// acknowledgment from the server.
 //
 // Example (QoS 0 - fire and forget):
 //
@@ -202,6 +233,36 @@ func WithProperties(props *Properties) PublishOption {
 //	    log.Printf("Publish timeout or failed: %v", err)
 //	}
 func (c *Client) Publish(topic string, payload []byte, opts ...PublishOption) Token {
+	return c.PublishContext(context.Background(), topic, payload, opts...)
+}
+
+// PublishContext is like Publish, but ctx bounds the enqueue step: if the
+// message cannot be handed to the network layer before ctx is done (the
+// outgoing queue is full, WithMaxInflightBytes or the server's Receive
+// Maximum hold it back), the message is discarded and the token completes
+// with ctx.Err().
+//
+// Once the message has been queued for sending, ctx no longer affects it;
+// use token.Wait with a context to bound the wait for the acknowledgment.
+//
+// Publish interceptors (WithPublishInterceptor) run as for Publish, and can
+// read ctx through PublishOptions.Context.
+//
+// Example (request with a tight deadline):
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+//	defer cancel()
+//	token := client.PublishContext(ctx, "rpc/requests", req, mq.WithQoS(1))
+//	if err := token.Wait(ctx); err != nil {
+//	    return fmt.Errorf("request not sent: %w", err)
+//	}
+func (c *Client) PublishContext(ctx context.Context, topic string, payload []byte, opts ...PublishOption) Token {
+	// Context() already reports Background without the extra option
+	if ctx != context.Background() {
+		opts = append(opts[:len(opts):len(opts)], func(o *PublishOptions) {
+			o.ctx = ctx
+		})
+	}
 	if c.publish == nil {
 		return c.basePublish(topic, payload, opts...)
 	}
@@ -209,30 +270,70 @@ func (c *Client) Publish(topic string, payload []byte, opts ...PublishOption) To
 }
 
 func (c *Client) basePublish(topic string, payload []byte, opts ...PublishOption) Token {
+	req, tok := c.newPublishRequest(topic, payload, opts...)
+	if req == nil {
+		return tok
+	}
+
+	// Execute directly (synchronous until packet is in outgoing channel or queue)
+	c.internalPublish(req)
+
+	return tok
+}
+
+// newPublishRequest applies the options, outgoing transforms and client-side
+// validation of a publish. If the message is rejected, the request is nil
+// and the token is already completed with the error.
+func (c *Client) newPublishRequest(topic string, payload []byte, opts ...PublishOption) (*publishRequest, *token) {
+	pubOpts := &PublishOptions{}
+	for _, opt := range opts {
+		opt(pubOpts)
+	}
+
+	if len(c.opts.PublishTransforms) > 0 {
+		view := &PublishView{
+			Topic:      topic,
+			Payload:    payload,
+			QoS:        QoS(pubOpts.QoS),
+			Retain:     pubOpts.Retain,
+			Properties: pubOpts.Properties,
+		}
+		for _, transform := range c.opts.PublishTransforms {
+			transform(view)
+		}
+		topic, payload = view.Topic, view.Payload
+		pubOpts.QoS = uint8(view.QoS)
+		pubOpts.Retain = view.Retain
+		pubOpts.Properties = view.Properties
+	}
+
 	c.opts.Logger.Debug("publishing message", "topic", topic, "payload_size", len(payload))
 
+	tok := newToken()
+
 	if err := validatePublishTopic(topic, c.opts); err != nil {
-		tok := newToken()
-		tok.complete(fmt.Errorf("invalid topic: %w", err))
-		return tok
+		tok.complete(fmt.Errorf("%w: %w", ErrInvalidTopic, err))
+		return nil, tok
 	}
 
 	if err := validatePayloadSize(payload, c.opts); err != nil {
-		tok := newToken()
 		tok.complete(fmt.Errorf("invalid payload: %w", err))
-		return tok
+		return nil, tok
 	}
 
-	pubOpts := &PublishOptions{}
-	for _, opt := range opts {
-		opt(pubOpts)
+	if err := c.checkTopicPayloadLimit(topic, payload); err != nil {
+		tok.complete(fmt.Errorf("invalid payload: %w", err))
+		return nil, tok
 	}
 
 	// Validate payload format if specified (MQTT v5.0)
 	if err := validatePayloadFormat(payload, pubOpts.Properties); err != nil {
-		tok := newToken()
 		tok.complete(fmt.Errorf("invalid payload format: %w", err))
-		return tok
+		return nil, tok
+	}
+
+	if pubOpts.NoAlias {
+		pubOpts.UseAlias, pubOpts.AliasID = false, 0
 	}
 
 	pkt := &packets.PublishPacket{
@@ -243,21 +344,24 @@ func (c *Client) basePublish(topic string, payload []byte, opts ...PublishOption
 		Version:    c.opts.ProtocolVersion,
 		Properties: toInternalProperties(pubOpts.Properties),
 		UseAlias:   pubOpts.UseAlias,
+		AliasID:    pubOpts.AliasID,
 	}
 
+	if pkt.AliasID != 0 && c.opts.ProtocolVersion < ProtocolV50 {
+		tok.complete(fmt.Errorf("%w: topic aliases require MQTT v5.0", ErrTopicAliasInvalid))
+		return nil, tok
+	}
 	if pkt.UseAlias && c.opts.ProtocolVersion >= ProtocolV50 {
-		c.applyTopicAlias(pkt)
+		if err := c.applyTopicAlias(pkt); err != nil {
+			tok.complete(err)
+			return nil, tok
+		}
 	}
 
-	tok := newToken()
-
 	req := &publishRequest{
 		packet: pkt,
 		token:  tok,
+		ctx:    pubOpts.ctx,
 	}
-
-	// Execute directly (synchronous until packet is in outgoing channel or queue)
-	c.internalPublish(req)
-
-	return tok
+	return req, tok
 }
@@ -31,3 +31,47 @@ func WithAlias() PublishOption {
 		o.UseAlias = true
 	}
 }
+
+// WithoutAlias makes this publish carry the full topic and no topic alias,
+// even if WithAlias or WithAliasID is also given (e.g. by an interceptor).
+// Use it for messages that must be decodable on their own, such as the first
+// message of a sequence read by a sniffer.
+//
+// The alias state of the topic is left untouched: later publishes with
+// WithAlias keep using its alias.
+//
+// Example:
+//
+//	client.Publish("plant/line-1/telemetry", header, mq.WithAlias(), mq.WithoutAlias())
+func WithoutAlias() PublishOption {
+	return func(o *PublishOptions) {
+		o.NoAlias = true
+	}
+}
+
+// WithAliasID is like WithAlias, but forces the outgoing topic alias to id
+// instead of letting the library choose one. Use it when the alias numbers
+// must be deterministic, e.g. to match a mapping agreed with a custom broker.
+//
+// The id must not exceed the number of aliases allowed on the connection
+// (the smaller of WithTopicAliasMaximum and the server's Topic Alias
+// Maximum); otherwise the token fails with ErrTopicAliasInvalid and nothing
+// is sent. An id of 0 lets the library choose, as with WithAlias. The first
+// publish with a given id sends the full topic and the alias, later ones
+// only the alias.
+//
+// Manually assigned IDs are reserved: automatically managed aliases
+// (WithAlias) never use them. Assigning an id already used by another topic
+// moves it to the new topic, and the previous topic gets a new alias the next
+// time it is published with WithAlias.
+//
+// Example:
+//
+//	client.Publish("plant/line-1/telemetry", data, mq.WithAliasID(1))
+//	client.Publish("plant/line-2/telemetry", data, mq.WithAliasID(2))
+func WithAliasID(id uint16) PublishOption {
+	return func(o *PublishOptions) {
+		o.UseAlias = true
+		o.AliasID = id
+	}
+}
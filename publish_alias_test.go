@@ -0,0 +1,28 @@
+package mq
+
+import "testing"
+
+func TestWithoutAlias(t *testing.T) {
+	c := newManualAliasClient(10)
+
+	steps := []struct {
+		opts      []PublishOption
+		wantTopic string
+		wantAlias uint16
+	}{
+		{[]PublishOption{WithoutAlias(), WithAlias()}, "plant/line-1", 0}, // Never assigned
+		{[]PublishOption{WithAlias()}, "plant/line-1", 1},
+		{[]PublishOption{WithAlias(), WithoutAlias()}, "plant/line-1", 0}, // Full topic, alias kept
+		{[]PublishOption{WithAliasID(1), WithoutAlias()}, "plant/line-1", 0},
+		{[]PublishOption{WithAlias()}, "", 1},
+	}
+	for i, s := range steps {
+		if err := c.Publish("plant/line-1", []byte("x"), s.opts...).Error(); err != nil {
+			t.Fatalf("step %d: publish failed: %v", i, err)
+		}
+		topic, alias := sentAlias(t, c)
+		if topic != s.wantTopic || alias != s.wantAlias {
+			t.Errorf("step %d: sent topic %q alias %d, want %q alias %d", i, topic, alias, s.wantTopic, s.wantAlias)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package mq
+
+import (
+	"testing"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestPublishBatch(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	c := newTestClient(opts)
+	c.serverCaps.MaximumQoS = 2
+	c.serverCaps.ReceiveMaximum = 3
+
+	batch := []PublishRequest{
+		{Topic: "t/1", Payload: []byte("1"), Options: []PublishOption{WithQoS(AtLeastOnce)}},
+		{Topic: "t/2", Payload: []byte("2"), Options: []PublishOption{WithQoS(ExactlyOnce)}},
+		{Topic: "bad/#", Payload: []byte("x"), Options: []PublishOption{WithQoS(AtLeastOnce)}},
+		{Topic: "t/3", Payload: []byte("3")},
+		{Topic: "t/4", Payload: []byte("4"), Options: []PublishOption{WithQoS(AtLeastOnce)}},
+		{Topic: "t/5", Payload: []byte("5"), Options: []PublishOption{WithQoS(AtLeastOnce)}},
+	}
+	tokens := c.PublishBatch(batch)
+	if len(tokens) != len(batch) {
+		t.Fatalf("got %d tokens, want %d", len(tokens), len(batch))
+	}
+
+	// Invalid messages fail without affecting the rest
+	select {
+	case <-tokens[2].Done():
+		if tokens[2].Error() == nil {
+			t.Error("expected invalid topic to fail")
+		}
+	default:
+		t.Error("expected the invalid message's token to be completed")
+	}
+
+	// Receive Maximum admits three QoS > 0 messages; QoS 0 is not limited
+	var sent []*packets.PublishPacket
+	for len(c.outgoing) > 0 {
+		sent = append(sent, (<-c.outgoing).(*packets.PublishPacket))
+	}
+	wantTopics := []string{"t/1", "t/2", "t/3", "t/4"}
+	if len(sent) != len(wantTopics) {
+		t.Fatalf("sent %d packets, want %d", len(sent), len(wantTopics))
+	}
+	ids := map[uint16]bool{}
+	for i, pkt := range sent {
+		if pkt.Topic != wantTopics[i] {
+			t.Errorf("packet %d topic = %q, want %q", i, pkt.Topic, wantTopics[i])
+		}
+		if pkt.QoS > 0 {
+			if pkt.PacketID == 0 || ids[pkt.PacketID] {
+				t.Errorf("packet %d has invalid or duplicate ID %d", i, pkt.PacketID)
+			}
+			ids[pkt.PacketID] = true
+		}
+	}
+
+	c.sessionLock.Lock()
+	if c.inFlightCount != 3 || len(c.publishQueue) != 1 {
+		t.Errorf("inFlightCount = %d, queued = %d; want 3, 1", c.inFlightCount, len(c.publishQueue))
+	}
+
+	// Tokens complete individually
+	c.handlePuback(&packets.PubackPacket{PacketID: sent[0].PacketID})
+	c.sessionLock.Unlock()
+
+	select {
+	case <-tokens[0].Done():
+		if err := tokens[0].Error(); err != nil {
+			t.Errorf("first publish failed: %v", err)
+		}
+	default:
+		t.Error("expected the acknowledged publish to complete")
+	}
+	select {
+	case <-tokens[4].Done():
+		t.Error("expected the unacknowledged publish to be pending")
+	default:
+	}
+
+	// The freed slot sends the queued message
+	if pkt := (<-c.outgoing).(*packets.PublishPacket); pkt.Topic != "t/5" {
+		t.Errorf("expected queued t/5 to be sent, got %q", pkt.Topic)
+	}
+}
+
+func TestPublishBatch_Interceptors(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	var seen []string
+	WithPublishInterceptor(func(next PublishFunc) PublishFunc {
+		return func(topic string, payload []byte, opts ...PublishOption) Token {
+			seen = append(seen, topic)
+			return next(topic, payload, opts...)
+		}
+	})(opts)
+	c := newTestClient(opts)
+	c.publish = applyPublishInterceptors(c.basePublish, opts.PublishInterceptors)
+
+	tokens := c.PublishBatch([]PublishRequest{{Topic: "a"}, {Topic: "b"}})
+	for i, tok := range tokens {
+		if err := tok.Error(); err != nil {
+			t.Errorf("token %d: %v", i, err)
+		}
+	}
+	if len(seen) != 2 || seen[0] != "a" || seen[1] != "b" {
+		t.Errorf("interceptor saw %v, want [a b]", seen)
+	}
+}
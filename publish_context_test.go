@@ -0,0 +1,201 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func newPublishContextClient(t *testing.T, opts *clientOptions) *Client {
+	t.Helper()
+	if opts == nil {
+		opts = defaultOptions("tcp://localhost:1883")
+	}
+	opts.Logger = testLogger()
+	c := newTestClient(opts)
+	c.serverCaps.MaximumQoS = 2
+	c.serverCaps.RetainAvailable = true
+	return c
+}
+
+func waitToken(t *testing.T, tok Token) error {
+	t.Helper()
+	select {
+	case <-tok.Done():
+		return tok.Error()
+	case <-time.After(2 * time.Second):
+		t.Fatal("token did not complete")
+		return nil
+	}
+}
+
+func TestPublishContext_AlreadyDone(t *testing.T) {
+	c := newPublishContextClient(t, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tok := c.PublishContext(ctx, "a", []byte("x"), WithQoS(AtLeastOnce))
+	if err := waitToken(t, tok); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if len(c.outgoing) != 0 || len(c.pending) != 0 {
+		t.Error("expected nothing to be queued")
+	}
+}
+
+func TestPublishContext_FullOutgoingQueue(t *testing.T) {
+	tests := []struct {
+		name string
+		qos  QoS
+	}{
+		{"qos0 block policy", AtMostOnce},
+		{"qos1", AtLeastOnce},
+		{"qos2", ExactlyOnce},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultOptions("tcp://localhost:1883")
+			opts.OutgoingQueueSize = 1
+			opts.QoS0Policy = QoS0LimitPolicyBlock
+			opts.MaxInflightBytes = 1000
+			c := newPublishContextClient(t, opts)
+			c.outgoing <- &packets.PingreqPacket{} // Stalled broker: queue full
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			tok := c.PublishContext(ctx, "a", []byte("payload"), WithQoS(tt.qos))
+			if err := waitToken(t, tok); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("publish blocked for %v", elapsed)
+			}
+
+			// The abandoned publish leaves no trace
+			c.sessionLock.Lock()
+			defer c.sessionLock.Unlock()
+			if len(c.pending) != 0 || c.inFlightCount != 0 || c.inFlightBytes != 0 {
+				t.Errorf("expected no in-flight state, got %d pending, count %d, %d bytes",
+					len(c.pending), c.inFlightCount, c.inFlightBytes)
+			}
+			if len(c.outgoing) != 1 {
+				t.Errorf("expected only the stalled packet in the queue, got %d", len(c.outgoing))
+			}
+		})
+	}
+}
+
+func TestPublishContext_ReceiveMaximumQueue(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.MaxInflightBytes = 1000
+	c := newPublishContextClient(t, opts)
+	c.serverCaps.ReceiveMaximum = 1
+
+	// Fill the server's receive window
+	busy := c.Publish("a", []byte("1"), WithQoS(AtLeastOnce))
+	first := (<-c.outgoing).(*packets.PublishPacket)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	queued := c.PublishContext(ctx, "a", []byte("22"), WithQoS(AtLeastOnce))
+	kept := c.Publish("a", []byte("333"), WithQoS(AtLeastOnce))
+
+	c.sessionLock.Lock()
+	if len(c.publishQueue) != 2 {
+		t.Fatalf("expected 2 queued publishes, got %d", len(c.publishQueue))
+	}
+	c.sessionLock.Unlock()
+
+	cancel()
+	if err := waitToken(t, queued); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	c.sessionLock.Lock()
+	if len(c.publishQueue) != 1 || c.inFlightBytes != 4 {
+		t.Errorf("expected the cancelled publish to be withdrawn, got %d queued, %d bytes",
+			len(c.publishQueue), c.inFlightBytes)
+	}
+	// Acknowledging the first publish sends the remaining one
+	c.handlePuback(&packets.PubackPacket{PacketID: first.PacketID})
+	c.sessionLock.Unlock()
+
+	if err := waitToken(t, busy); err != nil {
+		t.Fatalf("first publish failed: %v", err)
+	}
+	select {
+	case pkt := <-c.outgoing:
+		if p := pkt.(*packets.PublishPacket); string(p.Payload) != "333" {
+			t.Errorf("expected the uncancelled publish to be sent, got %q", p.Payload)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("queued publish not sent")
+	}
+	select {
+	case <-kept.Done():
+		t.Errorf("expected the publish to wait for its PUBACK, got %v", kept.Error())
+	default:
+	}
+}
+
+func TestPublishContext_Interceptors(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	var topics []string
+	WithPublishInterceptor(func(next PublishFunc) PublishFunc {
+		return func(topic string, payload []byte, opts ...PublishOption) Token {
+			topics = append(topics, topic)
+			return next(topic, payload, opts...)
+		}
+	})(opts)
+	c := newPublishContextClient(t, opts)
+	c.publish = applyPublishInterceptors(c.basePublish, opts.PublishInterceptors)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := waitToken(t, c.PublishContext(ctx, "a", nil)); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if err := waitToken(t, c.Publish("b", nil)); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if len(topics) != 2 || topics[0] != "a" || topics[1] != "b" {
+		t.Errorf("interceptor saw %v, want [a b]", topics)
+	}
+}
+
+func TestPublishOptions_Context(t *testing.T) {
+	type ctxKey struct{}
+
+	opts := defaultOptions("tcp://localhost:1883")
+	var seen []any
+	WithPublishInterceptor(func(next PublishFunc) PublishFunc {
+		return func(topic string, payload []byte, opts ...PublishOption) Token {
+			var o PublishOptions
+			for _, opt := range opts {
+				opt(&o)
+			}
+			seen = append(seen, o.Context().Value(ctxKey{}))
+			return next(topic, payload, opts...)
+		}
+	})(opts)
+	c := newPublishContextClient(t, opts)
+	c.publish = applyPublishInterceptors(c.basePublish, opts.PublishInterceptors)
+
+	// A value-only context has no Done channel and must still be visible
+	ctx := context.WithValue(context.Background(), ctxKey{}, "req-1")
+	if err := waitToken(t, c.PublishContext(ctx, "a", nil)); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if err := waitToken(t, c.Publish("b", nil)); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if len(seen) != 2 || seen[0] != "req-1" || seen[1] != nil {
+		t.Errorf("interceptor saw context values %v, want [req-1 <nil>]", seen)
+	}
+}
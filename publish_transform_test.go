@@ -0,0 +1,80 @@
+package mq
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestOutgoingPublishTransform(t *testing.T) {
+	var order []string
+	opts := defaultOptions("tcp://localhost:1883")
+	WithOutgoingPublishTransform(func(p *PublishView) {
+		order = append(order, "first")
+		if p.Properties == nil {
+			p.Properties = NewProperties()
+		}
+		p.Properties.SetUserProperty("checksum", "abc123")
+		p.Topic = "tenant-a/" + p.Topic
+	})(opts)
+	WithOutgoingPublishTransform(func(p *PublishView) {
+		order = append(order, "second")
+		if !strings.HasPrefix(p.Topic, "tenant-a/") {
+			t.Errorf("expected transforms to run in order, topic %q", p.Topic)
+		}
+		p.QoS = AtMostOnce // Policy: downgrade everything
+	})(opts)
+
+	c := newTestClient(opts)
+	c.opts.Logger = testLogger()
+	c.serverCaps.MaximumQoS = 2
+
+	tok := c.Publish("sensors/temp", []byte("21.5"), WithQoS(AtLeastOnce), WithContentType("text/plain"))
+	if err := tok.Error(); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if len(order) != 2 {
+		t.Fatalf("expected both transforms to run, got %v", order)
+	}
+
+	// Round-trip the queued packet through the wire encoding
+	var buf bytes.Buffer
+	if _, err := (<-c.outgoing).WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	pkt, err := packets.ReadPacket(&buf, ProtocolV50, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := pkt.(*packets.PublishPacket)
+
+	if pub.Topic != "tenant-a/sensors/temp" {
+		t.Errorf("Topic = %q, want tenant-a/sensors/temp", pub.Topic)
+	}
+	if pub.QoS != 0 {
+		t.Errorf("QoS = %d, want 0", pub.QoS)
+	}
+	props := toPublicProperties(pub.Properties)
+	if props == nil || props.GetUserProperty("checksum") != "abc123" {
+		t.Errorf("expected checksum user property in sent packet, got %+v", props)
+	}
+	if props == nil || props.ContentType != "text/plain" {
+		t.Errorf("expected properties from options to be kept, got %+v", props)
+	}
+}
+
+func TestOutgoingPublishTransform_ValidatedAfterwards(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	WithOutgoingPublishTransform(func(p *PublishView) {
+		p.Topic = "bad/#"
+	})(opts)
+	c := newTestClient(opts)
+	c.opts.Logger = testLogger()
+
+	tok := c.Publish("good/topic", nil)
+	if err := tok.Error(); err == nil || !strings.Contains(err.Error(), "invalid topic") {
+		t.Fatalf("expected the transformed topic to be validated, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package mq
+
+import "github.com/gonzalop/mq/internal/packets"
+
+// writeNotifyPacket wraps an outgoing packet whose token completes once the
+// write loop has flushed it to the connection (QoS0TokenCompleteOnWrite).
+// It forwards Type and WriteTo to the wrapped packet, so it can be queued in
+// c.outgoing like any other packet.
+type writeNotifyPacket struct {
+	packets.Packet
+	token *token
+}
+
+// unwrapPacket returns the packet that is actually written to the network.
+func unwrapPacket(pkt packets.Packet) packets.Packet {
+	if w, ok := pkt.(*writeNotifyPacket); ok {
+		return w.Packet
+	}
+	return pkt
+}
+
+// writeNotifier collects the tokens of the packets written in one batch so
+// they can be completed after the batch has been flushed.
+type writeNotifier struct {
+	tokens []*token
+}
+
+// track records pkt if it needs a completion notification.
+func (n *writeNotifier) track(pkt packets.Packet) {
+	if w, ok := pkt.(*writeNotifyPacket); ok {
+		n.tokens = append(n.tokens, w.token)
+	}
+}
+
+// done completes all tracked tokens with err and resets the batch.
+func (n *writeNotifier) done(err error) {
+	for _, tok := range n.tokens {
+		tok.complete(err)
+	}
+	n.tokens = n.tokens[:0]
+}
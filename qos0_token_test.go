@@ -0,0 +1,140 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestQoS0TokenBehavior_Enqueue(t *testing.T) {
+	tests := []struct {
+		name        string
+		behavior    QoS0TokenBehavior
+		fillQueue   bool
+		wantDropped bool
+	}{
+		{"complete on enqueue", QoS0TokenCompleteOnEnqueue, false, false},
+		{"complete on enqueue, queue full", QoS0TokenCompleteOnEnqueue, true, true},
+		{"never complete", QoS0TokenNeverComplete, false, false},
+		{"never complete, queue full", QoS0TokenNeverComplete, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultOptions("tcp://localhost:1883")
+			opts.OutgoingQueueSize = 1
+			opts.QoS0TokenBehavior = tt.behavior
+			c := newTestClient(opts)
+			if tt.fillQueue {
+				c.outgoing <- &packets.PingreqPacket{}
+			}
+
+			tok := newToken()
+			c.internalPublish(&publishRequest{
+				packet: &packets.PublishPacket{Topic: "t", QoS: 0},
+				token:  tok,
+			})
+
+			select {
+			case <-tok.Done():
+			default:
+				t.Fatal("expected token to be complete")
+			}
+			if err := tok.Error(); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tok.Dropped() != tt.wantDropped {
+				t.Errorf("Dropped() = %v, want %v", tok.Dropped(), tt.wantDropped)
+			}
+			if !tt.fillQueue {
+				if _, ok := (<-c.outgoing).(*packets.PublishPacket); !ok {
+					t.Error("expected the PUBLISH to be queued unwrapped")
+				}
+			}
+		})
+	}
+}
+
+// dialQoS0WritePipe connects a client configured with QoS0TokenCompleteOnWrite
+// to a synchronous in-memory pipe. Writes block until the server side reads,
+// which makes the write milestone observable.
+func dialQoS0WritePipe(t *testing.T) (*Client, net.Conn) {
+	t.Helper()
+	clientConn, serverConn := net.Pipe()
+
+	handshake := make(chan error, 1)
+	go func() {
+		if _, err := packets.ReadPacket(serverConn, ProtocolV50, 0); err != nil {
+			handshake <- err
+			return
+		}
+		connack := &packets.ConnackPacket{ReturnCode: packets.ConnAccepted}
+		_, err := connack.WriteTo(serverConn)
+		handshake <- err
+	}()
+
+	client, err := Dial("tcp://pipe:1883",
+		WithClientID("qos0-write"),
+		WithDialer(DialFunc(func(_ context.Context, _, _ string) (net.Conn, error) {
+			return clientConn, nil
+		})),
+		WithAutoReconnect(false),
+		WithKeepAlive(0),
+		WithQoS0TokenBehavior(QoS0TokenCompleteOnWrite),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	if err := <-handshake; err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	return client, serverConn
+}
+
+func TestQoS0TokenBehavior_CompleteOnWrite(t *testing.T) {
+	client, serverConn := dialQoS0WritePipe(t)
+	defer func() { _ = client.Disconnect(context.Background()) }()
+	defer serverConn.Close()
+
+	tok := client.Publish("t", []byte("payload"))
+
+	// The server is not reading yet, so the write cannot have completed
+	select {
+	case <-tok.Done():
+		t.Fatalf("token completed before the PUBLISH was written (err=%v)", tok.Error())
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	pkt, err := packets.ReadPacket(serverConn, ProtocolV50, 0)
+	if err != nil {
+		t.Fatalf("server read failed: %v", err)
+	}
+	if _, ok := pkt.(*packets.PublishPacket); !ok {
+		t.Fatalf("expected PUBLISH, got %T", pkt)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := tok.Wait(ctx); err != nil {
+		t.Errorf("expected token to complete after the write, got %v", err)
+	}
+}
+
+func TestQoS0TokenBehavior_CompleteOnWriteFailure(t *testing.T) {
+	client, serverConn := dialQoS0WritePipe(t)
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	tok := client.Publish("t", []byte("payload"))
+	time.Sleep(50 * time.Millisecond)
+	serverConn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := tok.Wait(ctx); !errors.Is(err, ErrClientDisconnected) {
+		t.Errorf("expected ErrClientDisconnected, got %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestQoS2PubrelTrackedWhenQueueFull(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.OutgoingQueueSize = 1
+	c := newTestClient(opts)
+	c.opts.Logger = testLogger()
+
+	c.pending[9] = &pendingOp{
+		packet: &packets.PublishPacket{PacketID: 9, QoS: 2, Topic: "t"},
+		token:  newToken(),
+		qos:    2,
+	}
+	c.outgoing <- &packets.PingreqPacket{} // Queue full
+
+	c.handlePubrec(&packets.PubrecPacket{PacketID: 9})
+
+	if _, ok := c.pending[9].packet.(*packets.PubrelPacket); !ok {
+		t.Fatalf("expected pending op to track PUBREL, got %T", c.pending[9].packet)
+	}
+
+	// The retry sends the PUBREL, never the PUBLISH again
+	<-c.outgoing
+	c.pending[9].timestamp = time.Now().Add(-time.Minute)
+	c.retryPending()
+	select {
+	case pkt := <-c.outgoing:
+		if rel, ok := pkt.(*packets.PubrelPacket); !ok || rel.PacketID != 9 {
+			t.Fatalf("expected PUBREL 9 on retry, got %#v", pkt)
+		}
+	default:
+		t.Fatal("expected PUBREL to be retransmitted")
+	}
+}
+
+// TestQoS2ResumeAfterPubrel drops the connection after the client sent
+// PUBREL but before PUBCOMP, and checks that the resumed session
+// retransmits PUBREL (not PUBLISH) with the same packet ID.
+func TestQoS2ResumeAfterPubrel(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- func() error {
+			// First connection: PUBLISH → PUBREC → PUBREL, then drop
+			conn1, err := ln.Accept()
+			if err != nil {
+				return err
+			}
+			if _, err := packets.ReadPacket(conn1, ProtocolV50, 0); err != nil {
+				return err
+			}
+			if _, err := (&packets.ConnackPacket{ReturnCode: 0}).WriteTo(conn1); err != nil {
+				return err
+			}
+			pkt, err := packets.ReadPacket(conn1, ProtocolV50, 0)
+			if err != nil {
+				return err
+			}
+			pub, ok := pkt.(*packets.PublishPacket)
+			if !ok || pub.QoS != 2 {
+				return fmt.Errorf("conn1: expected QoS 2 PUBLISH, got %#v", pkt)
+			}
+			id := pub.PacketID
+			pubrec := &packets.PubrecPacket{PacketID: id, Version: ProtocolV50}
+			if _, err := pubrec.WriteTo(conn1); err != nil {
+				return err
+			}
+			pkt, err = packets.ReadPacket(conn1, ProtocolV50, 0)
+			if err != nil {
+				return err
+			}
+			if rel, ok := pkt.(*packets.PubrelPacket); !ok || rel.PacketID != id {
+				return fmt.Errorf("conn1: expected PUBREL %d, got %#v", id, pkt)
+			}
+			conn1.Close() // No PUBCOMP
+
+			// Second connection: session resumed
+			conn2, err := ln.Accept()
+			if err != nil {
+				return err
+			}
+			defer conn2.Close()
+			pkt, err = packets.ReadPacket(conn2, ProtocolV50, 0)
+			if err != nil {
+				return err
+			}
+			if connect, ok := pkt.(*packets.ConnectPacket); !ok || connect.CleanSession {
+				return fmt.Errorf("conn2: expected CONNECT resuming the session, got %#v", pkt)
+			}
+			connack := &packets.ConnackPacket{ReturnCode: 0, SessionPresent: true}
+			if _, err := connack.WriteTo(conn2); err != nil {
+				return err
+			}
+			pkt, err = packets.ReadPacket(conn2, ProtocolV50, 0)
+			if err != nil {
+				return err
+			}
+			if rel, ok := pkt.(*packets.PubrelPacket); !ok || rel.PacketID != id {
+				return fmt.Errorf("conn2: expected PUBREL %d to be retransmitted, got %#v", id, pkt)
+			}
+			pubcomp := &packets.PubcompPacket{PacketID: id, Version: ProtocolV50}
+			if _, err := pubcomp.WriteTo(conn2); err != nil {
+				return err
+			}
+
+			// Nothing else may be retransmitted for this flow
+			_ = conn2.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+			if pkt, err := packets.ReadPacket(conn2, ProtocolV50, 0); err == nil {
+				if _, ok := pkt.(*packets.DisconnectPacket); !ok {
+					return fmt.Errorf("conn2: unexpected %T after PUBCOMP", pkt)
+				}
+			}
+			return nil
+		}()
+	}()
+
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("qos2-resume"),
+		WithCleanSession(false),
+		WithSessionExpiryInterval(3600),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	tok := client.Publish("qos2/resume", []byte("payload"), WithQoS(ExactlyOnce))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tok.Wait(ctx); err != nil {
+		t.Fatalf("expected publish to complete on PUBCOMP after resume, got %v", err)
+	}
+
+	select {
+	case err := <-serverErr:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for server")
+	}
+}
@@ -0,0 +1,16 @@
+module github.com/gonzalop/mq/quictransport
+
+go 1.24.6
+
+replace github.com/gonzalop/mq => ../
+
+require (
+	github.com/gonzalop/mq v0.0.0-00010101000000-000000000000
+	github.com/quic-go/quic-go v0.59.1
+)
+
+require (
+	golang.org/x/crypto v0.41.0 // indirect
+	golang.org/x/net v0.43.0 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+)
@@ -0,0 +1,130 @@
+// Package quictransport connects the mq MQTT client to servers over QUIC
+// (MQTT over QUIC, quic:// URLs).
+//
+// Dialer returns an mq.ContextDialer for mq.WithDialer. It opens a QUIC
+// connection, negotiating the MQTT ALPN protocol, and carries the MQTT
+// session on a single bidirectional stream adapted to the net.Conn the
+// client expects. Deadlines on the returned connection apply to the stream,
+// so keepalive and handshake timeouts work as over TCP.
+//
+// QUIC recovers from packet loss per stream and survives changes of the
+// client address, which helps on lossy mobile networks. The server must
+// support MQTT over QUIC (e.g. EMQX).
+//
+// QUIC always runs over TLS 1.3: the server certificate is verified as for
+// tls:// URLs unless WithTLSConfig says otherwise, and the server must accept
+// the "qmqtt" ALPN protocol. Closing the net.Conn closes the whole QUIC
+// connection, not only its stream.
+//
+// Example:
+//
+//	client, err := mq.Dial("quic://broker.example.com:14567",
+//	    mq.WithClientID("vehicle-42"),
+//	    mq.WithDialer(quictransport.Dialer()),
+//	)
+package quictransport
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/gonzalop/mq"
+	"github.com/quic-go/quic-go"
+)
+
+// ALPN is the TLS application protocol negotiated for MQTT over QUIC.
+const ALPN = "qmqtt"
+
+// DefaultPort is used when the server URL has no port.
+const DefaultPort = "14567"
+
+// Option configures a Dialer.
+type Option func(*config)
+
+type config struct {
+	tlsConfig  *tls.Config
+	quicConfig *quic.Config
+}
+
+// WithTLSConfig sets the TLS configuration of the QUIC handshake (for
+// example a private CA or a client certificate). mq.WithTLS does not apply
+// to custom dialers; use this option instead.
+//
+// If the configuration sets no NextProtos, ALPN is offered.
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(c *config) {
+		c.tlsConfig = tlsConfig
+	}
+}
+
+// WithQUICConfig sets QUIC transport parameters, such as the handshake
+// idle timeout or QUIC-level keepalives.
+func WithQUICConfig(quicConfig *quic.Config) Option {
+	return func(c *config) {
+		c.quicConfig = quicConfig
+	}
+}
+
+// Dialer returns a dialer for mq.WithDialer that connects to the quic://
+// server URL given to mq.Dial (port 14567 if none is given).
+//
+// Dialing is bounded by the connect context (mq.WithConnectTimeout);
+// once established, the connection is not tied to that context.
+func Dialer(opts ...Option) mq.ContextDialer {
+	cfg := &config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	tlsConfig := &tls.Config{}
+	if cfg.tlsConfig != nil {
+		tlsConfig = cfg.tlsConfig.Clone()
+	}
+	if len(tlsConfig.NextProtos) == 0 {
+		tlsConfig.NextProtos = []string{ALPN}
+	}
+
+	return mq.DialFunc(func(ctx context.Context, _ string, addr string) (net.Conn, error) {
+		u, err := url.Parse(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid server URL: %w", err)
+		}
+		if u.Scheme != "quic" {
+			return nil, fmt.Errorf("unsupported scheme: %s (supported: quic)", u.Scheme)
+		}
+
+		host := u.Host
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), DefaultPort)
+		}
+
+		conn, err := quic.DialAddr(ctx, host, tlsConfig, cfg.quicConfig)
+		if err != nil {
+			return nil, err
+		}
+		stream, err := conn.OpenStreamSync(ctx)
+		if err != nil {
+			_ = conn.CloseWithError(0, "")
+			return nil, err
+		}
+		return &streamConn{Stream: stream, conn: conn}, nil
+	})
+}
+
+// streamConn adapts a QUIC stream to net.Conn. Read, Write and the deadline
+// methods are those of the stream; closing it closes the whole connection.
+type streamConn struct {
+	*quic.Stream
+	conn *quic.Conn
+}
+
+func (c *streamConn) Close() error {
+	_ = c.Stream.Close()
+	return c.conn.CloseWithError(0, "")
+}
+
+func (c *streamConn) LocalAddr() net.Addr  { return c.conn.LocalAddr() }
+func (c *streamConn) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
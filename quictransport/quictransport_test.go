@@ -0,0 +1,173 @@
+package quictransport
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq"
+	"github.com/gonzalop/mq/internal/packets"
+	"github.com/quic-go/quic-go"
+)
+
+// listen starts a QUIC server for MQTT that answers CONNECT with a CONNACK
+// and reports the topic of the first PUBLISH. It returns the server URL and
+// the CA pool to trust.
+func listen(t *testing.T, published chan<- string) (string, *x509.CertPool) {
+	t.Helper()
+	cert := selfSignedCert(t)
+	ln, err := quic.ListenAddr("127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{ALPN},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept(context.Background())
+		if err != nil {
+			return
+		}
+		defer conn.CloseWithError(0, "")
+		stream, err := conn.AcceptStream(context.Background())
+		if err != nil {
+			return
+		}
+
+		if _, err := packets.ReadPacket(stream, mq.ProtocolV50, 0); err != nil {
+			return
+		}
+		if _, err := (&packets.ConnackPacket{ReturnCode: 0}).WriteTo(stream); err != nil {
+			return
+		}
+		for {
+			pkt, err := packets.ReadPacket(stream, mq.ProtocolV50, 0)
+			if err != nil {
+				return
+			}
+			if p, ok := pkt.(*packets.PublishPacket); ok {
+				published <- p.Topic
+			}
+		}
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert.Leaf)
+	return "quic://" + ln.Addr().String(), roots
+}
+
+func TestDialer(t *testing.T) {
+	published := make(chan string, 1)
+	server, roots := listen(t, published)
+
+	client, err := mq.Dial(server,
+		mq.WithClientID("quic-client"),
+		mq.WithAutoReconnect(false),
+		mq.WithDialer(Dialer(WithTLSConfig(&tls.Config{RootCAs: roots}))),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer client.Disconnect(context.Background())
+
+	// The connection outlives the connect context
+	time.Sleep(50 * time.Millisecond)
+	client.Publish("vehicle/telemetry", []byte("1"))
+	select {
+	case topic := <-published:
+		if topic != "vehicle/telemetry" {
+			t.Errorf("server received topic %q", topic)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for PUBLISH")
+	}
+}
+
+// TestDialer_Deadline verifies that deadlines on the connection apply to
+// the stream.
+func TestDialer_Deadline(t *testing.T) {
+	server, roots := listen(t, make(chan string, 1))
+
+	conn, err := Dialer(WithTLSConfig(&tls.Config{RootCAs: roots})).DialContext(context.Background(), "quic", server)
+	if err != nil {
+		t.Fatalf("DialContext failed: %v", err)
+	}
+	defer conn.Close()
+
+	if conn.RemoteAddr().String() != strings.TrimPrefix(server, "quic://") {
+		t.Errorf("RemoteAddr = %v, want %s", conn.RemoteAddr(), server)
+	}
+
+	// The server waits for CONNECT, so nothing arrives
+	if err := conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	_, err = conn.Read(make([]byte, 1))
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("Read error = %v, want a timeout", err)
+	}
+}
+
+func TestDialer_Errors(t *testing.T) {
+	server, _ := listen(t, make(chan string, 1))
+
+	tests := []struct {
+		name string
+		addr string
+		want string
+	}{
+		{"untrusted certificate", server, "certificate"},
+		{"tcp scheme", "tcp://localhost:1883", "unsupported scheme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_, err := Dialer().DialContext(ctx, "", tt.addr)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("DialContext(%s) error = %v, want it to mention %q", tt.addr, err, tt.want)
+			}
+		})
+	}
+}
+
+// selfSignedCert returns a certificate for 127.0.0.1, valid for one hour.
+func selfSignedCert(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
@@ -0,0 +1,94 @@
+package mq
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// readErrorDisconnectTimeout bounds how long readLoop waits for the
+// DISCONNECT sent after an invalid packet to be written.
+const readErrorDisconnectTimeout = time.Second
+
+// handleReadError classifies an error returned while reading a packet and
+// records the reason reported through OnConnectionLost:
+//
+//   - A packet that cannot be decoded yields ErrMalformedPacket, and for
+//     MQTT v5.0 a DISCONNECT with Reason Code 0x81 (Malformed Packet).
+//   - A packet that violates the protocol (e.g. invalid fixed header flags)
+//     yields ErrProtocolViolation, and for MQTT v5.0 a DISCONNECT with
+//     Reason Code 0x82 (Protocol Error).
+//   - Anything else is a network error and yields a "connection lost" error
+//     wrapping it.
+//
+// The connection itself is torn down by handleDisconnect when readLoop
+// returns.
+func (c *Client) handleReadError(err error, connDone <-chan struct{}) {
+	var (
+		malformed *packets.MalformedPacketError
+		protoErr  *packets.ProtocolError
+		reason    error
+		code      ReasonCode
+	)
+
+	switch {
+	case errors.As(err, &malformed):
+		c.opts.Logger.Error("malformed packet, disconnecting", "error", err)
+		reason = fmt.Errorf("%w: %v", ErrMalformedPacket, err)
+		code = ReasonCodeMalformedPacket
+	case errors.As(err, &protoErr):
+		c.opts.Logger.Error("protocol error, disconnecting", "error", err)
+		reason = fmt.Errorf("%w: %v", ErrProtocolViolation, err)
+		code = ReasonCodeProtocolError
+	default:
+		c.opts.Logger.Debug("read error, disconnecting", "error", err)
+		reason = fmt.Errorf("connection lost: %w", err)
+	}
+
+	c.connLock.Lock()
+	if c.lastDisconnectReason == nil {
+		c.lastDisconnectReason = reason
+	}
+	c.connLock.Unlock()
+
+	if code != 0 && c.opts.ProtocolVersion >= ProtocolV50 && c.IsConnected() {
+		// Section 4.13: the receiver SHOULD send a DISCONNECT with the
+		// matching reason code before closing the connection.
+		c.sendDisconnectAndWait(uint8(code), connDone)
+	}
+}
+
+// sendDisconnectAndWait queues a DISCONNECT and waits until the write loop
+// has flushed it, the connection is torn down, or a short timeout expires.
+func (c *Client) sendDisconnectAndWait(reasonCode uint8, connDone <-chan struct{}) {
+	tok := newToken()
+	pkt := &writeNotifyPacket{
+		Packet: &packets.DisconnectPacket{
+			Version:    c.opts.ProtocolVersion,
+			ReasonCode: reasonCode,
+		},
+		token: tok,
+	}
+
+	timer := time.NewTimer(readErrorDisconnectTimeout)
+	defer timer.Stop()
+
+	select {
+	case c.outgoing <- pkt:
+	case <-connDone:
+		return
+	case <-c.stop:
+		return
+	case <-timer.C:
+		return
+	}
+
+	select {
+	case <-tok.Done():
+	case <-connDone:
+	case <-c.stop:
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,111 @@
+package mq
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestReadErrorClassification(t *testing.T) {
+	tests := []struct {
+		name     string
+		send     []byte // Written by the server after CONNACK; nil closes abruptly
+		wantErr  error  // nil means a plain connection-lost error
+		wantCode ReasonCode
+	}{
+		{
+			name: "connection closed",
+		},
+		{
+			name:     "malformed packet",
+			send:     []byte{0x30, 0x01, 0x00}, // PUBLISH too short for its topic
+			wantErr:  ErrMalformedPacket,
+			wantCode: ReasonCodeMalformedPacket,
+		},
+		{
+			name:     "malformed remaining length",
+			send:     []byte{0x30, 0xFF, 0xFF, 0xFF, 0xFF, 0x7F},
+			wantErr:  ErrMalformedPacket,
+			wantCode: ReasonCodeMalformedPacket,
+		},
+		{
+			name:     "protocol error",
+			send:     []byte{0xD1, 0x00}, // PINGRESP with reserved flags set
+			wantErr:  ErrProtocolViolation,
+			wantCode: ReasonCodeProtocolError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+
+			disconnect := make(chan *packets.DisconnectPacket, 1)
+			go func() {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				_, _ = packets.ReadPacket(conn, ProtocolV50, 0)
+				_, _ = (&packets.ConnackPacket{ReturnCode: 0}).WriteTo(conn)
+				if tt.send == nil {
+					return
+				}
+				_, _ = conn.Write(tt.send)
+				if pkt, err := packets.ReadPacket(conn, ProtocolV50, 0); err == nil {
+					if disc, ok := pkt.(*packets.DisconnectPacket); ok {
+						disconnect <- disc
+					}
+				}
+			}()
+
+			lost := make(chan error, 1)
+			client, err := Dial("tcp://"+ln.Addr().String(),
+				WithAutoReconnect(false),
+				WithOnConnectionLost(func(_ *Client, err error) { lost <- err }),
+			)
+			if err != nil {
+				t.Fatalf("Dial failed: %v", err)
+			}
+			defer func() { _ = client.Disconnect(t.Context()) }()
+
+			var reason error
+			select {
+			case reason = <-lost:
+			case <-time.After(2 * time.Second):
+				t.Fatal("timeout waiting for OnConnectionLost")
+			}
+
+			if tt.wantErr == nil {
+				if errors.Is(reason, ErrMalformedPacket) || errors.Is(reason, ErrProtocolViolation) {
+					t.Errorf("network error classified as a packet error: %v", reason)
+				}
+				if !strings.Contains(reason.Error(), "connection lost") {
+					t.Errorf("expected a connection lost error, got %v", reason)
+				}
+				return
+			}
+
+			if !errors.Is(reason, tt.wantErr) {
+				t.Errorf("OnConnectionLost error = %v, want %v", reason, tt.wantErr)
+			}
+			select {
+			case disc := <-disconnect:
+				if disc.ReasonCode != uint8(tt.wantCode) {
+					t.Errorf("DISCONNECT reason code = 0x%02x, want 0x%02x", disc.ReasonCode, uint8(tt.wantCode))
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("timeout waiting for DISCONNECT")
+			}
+		})
+	}
+}
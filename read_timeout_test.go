@@ -0,0 +1,135 @@
+package mq
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestWithReadTimeout(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	if opts.ReadTimeout != 0 {
+		t.Errorf("default ReadTimeout = %v, want 0", opts.ReadTimeout)
+	}
+	WithReadTimeout(time.Minute)(opts)
+	if opts.ReadTimeout != time.Minute {
+		t.Errorf("ReadTimeout = %v, want 1m", opts.ReadTimeout)
+	}
+	WithReadTimeout(-time.Second)(opts)
+	if opts.ReadTimeout != time.Minute {
+		t.Errorf("negative timeout changed ReadTimeout to %v", opts.ReadTimeout)
+	}
+
+	c := &Client{opts: &clientOptions{KeepAlive: 10 * time.Second}}
+	if got := c.readTimeout(); got != 15*time.Second {
+		t.Errorf("readTimeout() = %v, want 1.5x keepalive", got)
+	}
+	c.opts.ReadTimeout = 3 * time.Second
+	if got := c.readTimeout(); got != 3*time.Second {
+		t.Errorf("readTimeout() = %v, want 3s", got)
+	}
+}
+
+// TestReadTimeoutWithoutKeepAlive verifies that WithKeepAlive(0) combined
+// with WithReadTimeout disconnects a silent connection without sending any
+// PINGREQ.
+func TestReadTimeoutWithoutKeepAlive(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	client := &Client{
+		opts: &clientOptions{
+			ReadTimeout:     200 * time.Millisecond,
+			Server:          "tcp://test:1883",
+			Logger:          testLogger(),
+			ProtocolVersion: ProtocolV311,
+		},
+		conn:           clientConn,
+		outgoing:       make(chan packets.Packet, 10),
+		packetReceived: make(chan struct{}, 1),
+		stop:           make(chan struct{}),
+		disconnected:   make(chan struct{}, 1),
+	}
+	client.connected.Store(true)
+
+	var written atomic.Int64
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, err := serverConn.Read(buf)
+			if err != nil {
+				return
+			}
+			written.Add(int64(n))
+		}
+	}()
+
+	done := make(chan struct{})
+	client.wg.Add(1)
+	go func() {
+		client.writeLoop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		close(client.stop)
+		t.Fatal("writeLoop did not exit after the read timeout")
+	}
+
+	if client.IsConnected() {
+		t.Error("client should be marked as disconnected")
+	}
+	if n := written.Load(); n != 0 {
+		t.Errorf("wrote %d bytes, want no PINGREQ with keepalive disabled", n)
+	}
+	if got := client.pingsSent.Load(); got != 0 {
+		t.Errorf("pingsSent = %d, want 0", got)
+	}
+}
+
+// TestReadTimeoutPrevented verifies that received packets reset the read
+// timeout.
+func TestReadTimeoutPrevented(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	client := &Client{
+		opts: &clientOptions{
+			ReadTimeout:     200 * time.Millisecond,
+			Server:          "tcp://test:1883",
+			Logger:          testLogger(),
+			ProtocolVersion: ProtocolV311,
+		},
+		conn:           clientConn,
+		outgoing:       make(chan packets.Packet, 10),
+		packetReceived: make(chan struct{}, 1),
+		stop:           make(chan struct{}),
+	}
+	client.connected.Store(true)
+
+	done := make(chan struct{})
+	client.wg.Add(1)
+	go func() {
+		client.writeLoop()
+		close(done)
+	}()
+
+	stopReceiving := make(chan struct{})
+	go keepReceiving(client, stopReceiving)
+
+	select {
+	case <-done:
+		t.Fatal("writeLoop exited although packets were received")
+	case <-time.After(500 * time.Millisecond):
+	}
+	close(stopReceiving)
+	close(client.stop)
+	<-done
+}
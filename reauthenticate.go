@@ -7,11 +7,14 @@ import (
 	"github.com/gonzalop/mq/internal/packets"
 )
 
-// Reauthenticate initiates re-authentication with the server (MQTT v5.0).
+// Reauthenticate re-authenticates with the server without dropping the
+// connection (MQTT v5.0).
 //
 // This sends an AUTH packet with reason code 0x19 (Re-authenticate) to start
-// a new authentication exchange. The authenticator's HandleChallenge method
-// will be called for each challenge from the server.
+// a new authentication exchange, using the configured Authenticator. If the
+// authenticator implements AuthenticatorResetter, Reset is called first.
+// The authenticator's HandleChallenge method is called for each challenge
+// from the server, and Complete when the server reports success.
 //
 // Re-authentication is useful for:
 //   - Refreshing expired tokens
@@ -22,13 +25,19 @@ import (
 // Packets (such as PUBLISH) during the re-authentication exchange. The
 // connection remains fully functional.
 //
-// This method returns immediately. Authentication happens asynchronously.
-// Use the authenticator's Complete method to know when it succeeds.
+// Reauthenticate blocks until the exchange completes: it returns nil once the
+// server sends AUTH with reason code 0x00 (Success). A server that rejects
+// the new credentials closes the connection with a DISCONNECT; Reauthenticate
+// then returns an error wrapping the *DisconnectError, and the client
+// reconnects as usual if AutoReconnect is enabled. If ctx is done first,
+// ctx.Err() is returned; the exchange itself is not aborted.
 //
 // Returns an error if:
 //   - Not using MQTT v5.0
 //   - No authenticator configured
 //   - Not connected
+//   - Another re-authentication is in progress
+//   - The authenticator fails, or the server rejects the credentials
 //
 // Example:
 //
@@ -36,13 +45,14 @@ import (
 //	ticker := time.NewTicker(30 * time.Minute)
 //	go func() {
 //	    for range ticker.C {
-//	        if err := client.Reauthenticate(context.Background()); err != nil {
+//	        ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	        if err := client.Reauthenticate(ctx); err != nil {
 //	            log.Printf("Re-authentication failed: %v", err)
 //	        }
+//	        cancel()
 //	    }
 //	}()
-func (c *Client) Reauthenticate(_ context.Context) error {
-	// ctx is currently unused but kept for future use and API consistency.
+func (c *Client) Reauthenticate(ctx context.Context) error {
 	if c.opts.ProtocolVersion < ProtocolV50 {
 		return fmt.Errorf("re-authentication requires MQTT v5.0")
 	}
@@ -55,7 +65,18 @@ func (c *Client) Reauthenticate(_ context.Context) error {
 		return fmt.Errorf("not connected")
 	}
 
+	done := make(chan error, 1)
+	c.reauthLock.Lock()
+	if c.reauthDone != nil {
+		c.reauthLock.Unlock()
+		return fmt.Errorf("re-authentication already in progress")
+	}
+	c.reauthDone = done
+	c.reauthLock.Unlock()
+	defer c.abandonReauth(done)
+
 	// Get initial data for re-auth
+	c.resetAuthenticator()
 	initialData, err := c.opts.Authenticator.InitialData()
 	if err != nil {
 		return fmt.Errorf("failed to get re-auth data: %w", err)
@@ -73,8 +94,43 @@ func (c *Client) Reauthenticate(_ context.Context) error {
 	}
 
 	c.authExchangeCount.Store(0)
-	c.outgoing <- authPkt
+	select {
+	case c.outgoing <- authPkt:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.stop:
+		return ErrClientDisconnected
+	}
 	c.opts.Logger.Debug("initiated re-authentication", "method", c.opts.Authenticator.Method())
 
-	return nil
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.stop:
+		return ErrClientDisconnected
+	}
+}
+
+// finishReauth reports the outcome of the re-authentication in progress,
+// if any.
+func (c *Client) finishReauth(err error) {
+	c.reauthLock.Lock()
+	defer c.reauthLock.Unlock()
+
+	if c.reauthDone != nil {
+		c.reauthDone <- err // Buffered, receives at most one value
+		c.reauthDone = nil
+	}
+}
+
+// abandonReauth stops waiting for the outcome delivered to done.
+func (c *Client) abandonReauth(done chan error) {
+	c.reauthLock.Lock()
+	defer c.reauthLock.Unlock()
+
+	if c.reauthDone == done {
+		c.reauthDone = nil
+	}
 }
@@ -0,0 +1,163 @@
+package mq
+
+import (
+	"context"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// waitForReceiveCapacity blocks the read loop while ReceiveMaximum messages
+// are still being processed (LimitPolicyBackpressure).
+// Returns false if the client is stopping or the connection is torn down.
+func (c *Client) waitForReceiveCapacity(connDone <-chan struct{}) bool {
+	limit := int(c.opts.ReceiveMaximum)
+	if limit == 0 {
+		limit = 65535
+	}
+
+	for {
+		c.sessionLock.Lock()
+		processing := c.inboundProcessing
+		c.sessionLock.Unlock()
+
+		if processing < limit {
+			return true
+		}
+
+		select {
+		case <-c.inboundAcked:
+		case <-connDone:
+			return false
+		case <-c.stop:
+			return false
+		}
+	}
+}
+
+// sendDeferredAck sends the PUBACK (QoS 1) or PUBREC (QoS 2) for a message
+// whose acknowledgment was held back until its handlers returned, and wakes
+// up the read loop if it is waiting for capacity. gen is the inboundGen of
+// the connection the message arrived on; the ack is dropped if that
+// connection is gone, since the packet ID means nothing on the new one and
+// the server redelivers the message.
+func (c *Client) sendDeferredAck(packetID uint16, qos uint8, gen uint64) {
+	if c.withholdAcks.Load() {
+		c.withholdAck(packetID, qos, gen)
+		return
+	}
+
+	c.sessionLock.Lock()
+	stale := gen != c.inboundGen
+	c.sessionLock.Unlock()
+	if stale {
+		c.opts.Logger.Debug("dropping ack from a previous connection", "packet_id", packetID, "qos", qos)
+		return
+	}
+
+	var ack packets.Packet
+	if qos == 1 {
+		ack = &packets.PubackPacket{PacketID: packetID}
+	} else {
+		ack = &packets.PubrecPacket{PacketID: packetID}
+	}
+
+	select {
+	case c.outgoing <- ack:
+	case <-c.stop:
+		return
+	}
+
+	c.sessionLock.Lock()
+	if gen == c.inboundGen {
+		if qos == 1 {
+			// QoS 2 stays unacked until PUBREL arrives
+			delete(c.inboundUnacked, packetID)
+		} else {
+			c.receivedQoS2[packetID] = struct{}{}
+			c.persistReceivedQoS2(packetID)
+		}
+		c.inboundProcessing--
+	}
+	c.sessionLock.Unlock()
+
+	select {
+	case c.inboundAcked <- struct{}{}:
+	default:
+	}
+}
+
+// waitForDeferredAcks waits up to timeout for the handlers of messages with a
+// deferred acknowledgment to return, so their acks are sent before the
+// DISCONNECT (see WithShutdownAckTimeout).
+func (c *Client) waitForDeferredAcks(ctx context.Context, timeout time.Duration) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		c.sessionLock.Lock()
+		processing := c.inboundProcessing
+		c.sessionLock.Unlock()
+
+		if processing == 0 {
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			c.opts.Logger.Warn("shutdown ack timeout expired, unacknowledged messages will be redelivered",
+				"processing", processing)
+			return
+		case <-ctx.Done():
+			return
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// withholdAck drops the acknowledgment of a message whose handlers returned
+// after Disconnect, leaving the message for the server to redeliver.
+func (c *Client) withholdAck(packetID uint16, qos uint8, gen uint64) {
+	c.opts.Logger.Debug("withholding ack after disconnect", "packet_id", packetID, "qos", qos)
+
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+
+	if gen != c.inboundGen {
+		return
+	}
+	c.inboundProcessing--
+}
+
+// resetInbound starts tracking the messages of a new connection. Deferred
+// acks of the previous one are dropped when sent (see sendDeferredAck), and
+// the messages they belong to no longer count against ReceiveMaximum: the
+// server redelivers them. It acquires the session lock.
+func (c *Client) resetInbound() {
+	c.sessionLock.Lock()
+	c.inboundGen++
+	c.inboundProcessing = 0
+	clear(c.inboundUnacked)
+	c.receiveMaxExceededLogged = false
+	c.sessionLock.Unlock()
+
+	select {
+	case c.inboundAcked <- struct{}{}:
+	default:
+	}
+}
+
+// persistReceivedQoS2 saves a received QoS 2 packet ID to the session store,
+// if any. Must be called with sessionLock held.
+func (c *Client) persistReceivedQoS2(packetID uint16) {
+	if c.opts.SessionStore == nil {
+		return
+	}
+	if err := c.opts.SessionStore.SaveReceivedQoS2(packetID); err != nil {
+		c.opts.Logger.Warn("failed to persist QoS2 ID", "packet_id", packetID, "error", err)
+	}
+}
@@ -0,0 +1,223 @@
+package mq
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestReceiveMaximum_BackpressureDefersAck(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	opts.ReceiveMaximum = 1
+	opts.ReceiveMaximumPolicy = LimitPolicyBackpressure
+	c := newTestClient(opts)
+	c.inboundAcked = make(chan struct{}, 1)
+
+	release := make(chan struct{})
+	c.subscriptions["t"] = subscriptionEntry{
+		handler: func(_ *Client, _ Message) { <-release },
+	}
+
+	c.sessionLock.Lock()
+	c.handleIncoming(&packets.PublishPacket{Topic: "t", QoS: 1, PacketID: 7})
+	c.sessionLock.Unlock()
+
+	select {
+	case pkt := <-c.outgoing:
+		t.Fatalf("expected ack to be deferred, got %T", pkt)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// At capacity: the read loop must wait
+	waitDone := make(chan bool, 1)
+	go func() { waitDone <- c.waitForReceiveCapacity(nil) }()
+
+	select {
+	case <-waitDone:
+		t.Fatal("expected waitForReceiveCapacity to block at capacity")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+
+	select {
+	case pkt := <-c.outgoing:
+		ack, ok := pkt.(*packets.PubackPacket)
+		if !ok || ack.PacketID != 7 {
+			t.Fatalf("expected PUBACK for packet 7, got %#v", pkt)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for deferred PUBACK")
+	}
+
+	select {
+	case ok := <-waitDone:
+		if !ok {
+			t.Error("expected waitForReceiveCapacity to report capacity")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waitForReceiveCapacity did not resume after ack")
+	}
+
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+	if c.inboundProcessing != 0 {
+		t.Errorf("expected 0 messages processing, got %d", c.inboundProcessing)
+	}
+	if len(c.inboundUnacked) != 0 {
+		t.Errorf("expected 0 unacked, got %d", len(c.inboundUnacked))
+	}
+}
+
+func TestReceiveMaximum_BackpressurePausesReadLoop(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	const total = 20
+	const limit = 2
+
+	var acks atomic.Int32
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+			return
+		}
+		connack := &packets.ConnackPacket{ReturnCode: 0}
+		if _, err := connack.WriteTo(conn); err != nil {
+			return
+		}
+
+		// Misbehave on purpose: send everything at once, ignoring the limit
+		for i := 1; i <= total; i++ {
+			pub := &packets.PublishPacket{
+				Topic:    "load",
+				QoS:      1,
+				PacketID: uint16(i),
+				Payload:  []byte("x"),
+				Version:  ProtocolV50,
+			}
+			if _, err := pub.WriteTo(conn); err != nil {
+				return
+			}
+		}
+
+		for acks.Load() < total {
+			pkt, err := packets.ReadPacket(conn, ProtocolV50, 0)
+			if err != nil {
+				return
+			}
+			if _, ok := pkt.(*packets.PubackPacket); ok {
+				acks.Add(1)
+			}
+		}
+	}()
+
+	var delivered atomic.Int32
+	release := make(chan struct{})
+	var once sync.Once
+
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("backpressure"),
+		WithAutoReconnect(false),
+		WithIncomingQueueSize(1),
+		WithReceiveMaximum(limit, LimitPolicyBackpressure),
+		WithDefaultPublishHandler(func(_ *Client, _ Message) {
+			delivered.Add(1)
+			<-release
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+	defer once.Do(func() { close(release) })
+
+	// Wait until the reader stops making progress
+	deadline := time.Now().Add(2 * time.Second)
+	for delivered.Load() < limit && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	if got := delivered.Load(); got >= total {
+		t.Fatalf("expected read loop to pause, but all %d messages were delivered", got)
+	}
+	if got := acks.Load(); got != 0 {
+		t.Fatalf("expected no PUBACKs while handlers are blocked, got %d", got)
+	}
+
+	once.Do(func() { close(release) })
+
+	select {
+	case <-serverDone:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout: delivered=%d acks=%d", delivered.Load(), acks.Load())
+	}
+
+	if got := delivered.Load(); got != total {
+		t.Errorf("expected %d messages delivered after resume, got %d", total, got)
+	}
+	if got := acks.Load(); got != total {
+		t.Errorf("expected %d PUBACKs after resume, got %d", total, got)
+	}
+}
+
+func TestReceiveMaximum_DeferredAckDroppedAfterReconnect(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	opts.ReceiveMaximum = 1
+	opts.ReceiveMaximumPolicy = LimitPolicyBackpressure
+	c := newTestClient(opts)
+	c.inboundAcked = make(chan struct{}, 1)
+
+	release := make(chan struct{})
+	handled := make(chan struct{})
+	c.subscriptions["t"] = subscriptionEntry{
+		handler: func(_ *Client, _ Message) {
+			<-release
+			close(handled)
+		},
+	}
+
+	c.sessionLock.Lock()
+	c.handleIncoming(&packets.PublishPacket{Topic: "t", QoS: 1, PacketID: 7})
+	c.sessionLock.Unlock()
+
+	// The connection is replaced while the handler runs: the message no
+	// longer uses up the receive window...
+	c.resetInbound()
+	if !c.waitForReceiveCapacity(nil) {
+		t.Fatal("expected capacity after reconnect")
+	}
+
+	// ...and its ack is not sent on the new connection
+	close(release)
+	<-handled
+	select {
+	case pkt := <-c.outgoing:
+		t.Fatalf("expected stale ack to be dropped, got %#v", pkt)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+	if c.inboundProcessing != 0 {
+		t.Errorf("expected 0 messages processing, got %d", c.inboundProcessing)
+	}
+}
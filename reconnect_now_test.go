@@ -0,0 +1,84 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestReconnectNow(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	accepted := make(chan int, 4)
+	go serveDroppingConnection(ln, accepted)
+
+	// A backoff far longer than the test: only ReconnectNow can reconnect
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("reconnect-now"),
+		WithReconnectBackoff(time.Hour, time.Hour, 1),
+		WithLogger(testLogger()),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	if err := client.ReconnectNow(); err != nil {
+		t.Errorf("ReconnectNow while connected = %v, want nil", err)
+	}
+
+	<-accepted
+	deadline := time.Now().Add(2 * time.Second)
+	for client.IsConnected() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if client.IsConnected() {
+		t.Fatal("expected the first connection to drop")
+	}
+
+	if err := client.ReconnectNow(); err != nil {
+		t.Fatalf("ReconnectNow failed: %v", err)
+	}
+
+	select {
+	case n := <-accepted:
+		if n != 2 {
+			t.Fatalf("expected connection 2, got %d", n)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("ReconnectNow did not interrupt the backoff")
+	}
+
+	deadline = time.Now().Add(2 * time.Second)
+	for !client.IsConnected() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !client.IsConnected() {
+		t.Fatal("client did not reconnect")
+	}
+	if got := client.ReconnectBackoff(); got != time.Hour {
+		t.Errorf("ReconnectBackoff() = %v, want the initial %v", got, time.Hour)
+	}
+
+	// A stopped client cannot be reconnected
+	_ = client.Disconnect(context.Background())
+	if err := client.ReconnectNow(); !errors.Is(err, ErrClientDisconnected) {
+		t.Errorf("ReconnectNow after Disconnect = %v, want ErrClientDisconnected", err)
+	}
+}
+
+func TestReconnectNow_WithoutAutoReconnect(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.AutoReconnect = false
+	c := newTestClient(opts)
+
+	if err := c.ReconnectNow(); err == nil {
+		t.Error("expected an error without automatic reconnection")
+	}
+}
@@ -0,0 +1,99 @@
+package mq
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// defaultResponseTopicPrefix is the prefix of the response topics of Request
+// when the server provides no Response Information.
+const defaultResponseTopicPrefix = "mq/responses"
+
+// Request sends a request and waits for its response, using the MQTT v5.0
+// request/response properties.
+//
+// Request subscribes to a temporary response topic, publishes payload to
+// topic with that Response Topic and freshly generated Correlation Data, and
+// returns the first message received on the response topic that carries the
+// same Correlation Data. The response topic is placed under the server's
+// Response Information when the server provides it (see
+// ResponseInformation and WithRequestResponseInformation), and under
+// "mq/responses" otherwise. The temporary subscription is removed before
+// Request returns.
+//
+// The responder is expected to publish its answer to the request's Response
+// Topic and to copy its Correlation Data, as in:
+//
+//	client.Subscribe("rpc/time", mq.AtLeastOnce, func(c *mq.Client, msg mq.Message) {
+//	    c.Publish(msg.Properties.ResponseTopic, []byte(time.Now().String()),
+//	        mq.WithCorrelationData(msg.Properties.CorrelationData))
+//	})
+//
+// opts apply to the request publish (e.g. WithQoS or WithContentType); the
+// Response Topic and Correlation Data are always set by Request. Request
+// returns ctx.Err() if ctx is done before the response arrives, and an error
+// if the subscription or the publish fail or the connection is not MQTT v5.0.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//	resp, err := client.Request(ctx, "rpc/time", nil, mq.WithQoS(1))
+//	if err != nil {
+//	    return err
+//	}
+//	fmt.Printf("server time: %s\n", resp.Payload)
+func (c *Client) Request(ctx context.Context, topic string, payload []byte, opts ...PublishOption) (Message, error) {
+	if c.opts.ProtocolVersion < ProtocolV50 {
+		return Message{}, fmt.Errorf("request/response requires MQTT v5.0")
+	}
+
+	correlation := make([]byte, 16)
+	if _, err := rand.Read(correlation); err != nil {
+		return Message{}, fmt.Errorf("failed to generate correlation data: %w", err)
+	}
+	responseTopic := c.responseTopicPrefix() + "/" + hex.EncodeToString(correlation)
+
+	responses := make(chan Message, 1)
+	handler := func(_ *Client, msg Message) {
+		if msg.Properties == nil || string(msg.Properties.CorrelationData) != string(correlation) {
+			return // Not ours
+		}
+		select {
+		case responses <- msg:
+		default: // Already answered
+		}
+	}
+
+	if err := c.Subscribe(responseTopic, AtLeastOnce, handler).Wait(ctx); err != nil {
+		c.Unsubscribe(responseTopic)
+		return Message{}, fmt.Errorf("failed to subscribe to response topic: %w", err)
+	}
+	defer c.Unsubscribe(responseTopic)
+
+	opts = append(opts[:len(opts):len(opts)],
+		WithResponseTopic(responseTopic),
+		WithCorrelationData(correlation))
+	if err := c.PublishContext(ctx, topic, payload, opts...).Wait(ctx); err != nil {
+		return Message{}, fmt.Errorf("failed to publish request: %w", err)
+	}
+
+	select {
+	case msg := <-responses:
+		return msg, nil
+	case <-ctx.Done():
+		return Message{}, ctx.Err()
+	}
+}
+
+// responseTopicPrefix returns the topic under which Request places its
+// response topics.
+func (c *Client) responseTopicPrefix() string {
+	if info := strings.TrimRight(c.ResponseInformation(), "/"); info != "" {
+		return info
+	}
+	return defaultResponseTopicPrefix
+}
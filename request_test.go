@@ -0,0 +1,158 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// serveResponder starts a server that answers every PUBLISH to "rpc/echo" on
+// its Response Topic, after a response with other Correlation Data. It
+// reports the topics of UNSUBSCRIBE packets on the returned channel.
+func serveResponder(t *testing.T, responseInfo string) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	unsubscribed := make(chan string, 4)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+			return
+		}
+		connack := &packets.ConnackPacket{}
+		if responseInfo != "" {
+			connack.Properties = &packets.Properties{ResponseInformation: responseInfo, Presence: packets.PresResponseInformation}
+		}
+		_, _ = connack.WriteTo(conn)
+
+		for {
+			pkt, err := packets.ReadPacket(conn, ProtocolV50, 0)
+			if err != nil {
+				return
+			}
+			switch p := pkt.(type) {
+			case *packets.SubscribePacket:
+				_, _ = (&packets.SubackPacket{PacketID: p.PacketID, ReturnCodes: []uint8{1}, Version: ProtocolV50}).WriteTo(conn)
+			case *packets.UnsubscribePacket:
+				_, _ = (&packets.UnsubackPacket{PacketID: p.PacketID, ReasonCodes: []uint8{0}, Version: ProtocolV50}).WriteTo(conn)
+				unsubscribed <- p.Topics[0]
+			case *packets.PublishPacket:
+				if p.QoS > 0 {
+					_, _ = (&packets.PubackPacket{PacketID: p.PacketID, Version: ProtocolV50}).WriteTo(conn)
+				}
+				if p.Topic != "rpc/echo" || p.Properties == nil {
+					continue
+				}
+				for _, correlation := range [][]byte{[]byte("someone else"), p.Properties.CorrelationData} {
+					resp := &packets.PublishPacket{
+						Topic:   p.Properties.ResponseTopic,
+						Payload: append([]byte("echo: "), p.Payload...),
+						Properties: &packets.Properties{
+							CorrelationData: correlation,
+							Presence:        packets.PresCorrelationData,
+						},
+						Version: ProtocolV50,
+					}
+					_, _ = resp.WriteTo(conn)
+				}
+			case *packets.DisconnectPacket:
+				return
+			}
+		}
+	}()
+	return "tcp://" + ln.Addr().String(), unsubscribed
+}
+
+func TestRequest(t *testing.T) {
+	tests := []struct {
+		name         string
+		responseInfo string
+		wantPrefix   string
+	}{
+		{"default response topic", "", "mq/responses/"},
+		{"server response information", "replies/client-7/", "replies/client-7/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, unsubscribed := serveResponder(t, tt.responseInfo)
+			client, err := Dial(server, WithClientID("requester"), WithLogger(testLogger()))
+			if err != nil {
+				t.Fatalf("Dial failed: %v", err)
+			}
+			defer func() { _ = client.Disconnect(context.Background()) }()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			resp, err := client.Request(ctx, "rpc/echo", []byte("ping"), WithQoS(AtLeastOnce))
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			if string(resp.Payload) != "echo: ping" {
+				t.Errorf("response payload = %q", resp.Payload)
+			}
+			if !strings.HasPrefix(resp.Topic, tt.wantPrefix) {
+				t.Errorf("response topic %q does not start with %q", resp.Topic, tt.wantPrefix)
+			}
+
+			select {
+			case topic := <-unsubscribed:
+				if topic != resp.Topic {
+					t.Errorf("unsubscribed from %q, want %q", topic, resp.Topic)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("response topic was not unsubscribed")
+			}
+			client.sessionLock.Lock()
+			subs := len(client.subscriptions)
+			client.sessionLock.Unlock()
+			if subs != 0 {
+				t.Errorf("expected no subscriptions left, got %d", subs)
+			}
+		})
+	}
+}
+
+func TestRequest_Timeout(t *testing.T) {
+	server, unsubscribed := serveResponder(t, "")
+	client, err := Dial(server, WithClientID("requester"), WithLogger(testLogger()))
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := client.Request(ctx, "rpc/nobody", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	select {
+	case <-unsubscribed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("response topic was not unsubscribed after the timeout")
+	}
+}
+
+func TestRequest_RequiresV5(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.ProtocolVersion = ProtocolV311
+	c := newTestClient(opts)
+
+	if _, err := c.Request(context.Background(), "rpc/echo", nil); err == nil {
+		t.Fatal("expected an error for MQTT v3.1.1")
+	}
+}
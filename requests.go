@@ -1,106 +1,239 @@
 package mq
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"maps"
+	"slices"
 	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
 )
 
 // internalPublish processes a publish request synchronously with locking.
 func (c *Client) internalPublish(req *publishRequest) {
-	pkt := req.packet
+	if req.ctx != nil && req.ctx.Err() != nil {
+		req.token.complete(req.ctx.Err())
+		return
+	}
 
 	c.sessionLock.Lock()
+	ready := c.admitPublishLocked(req)
+	c.sessionLock.Unlock()
 
-	// Validate packet size against server's maximum (fail-fast)
-	if c.serverCaps.MaximumPacketSize > 0 {
-		n, _ := pkt.WriteTo(io.Discard)
-		packetSize := uint32(n)
+	if ready {
+		c.enqueuePublish(req)
+	}
+}
 
-		if packetSize > c.serverCaps.MaximumPacketSize {
-			req.token.complete(fmt.Errorf("packet size %d bytes exceeds server maximum %d bytes",
-				packetSize, c.serverCaps.MaximumPacketSize))
-			c.sessionLock.Unlock()
-			return
-		}
+// admitPublishLocked validates a publish against the server limits and, for
+// QoS > 0, applies flow control and registers it as pending. It reports
+// whether the packet is ready to be queued for the network with
+// enqueuePublish; otherwise its token was completed or it was queued until
+// ReceiveMaximum allows sending it. Must be called with sessionLock held,
+// which is released while waiting for in-flight byte capacity.
+func (c *Client) admitPublishLocked(req *publishRequest) bool {
+	pkt := req.packet
+
+	// Validate packet size against server's maximum (fail-fast)
+	if err := c.checkPublishSize(pkt); err != nil {
+		req.token.complete(err)
+		return false
 	}
 
 	// Enforce RetainAvailable validation (fail-fast)
 	if pkt.Retain && !c.serverCaps.RetainAvailable {
-		req.token.complete(fmt.Errorf("server does not support retained messages"))
-		c.sessionLock.Unlock()
-		return
+		req.token.complete(ErrRetainNotSupported)
+		return false
 	}
 
 	// Enforce MaximumQoS validation (fail-fast)
 	if pkt.QoS > c.serverCaps.MaximumQoS {
 		req.token.complete(fmt.Errorf("qos %d exceeds server maximum %d",
 			pkt.QoS, c.serverCaps.MaximumQoS))
-		c.sessionLock.Unlock()
-		return
+		return false
 	}
 
 	if pkt.QoS == 0 {
-		c.sessionLock.Unlock()
-		if c.opts.QoS0Policy == QoS0LimitPolicyBlock {
-			select {
-			case c.outgoing <- pkt:
-				req.token.complete(nil)
-			case <-c.stop:
-				req.token.complete(ErrClientDisconnected)
-			}
-			return
-		}
+		return true
+	}
 
-		// Default Drop behavior
-		select {
-		case c.outgoing <- pkt:
-			req.token.complete(nil)
-		case <-c.stop:
+	// Payload byte budget for QoS > 0 (may wait for acknowledgments)
+	if !c.reserveInflightBytes(len(pkt.Payload), req.done()) {
+		if req.ctx != nil && req.ctx.Err() != nil {
+			req.token.complete(req.ctx.Err())
+		} else {
 			req.token.complete(ErrClientDisconnected)
-		default:
-			// Channel full, drop QoS 0 message (at most once)
-			req.token.dropped = true
-			req.token.complete(nil)
 		}
-		return
+		return false
 	}
 
 	// Flow control for QoS > 0
 	if c.serverCaps.ReceiveMaximum > 0 {
 		if c.inFlightCount >= int(c.serverCaps.ReceiveMaximum) {
-			c.publishQueue = append(c.publishQueue, req)
-			c.sessionLock.Unlock()
-			return
+			c.queuePublishLocked(req)
+			return false
 		}
 	}
 
-	pkt.PacketID = c.nextID()
+	// Every packet ID in use: wait for one to be released, like above
+	id, ok := c.nextID()
+	if !ok {
+		c.queuePublishLocked(req)
+		return false
+	}
+	pkt.PacketID = id
+	req.token.packetID.Store(uint32(pkt.PacketID))
 
 	c.pending[pkt.PacketID] = &pendingOp{
 		packet:    pkt,
 		token:     req.token,
 		qos:       pkt.QoS,
 		timestamp: time.Now(),
+		created:   time.Now(),
+		size:      len(pkt.Payload),
 	}
 
-	if pkt.QoS > 0 {
-		c.inFlightCount++
-	}
+	c.setInFlightCount(c.inFlightCount + 1)
 
-	if c.opts.SessionStore != nil && pkt.QoS > 0 {
+	if c.opts.SessionStore != nil {
 		pub := c.convertToPersistedPublish(req)
 		if err := c.opts.SessionStore.SavePendingPublish(pkt.PacketID, pub); err != nil {
 			c.opts.Logger.Warn("failed to persist publish", "packet_id", pkt.PacketID, "error", err)
 		}
 	}
 
-	c.sessionLock.Unlock()
+	return true
+}
+
+// enqueuePublish hands a publish admitted by admitPublishLocked to the
+// network layer. Must be called without sessionLock held.
+func (c *Client) enqueuePublish(req *publishRequest) {
+	pkt := req.packet
+
+	if pkt.QoS == 0 {
+		var out packets.Packet = pkt
+		onEnqueue := func() { req.token.complete(nil) }
+		switch c.opts.QoS0TokenBehavior {
+		case QoS0TokenCompleteOnWrite:
+			out = &writeNotifyPacket{Packet: pkt, token: req.token}
+			onEnqueue = func() {}
+		case QoS0TokenNeverComplete:
+			req.token.complete(nil)
+		}
+
+		if c.opts.QoS0Policy == QoS0LimitPolicyBlock {
+			select {
+			case c.outgoing <- out:
+				c.observePublished(pkt)
+				onEnqueue()
+			case <-c.stop:
+				req.token.complete(c.stopError())
+			case <-req.done():
+				req.token.complete(req.ctx.Err())
+			}
+			return
+		}
+
+		// Default Drop behavior
+		select {
+		case c.outgoing <- out:
+			c.observePublished(pkt)
+			onEnqueue()
+		case <-c.stop:
+			req.token.complete(c.stopError())
+		default:
+			// Channel full, drop QoS 0 message (at most once)
+			if c.opts.QoS0TokenBehavior != QoS0TokenNeverComplete {
+				req.token.dropped = true
+			}
+			req.token.complete(nil)
+		}
+		return
+	}
+
 	select {
 	case c.outgoing <- pkt:
+		c.observePublished(pkt)
 	case <-c.stop:
-		req.token.complete(fmt.Errorf("client stopped"))
+		req.token.complete(c.stopError())
+	case <-req.done():
+		c.sessionLock.Lock()
+		c.abandonPendingPublish(pkt.PacketID)
+		c.sessionLock.Unlock()
+		req.token.complete(req.ctx.Err())
+	}
+}
+
+// observePublished reports the payload size of a publish queued for the
+// network to the Metrics, if any.
+func (c *Client) observePublished(pkt *packets.PublishPacket) {
+	if c.opts.Metrics != nil {
+		c.opts.Metrics.ObservePublishSize(len(pkt.Payload))
+	}
+}
+
+// queuePublishLocked queues a publish until ReceiveMaximum allows sending
+// it and a packet ID is free. If the request has a context, it is withdrawn from the queue when the
+// context is done. Must be called with sessionLock held.
+func (c *Client) queuePublishLocked(req *publishRequest) {
+	c.publishQueue = append(c.publishQueue, req)
+	if req.done() == nil {
+		return
 	}
+
+	req.stopCancel = context.AfterFunc(req.ctx, func() {
+		c.sessionLock.Lock()
+		defer c.sessionLock.Unlock()
+
+		i := slices.Index(c.publishQueue, req)
+		if i < 0 {
+			return // Sent meanwhile
+		}
+		c.publishQueue = slices.Delete(c.publishQueue, i, i+1)
+		c.releaseInflightBytes(len(req.packet.Payload))
+		c.signalPendingSettled()
+		req.token.complete(req.ctx.Err())
+	})
+}
+
+// checkPublishSize returns an error wrapping ErrPacketTooLarge if pkt
+// exceeds the server's Maximum Packet Size. The encoded size includes the
+// fixed header, remaining-length varint, topic and properties; sending an
+// oversized packet would get the connection closed with 0x95.
+func (c *Client) checkPublishSize(pkt *packets.PublishPacket) error {
+	if c.serverCaps.MaximumPacketSize == 0 {
+		return nil
+	}
+	n, _ := pkt.WriteTo(io.Discard)
+	if packetSize := uint32(n); packetSize > c.serverCaps.MaximumPacketSize {
+		return fmt.Errorf("%w: packet size %d bytes exceeds server maximum %d bytes",
+			ErrPacketTooLarge, packetSize, c.serverCaps.MaximumPacketSize)
+	}
+	return nil
+}
+
+// abandonPendingPublish undoes the registration of a QoS 1/2 publish whose
+// context expired before the packet could be queued for the network. Must
+// be called with sessionLock held.
+func (c *Client) abandonPendingPublish(id uint16) {
+	op, ok := c.pending[id]
+	if !ok {
+		return
+	}
+	delete(c.pending, id)
+
+	if c.opts.SessionStore != nil {
+		if err := c.opts.SessionStore.DeletePendingPublish(id); err != nil {
+			c.opts.Logger.Warn("failed to delete pending publish", "packet_id", id, "error", err)
+		}
+	}
+
+	c.setInFlightCount(c.inFlightCount - 1)
+	c.releaseInflightBytes(op.size)
+	c.processPublishQueue()
+	c.signalPendingSettled()
 }
 
 // helper for sending - assumes lock is HELD
@@ -108,19 +241,28 @@ func (c *Client) internalPublish(req *publishRequest) {
 func (c *Client) sendPublishLocked(req *publishRequest) bool {
 	pkt := req.packet
 
-	pkt.PacketID = c.nextID()
+	id, ok := c.nextID()
+	if !ok {
+		// Every packet ID in use, retried when one is released
+		return false
+	}
+	pkt.PacketID = id
+	req.token.packetID.Store(uint32(pkt.PacketID))
 
 	c.pending[pkt.PacketID] = &pendingOp{
 		packet:    pkt,
 		token:     req.token,
 		qos:       pkt.QoS,
 		timestamp: time.Now(),
+		created:   time.Now(),
+		size:      len(pkt.Payload),
 	}
 
 	select {
 	case c.outgoing <- pkt:
+		c.observePublished(pkt)
 		if pkt.QoS > 0 {
-			c.inFlightCount++
+			c.setInFlightCount(c.inFlightCount + 1)
 		}
 
 		if c.opts.SessionStore != nil && pkt.QoS > 0 {
@@ -147,6 +289,11 @@ func (c *Client) sendPublishLocked(req *publishRequest) bool {
 
 // internalSubscribe processes a subscribe request synchronously with locking.
 func (c *Client) internalSubscribe(req *subscribeRequest) {
+	if req.ctx != nil && req.ctx.Err() != nil {
+		req.token.complete(req.ctx.Err())
+		return
+	}
+
 	pkt := req.packet
 
 	c.sessionLock.Lock()
@@ -156,27 +303,134 @@ func (c *Client) internalSubscribe(req *subscribeRequest) {
 		n, _ := pkt.WriteTo(io.Discard)
 		packetSize := uint32(n)
 		if packetSize > c.serverCaps.MaximumPacketSize {
-			req.token.complete(fmt.Errorf("SUBSCRIBE packet size %d bytes exceeds server maximum %d bytes",
-				packetSize, c.serverCaps.MaximumPacketSize))
+			req.token.complete(fmt.Errorf("%w: SUBSCRIBE packet size %d bytes exceeds server maximum %d bytes",
+				ErrPacketTooLarge, packetSize, c.serverCaps.MaximumPacketSize))
+			c.sessionLock.Unlock()
+			return
+		}
+	}
+
+	// Reject features the server does not support (fail-fast)
+	if err := c.checkSubscribeSupport(pkt); err != nil {
+		req.token.complete(err)
+		c.sessionLock.Unlock()
+		return
+	}
+
+	// Enforce the local subscription quota (fail-fast)
+	if c.opts.MaxSubscriptions > 0 {
+		active := len(c.subscriptions)
+		for _, topic := range pkt.Topics {
+			if _, exists := c.subscriptions[topic]; !exists {
+				active++
+			}
+		}
+		if active > c.opts.MaxSubscriptions {
+			req.token.complete(fmt.Errorf("%w: %d active, maximum %d",
+				ErrSubscriptionLimitExceeded, len(c.subscriptions), c.opts.MaxSubscriptions))
 			c.sessionLock.Unlock()
 			return
 		}
 	}
 
-	pkt.PacketID = c.nextID()
+	if !c.IsConnected() {
+		if c.opts.SubscribeWhileDisconnected == SubscribeWhileDisconnectedQueue && c.opts.AutoReconnect {
+			// Sent by resubscribeAll on reconnect; the token completes on
+			// its SUBACK
+			c.registerSubscriptions(req)
+			c.deferSubscribe(pkt.Topics, req.token)
+		} else {
+			req.token.complete(fmt.Errorf("%w: cannot subscribe while disconnected", ErrClientDisconnected))
+		}
+		c.sessionLock.Unlock()
+		return
+	}
+
+	id, ok := c.nextID()
+	if !ok {
+		req.token.complete(ErrPacketIDsExhausted)
+		c.sessionLock.Unlock()
+		return
+	}
+	pkt.PacketID = id
 
 	c.pending[pkt.PacketID] = &pendingOp{
 		packet:    pkt,
 		token:     req.token,
 		timestamp: time.Now(),
+		created:   time.Now(),
 	}
 
 	// Register before receiving SUBACK to avoid racing
 	// with the server since it might sent messages right away
 	// before we get a SUBACK.
+	var previous map[string]subscriptionEntry
+	if req.ctx != nil {
+		previous = c.subscriptionSnapshotLocked(pkt.Topics)
+	}
+	c.registerSubscriptions(req)
+
+	c.sessionLock.Unlock()
+	select {
+	case c.outgoing <- pkt:
+	case <-c.stop:
+		req.token.complete(c.stopError())
+	case <-req.done():
+		// Never sent: forget the packet and the filters it registered
+		c.sessionLock.Lock()
+		c.abandonPendingLocked(pkt.PacketID, req.token)
+		for _, topic := range pkt.Topics {
+			if entry, ok := previous[topic]; ok {
+				c.subscriptions[topic] = entry
+			} else {
+				delete(c.subscriptions, topic)
+			}
+		}
+		c.sessionLock.Unlock()
+		req.token.complete(req.ctx.Err())
+	}
+}
+
+// subscriptionSnapshotLocked returns the current subscription entries of
+// topics, so that a request that is never sent can restore them. Must be
+// called with sessionLock held.
+func (c *Client) subscriptionSnapshotLocked(topics []string) map[string]subscriptionEntry {
+	snapshot := make(map[string]subscriptionEntry, len(topics))
+	for _, topic := range topics {
+		if entry, ok := c.subscriptions[topic]; ok {
+			snapshot[topic] = entry
+		}
+	}
+	return snapshot
+}
+
+// abandonPendingLocked drops the pending operation id if it still belongs to
+// tok, releasing its packet ID. Must be called with sessionLock held.
+func (c *Client) abandonPendingLocked(id uint16, tok *token) {
+	if op, ok := c.pending[id]; ok && op.token == tok {
+		delete(c.pending, id)
+		c.signalPendingSettled()
+	}
+}
+
+// registerSubscriptions adds the filters of a subscribe request to the local
+// subscription table. Must be called with sessionLock held.
+func (c *Client) registerSubscriptions(req *subscribeRequest) {
+	pkt := req.packet
 	for i, topic := range pkt.Topics {
+		target := subscribeTarget{
+			handler:        req.handler,
+			contextHandler: req.contextHandler,
+			persistence:    req.persistence,
+			ordered:        req.ordered,
+		}
+		if i < len(req.targets) {
+			target = req.targets[i]
+		}
+
 		var subOpts SubscribeOptions
-		subOpts.Persistence = req.persistence
+		subOpts.Persistence = target.persistence
+		subOpts.Ordered = target.ordered
 
 		if pkt.Version >= 5 {
 			if i < len(pkt.NoLocal) {
@@ -207,23 +461,29 @@ func (c *Client) internalSubscribe(req *subscribeRequest) {
 			qos = pkt.QoS[i]
 		}
 
-		c.subscriptions[topic] = subscriptionEntry{
-			handler: c.wrapHandler(req.handler),
+		entry := subscriptionEntry{
 			options: subOpts,
 			qos:     qos,
 		}
-	}
-
-	c.sessionLock.Unlock()
-	select {
-	case c.outgoing <- pkt:
-	case <-c.stop:
-		req.token.complete(fmt.Errorf("client stopped"))
+		if target.contextHandler != nil {
+			entry.contextHandler = target.contextHandler
+			entry.handler = c.wrapContextHandler(target.contextHandler)
+		} else {
+			entry.handler = c.wrapHandler(target.handler)
+		}
+		c.subscriptions[topic] = entry
 	}
 }
 
 // internalUnsubscribe processes an unsubscribe request synchronously with locking.
-func (c *Client) internalUnsubscribe(req *unsubscribeRequest) {
+// It reports whether the local subscriptions were removed; they are kept if
+// the request fails before anything is queued.
+func (c *Client) internalUnsubscribe(req *unsubscribeRequest) bool {
+	if req.ctx != nil && req.ctx.Err() != nil {
+		req.token.complete(req.ctx.Err())
+		return false
+	}
+
 	pkt := req.packet
 
 	c.sessionLock.Lock()
@@ -233,29 +493,50 @@ func (c *Client) internalUnsubscribe(req *unsubscribeRequest) {
 		n, _ := pkt.WriteTo(io.Discard)
 		packetSize := uint32(n)
 		if packetSize > c.serverCaps.MaximumPacketSize {
-			req.token.complete(fmt.Errorf("UNSUBSCRIBE packet size %d bytes exceeds server maximum %d bytes",
-				packetSize, c.serverCaps.MaximumPacketSize))
+			req.token.complete(fmt.Errorf("%w: UNSUBSCRIBE packet size %d bytes exceeds server maximum %d bytes",
+				ErrPacketTooLarge, packetSize, c.serverCaps.MaximumPacketSize))
 			c.sessionLock.Unlock()
-			return
+			return false
 		}
 	}
 
-	pkt.PacketID = c.nextID()
+	id, ok := c.nextID()
+	if !ok {
+		req.token.complete(ErrPacketIDsExhausted)
+		c.sessionLock.Unlock()
+		return false
+	}
+	pkt.PacketID = id
 
 	c.pending[pkt.PacketID] = &pendingOp{
 		packet:    pkt,
 		token:     req.token,
 		timestamp: time.Now(),
+		created:   time.Now(),
 	}
 
+	var previous map[string]subscriptionEntry
+	if req.ctx != nil {
+		previous = c.subscriptionSnapshotLocked(req.topics)
+	}
 	for _, topic := range req.topics {
 		delete(c.subscriptions, topic)
+		delete(c.orderedQueues, topic) // Messages already queued still run
 	}
 
 	c.sessionLock.Unlock()
 	select {
 	case c.outgoing <- pkt:
 	case <-c.stop:
-		req.token.complete(fmt.Errorf("client stopped"))
+		req.token.complete(c.stopError())
+	case <-req.done():
+		// Never sent: the server still has the subscriptions, keep them
+		c.sessionLock.Lock()
+		c.abandonPendingLocked(pkt.PacketID, req.token)
+		maps.Copy(c.subscriptions, previous)
+		c.sessionLock.Unlock()
+		req.token.complete(req.ctx.Err())
+		return false
 	}
+	return true
 }
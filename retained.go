@@ -0,0 +1,32 @@
+package mq
+
+// PublishRetained publishes a retained message: it is Publish with
+// WithRetain(true) added after opts. The server stores the message and
+// delivers it to future subscribers of the topic.
+//
+// If the server reported that it does not support retained messages (MQTT
+// v5.0), the token fails at once with ErrRetainNotSupported.
+//
+// Example:
+//
+//	client.PublishRetained("devices/pump-1/status", []byte("online"), mq.WithQoS(1))
+func (c *Client) PublishRetained(topic string, payload []byte, opts ...PublishOption) Token {
+	return c.Publish(topic, payload, append(opts[:len(opts):len(opts)], WithRetain(true))...)
+}
+
+// ClearRetained removes the retained message of topic, by publishing a
+// zero-length retained message as MQTT specifies. Subscribers currently
+// connected receive the empty message like any other publish, so handlers
+// of retained state topics should treat an empty payload as "cleared".
+//
+// If the server reported that it does not support retained messages (MQTT
+// v5.0), the token fails at once with ErrRetainNotSupported.
+//
+// Example:
+//
+//	if err := client.ClearRetained("devices/pump-1/status", mq.WithQoS(1)).Wait(ctx); err != nil {
+//	    log.Printf("could not clear status: %v", err)
+//	}
+func (c *Client) ClearRetained(topic string, opts ...PublishOption) Token {
+	return c.PublishRetained(topic, nil, opts...)
+}
@@ -0,0 +1,179 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// acceptSubscribe completes the handshake on a new connection, waits for a
+// SUBSCRIBE and acknowledges it.
+func acceptSubscribe(ln net.Listener) (net.Conn, error) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+		return nil, err
+	}
+	if _, err := (&packets.ConnackPacket{ReturnCode: 0}).WriteTo(conn); err != nil {
+		return nil, err
+	}
+	for {
+		pkt, err := packets.ReadPacket(conn, ProtocolV50, 0)
+		if err != nil {
+			return nil, err
+		}
+		if sub, ok := pkt.(*packets.SubscribePacket); ok {
+			suback := &packets.SubackPacket{PacketID: sub.PacketID, ReturnCodes: []uint8{1}, Version: ProtocolV50}
+			if _, err := suback.WriteTo(conn); err != nil {
+				return nil, err
+			}
+			return conn, nil
+		}
+	}
+}
+
+func TestSuppressRetainedOnReconnect(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the reconnect backoff")
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	retained := func(id uint16) *packets.PublishPacket {
+		return &packets.PublishPacket{Topic: "state/a", Payload: []byte("on"), QoS: 1,
+			PacketID: id, Retain: true, Version: ProtocolV50}
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- func() error {
+			// Initial connection: retained state is delivered
+			conn1, err := acceptSubscribe(ln)
+			if err != nil {
+				return err
+			}
+			if _, err := retained(1).WriteTo(conn1); err != nil {
+				return err
+			}
+			if _, err := packets.ReadPacket(conn1, ProtocolV50, 0); err != nil {
+				return err
+			}
+			conn1.Close()
+
+			// After reconnect: the server resends it, then a live update
+			conn2, err := acceptSubscribe(ln)
+			if err != nil {
+				return err
+			}
+			defer conn2.Close()
+			if _, err := retained(2).WriteTo(conn2); err != nil {
+				return err
+			}
+			live := &packets.PublishPacket{Topic: "state/b", Payload: []byte("live"), Version: ProtocolV50}
+			if _, err := live.WriteTo(conn2); err != nil {
+				return err
+			}
+			pkt, err := packets.ReadPacket(conn2, ProtocolV50, 0)
+			if err != nil {
+				return err
+			}
+			if ack, ok := pkt.(*packets.PubackPacket); !ok || ack.PacketID != 2 {
+				return fmt.Errorf("expected suppressed message to be acknowledged, got %#v", pkt)
+			}
+			return nil
+		}()
+	}()
+
+	received := make(chan Message, 4)
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("suppress-retained"),
+		WithSuppressRetainedOnReconnect(true),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	client.Subscribe("state/#", AtLeastOnce, func(_ *Client, msg Message) { received <- msg })
+
+	next := func() Message {
+		t.Helper()
+		select {
+		case msg := <-received:
+			return msg
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for message")
+			return Message{}
+		}
+	}
+
+	if msg := next(); msg.Topic != "state/a" || !msg.Retained {
+		t.Fatalf("expected retained state/a on initial connect, got %+v", msg)
+	}
+	if msg := next(); msg.Topic != "state/b" || msg.Retained {
+		t.Fatalf("expected the resent retained message to be dropped and live state/b delivered, got %+v", msg)
+	}
+
+	select {
+	case err := <-serverErr:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for server")
+	}
+}
+
+func TestSuppressRetainedOnReconnect_Exemptions(t *testing.T) {
+	c := newTestClient(nil)
+	c.opts.Logger = testLogger()
+
+	delivered := make(chan string, 4)
+	handler := func(_ *Client, msg Message) { delivered <- msg.Topic }
+	c.subscriptions["plain/#"] = subscriptionEntry{handler: handler}
+	c.subscriptions["rap/#"] = subscriptionEntry{handler: handler, options: SubscribeOptions{RetainAsPublished: true}}
+	c.opts.DefaultPublishHandler = func(_ *Client, msg Message) { delivered <- "default:" + msg.Topic }
+
+	c.suppressRetainedOnResubscribe()
+
+	c.handlePublish(&packets.PublishPacket{Topic: "plain/x", Retain: true})
+	c.handlePublish(&packets.PublishPacket{Topic: "rap/x", Retain: true})
+
+	select {
+	case got := <-delivered:
+		if got != "rap/x" {
+			t.Fatalf("expected only the RetainAsPublished subscription to deliver, got %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected RetainAsPublished subscription to be exempt")
+	}
+
+	// A new Subscribe for the filter restores retained delivery
+	c.subscriptions["plain/#"] = subscriptionEntry{handler: handler}
+	c.handlePublish(&packets.PublishPacket{Topic: "plain/x", Retain: true})
+
+	select {
+	case got := <-delivered:
+		if got != "plain/x" {
+			t.Fatalf("expected plain/x after resubscribing, got %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected retained delivery after a fresh subscription")
+	}
+
+	select {
+	case got := <-delivered:
+		t.Fatalf("unexpected delivery %q", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
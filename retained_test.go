@@ -0,0 +1,60 @@
+package mq
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestPublishRetained(t *testing.T) {
+	tests := []struct {
+		name    string
+		publish func(c *Client) Token
+		payload string
+	}{
+		{
+			name: "publish retained",
+			publish: func(c *Client) Token {
+				return c.PublishRetained("status/pump", []byte("on"), WithRetain(false))
+			},
+			payload: "on",
+		},
+		{
+			name: "clear retained",
+			publish: func(c *Client) Token {
+				return c.ClearRetained("status/pump", WithQoS(AtLeastOnce))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(nil)
+			c.opts.Logger = testLogger()
+			c.serverCaps.MaximumQoS = 2
+			c.serverCaps.RetainAvailable = true
+
+			tt.publish(c)
+			select {
+			case pkt := <-c.outgoing:
+				pub := unwrapPacket(pkt).(*packets.PublishPacket)
+				if !pub.Retain || pub.Topic != "status/pump" || string(pub.Payload) != tt.payload {
+					t.Errorf("sent retain=%v topic=%q payload=%q", pub.Retain, pub.Topic, pub.Payload)
+				}
+			default:
+				t.Fatal("nothing was sent")
+			}
+
+			// Refused locally when the server has no retained messages
+			c.serverCaps.RetainAvailable = false
+			tok := tt.publish(c)
+			if !errors.Is(tok.Error(), ErrRetainNotSupported) {
+				t.Errorf("expected ErrRetainNotSupported, got %v", tok.Error())
+			}
+			if len(c.outgoing) != 0 {
+				t.Error("refused publish must not be sent")
+			}
+		})
+	}
+}
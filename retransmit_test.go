@@ -0,0 +1,61 @@
+package mq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestOnRetransmit(t *testing.T) {
+	type event struct {
+		id         uint16
+		packetType string
+		attempt    int
+	}
+	events := make(chan event, 4)
+
+	c := newTestClient(nil)
+	c.opts.Logger = testLogger()
+	WithOnRetransmit(func(id uint16, packetType string, attempt int) {
+		events <- event{id, packetType, attempt}
+	})(c.opts)
+
+	old := time.Now().Add(-time.Minute)
+	c.pending[7] = &pendingOp{packet: &packets.PublishPacket{Topic: "t", QoS: 1, PacketID: 7}, token: newToken(), qos: 1, timestamp: old}
+	c.pending[9] = &pendingOp{packet: &packets.SubscribePacket{PacketID: 9, Topics: []string{"t"}, QoS: []uint8{1}}, token: newToken(), timestamp: time.Now()}
+
+	next := func() event {
+		t.Helper()
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for OnRetransmit")
+			return event{}
+		}
+	}
+
+	// Only the PUBLISH is overdue
+	c.retryPending()
+	if e := next(); e != (event{7, "PUBLISH", 1}) {
+		t.Errorf("event = %+v, want PUBLISH 7 attempt 1", e)
+	}
+
+	// Resent again after a reconnect, once written
+	<-c.outgoing
+	c.resendPending()
+	if e := next(); e != (event{7, "PUBLISH", 2}) {
+		t.Errorf("event = %+v, want PUBLISH 7 attempt 2", e)
+	}
+
+	c.pending[9].timestamp = old
+	c.retryPending()
+	if e := next(); e != (event{9, "SUBSCRIBE", 1}) {
+		t.Errorf("event = %+v, want SUBSCRIBE 9 attempt 1", e)
+	}
+
+	if got := c.GetStats().Retransmissions; got != 3 {
+		t.Errorf("Retransmissions = %d, want 3", got)
+	}
+}
@@ -0,0 +1,152 @@
+package mq
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestWithRetryInterval(t *testing.T) {
+	tests := []struct {
+		name string
+		d    time.Duration
+		want time.Duration
+	}{
+		{"positive", 45 * time.Second, 45 * time.Second},
+		{"zero ignored", 0, defaultRetryInterval},
+		{"negative ignored", -time.Second, defaultRetryInterval},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultOptions("tcp://localhost:1883")
+			WithRetryInterval(tt.d)(opts)
+			if opts.RetryInterval != tt.want {
+				t.Errorf("RetryInterval = %v, want %v", opts.RetryInterval, tt.want)
+			}
+			c := newTestClient(opts)
+			if got := c.retryTickPeriod(); got != tt.want/2 {
+				t.Errorf("retry tick period = %v, want %v", got, tt.want/2)
+			}
+		})
+	}
+}
+
+func TestRetryPending_Interval(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.RetryInterval = time.Minute
+	c := newTestClient(opts)
+	c.opts.Logger = testLogger()
+
+	now := time.Now()
+	c.pending[1] = &pendingOp{
+		packet:    &packets.PublishPacket{PacketID: 1, QoS: 1, Topic: "old"},
+		token:     newToken(),
+		qos:       1,
+		timestamp: now.Add(-2 * time.Minute),
+	}
+	c.pending[2] = &pendingOp{
+		packet:    &packets.PubrelPacket{PacketID: 2},
+		token:     newToken(),
+		qos:       2,
+		timestamp: now.Add(-2 * time.Minute),
+	}
+	c.pending[3] = &pendingOp{
+		packet:    &packets.PublishPacket{PacketID: 3, QoS: 1, Topic: "recent"},
+		token:     newToken(),
+		qos:       1,
+		timestamp: now.Add(-30 * time.Second), // Older than the 10s default
+	}
+
+	c.retryPending()
+
+	resent := map[uint16]packets.Packet{}
+	for len(c.outgoing) > 0 {
+		pkt := <-c.outgoing
+		switch p := pkt.(type) {
+		case *packets.PublishPacket:
+			if !p.Dup {
+				t.Errorf("PUBLISH %d retransmitted without DUP", p.PacketID)
+			}
+			resent[p.PacketID] = p
+		case *packets.PubrelPacket:
+			resent[p.PacketID] = p
+		default:
+			t.Errorf("unexpected retransmission %T", pkt)
+		}
+	}
+	if len(resent) != 2 || resent[1] == nil || resent[2] == nil {
+		t.Errorf("expected packets 1 and 2 to be retransmitted, got %v", resent)
+	}
+	if _, ok := resent[2].(*packets.PubrelPacket); !ok {
+		t.Errorf("expected PUBREL 2, got %T", resent[2])
+	}
+	if c.pending[3].packet.(*packets.PublishPacket).Dup {
+		t.Error("packet within the retry interval must not be retransmitted")
+	}
+}
+
+func TestRetryInterval_LiveConnection(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	transmissions := make(chan *packets.PublishPacket, 4)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+			return
+		}
+		_, _ = (&packets.ConnackPacket{}).WriteTo(conn)
+		for {
+			pkt, err := packets.ReadPacket(conn, ProtocolV50, 0)
+			if err != nil {
+				return
+			}
+			pub, ok := pkt.(*packets.PublishPacket)
+			if !ok {
+				continue
+			}
+			transmissions <- pub
+			if pub.Dup { // Acknowledge the retransmission only
+				_, _ = (&packets.PubackPacket{PacketID: pub.PacketID, Version: ProtocolV50}).WriteTo(conn)
+			}
+		}
+	}()
+
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("retry-interval"),
+		WithRetryInterval(100*time.Millisecond),
+		WithLogger(testLogger()),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	tok := client.Publish("retry/test", []byte("x"), WithQoS(AtLeastOnce))
+	for i, wantDup := range []bool{false, true} {
+		select {
+		case pub := <-transmissions:
+			if pub.Dup != wantDup {
+				t.Errorf("transmission %d: DUP = %v, want %v", i, pub.Dup, wantDup)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for transmission %d", i)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := tok.Wait(ctx); err != nil {
+		t.Errorf("expected publish to complete after the retransmission, got %v", err)
+	}
+}
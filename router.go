@@ -0,0 +1,150 @@
+package mq
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Router dispatches messages to handlers by topic filter, for client-side
+// routing of messages that arrive through broad subscriptions.
+//
+// Filters are kept in a trie of topic levels, so matching a topic costs time
+// proportional to its number of levels rather than to the number of routes.
+// Wildcards match exactly as with MatchTopic: '+' matches one level, '#'
+// matches the remaining levels (including none), and filters starting with
+// a wildcard do not match topics starting with '$'.
+//
+// The zero value is an empty router ready to use. A Router is safe for
+// concurrent use, so routes can be added and removed while messages are
+// being dispatched.
+//
+// Example:
+//
+//	var router mq.Router
+//	router.Add("system/alerts/#", handleAlert)
+//	router.Add("sensors/+/temp", handleTemperature)
+//
+//	client, _ := mq.Dial(uri, mq.WithDefaultPublishHandler(router.Handle))
+//	client.Subscribe("#", mq.AtLeastOnce, nil)
+type Router struct {
+	mu   sync.RWMutex
+	root routerNode
+}
+
+// routerNode is one topic level of a Router. Filters ending at this level
+// have their handler in handler.
+type routerNode struct {
+	children map[string]*routerNode
+	handler  MessageHandler
+}
+
+// Add routes messages whose topic matches filter to handler, replacing the
+// handler previously added for the same filter. It returns an error if
+// filter is not a valid topic filter.
+func (r *Router) Add(filter string, handler MessageHandler) error {
+	if err := ValidateTopicFilter(filter); err != nil {
+		return err
+	}
+	if handler == nil {
+		return fmt.Errorf("handler for %q is nil", filter)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := &r.root
+	for level := range strings.SplitSeq(filter, "/") {
+		child, ok := n.children[level]
+		if !ok {
+			if n.children == nil {
+				n.children = make(map[string]*routerNode)
+			}
+			child = &routerNode{}
+			n.children[level] = child
+		}
+		n = child
+	}
+	n.handler = handler
+	return nil
+}
+
+// Remove removes the route for filter, if any.
+func (r *Router) Remove(filter string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.root.remove(strings.Split(filter, "/"))
+}
+
+// remove removes the handler of the filter made of levels below n. It
+// reports whether n is left empty and can be pruned.
+func (n *routerNode) remove(levels []string) bool {
+	if len(levels) == 0 {
+		n.handler = nil
+	} else if child, ok := n.children[levels[0]]; ok && child.remove(levels[1:]) {
+		delete(n.children, levels[0])
+	}
+	return n.handler == nil && len(n.children) == 0
+}
+
+// Match returns the handlers of all filters matching topic, in no
+// particular order. It returns nil if no filter matches.
+func (r *Router) Match(topic string) []MessageHandler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var handlers []MessageHandler
+	// MQTT-4.7.2-1: wildcards at the first level don't match '$' topics
+	wildcards := !strings.HasPrefix(topic, "$")
+	r.root.match(topic, wildcards, &handlers)
+	return handlers
+}
+
+// match appends the handlers of the filters below n matching the remaining
+// topic levels. wildcards is false while '+' and '#' must not match.
+func (n *routerNode) match(topic string, wildcards bool, handlers *[]MessageHandler) {
+	if wildcards {
+		// '#' also matches the parent level ("a/#" matches "a")
+		if hash, ok := n.children["#"]; ok && hash.handler != nil {
+			*handlers = append(*handlers, hash.handler)
+		}
+	}
+
+	level, rest, more := strings.Cut(topic, "/")
+	// A topic level equal to a wildcard is matched by the wildcard nodes
+	// only, so that no handler is returned twice
+	if level != "+" && level != "#" {
+		if child, ok := n.children[level]; ok {
+			child.matchRest(rest, more, handlers)
+		}
+	}
+	if wildcards {
+		if plus, ok := n.children["+"]; ok {
+			plus.matchRest(rest, more, handlers)
+		}
+	}
+}
+
+// matchRest continues matching below n, which matched a topic level.
+// more reports whether the topic has levels left in rest.
+func (n *routerNode) matchRest(rest string, more bool, handlers *[]MessageHandler) {
+	if more {
+		n.match(rest, true, handlers)
+		return
+	}
+	if n.handler != nil {
+		*handlers = append(*handlers, n.handler)
+	}
+	if hash, ok := n.children["#"]; ok && hash.handler != nil {
+		*handlers = append(*handlers, hash.handler)
+	}
+}
+
+// Handle calls the handlers of all filters matching the topic of msg. Use
+// it with WithDefaultPublishHandler or as the handler of a subscription.
+func (r *Router) Handle(c *Client, msg Message) {
+	for _, handler := range r.Match(msg.Topic) {
+		handler(c, msg)
+	}
+}
@@ -0,0 +1,56 @@
+package mq
+
+import (
+	"fmt"
+	"testing"
+)
+
+// routerBenchRoutes returns n filters like "site/3/device/7/+" plus a few
+// wildcard routes, the shape of a gateway dispatching per-device messages.
+func routerBenchRoutes(n int) []string {
+	filters := []string{"site/#", "+/+/device/+/status", "alarms/#"}
+	for i := range n {
+		filters = append(filters, fmt.Sprintf("site/%d/device/%d/+", i%10, i))
+	}
+	return filters
+}
+
+func benchmarkRouter(b *testing.B, n int) {
+	var r Router
+	for _, f := range routerBenchRoutes(n) {
+		_ = r.Add(f, func(*Client, Message) {})
+	}
+	topic := fmt.Sprintf("site/%d/device/%d/temp", (n/2)%10, n/2)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for b.Loop() {
+		r.Match(topic)
+	}
+}
+
+// benchmarkMatchTopicLoop measures the naive approach of checking every
+// route with MatchTopic.
+func benchmarkMatchTopicLoop(b *testing.B, n int) {
+	routes := make(map[string]MessageHandler)
+	for _, f := range routerBenchRoutes(n) {
+		routes[f] = func(*Client, Message) {}
+	}
+	topic := fmt.Sprintf("site/%d/device/%d/temp", (n/2)%10, n/2)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for b.Loop() {
+		var handlers []MessageHandler
+		for filter, handler := range routes {
+			if MatchTopic(filter, topic) {
+				handlers = append(handlers, handler)
+			}
+		}
+	}
+}
+
+func BenchmarkRouter_10Routes(b *testing.B)           { benchmarkRouter(b, 10) }
+func BenchmarkRouter_1000Routes(b *testing.B)         { benchmarkRouter(b, 1000) }
+func BenchmarkMatchTopicLoop_10Routes(b *testing.B)   { benchmarkMatchTopicLoop(b, 10) }
+func BenchmarkMatchTopicLoop_1000Routes(b *testing.B) { benchmarkMatchTopicLoop(b, 1000) }
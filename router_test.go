@@ -0,0 +1,103 @@
+package mq
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// routeTo returns a handler that records filter in *got.
+func routeTo(filter string, got *[]string) MessageHandler {
+	return func(*Client, Message) { *got = append(*got, filter) }
+}
+
+// TestRouterMatchesLikeMatchTopic verifies that Router selects exactly the
+// filters for which MatchTopic reports a match.
+func TestRouterMatchesLikeMatchTopic(t *testing.T) {
+	filters := []string{
+		"#", "+", "+/+", "+/#", "a", "a/#", "a/+", "a/b", "a/b/#", "a/+/c",
+		"+/b/c", "a//c", "a/+/+", "$SYS/#", "$SYS/+/load", "/#", "/+", "+/",
+	}
+	topics := []string{
+		"a", "a/b", "a/b/c", "a/x/c", "a//c", "a/", "/", "/a", "", "b",
+		"$SYS", "$SYS/broker/load", "$other/x", "a/b/c/d", "x/b/c",
+	}
+
+	var got []string
+	var r Router
+	for _, f := range filters {
+		if err := r.Add(f, routeTo(f, &got)); err != nil {
+			t.Fatalf("Add(%q): %v", f, err)
+		}
+	}
+
+	for _, topic := range topics {
+		var want []string
+		for _, f := range filters {
+			if MatchTopic(f, topic) {
+				want = append(want, f)
+			}
+		}
+
+		got = nil
+		r.Handle(nil, Message{Topic: topic})
+		slices.Sort(got)
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			t.Errorf("topic %q matched %v, want %v", topic, got, want)
+		}
+	}
+}
+
+func TestRouterAddRemove(t *testing.T) {
+	var got []string
+	var r Router
+
+	if err := r.Add("a/#/b", routeTo("bad", &got)); err == nil {
+		t.Error("Add accepted an invalid filter")
+	}
+	if err := r.Add("a/b", nil); err == nil {
+		t.Error("Add accepted a nil handler")
+	}
+
+	if r.Match("a/b") != nil {
+		t.Error("empty router matched")
+	}
+
+	_ = r.Add("a/b", routeTo("first", &got))
+	_ = r.Add("a/b", routeTo("second", &got))
+	_ = r.Add("a/#", routeTo("a/#", &got))
+	r.Handle(nil, Message{Topic: "a/b"})
+	slices.Sort(got)
+	if !slices.Equal(got, []string{"a/#", "second"}) {
+		t.Errorf("matched %v, want the replaced handler and a/#", got)
+	}
+
+	// Removing a filter keeps the routes sharing its levels
+	r.Remove("a/b")
+	r.Remove("a/b/c") // Not added
+	got = nil
+	r.Handle(nil, Message{Topic: "a/b"})
+	if !slices.Equal(got, []string{"a/#"}) {
+		t.Errorf("after Remove matched %v, want [a/#]", got)
+	}
+
+	r.Remove("a/#")
+	if len(r.root.children) != 0 {
+		t.Errorf("empty levels not pruned: %v", r.root.children)
+	}
+}
+
+func ExampleRouter() {
+	var router Router
+	_ = router.Add("sensors/+/temp", func(_ *Client, msg Message) {
+		fmt.Println("temperature:", msg.Topic)
+	})
+	_ = router.Add("sensors/#", func(_ *Client, msg Message) {
+		fmt.Println("any sensor:", msg.Topic)
+	})
+
+	// Typically passed to WithDefaultPublishHandler
+	router.Handle(nil, Message{Topic: "sensors/kitchen/humidity"})
+	// Output: any sensor: sensors/kitchen/humidity
+}
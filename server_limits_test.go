@@ -1,8 +1,13 @@
 package mq
 
 import (
+	"context"
+	"errors"
+	"net"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gonzalop/mq/internal/packets"
 )
@@ -80,6 +85,9 @@ func TestMaximumPacketSizeEnforcement(t *testing.T) {
 				if tt.wantError && err != nil && !strings.Contains(err.Error(), "exceeds server maximum") {
 					t.Errorf("expected packet size error, got: %v", err)
 				}
+				if tt.wantError && !errors.Is(err, ErrPacketTooLarge) {
+					t.Errorf("expected ErrPacketTooLarge, got: %v", err)
+				}
 			default:
 				if tt.wantError {
 					t.Error("expected immediate error, token not completed")
@@ -211,3 +219,265 @@ func TestReceiveMaximumEnforcement(t *testing.T) {
 		})
 	}
 }
+
+func TestMaximumPacketSize_RejectedBeforeSending(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan packets.Packet, 4)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+			return
+		}
+		connack := &packets.ConnackPacket{
+			ReturnCode: 0,
+			Properties: &packets.Properties{
+				MaximumPacketSize: 64,
+				Presence:          packets.PresMaximumPacketSize,
+			},
+		}
+		if _, err := connack.WriteTo(conn); err != nil {
+			return
+		}
+		for {
+			pkt, err := packets.ReadPacket(conn, ProtocolV50, 0)
+			if err != nil {
+				return
+			}
+			received <- pkt
+		}
+	}()
+
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("max-packet"),
+		WithAutoReconnect(false),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	// Topic + payload + properties well over 64 bytes
+	tok := client.Publish("big/topic", []byte(strings.Repeat("x", 100)),
+		WithQoS(AtLeastOnce), WithContentType("text/plain"))
+	select {
+	case <-tok.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected oversized publish to fail immediately")
+	}
+	if err := tok.Error(); !errors.Is(err, ErrPacketTooLarge) {
+		t.Fatalf("expected ErrPacketTooLarge, got %v", err)
+	}
+
+	// Follow up with a small publish: it must be the first thing the server
+	// sees, proving the oversized one never reached the socket.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := client.Publish("small", []byte("ok")).Wait(ctx); err != nil {
+		t.Fatalf("small publish failed: %v", err)
+	}
+
+	select {
+	case pkt := <-received:
+		pub, ok := pkt.(*packets.PublishPacket)
+		if !ok || pub.Topic != "small" {
+			t.Fatalf("expected only the small PUBLISH, got %#v", pkt)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for small PUBLISH")
+	}
+}
+
+// TestReceiveMaximum_ThrottlesPublishes verifies against a server that
+// advertises a ReceiveMaximum of 2 that the client never has more than two
+// unacknowledged QoS 1 publishes outstanding, and sends the rest as
+// PUBACKs arrive.
+func TestReceiveMaximum_ThrottlesPublishes(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	const receiveMaximum = 2
+	maxOutstanding := make(chan int, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+			return
+		}
+		connack := &packets.ConnackPacket{
+			ReturnCode: 0,
+			Properties: &packets.Properties{
+				ReceiveMaximum: receiveMaximum,
+				Presence:       packets.PresReceiveMaximum,
+			},
+		}
+		if _, err := connack.WriteTo(conn); err != nil {
+			return
+		}
+
+		// Acknowledge the oldest publish only once the client went quiet,
+		// so that a client ignoring the limit sends everything at once
+		var unacked []uint16
+		highest := 0
+		defer func() { maxOutstanding <- highest }()
+		for {
+			_ = conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+			pkt, err := packets.ReadPacket(conn, ProtocolV50, 0)
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				if len(unacked) > 0 {
+					_, _ = (&packets.PubackPacket{PacketID: unacked[0], Version: ProtocolV50}).WriteTo(conn)
+					unacked = unacked[1:]
+				}
+				continue
+			}
+			if err != nil {
+				return
+			}
+			if pub, ok := pkt.(*packets.PublishPacket); ok && pub.QoS > 0 {
+				unacked = append(unacked, pub.PacketID)
+				highest = max(highest, len(unacked))
+			}
+		}
+	}()
+
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("flow-control"),
+		WithAutoReconnect(false),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+
+	var tokens []Token
+	for range 5 {
+		tokens = append(tokens, client.Publish("t", []byte("x"), WithQoS(AtLeastOnce)))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i, tok := range tokens {
+		if err := tok.Wait(ctx); err != nil {
+			t.Fatalf("publish %d failed: %v", i, err)
+		}
+	}
+	_ = client.Disconnect(context.Background())
+
+	if got := <-maxOutstanding; got != receiveMaximum {
+		t.Errorf("server saw up to %d unacknowledged publishes, want %d", got, receiveMaximum)
+	}
+}
+
+// TestResendPending_RespectsReceiveMaximum verifies that retransmissions
+// after a reconnect stay within the server's ReceiveMaximum and that
+// withheld publishes are sent, in order, as acknowledgments free slots.
+func TestResendPending_RespectsReceiveMaximum(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	opts.RetryInterval = time.Millisecond
+	c := newTestClient(opts)
+	c.serverCaps.ReceiveMaximum = 2
+
+	// A QoS 2 publish awaiting PUBCOMP and three QoS 1 publishes
+	created := time.Now().Add(-time.Minute)
+	c.pending[1] = &pendingOp{packet: &packets.PubrelPacket{PacketID: 1}, token: newToken(), qos: 2, created: created}
+	for id := uint16(2); id <= 4; id++ {
+		c.pending[id] = &pendingOp{
+			packet:  &packets.PublishPacket{Topic: "t", QoS: 1, PacketID: id},
+			token:   newToken(),
+			qos:     1,
+			created: created.Add(time.Duration(id) * time.Second),
+		}
+	}
+	c.setInFlightCount(4)
+
+	sent := func() []uint16 {
+		var ids []uint16
+		for len(c.outgoing) > 0 {
+			pkt := <-c.outgoing
+			if pub, ok := pkt.(*packets.PublishPacket); ok && !pub.Dup {
+				t.Errorf("publish %d resent without the DUP flag", pub.PacketID)
+			}
+			ids = append(ids, packetID(pkt))
+		}
+		return ids
+	}
+
+	c.resendPending()
+	if got := sent(); !slices.Equal(got, []uint16{1, 2}) {
+		t.Fatalf("resent %v, want [1 2]", got)
+	}
+
+	// Withheld publishes are not retried before they are sent
+	time.Sleep(5 * time.Millisecond)
+	c.retryPending()
+	if got := sent(); slices.Contains(got, 3) || slices.Contains(got, 4) {
+		t.Fatalf("retryPending sent withheld publishes: %v", got)
+	}
+
+	c.handlePuback(&packets.PubackPacket{PacketID: 2})
+	if got := sent(); !slices.Equal(got, []uint16{3}) {
+		t.Fatalf("after PUBACK sent %v, want [3]", got)
+	}
+	c.handlePubcomp(&packets.PubcompPacket{PacketID: 1})
+	if got := sent(); !slices.Equal(got, []uint16{4}) {
+		t.Fatalf("after PUBCOMP sent %v, want [4]", got)
+	}
+	if len(c.withheld) != 0 {
+		t.Errorf("%d publishes still withheld", len(c.withheld))
+	}
+}
+
+// TestResendPending_DropsOversizedPublishes verifies that a publish pending
+// from a previous connection is failed with ErrPacketTooLarge instead of
+// being resent when the server now accepts smaller packets.
+func TestResendPending_DropsOversizedPublishes(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	c := newTestClient(opts)
+	c.serverCaps.MaximumPacketSize = 64
+
+	big, small := newToken(), newToken()
+	c.pending[1] = &pendingOp{
+		packet: &packets.PublishPacket{Topic: "t", QoS: 1, PacketID: 1, Payload: []byte(strings.Repeat("x", 100))},
+		token:  big,
+		qos:    1,
+		size:   100,
+	}
+	c.pending[2] = &pendingOp{
+		packet: &packets.PublishPacket{Topic: "t", QoS: 1, PacketID: 2, Payload: []byte("ok")},
+		token:  small,
+		qos:    1,
+		size:   2,
+	}
+	c.setInFlightCount(2)
+	c.inFlightBytes = 102
+
+	c.resendPending()
+
+	if err := big.Error(); !errors.Is(err, ErrPacketTooLarge) {
+		t.Errorf("oversized publish error = %v, want ErrPacketTooLarge", err)
+	}
+	if len(c.outgoing) != 1 || packetID(<-c.outgoing) != 2 {
+		t.Error("expected only the small publish to be resent")
+	}
+	if _, ok := c.pending[1]; ok || c.inFlightCount != 1 || c.inFlightBytes != 2 {
+		t.Errorf("oversized publish still accounted for: pending=%v inFlight=%d bytes=%d",
+			ok, c.inFlightCount, c.inFlightBytes)
+	}
+}
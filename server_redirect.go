@@ -0,0 +1,155 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// maxServerRedirects is the number of consecutive redirects followed without
+// an established connection (WithAutoServerRedirect).
+const maxServerRedirects = 3
+
+// serverRedirect is a redirect to follow on the next connection attempt.
+type serverRedirect struct {
+	server    string // URL to connect to
+	permanent bool   // Server moved (0x9D), as opposed to Use another server (0x9C)
+}
+
+// redirectReference returns the Server Reference and reason code of err if it
+// is a redirect: a refused CONNACK or a server DISCONNECT with reason code
+// Use another server or Server moved.
+func redirectReference(err error) (string, ReasonCode, bool) {
+	var ref string
+	var code ReasonCode
+
+	var refused *ConnectRefusedError
+	var disconnect *DisconnectError
+	switch {
+	case errors.As(err, &refused):
+		ref, code = refused.ServerReference, refused.ReasonCode
+	case errors.As(err, &disconnect):
+		ref, code = disconnect.ServerReference, disconnect.ReasonCode
+	default:
+		return "", 0, false
+	}
+
+	if strings.TrimSpace(ref) == "" || (code != ReasonCodeUseAnotherServer && code != ReasonCodeServerMoved) {
+		return "", 0, false
+	}
+	return ref, code, true
+}
+
+// redirectURL turns a Server Reference into a URL to dial. A reference
+// without a scheme ("host" or "host:port") keeps the scheme of current; of
+// several space-separated references, the first is used.
+func redirectURL(current, ref string) string {
+	ref = strings.Fields(ref)[0]
+	if strings.Contains(ref, "://") {
+		return ref
+	}
+
+	scheme := "tcp"
+	if u, err := url.Parse(current); err == nil && u.Scheme != "" {
+		scheme = u.Scheme
+	}
+	return scheme + "://" + ref
+}
+
+// noteRedirect reports the redirect carried by err, if any, and records it
+// for the next connection attempt when WithAutoServerRedirect is enabled.
+// It returns whether a redirect was recorded.
+func (c *Client) noteRedirect(err error) bool {
+	ref, code, ok := redirectReference(err)
+	if !ok {
+		return false
+	}
+
+	if c.opts.OnServerRedirect != nil {
+		go c.opts.OnServerRedirect(ref)
+	}
+	if !c.opts.AutoServerRedirect {
+		return false
+	}
+
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	c.redirect = &serverRedirect{
+		server:    redirectURL(c.server, ref),
+		permanent: code == ReasonCodeServerMoved,
+	}
+	return true
+}
+
+// hasRedirect reports whether a redirect is waiting to be followed.
+func (c *Client) hasRedirect() bool {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	return c.redirect != nil
+}
+
+// takeRedirect returns and clears the redirect to follow, if any.
+func (c *Client) takeRedirect() *serverRedirect {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	r := c.redirect
+	c.redirect = nil
+	return r
+}
+
+// currentServer returns the server of the current or next connection, which
+// differs from the one given to Dial after following a redirect.
+func (c *Client) currentServer() string {
+	c.connLock.RLock()
+	defer c.connLock.RUnlock()
+	return c.server
+}
+
+// setServer changes the server of the next connection attempt.
+func (c *Client) setServer(server string) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	c.server = server
+}
+
+// chooseServer selects the server of the next connection attempt: the
+// pending redirect if there is one, otherwise the original server unless the
+// client was permanently moved elsewhere.
+func (c *Client) chooseServer() {
+	r := c.takeRedirect()
+	if r == nil {
+		if c.currentServer() != c.homeServer && !c.onMovedServer {
+			c.opts.Logger.Info("returning to the original server", "server", c.homeServer)
+			c.setServer(c.homeServer)
+		}
+		return
+	}
+
+	c.redirects++
+	if c.redirects > maxServerRedirects {
+		c.opts.Logger.Warn("too many consecutive server redirects, returning to the original server",
+			"ignored", r.server, "server", c.homeServer)
+		c.redirects = 0
+		c.setServer(c.homeServer)
+		c.onMovedServer = false
+		return
+	}
+
+	c.opts.Logger.Info("following server redirect", "from", c.currentServer(), "to", r.server, "permanent", r.permanent)
+	c.setServer(r.server)
+	c.onMovedServer = r.permanent
+}
+
+// connectFollowingRedirects makes the initial connection, following up to
+// maxServerRedirects redirects when WithAutoServerRedirect is enabled.
+func (c *Client) connectFollowingRedirects(ctx context.Context) error {
+	err := c.connect(ctx)
+	for i := 0; err != nil && i < maxServerRedirects && c.noteRedirect(err); i++ {
+		c.chooseServer()
+		err = c.connect(ctx)
+	}
+	c.takeRedirect() // Not followed any further
+	c.redirects = 0
+	return err
+}
@@ -0,0 +1,264 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// redirectStep is how a test server answers one connection.
+type redirectStep struct {
+	refuse     uint8  // CONNACK reason code; 0 accepts the connection
+	disconnect uint8  // DISCONNECT reason code sent after accepting, if not 0
+	ref        string // Server Reference of the CONNACK or DISCONNECT
+	drop       bool   // Close the accepted connection shortly after CONNACK
+}
+
+// serveRedirect starts a server answering its n-th connection (from 1) with
+// script(n). It returns the server address and a channel receiving n for
+// every connection.
+func serveRedirect(t *testing.T, script func(n int) redirectStep) (string, <-chan int) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	accepted := make(chan int, 16)
+	go func() {
+		for n := 1; ; n++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn, n int) {
+				defer conn.Close()
+				if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+					return
+				}
+				accepted <- n
+				step := script(n)
+
+				var props *packets.Properties
+				if step.ref != "" {
+					props = &packets.Properties{ServerReference: step.ref, Presence: packets.PresServerReference}
+				}
+				if step.refuse != 0 {
+					_, _ = (&packets.ConnackPacket{ReturnCode: step.refuse, Properties: props}).WriteTo(conn)
+					return
+				}
+				if _, err := (&packets.ConnackPacket{ReturnCode: 0}).WriteTo(conn); err != nil {
+					return
+				}
+				if step.disconnect != 0 {
+					time.Sleep(20 * time.Millisecond)
+					_, _ = (&packets.DisconnectPacket{ReasonCode: step.disconnect, Properties: props, Version: ProtocolV50}).WriteTo(conn)
+					time.Sleep(100 * time.Millisecond) // Let the client process it before the close
+					return
+				}
+				if step.drop {
+					time.Sleep(20 * time.Millisecond)
+					return
+				}
+				for {
+					if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+						return
+					}
+				}
+			}(conn, n)
+		}
+	}()
+	return ln.Addr().String(), accepted
+}
+
+func waitAccepted(t *testing.T, accepted <-chan int, name string, want int) {
+	t.Helper()
+	select {
+	case n := <-accepted:
+		if n != want {
+			t.Fatalf("server %s: got connection %d, want %d", name, n, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout waiting for connection %d to server %s", want, name)
+	}
+}
+
+func TestAutoServerRedirect_Dial(t *testing.T) {
+	addrB, acceptedB := serveRedirect(t, func(int) redirectStep { return redirectStep{} })
+	addrA, acceptedA := serveRedirect(t, func(int) redirectStep {
+		return redirectStep{refuse: uint8(ReasonCodeUseAnotherServer), ref: addrB + " other.example.com:1883"}
+	})
+
+	redirected := make(chan string, 1)
+	client, err := Dial("tcp://"+addrA,
+		WithClientID("redirect-dial"),
+		WithAutoServerRedirect(true),
+		WithOnServerRedirect(func(server string) { redirected <- server }),
+		WithLogger(testLogger()),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	waitAccepted(t, acceptedA, "A", 1)
+	waitAccepted(t, acceptedB, "B", 1)
+	select {
+	case got := <-redirected:
+		if want := addrB + " other.example.com:1883"; got != want {
+			t.Errorf("OnServerRedirect got %q, want %q", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Error("OnServerRedirect was not called")
+	}
+}
+
+func TestAutoServerRedirect_Disabled(t *testing.T) {
+	addrB, acceptedB := serveRedirect(t, func(int) redirectStep { return redirectStep{} })
+	addrA, _ := serveRedirect(t, func(int) redirectStep {
+		return redirectStep{refuse: uint8(ReasonCodeServerMoved), ref: "tcp://" + addrB}
+	})
+
+	_, err := Dial("tcp://"+addrA, WithClientID("redirect-disabled"), WithLogger(testLogger()))
+	var refused *ConnectRefusedError
+	if !errors.As(err, &refused) {
+		t.Fatalf("expected a ConnectRefusedError, got %v", err)
+	}
+	if refused.ServerReference != "tcp://"+addrB {
+		t.Errorf("ServerReference = %q, want %q", refused.ServerReference, "tcp://"+addrB)
+	}
+	select {
+	case <-acceptedB:
+		t.Error("redirect followed without WithAutoServerRedirect")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestAutoServerRedirect_Disconnect(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     ReasonCode
+		wantHome bool // Next connection after losing B goes back to A
+	}{
+		{"use another server", ReasonCodeUseAnotherServer, true},
+		{"server moved", ReasonCodeServerMoved, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addrB, acceptedB := serveRedirect(t, func(n int) redirectStep {
+				// The first connection to B is lost
+				return redirectStep{drop: n == 1}
+			})
+			addrA, acceptedA := serveRedirect(t, func(n int) redirectStep {
+				if n == 1 {
+					return redirectStep{disconnect: uint8(tt.code), ref: addrB}
+				}
+				return redirectStep{}
+			})
+
+			client, err := Dial("tcp://"+addrA,
+				WithClientID("redirect-disconnect"),
+				WithAutoServerRedirect(true),
+				WithReconnectBackoff(10*time.Millisecond, 10*time.Millisecond, 1),
+				WithLogger(testLogger()),
+			)
+			if err != nil {
+				t.Fatalf("Dial failed: %v", err)
+			}
+			defer func() { _ = client.Disconnect(context.Background()) }()
+
+			waitAccepted(t, acceptedA, "A", 1)
+			waitAccepted(t, acceptedB, "B", 1)
+			if tt.wantHome {
+				waitAccepted(t, acceptedA, "A", 2)
+			} else {
+				waitAccepted(t, acceptedB, "B", 2)
+			}
+		})
+	}
+}
+
+func TestAutoServerRedirect_LoopLimit(t *testing.T) {
+	var addrA, addrB string
+	addrA, acceptedA := serveRedirect(t, func(int) redirectStep {
+		return redirectStep{refuse: uint8(ReasonCodeUseAnotherServer), ref: addrB}
+	})
+	addrB, acceptedB := serveRedirect(t, func(int) redirectStep {
+		return redirectStep{refuse: uint8(ReasonCodeUseAnotherServer), ref: addrA}
+	})
+
+	_, err := Dial("tcp://"+addrA,
+		WithClientID("redirect-loop"),
+		WithAutoServerRedirect(true),
+		WithLogger(testLogger()),
+	)
+	if !errors.Is(err, ReasonCodeUseAnotherServer) {
+		t.Fatalf("expected the last refusal, got %v", err)
+	}
+	// A, then maxServerRedirects redirects: B, A, B
+	if got := len(acceptedA) + len(acceptedB); got != 1+maxServerRedirects {
+		t.Errorf("got %d connections, want %d", got, 1+maxServerRedirects)
+	}
+}
+
+func TestChooseServer(t *testing.T) {
+	c := &Client{opts: defaultOptions("tcp://home:1883"), homeServer: "tcp://home:1883", server: "tcp://home:1883"}
+	c.opts.Logger = testLogger()
+
+	// Temporary redirects, up to the limit
+	for i := 1; i <= maxServerRedirects; i++ {
+		c.redirect = &serverRedirect{server: "tcp://other:1883"}
+		c.chooseServer()
+		if c.server != "tcp://other:1883" {
+			t.Fatalf("redirect %d: server = %q", i, c.server)
+		}
+	}
+	c.redirect = &serverRedirect{server: "tcp://loop:1883", permanent: true}
+	c.chooseServer()
+	if c.server != "tcp://home:1883" || c.redirects != 0 || c.onMovedServer {
+		t.Fatalf("after too many redirects: server = %q, redirects = %d, moved = %v",
+			c.server, c.redirects, c.onMovedServer)
+	}
+
+	// A moved server is kept when no redirect is pending
+	c.redirect = &serverRedirect{server: "tcp://moved:1883", permanent: true}
+	c.chooseServer()
+	c.chooseServer()
+	if c.server != "tcp://moved:1883" {
+		t.Errorf("moved server not kept: %q", c.server)
+	}
+
+	// A temporary one is not
+	c.redirect = &serverRedirect{server: "tcp://temporary:1883"}
+	c.chooseServer()
+	c.chooseServer()
+	if c.server != "tcp://home:1883" {
+		t.Errorf("expected the original server after a temporary redirect, got %q", c.server)
+	}
+	if c.opts.Server != "tcp://home:1883" {
+		t.Errorf("options changed to %q", c.opts.Server)
+	}
+}
+
+func TestRedirectURL(t *testing.T) {
+	tests := []struct {
+		current, ref, want string
+	}{
+		{"tcp://a:1883", "tls://b:8883", "tls://b:8883"},
+		{"tcp://a:1883", "b:1884", "tcp://b:1884"},
+		{"tls://a:8883", "b", "tls://b"},
+		{"tcp://a:1883", "b:1883 c:1883", "tcp://b:1883"},
+		{"tcp://a:1883", "  ws://b/mqtt  c:1883", "ws://b/mqtt"},
+	}
+	for _, tt := range tests {
+		if got := redirectURL(tt.current, tt.ref); got != tt.want {
+			t.Errorf("redirectURL(%q, %q) = %q, want %q", tt.current, tt.ref, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestSessionPresent(t *testing.T) {
+	tests := []struct {
+		name           string
+		cleanSession   bool
+		sessionPresent bool
+	}{
+		{"clean start", true, false},
+		{"resumed session", false, true},
+		{"resume requested but session expired", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+
+			go func() {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+
+				if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+					return
+				}
+				connack := &packets.ConnackPacket{ReturnCode: 0, SessionPresent: tt.sessionPresent}
+				if _, err := connack.WriteTo(conn); err != nil {
+					return
+				}
+				_, _ = packets.ReadPacket(conn, ProtocolV50, 0)
+			}()
+
+			client, err := Dial("tcp://"+ln.Addr().String(),
+				WithClientID("session-present"),
+				WithCleanSession(tt.cleanSession),
+				WithSessionExpiryInterval(3600),
+				WithAutoReconnect(false),
+			)
+			if err != nil {
+				t.Fatalf("Dial failed: %v", err)
+			}
+			defer func() { _ = client.Disconnect(context.Background()) }()
+
+			if got := client.SessionPresent(); got != tt.sessionPresent {
+				t.Errorf("SessionPresent() = %v, want %v", got, tt.sessionPresent)
+			}
+		})
+	}
+}
+
+func TestCleanStartWithSessionPresentIsProtocolError(t *testing.T) {
+	tests := []struct {
+		name    string
+		version uint8
+	}{
+		{"v5.0", ProtocolV50},
+		{"v3.1.1", ProtocolV311},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+
+			// The packet read after CONNACK: a DISCONNECT (v5.0) or nil if the
+			// connection was closed without one.
+			after := make(chan packets.Packet, 1)
+			go func() {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+
+				if _, err := packets.ReadPacket(conn, tt.version, 0); err != nil {
+					return
+				}
+				// Broken server: claims a session exists despite the clean start
+				connack := &packets.ConnackPacket{ReturnCode: 0, SessionPresent: true}
+				if _, err := connack.WriteTo(conn); err != nil {
+					return
+				}
+				pkt, _ := packets.ReadPacket(conn, tt.version, 0)
+				after <- pkt
+			}()
+
+			client, err := Dial("tcp://"+ln.Addr().String(),
+				WithClientID("clean-start"),
+				WithProtocolVersion(tt.version),
+				WithCleanSession(true),
+				WithAutoReconnect(false),
+			)
+			if err == nil {
+				_ = client.Disconnect(context.Background())
+				t.Fatal("expected Dial to fail")
+			}
+			if !errors.Is(err, ErrProtocolViolation) {
+				t.Errorf("expected ErrProtocolViolation, got %v", err)
+			}
+
+			select {
+			case pkt := <-after:
+				if tt.version < ProtocolV50 {
+					if pkt != nil {
+						t.Errorf("expected connection to be closed, got %T", pkt)
+					}
+					return
+				}
+				disc, ok := pkt.(*packets.DisconnectPacket)
+				if !ok {
+					t.Fatalf("expected DISCONNECT, got %T", pkt)
+				}
+				if disc.ReasonCode != uint8(ReasonCodeProtocolError) {
+					t.Errorf("DISCONNECT reason = 0x%02X, want 0x82", disc.ReasonCode)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("timeout waiting for the client to close the connection")
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// takeoverServer accepts connections, sends DISCONNECT with reason code 0x8E
+// (Session taken over) after the CONNACK of the first one and reports every
+// CONNECT.
+func takeoverServer(t *testing.T, connects chan<- string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for n := 1; ; n++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn, first bool) {
+				defer conn.Close()
+				pkt, err := packets.ReadPacket(conn, ProtocolV50, 0)
+				if err != nil {
+					return
+				}
+				connects <- pkt.(*packets.ConnectPacket).ClientID
+				if _, err := (&packets.ConnackPacket{ReturnCode: 0}).WriteTo(conn); err != nil {
+					return
+				}
+				if first {
+					disc := &packets.DisconnectPacket{
+						ReasonCode: uint8(ReasonCodeSessionTakenOver),
+						Version:    ProtocolV50,
+					}
+					_, _ = disc.WriteTo(conn)
+					time.Sleep(100 * time.Millisecond)
+					return
+				}
+				for {
+					if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+						return
+					}
+				}
+			}(conn, n == 1)
+		}
+	}()
+
+	return "tcp://" + ln.Addr().String()
+}
+
+func TestSessionTakeover_StopsReconnecting(t *testing.T) {
+	connects := make(chan string, 4)
+	server := takeoverServer(t, connects)
+
+	takenOver := make(chan error, 1)
+	lost := make(chan error, 1)
+	client, err := Dial(server,
+		WithClientID("worker"),
+		WithReconnectBackoff(10*time.Millisecond, 10*time.Millisecond, 1),
+		WithConnectionLostGracePeriod(time.Hour),
+		WithOnSessionTakenOver(func(_ *Client, err error) { takenOver <- err }),
+		WithOnConnectionLost(func(_ *Client, err error) { lost <- err }),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+	<-connects
+
+	for name, ch := range map[string]chan error{"OnSessionTakenOver": takenOver, "OnConnectionLost": lost} {
+		select {
+		case err := <-ch:
+			if !errors.Is(err, ReasonCodeSessionTakenOver) {
+				t.Errorf("%s error = %v, want session taken over", name, err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for %s (grace period must not delay it)", name)
+		}
+	}
+
+	select {
+	case <-connects:
+		t.Fatal("client reconnected after the session was taken over")
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	select {
+	case <-client.stop:
+	case <-time.After(time.Second):
+		t.Fatal("client not stopped")
+	}
+}
+
+func TestSessionTakeover_ReconnectPolicy(t *testing.T) {
+	connects := make(chan string, 4)
+	server := takeoverServer(t, connects)
+
+	takenOver := make(chan error, 1)
+	client, err := Dial(server,
+		WithClientID("worker"),
+		WithReconnectBackoff(10*time.Millisecond, 10*time.Millisecond, 1),
+		WithSessionTakeoverPolicy(SessionTakeoverReconnect),
+		WithOnSessionTakenOver(func(_ *Client, err error) { takenOver <- err }),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+	<-connects
+
+	select {
+	case <-takenOver:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for OnSessionTakenOver")
+	}
+	select {
+	case id := <-connects:
+		if id != "worker" {
+			t.Errorf("reconnected with client ID %q, want worker", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("client did not reconnect")
+	}
+}
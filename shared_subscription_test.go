@@ -18,6 +18,7 @@ func TestSharedSubscriptionNoLocalValidation(t *testing.T) {
 		subscriptions: make(map[string]subscriptionEntry),
 		pending:       make(map[uint16]*pendingOp),
 	}
+	c.connected.Store(true)
 	// Simple logger
 	c.opts.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
 
@@ -0,0 +1,248 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// readUntilDisconnect returns the types of the packets read from conn up to
+// and including DISCONNECT.
+func readUntilDisconnect(conn net.Conn) ([]string, error) {
+	var got []string
+	for {
+		pkt, err := packets.ReadPacket(conn, ProtocolV50, 0)
+		if err != nil {
+			return got, err
+		}
+		got = append(got, fmt.Sprintf("%T", pkt))
+		if _, ok := pkt.(*packets.DisconnectPacket); ok {
+			return got, nil
+		}
+	}
+}
+
+func TestShutdownAckTimeout_HandlerFinishes(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	serverDone := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = packets.ReadPacket(conn, ProtocolV50, 0)
+		_, _ = (&packets.ConnackPacket{ReturnCode: 0}).WriteTo(conn)
+		pub := &packets.PublishPacket{Topic: "jobs/1", QoS: 1, PacketID: 1, Payload: []byte("a"), Version: ProtocolV50}
+		_, _ = pub.WriteTo(conn)
+		got, _ := readUntilDisconnect(conn)
+		serverDone <- got
+	}()
+
+	started := make(chan struct{})
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithAutoReconnect(false),
+		WithReceiveMaximum(10, LimitPolicyBackpressure),
+		WithShutdownAckTimeout(2*time.Second),
+		WithDefaultPublishHandler(func(_ *Client, _ Message) {
+			close(started)
+			time.Sleep(200 * time.Millisecond)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+
+	<-started
+	if err := client.Disconnect(context.Background()); err != nil {
+		t.Fatalf("Disconnect failed: %v", err)
+	}
+
+	select {
+	case got := <-serverDone:
+		want := []string{"*packets.PubackPacket", "*packets.DisconnectPacket"}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("server received %v, want %v", got, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for server")
+	}
+}
+
+func TestShutdownAckTimeout_UnackedRedelivered(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	jobs := []*packets.PublishPacket{
+		{Topic: "jobs/1", QoS: 1, PacketID: 1, Payload: []byte("a"), Version: ProtocolV50},
+		{Topic: "jobs/2", QoS: 2, PacketID: 2, Payload: []byte("b"), Version: ProtocolV50},
+	}
+
+	firstSession := make(chan []string, 1)
+	gotDisconnect := make(chan struct{})
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- func() error {
+			// First connection: the handlers are still running at shutdown
+			conn1, err := ln.Accept()
+			if err != nil {
+				return err
+			}
+			_, _ = packets.ReadPacket(conn1, ProtocolV50, 0)
+			_, _ = (&packets.ConnackPacket{ReturnCode: 0}).WriteTo(conn1)
+			for _, pub := range jobs {
+				_, _ = pub.WriteTo(conn1)
+			}
+			got, _ := readUntilDisconnect(conn1)
+			close(gotDisconnect)
+			// Anything sent after the DISCONNECT, until the client closes
+			// the connection
+			for {
+				pkt, err := packets.ReadPacket(conn1, ProtocolV50, 0)
+				if err != nil {
+					break
+				}
+				got = append(got, fmt.Sprintf("%T", pkt))
+			}
+			conn1.Close()
+			firstSession <- got
+
+			// Second connection: the session is resumed and the messages
+			// redelivered
+			conn2, err := ln.Accept()
+			if err != nil {
+				return err
+			}
+			defer conn2.Close()
+			_, _ = packets.ReadPacket(conn2, ProtocolV50, 0)
+			connack := &packets.ConnackPacket{ReturnCode: 0, SessionPresent: true}
+			_, _ = connack.WriteTo(conn2)
+			for _, pub := range jobs {
+				pub.Dup = true
+				_, _ = pub.WriteTo(conn2)
+			}
+
+			var pubacked, pubreced bool
+			for !pubacked || !pubreced {
+				pkt, err := packets.ReadPacket(conn2, ProtocolV50, 0)
+				if err != nil {
+					return fmt.Errorf("conn2: waiting for acks: %w", err)
+				}
+				switch p := pkt.(type) {
+				case *packets.PubackPacket:
+					pubacked = p.PacketID == 1
+				case *packets.PubrecPacket:
+					pubreced = p.PacketID == 2
+				default:
+					return fmt.Errorf("conn2: unexpected %T", pkt)
+				}
+			}
+			return nil
+		}()
+	}()
+
+	store, err := NewFileStore(t.TempDir(), "shutdown-ack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionOpts := []Option{
+		WithClientID("shutdown-ack"),
+		WithCleanSession(false),
+		WithSessionExpiryInterval(3600),
+		WithSessionStore(store),
+		WithAutoReconnect(false),
+		WithReceiveMaximum(10, LimitPolicyBackpressure),
+		WithShutdownAckTimeout(100 * time.Millisecond),
+	}
+
+	release := make(chan struct{})
+	var started atomic.Int32
+	client, err := Dial("tcp://"+ln.Addr().String(), append(sessionOpts,
+		WithDefaultPublishHandler(func(_ *Client, _ Message) {
+			started.Add(1)
+			<-release
+		}),
+	)...)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for started.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if started.Load() != 2 {
+		t.Fatalf("expected 2 handlers running, got %d", started.Load())
+	}
+
+	disconnectErr := make(chan error, 1)
+	go func() { disconnectErr <- client.Disconnect(context.Background()) }()
+
+	// The handlers return after the DISCONNECT, while the connection is
+	// still open: too late, their acks must be withheld
+	select {
+	case <-gotDisconnect:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for DISCONNECT")
+	}
+	close(release)
+
+	if err := <-disconnectErr; err != nil {
+		t.Fatalf("Disconnect failed: %v", err)
+	}
+
+	select {
+	case got := <-firstSession:
+		want := []string{"*packets.DisconnectPacket"}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("first session: server received %v, want %v", got, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the first session")
+	}
+
+	if ids, _ := store.LoadReceivedQoS2(); len(ids) != 0 {
+		t.Errorf("expected unacked QoS 2 ID not to be persisted, got %v", ids)
+	}
+
+	received := make(chan string, 2)
+	client2, err := Dial("tcp://"+ln.Addr().String(), append(sessionOpts,
+		WithDefaultPublishHandler(func(_ *Client, msg Message) {
+			received <- msg.Topic
+		}),
+	)...)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client2.Disconnect(context.Background()) }()
+
+	for range 2 {
+		select {
+		case <-received:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for redelivered messages")
+		}
+	}
+
+	select {
+	case err := <-serverErr:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for server")
+	}
+}
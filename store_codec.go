@@ -0,0 +1,184 @@
+package mq
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// StoreCodec serializes the entries persisted by FileStore: PersistedPublish
+// values, the subscription map (map[string]*PersistedSubscription) and the
+// list of received QoS 2 packet IDs ([]uint16).
+//
+// Two codecs are provided: JSONCodec (the default) and BinaryCodec.
+// Custom codecs can be supplied with WithStoreCodec.
+type StoreCodec interface {
+	// Marshal encodes v.
+	Marshal(v any) ([]byte, error)
+
+	// Unmarshal decodes data into v, which is a pointer.
+	Unmarshal(data []byte, v any) error
+
+	// Extension is the file name extension used for entries written by
+	// this codec, without the leading dot (e.g. "json").
+	Extension() string
+}
+
+// JSONCodec stores entries as indented JSON. Files are human-readable and
+// can be inspected or edited by hand while the client is stopped, which is
+// useful when debugging session state. Payloads appear base64 encoded.
+//
+// This is the default codec and matches the format of stores written by
+// earlier versions.
+var JSONCodec StoreCodec = jsonCodec{}
+
+// BinaryCodec stores entries in a compact binary form (encoding/gob).
+// It is smaller and faster to encode than JSONCodec, at the cost of
+// readability.
+var BinaryCodec StoreCodec = binaryCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.MarshalIndent(v, "", "  ")
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Extension() string { return "json" }
+
+type binaryCodec struct{}
+
+// gob does not transmit zero values, so a pointer to 0 (e.g. a message
+// expiry of 0) would decode as nil. The binary codec wraps the persisted
+// types to record which optional properties were present.
+const (
+	gobHasPayloadFormat uint8 = 1 << iota
+	gobHasMessageExpiry
+	gobHasTopicAlias
+	gobHasSubscriptionIdentifier
+)
+
+type gobPublish struct {
+	Publish PersistedPublish
+	Present uint8
+}
+
+type gobSubscription struct {
+	Subscription      PersistedSubscription
+	HasSubscriptionID bool
+}
+
+func (binaryCodec) Marshal(v any) ([]byte, error) {
+	switch t := v.(type) {
+	case *PersistedPublish:
+		v = toGobPublish(t)
+	case map[string]*PersistedSubscription:
+		subs := make(map[string]*gobSubscription, len(t))
+		for topic, sub := range t {
+			subs[topic] = toGobSubscription(sub)
+		}
+		v = subs
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (binaryCodec) Unmarshal(data []byte, v any) error {
+	dec := gob.NewDecoder(bytes.NewReader(data))
+
+	switch t := v.(type) {
+	case *PersistedPublish:
+		var g gobPublish
+		if err := dec.Decode(&g); err != nil {
+			return err
+		}
+		*t = g.fromGob()
+		return nil
+	case *map[string]*PersistedSubscription:
+		var subs map[string]*gobSubscription
+		if err := dec.Decode(&subs); err != nil {
+			return err
+		}
+		*t = make(map[string]*PersistedSubscription, len(subs))
+		for topic, g := range subs {
+			(*t)[topic] = g.fromGob()
+		}
+		return nil
+	}
+
+	return dec.Decode(v)
+}
+
+func (binaryCodec) Extension() string { return "bin" }
+
+func toGobPublish(pub *PersistedPublish) *gobPublish {
+	g := &gobPublish{Publish: *pub}
+	if p := pub.Properties; p != nil {
+		if p.PayloadFormat != nil {
+			g.Present |= gobHasPayloadFormat
+		}
+		if p.MessageExpiry != nil {
+			g.Present |= gobHasMessageExpiry
+		}
+		if p.TopicAlias != nil {
+			g.Present |= gobHasTopicAlias
+		}
+		if p.SubscriptionIdentifier != nil {
+			g.Present |= gobHasSubscriptionIdentifier
+		}
+	}
+	return g
+}
+
+func (g *gobPublish) fromGob() PersistedPublish {
+	pub := g.Publish
+	if g.Present != 0 && pub.Properties == nil {
+		pub.Properties = &PublishProperties{}
+	}
+	if p := pub.Properties; p != nil {
+		if g.Present&gobHasPayloadFormat != 0 && p.PayloadFormat == nil {
+			p.PayloadFormat = new(uint8)
+		}
+		if g.Present&gobHasMessageExpiry != 0 && p.MessageExpiry == nil {
+			p.MessageExpiry = new(uint32)
+		}
+		if g.Present&gobHasTopicAlias != 0 && p.TopicAlias == nil {
+			p.TopicAlias = new(uint16)
+		}
+		if g.Present&gobHasSubscriptionIdentifier != 0 && p.SubscriptionIdentifier == nil {
+			p.SubscriptionIdentifier = new(uint32)
+		}
+	}
+	return pub
+}
+
+func toGobSubscription(sub *PersistedSubscription) *gobSubscription {
+	if sub == nil {
+		return &gobSubscription{}
+	}
+	g := &gobSubscription{Subscription: *sub}
+	if sub.Options != nil && sub.Options.SubscriptionID != nil {
+		g.HasSubscriptionID = true
+	}
+	return g
+}
+
+func (g *gobSubscription) fromGob() *PersistedSubscription {
+	sub := g.Subscription
+	if g.HasSubscriptionID {
+		if sub.Options == nil {
+			sub.Options = &PersistedSubscriptionOptions{}
+		}
+		if sub.Options.SubscriptionID == nil {
+			sub.Options.SubscriptionID = new(uint32)
+		}
+	}
+	return &sub
+}
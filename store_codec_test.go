@@ -0,0 +1,219 @@
+package mq
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFileStore_CodecRoundTrip(t *testing.T) {
+	format := uint8(1)
+	zeroExpiry := uint32(0)
+	subID := uint32(42)
+
+	pending := map[uint16]*PersistedPublish{
+		1: {
+			Topic:   "sensors/temp",
+			Payload: []byte{0x00, 0xff, 0x10},
+			QoS:     1,
+		},
+		2: {
+			Topic:   "sensors/humidity",
+			Payload: []byte("52%"),
+			QoS:     2,
+			Retain:  true,
+			Properties: &PublishProperties{
+				PayloadFormat:   &format,
+				MessageExpiry:   &zeroExpiry, // Zero, but present
+				ResponseTopic:   "replies/1",
+				CorrelationData: []byte("req-1"),
+				UserProperties:  map[string]string{"unit": "percent"},
+				ContentType:     "text/plain",
+			},
+		},
+	}
+	subs := map[string]*PersistedSubscription{
+		"sensors/#": {QoS: 1},
+		"cmd/+": {
+			QoS: 2,
+			Options: &PersistedSubscriptionOptions{
+				NoLocal:           true,
+				RetainAsPublished: true,
+				RetainHandling:    2,
+				SubscriptionID:    &subID,
+				UserProperties:    map[string]string{"k": "v"},
+			},
+		},
+	}
+	qos2 := map[uint16]struct{}{7: {}, 300: {}}
+
+	tests := []struct {
+		name  string
+		codec StoreCodec
+	}{
+		{"json", JSONCodec},
+		{"binary", BinaryCodec},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewFileStore(t.TempDir(), "codec-client", WithStoreCodec(tt.codec))
+			if err != nil {
+				t.Fatalf("NewFileStore failed: %v", err)
+			}
+
+			for id, pub := range pending {
+				if err := store.SavePendingPublish(id, pub); err != nil {
+					t.Fatalf("SavePendingPublish failed: %v", err)
+				}
+			}
+			for topic, sub := range subs {
+				if err := store.SaveSubscription(topic, sub); err != nil {
+					t.Fatalf("SaveSubscription failed: %v", err)
+				}
+			}
+			for id := range qos2 {
+				if err := store.SaveReceivedQoS2(id); err != nil {
+					t.Fatalf("SaveReceivedQoS2 failed: %v", err)
+				}
+			}
+
+			// Files use the codec's extension
+			want := filepath.Join(store.dir, "pending_1."+tt.codec.Extension())
+			if _, err := os.Stat(want); err != nil {
+				t.Errorf("expected %s to exist: %v", want, err)
+			}
+
+			gotPending, err := store.LoadPendingPublishes()
+			if err != nil {
+				t.Fatalf("LoadPendingPublishes failed: %v", err)
+			}
+			if !reflect.DeepEqual(gotPending, pending) {
+				t.Errorf("pending publishes mismatch:\ngot  %#v\nwant %#v", gotPending, pending)
+			}
+			if p := gotPending[2].Properties; p == nil || p.MessageExpiry == nil || p.TopicAlias != nil {
+				t.Errorf("expected presence of optional properties to be preserved, got %#v", p)
+			}
+
+			gotSubs, err := store.LoadSubscriptions()
+			if err != nil {
+				t.Fatalf("LoadSubscriptions failed: %v", err)
+			}
+			if !reflect.DeepEqual(gotSubs, subs) {
+				t.Errorf("subscriptions mismatch:\ngot  %#v\nwant %#v", gotSubs, subs)
+			}
+
+			gotQoS2, err := store.LoadReceivedQoS2()
+			if err != nil {
+				t.Fatalf("LoadReceivedQoS2 failed: %v", err)
+			}
+			if !reflect.DeepEqual(gotQoS2, qos2) {
+				t.Errorf("QoS2 IDs = %v, want %v", gotQoS2, qos2)
+			}
+
+			if err := store.Clear(); err != nil {
+				t.Fatalf("Clear failed: %v", err)
+			}
+			entries, _ := os.ReadDir(store.dir)
+			if len(entries) != 0 {
+				t.Errorf("expected empty directory after Clear, got %d entries", len(entries))
+			}
+		})
+	}
+}
+
+func TestFileStore_JSONIsEditable(t *testing.T) {
+	store, err := NewFileStore(t.TempDir(), "json-client")
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+
+	if err := store.SaveSubscription("a/b", &PersistedSubscription{QoS: 1}); err != nil {
+		t.Fatalf("SaveSubscription failed: %v", err)
+	}
+	if err := store.SavePendingPublish(5, &PersistedPublish{Topic: "a/b", QoS: 1}); err != nil {
+		t.Fatalf("SavePendingPublish failed: %v", err)
+	}
+
+	path := filepath.Join(store.dir, "subscriptions.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+
+	// Human-readable: valid, indented JSON naming the fields
+	if !json.Valid(data) {
+		t.Fatalf("subscriptions file is not valid JSON: %s", data)
+	}
+	if !bytes.Contains(data, []byte("\n  ")) || !bytes.Contains(data, []byte(`"QoS": 1`)) {
+		t.Errorf("expected indented JSON with named fields, got:\n%s", data)
+	}
+
+	// Edit by hand: raise the QoS and add a subscription
+	var raw map[string]map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	raw["a/b"]["QoS"] = 2
+	raw["c/#"] = map[string]any{"QoS": 0}
+	edited, _ := json.MarshalIndent(raw, "", "  ")
+	if err := os.WriteFile(path, edited, 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	// Edit a pending publish in place
+	pendingPath := filepath.Join(store.dir, "pending_5.json")
+	if err := os.WriteFile(pendingPath, []byte(`{"Topic": "a/edited", "QoS": 2}`), 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	reopened, err := NewFileStore(filepath.Dir(store.dir), "json-client")
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+
+	subs, err := reopened.LoadSubscriptions()
+	if err != nil {
+		t.Fatalf("LoadSubscriptions failed: %v", err)
+	}
+	if len(subs) != 2 || subs["a/b"].QoS != 2 || subs["c/#"] == nil {
+		t.Errorf("edits not picked up, got %#v", subs)
+	}
+
+	pending, err := reopened.LoadPendingPublishes()
+	if err != nil {
+		t.Fatalf("LoadPendingPublishes failed: %v", err)
+	}
+	if pub := pending[5]; pub == nil || pub.Topic != "a/edited" || pub.QoS != 2 {
+		t.Errorf("edited pending publish not picked up, got %#v", pub)
+	}
+}
+
+func TestFileStore_CodecsDoNotMix(t *testing.T) {
+	dir := t.TempDir()
+
+	jsonStore, _ := NewFileStore(dir, "mixed")
+	if err := jsonStore.SavePendingPublish(1, &PersistedPublish{Topic: "t", QoS: 1}); err != nil {
+		t.Fatalf("SavePendingPublish failed: %v", err)
+	}
+
+	binStore, _ := NewFileStore(dir, "mixed", WithStoreCodec(BinaryCodec))
+	pending, err := binStore.LoadPendingPublishes()
+	if err != nil {
+		t.Fatalf("LoadPendingPublishes failed: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("expected binary store to ignore JSON entries, got %d", len(pending))
+	}
+
+	// Clear removes entries of either format
+	if err := binStore.Clear(); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if got, _ := jsonStore.LoadPendingPublishes(); len(got) != 0 {
+		t.Errorf("expected Clear to remove JSON entries, got %d", len(got))
+	}
+}
@@ -1,7 +1,9 @@
 package mq
 
 import (
+	"context"
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -17,6 +19,10 @@ type SubscribeOptions struct {
 	Persistence       bool              // Persistence enabled by default (must be manually set to true by default logic)
 	SubscriptionID    int               // MQTT v5.0: Subscription identifier (1-268435455, 0 = none).
 	UserProperties    map[string]string // MQTT v5.0: User properties
+	Ordered           bool              // Deliver messages sequentially (see WithOrdered)
+
+	contextHandler SubscriptionContextHandler // Set by WithSubscriptionContext
+	ctx            context.Context            // Set by SubscribeContext
 }
 
 // SubscribeOption is a functional option for configuring a subscription.
@@ -104,9 +110,31 @@ func WithSubscriptionIdentifier(id int) SubscribeOption {
 	}
 }
 
+// WithOrdered delivers the messages of this subscription to its handler one
+// at a time, in the order they were received, like WithOrderedDelivery does
+// for every subscription of the client.
+//
+// Messages are buffered in a queue owned by the subscription, so the logic
+// loop is never blocked, but a slow message delays every later message of
+// the subscription (head-of-line blocking). Other subscriptions keep their
+// concurrent delivery. This is a local option: it is not sent to the server
+// and not saved in the session store.
+//
+// Example:
+//
+//	client.Subscribe("machines/+/events", mq.AtLeastOnce, stateMachine.Apply,
+//	    mq.WithOrdered())
+func WithOrdered() SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.Ordered = true
+	}
+}
+
 // UnsubscribeOptions holds configuration for an unsubscription.
 type UnsubscribeOptions struct {
 	UserProperties map[string]string // MQTT v5.0: User properties
+
+	ctx context.Context // Set by UnsubscribeContext
 }
 
 // UnsubscribeOption is a functional option for configuring an unsubscription.
@@ -138,8 +166,9 @@ func WithUnsubscribeUserProperty(key, value string) UnsubscribeOption {
 //   - '+' matches a single level (e.g., "sensors/+/temperature")
 //   - '#' matches multiple levels (e.g., "sensors/#")
 //
-// The function returns a Token that completes when the subscription is
-// acknowledged by the server.
+// The function returns a SubscribeToken that completes when the subscription
+// is acknowledged by the server. Its Results method reports the server's
+// answer for the filter, including the granted QoS or the failure reason code.
 //
 // For persistent sessions (CleanSession=false), it is recommended to use the
 // mq.WithSubscription option during Dial instead. This ensures handlers are
@@ -159,15 +188,75 @@ func WithUnsubscribeUserProperty(key, value string) UnsubscribeOption {
 // Example with options (MQTT v5.0):
 //
 //	client.Subscribe("chat/room", 1, handler, mq.WithNoLocal(true))
-func (c *Client) Subscribe(topic string, qos QoS, handler MessageHandler, opts ...SubscribeOption) Token {
+func (c *Client) Subscribe(topic string, qos QoS, handler MessageHandler, opts ...SubscribeOption) SubscribeToken {
 	c.opts.Logger.Debug("subscribing to topic", "topic", topic, "qos", qos)
 
-	if err := validateSubscribeTopic(topic, c.opts); err != nil {
+	subOpts, err := c.subscribeOptions(topic, opts)
+	if err != nil {
 		tok := newToken()
-		tok.complete(fmt.Errorf("invalid topic filter: %w", err))
+		tok.complete(err)
 		return tok
 	}
 
+	pkt := &packets.SubscribePacket{
+		PacketID:          0, // Assigned by internalSubscribe
+		Topics:            []string{topic},
+		QoS:               []uint8{uint8(qos)},
+		NoLocal:           []bool{subOpts.NoLocal},
+		RetainAsPublished: []bool{subOpts.RetainAsPublished},
+		RetainHandling:    []uint8{subOpts.RetainHandling},
+		Version:           c.opts.ProtocolVersion,
+		Properties:        c.subscribeProperties(subOpts),
+	}
+
+	tok := newToken()
+
+	req := &subscribeRequest{
+		packet:         pkt,
+		handler:        handler,
+		contextHandler: subOpts.contextHandler,
+		token:          tok,
+		persistence:    subOpts.Persistence,
+		ordered:        subOpts.Ordered,
+		ctx:            subOpts.ctx,
+	}
+
+	c.internalSubscribe(req)
+
+	return tok
+}
+
+// SubscribeContext is like Subscribe, but ctx bounds the enqueue step: if the
+// SUBSCRIBE packet cannot be handed to the network layer before ctx is done
+// (for example because the outgoing queue is full while the server stalls),
+// nothing is sent, the subscription is not registered and the token
+// completes with ctx.Err().
+//
+// Once the packet has been queued for sending, ctx no longer affects it; use
+// token.Wait with a context to bound the wait for the SUBACK.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+//	defer cancel()
+//	token := client.SubscribeContext(ctx, "jobs/#", 1, handler)
+//	if err := token.Wait(ctx); err != nil {
+//	    return fmt.Errorf("subscribe failed: %w", err)
+//	}
+func (c *Client) SubscribeContext(ctx context.Context, topic string, qos QoS, handler MessageHandler, opts ...SubscribeOption) SubscribeToken {
+	opts = append(opts[:len(opts):len(opts)], func(o *SubscribeOptions) {
+		o.ctx = ctx
+	})
+	return c.Subscribe(topic, qos, handler, opts...)
+}
+
+// subscribeOptions applies opts for a subscription to topic and validates
+// the result.
+func (c *Client) subscribeOptions(topic string, opts []SubscribeOption) (*SubscribeOptions, error) {
+	if err := validateSubscribeTopic(topic, c.opts); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTopicFilter, err)
+	}
+
 	subOpts := &SubscribeOptions{
 		Persistence: true,
 	}
@@ -177,64 +266,78 @@ func (c *Client) Subscribe(topic string, qos QoS, handler MessageHandler, opts .
 
 	// Validate subscription ID (MQTT v5.0)
 	if subOpts.SubscriptionID != 0 && (subOpts.SubscriptionID < 1 || subOpts.SubscriptionID > 268435455) {
-		tok := newToken()
-		tok.complete(fmt.Errorf("subscription identifier must be in range 0-268435455, got %d", subOpts.SubscriptionID))
-		return tok
+		return nil, fmt.Errorf("subscription identifier must be in range 0-268435455, got %d", subOpts.SubscriptionID)
 	}
 
 	// Validate Shared Subscription constraints (MQTT v5.0)
 	// it is a Protocol Error to set the No Local option to 1 on a Shared Subscription
 	if subOpts.NoLocal && strings.HasPrefix(topic, "$share/") {
-		tok := newToken()
-		tok.complete(fmt.Errorf("protocol error: NoLocal cannot be set on a Shared Subscription"))
-		return tok
+		return nil, fmt.Errorf("protocol error: NoLocal cannot be set on a Shared Subscription")
 	}
 
-	pkt := &packets.SubscribePacket{
-		PacketID:          0, // Assigned by internalSubscribe
-		Topics:            []string{topic},
-		QoS:               []uint8{uint8(qos)},
-		NoLocal:           []bool{subOpts.NoLocal},
-		RetainAsPublished: []bool{subOpts.RetainAsPublished},
-		RetainHandling:    []uint8{subOpts.RetainHandling},
-		Version:           c.opts.ProtocolVersion,
+	return subOpts, nil
+}
+
+// checkSubscribeSupport returns an error if pkt uses a feature the server
+// reported as unavailable in CONNACK: wildcards, shared subscriptions or
+// subscription identifiers. The server would otherwise reject the
+// subscription in SUBACK or disconnect. MQTT v3.1.1 servers report nothing,
+// so nothing is checked.
+func (c *Client) checkSubscribeSupport(pkt *packets.SubscribePacket) error {
+	if c.opts.ProtocolVersion < ProtocolV50 {
+		return nil
 	}
 
-	if c.opts.ProtocolVersion >= ProtocolV50 {
-		props := &packets.Properties{}
-		hasProps := false
+	caps := c.serverCaps
+	if !caps.fromConnack {
+		// Not connected yet: everything is available until a CONNACK says
+		// otherwise
+		caps = extractServerCapabilities(nil)
+	}
 
-		if subOpts.SubscriptionID > 0 {
-			props.SubscriptionIdentifier = []int{subOpts.SubscriptionID}
-			hasProps = true
+	if !caps.SubscriptionIDAvailable && pkt.Properties != nil &&
+		len(pkt.Properties.SubscriptionIdentifier) > 0 {
+		return ErrSubscriptionIDNotSupported
+	}
+	for _, topic := range pkt.Topics {
+		if !caps.SharedSubscriptionAvailable && strings.HasPrefix(topic, "$share/") {
+			return fmt.Errorf("%w: %s", ErrSharedSubNotSupported, topic)
 		}
-		if len(subOpts.UserProperties) > 0 {
-			for k, v := range subOpts.UserProperties {
-				props.UserProperties = append(props.UserProperties, packets.UserProperty{
-					Key:   k,
-					Value: v,
-				})
-			}
-			hasProps = true
+		if !caps.WildcardAvailable && strings.ContainsAny(topic, "+#") {
+			return fmt.Errorf("%w: %s", ErrWildcardNotSupported, topic)
 		}
+	}
+	return nil
+}
 
-		if hasProps {
-			pkt.Properties = props
-		}
+// subscribeProperties returns the SUBSCRIBE properties for subOpts, or nil
+// if there are none (or the protocol is older than MQTT v5.0).
+func (c *Client) subscribeProperties(subOpts *SubscribeOptions) *packets.Properties {
+	if c.opts.ProtocolVersion < ProtocolV50 {
+		return nil
 	}
 
-	tok := newToken()
+	props := &packets.Properties{}
+	hasProps := false
 
-	req := &subscribeRequest{
-		packet:      pkt,
-		handler:     handler,
-		token:       tok,
-		persistence: subOpts.Persistence,
+	if subOpts.SubscriptionID > 0 {
+		props.SubscriptionIdentifier = []int{subOpts.SubscriptionID}
+		hasProps = true
+	}
+	if len(subOpts.UserProperties) > 0 {
+		for k, v := range subOpts.UserProperties {
+			props.UserProperties = append(props.UserProperties, packets.UserProperty{
+				Key:   k,
+				Value: v,
+			})
+		}
+		hasProps = true
 	}
 
-	c.internalSubscribe(req)
-
-	return tok
+	if !hasProps {
+		return nil
+	}
+	return props
 }
 
 // Unsubscribe unsubscribes from a single topic.
@@ -254,7 +357,59 @@ func (c *Client) Subscribe(topic string, qos QoS, handler MessageHandler, opts .
 //
 //	client.Unsubscribe("logs", mq.WithUnsubscribeUserProperty("reason", "done"))
 func (c *Client) Unsubscribe(topic string, opts ...UnsubscribeOption) Token {
-	c.opts.Logger.Debug("unsubscribing from topic", "topic", topic)
+	return c.UnsubscribeWithOptions([]string{topic}, opts...)
+}
+
+// UnsubscribeContext is like Unsubscribe, but ctx bounds the enqueue step: if
+// the UNSUBSCRIBE packet cannot be handed to the network layer before ctx is
+// done, nothing is sent, the local subscription is kept and the token
+// completes with ctx.Err().
+//
+// Once the packet has been queued for sending, ctx no longer affects it; use
+// token.Wait with a context to bound the wait for the UNSUBACK.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+//	defer cancel()
+//	if err := client.UnsubscribeContext(ctx, "jobs/#").Wait(ctx); err != nil {
+//	    log.Printf("unsubscribe failed: %v", err)
+//	}
+func (c *Client) UnsubscribeContext(ctx context.Context, topic string, opts ...UnsubscribeOption) Token {
+	opts = append(opts[:len(opts):len(opts)], func(o *UnsubscribeOptions) {
+		o.ctx = ctx
+	})
+	return c.Unsubscribe(topic, opts...)
+}
+
+// UnsubscribeWithOptions unsubscribes from several topic filters with a single
+// UNSUBSCRIBE packet. The options, such as WithUnsubscribeUserProperty, apply
+// to the whole packet; MQTT v5.0 user properties are ignored on v3.1.1
+// connections.
+//
+// The returned Token completes when the UNSUBACK arrives, and fails if the
+// server rejected any of the filters (MQTT v5.0). An empty or duplicated
+// topic list fails the call without sending anything.
+//
+// Example:
+//
+//	token := client.UnsubscribeWithOptions([]string{"jobs/#", "status"},
+//	    mq.WithUnsubscribeUserProperty("audit", "shutdown"))
+//	token.Wait(ctx)
+func (c *Client) UnsubscribeWithOptions(topics []string, opts ...UnsubscribeOption) Token {
+	c.opts.Logger.Debug("unsubscribing from topics", "topics", topics)
+
+	tok := newToken()
+	if len(topics) == 0 {
+		tok.complete(fmt.Errorf("no topics to unsubscribe from"))
+		return tok
+	}
+	for i, topic := range topics {
+		if slices.Contains(topics[:i], topic) {
+			tok.complete(fmt.Errorf("duplicate topic %q", topic))
+			return tok
+		}
+	}
 
 	unsubOpts := &UnsubscribeOptions{}
 	for _, opt := range opts {
@@ -262,7 +417,7 @@ func (c *Client) Unsubscribe(topic string, opts ...UnsubscribeOption) Token {
 	}
 
 	pkt := &packets.UnsubscribePacket{
-		Topics:  []string{topic},
+		Topics:  slices.Clone(topics),
 		Version: c.opts.ProtocolVersion,
 	}
 
@@ -285,13 +440,18 @@ func (c *Client) Unsubscribe(topic string, opts ...UnsubscribeOption) Token {
 		}
 	}
 
-	tok := newToken()
 	req := &unsubscribeRequest{
 		packet: pkt,
-		topics: []string{topic},
+		topics: pkt.Topics,
 		token:  tok,
+		ctx:    unsubOpts.ctx,
+	}
+	if !c.internalUnsubscribe(req) {
+		return tok
+	}
+	for _, topic := range topics {
+		c.removeDynamicSubscription(topic)
 	}
-	c.internalUnsubscribe(req)
 
 	return tok
 }
@@ -361,8 +521,14 @@ func (c *Client) resubscribeAll() {
 
 		// Send one packet for each group
 		for _, g := range groups {
+			id, ok := c.nextID()
+			if !ok {
+				c.opts.Logger.Warn("cannot resubscribe, every packet ID is in use",
+					"topics_count", len(g.topics))
+				continue
+			}
 			pkt := &packets.SubscribePacket{
-				PacketID:          c.nextID(),
+				PacketID:          id,
 				Topics:            g.topics,
 				QoS:               g.qos,
 				NoLocal:           g.noLocal,
@@ -400,6 +566,7 @@ func (c *Client) resubscribeAll() {
 				token:     newToken(),
 				qos:       1,
 				timestamp: time.Now(),
+				created:   time.Now(),
 			}
 
 			select {
@@ -435,3 +602,20 @@ func subGroupKey(id int, props map[string]string) string {
 	}
 	return sb.String()
 }
+
+// suppressRetainedOnResubscribe marks the active subscriptions so retained
+// messages the server resends for them after a reconnect are dropped.
+// Subscriptions with RetainAsPublished are skipped: there live messages
+// can carry the Retained flag too.
+func (c *Client) suppressRetainedOnResubscribe() {
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+
+	for filter, entry := range c.subscriptions {
+		if entry.options.RetainAsPublished || entry.suppressRetained {
+			continue
+		}
+		entry.suppressRetained = true
+		c.subscriptions[filter] = entry
+	}
+}
@@ -0,0 +1,133 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// newStalledClient returns a connected client whose outgoing queue is full,
+// as when the broker stops reading.
+func newStalledClient(t *testing.T) *Client {
+	t.Helper()
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.OutgoingQueueSize = 1
+	c := newPublishContextClient(t, opts)
+	c.connected.Store(true)
+	c.outgoing <- &packets.PingreqPacket{}
+	return c
+}
+
+func TestSubscribeContext(t *testing.T) {
+	handler := func(*Client, Message) {}
+
+	t.Run("already done", func(t *testing.T) {
+		c := newStalledClient(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if err := waitToken(t, c.SubscribeContext(ctx, "a/#", AtLeastOnce, handler)); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+		if len(c.pending) != 0 || len(c.subscriptions) != 0 {
+			t.Error("expected nothing to be registered")
+		}
+	})
+
+	t.Run("full outgoing queue", func(t *testing.T) {
+		c := newStalledClient(t)
+		previous := subscriptionEntry{handler: handler, qos: 0}
+		c.subscriptions["b"] = previous
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		tok := c.SubscribeContext(ctx, "a/#", AtLeastOnce, handler)
+		if err := waitToken(t, tok); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("subscribe blocked for %v", elapsed)
+		}
+		if len(c.pending) != 0 {
+			t.Errorf("expected the packet ID to be released, pending: %v", c.pending)
+		}
+		if _, ok := c.subscriptions["a/#"]; ok {
+			t.Error("unsent subscription must not stay registered")
+		}
+
+		// Resubscribing an existing filter restores the old entry
+		ctx2, cancel2 := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel2()
+		if err := waitToken(t, c.SubscribeContext(ctx2, "b", ExactlyOnce, handler)); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+		}
+		if got := c.subscriptions["b"]; got.qos != previous.qos {
+			t.Errorf("existing subscription changed to QoS %d", got.qos)
+		}
+	})
+
+	t.Run("queued", func(t *testing.T) {
+		c := newPublishContextClient(t, nil)
+		c.connected.Store(true)
+
+		tok := c.SubscribeContext(context.Background(), "a/#", AtLeastOnce, handler)
+		select {
+		case pkt := <-c.outgoing:
+			if _, ok := pkt.(*packets.SubscribePacket); !ok {
+				t.Fatalf("expected SUBSCRIBE, got %T", pkt)
+			}
+		default:
+			t.Fatal("nothing was sent")
+		}
+		select {
+		case <-tok.Done():
+			t.Fatalf("token completed before the SUBACK: %v", tok.Error())
+		default:
+		}
+	})
+}
+
+func TestUnsubscribeContext(t *testing.T) {
+	t.Run("full outgoing queue", func(t *testing.T) {
+		c := newStalledClient(t)
+		c.subscriptions["a/#"] = subscriptionEntry{handler: func(*Client, Message) {}}
+		c.dynamicSubs = map[string]*dynamicSubscription{"a/#": {}}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if err := waitToken(t, c.UnsubscribeContext(ctx, "a/#")); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+		}
+		if len(c.pending) != 0 {
+			t.Errorf("expected the packet ID to be released, pending: %v", c.pending)
+		}
+		if _, ok := c.subscriptions["a/#"]; !ok {
+			t.Error("unsent unsubscribe must keep the subscription")
+		}
+		if _, ok := c.dynamicSubs["a/#"]; !ok {
+			t.Error("unsent unsubscribe must keep the subscription for reconnects")
+		}
+	})
+
+	t.Run("queued", func(t *testing.T) {
+		c := newPublishContextClient(t, nil)
+		c.subscriptions["a/#"] = subscriptionEntry{handler: func(*Client, Message) {}}
+
+		_ = c.UnsubscribeContext(context.Background(), "a/#")
+		select {
+		case pkt := <-c.outgoing:
+			if _, ok := pkt.(*packets.UnsubscribePacket); !ok {
+				t.Fatalf("expected UNSUBSCRIBE, got %T", pkt)
+			}
+		default:
+			t.Fatal("nothing was sent")
+		}
+		if _, ok := c.subscriptions["a/#"]; ok {
+			t.Error("subscription must be removed once queued")
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package mq
+
+import "slices"
+
+// deferredSubscribe is a subscription requested while disconnected
+// (SubscribeWhileDisconnectedQueue). Its filters are already registered
+// locally and resubscribeAll sends them on reconnect; the token completes once
+// a SUBACK has reported on every filter.
+type deferredSubscribe struct {
+	topics  []string
+	token   *token
+	results map[string]SubscribeResult
+}
+
+// deferSubscribe records a subscribe token to complete after the reconnect.
+// Must be called with sessionLock held.
+func (c *Client) deferSubscribe(topics []string, tok *token) {
+	c.deferredSubscribes = append(c.deferredSubscribes, &deferredSubscribe{
+		topics:  topics,
+		token:   tok,
+		results: make(map[string]SubscribeResult, len(topics)),
+	})
+}
+
+// completeDeferredSubscribes completes the deferred subscribe tokens covered
+// by the results of a SUBACK. Must be called with sessionLock held.
+func (c *Client) completeDeferredSubscribes(results []SubscribeResult) {
+	if len(c.deferredSubscribes) == 0 {
+		return
+	}
+
+	c.deferredSubscribes = slices.DeleteFunc(c.deferredSubscribes, func(d *deferredSubscribe) bool {
+		for _, r := range results {
+			if slices.Contains(d.topics, r.Topic) {
+				d.results[r.Topic] = r
+			}
+		}
+		if len(d.results) < len(d.topics) {
+			return false
+		}
+
+		var err error
+		ordered := make([]SubscribeResult, len(d.topics))
+		for i, topic := range d.topics {
+			ordered[i] = d.results[topic]
+			if err == nil && !ordered[i].Success {
+				err = ErrSubscriptionFailed
+				if c.opts.ProtocolVersion >= ProtocolV50 {
+					err = &MqttError{ReasonCode: ordered[i].ReasonCode, Parent: ErrSubscriptionFailed}
+				}
+			}
+		}
+		d.token.results = ordered
+		d.token.reasonCode = ordered[0].ReasonCode
+		d.token.complete(err)
+		return true
+	})
+}
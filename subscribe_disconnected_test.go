@@ -0,0 +1,115 @@
+package mq
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestSubscribeWhileDisconnected_Fail(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		policy        SubscribeWhileDisconnectedPolicy
+		autoReconnect bool
+	}{
+		{"default policy", SubscribeWhileDisconnectedFail, true},
+		{"queue without auto-reconnect", SubscribeWhileDisconnectedQueue, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := defaultOptions("tcp://localhost:1883")
+			opts.Logger = testLogger()
+			opts.AutoReconnect = tc.autoReconnect
+			opts.SubscribeWhileDisconnected = tc.policy
+			c := newTestClient(opts)
+
+			tok := c.Subscribe("alerts/#", AtLeastOnce, func(*Client, Message) {})
+			select {
+			case <-tok.Done():
+			default:
+				t.Fatal("expected the token to complete immediately")
+			}
+			if !errors.Is(tok.Error(), ErrClientDisconnected) {
+				t.Errorf("expected ErrClientDisconnected, got %v", tok.Error())
+			}
+			if len(c.subscriptions) != 0 || len(c.pending) != 0 || len(c.outgoing) != 0 {
+				t.Errorf("expected nothing registered or queued, got %d subscriptions, %d pending, %d queued",
+					len(c.subscriptions), len(c.pending), len(c.outgoing))
+			}
+		})
+	}
+}
+
+func TestSubscribeWhileDisconnected_Queue(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     uint8
+		wantErr  bool
+		wantCode ReasonCode
+	}{
+		{"granted", 0x01, false, ReasonCodeGrantedQoS1},
+		{"rejected", 0x87, true, ReasonCodeNotAuthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultOptions("tcp://localhost:1883")
+			opts.Logger = testLogger()
+			opts.SubscribeWhileDisconnected = SubscribeWhileDisconnectedQueue
+			c := newTestClient(opts)
+
+			tok := c.Subscribe("alerts/#", AtLeastOnce, func(*Client, Message) {})
+
+			select {
+			case <-tok.Done():
+				t.Fatalf("expected the token to wait for the reconnect, got %v", tok.Error())
+			default:
+			}
+			if _, ok := c.subscriptions["alerts/#"]; !ok {
+				t.Fatal("expected the subscription to be registered locally")
+			}
+			if len(c.outgoing) != 0 {
+				t.Fatal("expected nothing to be sent while disconnected")
+			}
+
+			// Reconnect: resubscribeAll sends the queued subscription
+			c.connected.Store(true)
+			c.resubscribeAll()
+
+			var sub *packets.SubscribePacket
+			select {
+			case pkt := <-c.outgoing:
+				sub, _ = pkt.(*packets.SubscribePacket)
+			default:
+			}
+			if sub == nil || len(sub.Topics) != 1 || sub.Topics[0] != "alerts/#" {
+				t.Fatalf("expected SUBSCRIBE for alerts/# after reconnect, got %#v", sub)
+			}
+
+			c.sessionLock.Lock()
+			c.handleIncoming(&packets.SubackPacket{PacketID: sub.PacketID, ReturnCodes: []uint8{tt.code}})
+			c.sessionLock.Unlock()
+
+			select {
+			case <-tok.Done():
+			default:
+				t.Fatal("expected the token to complete on the reconnect SUBACK")
+			}
+			if gotErr := tok.Error() != nil; gotErr != tt.wantErr {
+				t.Errorf("Error() = %v, wantErr %v", tok.Error(), tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(tok.Error(), ErrSubscriptionFailed) {
+				t.Errorf("expected ErrSubscriptionFailed, got %v", tok.Error())
+			}
+			if tok.ReasonCode() != tt.wantCode {
+				t.Errorf("ReasonCode() = %v, want %v", tok.ReasonCode(), tt.wantCode)
+			}
+			if res := tok.Results(); len(res) != 1 || res[0].Topic != "alerts/#" {
+				t.Errorf("unexpected results %+v", res)
+			}
+			if len(c.deferredSubscribes) != 0 {
+				t.Errorf("expected no deferred subscriptions left, got %d", len(c.deferredSubscribes))
+			}
+		})
+	}
+}
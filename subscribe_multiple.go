@@ -0,0 +1,122 @@
+package mq
+
+import (
+	"fmt"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// TopicSubscription is a subscription to make with SubscribeMultiple.
+type TopicSubscription struct {
+	Topic   string
+	QoS     QoS
+	Handler MessageHandler
+	Options []SubscribeOption
+}
+
+// SubscribeMultiple subscribes to several topic filters with a single
+// SUBSCRIBE packet, each with its own QoS, handler and options.
+//
+// All handlers are registered before the packet is sent, so messages the
+// server delivers before its SUBACK are not missed. The returned token
+// completes when the SUBACK arrives; it fails if any filter was rejected,
+// and Results reports the outcome (including the granted QoS) of every
+// filter, in the order given.
+//
+// A SUBSCRIBE packet carries a single Subscription Identifier and a single
+// set of User Properties (MQTT v5.0), so every entry must use the same
+// WithSubscriptionIdentifier and WithSubscribeUserProperty values. Use
+// separate Subscribe calls for subscriptions that need different ones.
+// Invalid entries, or an empty or duplicated topic list, fail the whole
+// call without sending anything.
+//
+// Example:
+//
+//	token := client.SubscribeMultiple([]mq.TopicSubscription{
+//	    {Topic: "sensors/+/temp", QoS: mq.AtLeastOnce, Handler: onTemp},
+//	    {Topic: "alerts/#", QoS: mq.ExactlyOnce, Handler: onAlert},
+//	    {Topic: "status", QoS: mq.AtMostOnce, Handler: onStatus,
+//	        Options: []mq.SubscribeOption{mq.WithPersistence(false)}},
+//	})
+//	if err := token.Wait(ctx); err != nil {
+//	    for _, r := range token.Results() {
+//	        log.Printf("%s: success=%v qos=%d", r.Topic, r.Success, r.GrantedQoS)
+//	    }
+//	}
+func (c *Client) SubscribeMultiple(subs []TopicSubscription) SubscribeToken {
+	c.opts.Logger.Debug("subscribing to topics", "topics_count", len(subs))
+
+	tok := newToken()
+
+	req, err := c.newSubscribeMultipleRequest(subs)
+	if err != nil {
+		tok.complete(err)
+		return tok
+	}
+	req.token = tok
+
+	c.internalSubscribe(req)
+
+	return tok
+}
+
+// newSubscribeMultipleRequest validates subs and builds the subscribeRequest
+// sending all of them in one packet.
+func (c *Client) newSubscribeMultipleRequest(subs []TopicSubscription) (*subscribeRequest, error) {
+	if len(subs) == 0 {
+		return nil, fmt.Errorf("no topic filters to subscribe to")
+	}
+
+	pkt := &packets.SubscribePacket{
+		PacketID:          0, // Assigned by internalSubscribe
+		Topics:            make([]string, 0, len(subs)),
+		QoS:               make([]uint8, 0, len(subs)),
+		NoLocal:           make([]bool, 0, len(subs)),
+		RetainAsPublished: make([]bool, 0, len(subs)),
+		RetainHandling:    make([]uint8, 0, len(subs)),
+		Version:           c.opts.ProtocolVersion,
+	}
+	targets := make([]subscribeTarget, 0, len(subs))
+	seen := make(map[string]struct{}, len(subs))
+
+	var first *SubscribeOptions
+	var groupKey string
+	for _, sub := range subs {
+		subOpts, err := c.subscribeOptions(sub.Topic, sub.Options)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", sub.Topic, err)
+		}
+		if _, dup := seen[sub.Topic]; dup {
+			return nil, fmt.Errorf("duplicate topic filter %q", sub.Topic)
+		}
+		seen[sub.Topic] = struct{}{}
+
+		// Same grouping rule as resubscribeAll: one packet per
+		// Subscription Identifier and User Properties combination
+		key := subGroupKey(subOpts.SubscriptionID, subOpts.UserProperties)
+		if first == nil {
+			first, groupKey = subOpts, key
+		} else if key != groupKey {
+			return nil, fmt.Errorf("%s: subscription identifier and user properties must be the same for all topic filters", sub.Topic)
+		}
+
+		pkt.Topics = append(pkt.Topics, sub.Topic)
+		pkt.QoS = append(pkt.QoS, uint8(sub.QoS))
+		pkt.NoLocal = append(pkt.NoLocal, subOpts.NoLocal)
+		pkt.RetainAsPublished = append(pkt.RetainAsPublished, subOpts.RetainAsPublished)
+		pkt.RetainHandling = append(pkt.RetainHandling, subOpts.RetainHandling)
+
+		targets = append(targets, subscribeTarget{
+			handler:        sub.Handler,
+			contextHandler: subOpts.contextHandler,
+			persistence:    subOpts.Persistence,
+			ordered:        subOpts.Ordered,
+		})
+	}
+	pkt.Properties = c.subscribeProperties(first)
+
+	return &subscribeRequest{
+		packet:  pkt,
+		targets: targets,
+	}, nil
+}
@@ -0,0 +1,179 @@
+package mq
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestSubscribeMultiple(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	opts.ProtocolVersion = ProtocolV50
+	c := newTestClient(opts)
+	c.connected.Store(true)
+
+	var got []string
+	handler := func(name string) MessageHandler {
+		return func(*Client, Message) { got = append(got, name) }
+	}
+
+	tok := c.SubscribeMultiple([]TopicSubscription{
+		{Topic: "sensors/+/temp", QoS: AtLeastOnce, Handler: handler("temp"),
+			Options: []SubscribeOption{WithSubscriptionIdentifier(7)}},
+		{Topic: "alerts/#", QoS: ExactlyOnce, Handler: handler("alerts"),
+			Options: []SubscribeOption{WithNoLocal(true), WithSubscriptionIdentifier(7)}},
+		{Topic: "status", QoS: AtMostOnce, Handler: handler("status"),
+			Options: []SubscribeOption{WithPersistence(false), WithSubscriptionIdentifier(7)}},
+	})
+
+	var sub *packets.SubscribePacket
+	select {
+	case pkt := <-c.outgoing:
+		sub, _ = pkt.(*packets.SubscribePacket)
+	default:
+	}
+	if sub == nil {
+		t.Fatalf("expected a SUBSCRIBE packet to be queued, token error: %v", tok.Error())
+	}
+	if len(c.outgoing) != 0 {
+		t.Fatalf("expected a single SUBSCRIBE packet, %d more queued", len(c.outgoing))
+	}
+	if strings.Join(sub.Topics, ",") != "sensors/+/temp,alerts/#,status" {
+		t.Errorf("Topics = %v", sub.Topics)
+	}
+	if len(sub.QoS) != 3 || sub.QoS[0] != 1 || sub.QoS[1] != 2 || sub.QoS[2] != 0 {
+		t.Errorf("QoS = %v, want [1 2 0]", sub.QoS)
+	}
+	if len(sub.NoLocal) != 3 || sub.NoLocal[0] || !sub.NoLocal[1] || sub.NoLocal[2] {
+		t.Errorf("NoLocal = %v, want [false true false]", sub.NoLocal)
+	}
+	if sub.Properties == nil || len(sub.Properties.SubscriptionIdentifier) != 1 || sub.Properties.SubscriptionIdentifier[0] != 7 {
+		t.Errorf("expected subscription identifier 7, got %+v", sub.Properties)
+	}
+
+	// Handlers are registered before the SUBACK
+	for _, name := range []string{"sensors/+/temp", "alerts/#", "status"} {
+		entry, ok := c.subscriptions[name]
+		if !ok {
+			t.Fatalf("expected %s to be registered before the SUBACK", name)
+		}
+		entry.handler(c, Message{Topic: name})
+	}
+	if strings.Join(got, ",") != "temp,alerts,status" {
+		t.Errorf("handlers called = %v, want each filter's own handler", got)
+	}
+	if !c.subscriptions["alerts/#"].options.Persistence || c.subscriptions["status"].options.Persistence {
+		t.Error("expected per-filter persistence to be kept")
+	}
+
+	c.sessionLock.Lock()
+	c.handleIncoming(&packets.SubackPacket{PacketID: sub.PacketID, ReturnCodes: []uint8{0x01, 0x01, 0x00}})
+	c.sessionLock.Unlock()
+
+	select {
+	case <-tok.Done():
+	default:
+		t.Fatal("expected the token to complete on the SUBACK")
+	}
+	if err := tok.Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []SubscribeResult{
+		{Topic: "sensors/+/temp", Success: true, GrantedQoS: 1, ReasonCode: ReasonCodeGrantedQoS1},
+		{Topic: "alerts/#", Success: true, GrantedQoS: 1, ReasonCode: ReasonCodeGrantedQoS1},
+		{Topic: "status", Success: true, GrantedQoS: 0, ReasonCode: ReasonCodeGrantedQoS0},
+	}
+	res := tok.Results()
+	if len(res) != len(want) {
+		t.Fatalf("Results() = %+v, want %+v", res, want)
+	}
+	for i := range want {
+		if res[i] != want[i] {
+			t.Errorf("Results()[%d] = %+v, want %+v", i, res[i], want[i])
+		}
+	}
+}
+
+func TestSubscribeMultiple_Invalid(t *testing.T) {
+	noop := func(*Client, Message) {}
+
+	tests := []struct {
+		name string
+		subs []TopicSubscription
+		want string
+	}{
+		{"empty", nil, "no topic filters"},
+		{"invalid filter", []TopicSubscription{
+			{Topic: "ok", Handler: noop},
+			{Topic: "bad/#/filter", Handler: noop},
+		}, "invalid topic filter"},
+		{"duplicate", []TopicSubscription{
+			{Topic: "a", Handler: noop},
+			{Topic: "a", QoS: AtLeastOnce, Handler: noop},
+		}, "duplicate"},
+		{"different subscription identifiers", []TopicSubscription{
+			{Topic: "a", Handler: noop, Options: []SubscribeOption{WithSubscriptionIdentifier(1)}},
+			{Topic: "b", Handler: noop, Options: []SubscribeOption{WithSubscriptionIdentifier(2)}},
+		}, "must be the same"},
+		{"different user properties", []TopicSubscription{
+			{Topic: "a", Handler: noop, Options: []SubscribeOption{WithSubscribeUserProperty("k", "1")}},
+			{Topic: "b", Handler: noop},
+		}, "must be the same"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultOptions("tcp://localhost:1883")
+			opts.Logger = testLogger()
+			opts.ProtocolVersion = ProtocolV50
+			c := newTestClient(opts)
+			c.connected.Store(true)
+
+			tok := c.SubscribeMultiple(tt.subs)
+			select {
+			case <-tok.Done():
+			default:
+				t.Fatal("expected the token to complete immediately")
+			}
+			if err := tok.Error(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Error() = %v, want it to contain %q", err, tt.want)
+			}
+			if len(c.subscriptions) != 0 || len(c.outgoing) != 0 {
+				t.Errorf("expected nothing registered or sent, got %d subscriptions, %d queued",
+					len(c.subscriptions), len(c.outgoing))
+			}
+		})
+	}
+}
+
+func TestSubscribeMultiple_PartialFailure(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	opts.ProtocolVersion = ProtocolV50
+	c := newTestClient(opts)
+	c.connected.Store(true)
+
+	noop := func(*Client, Message) {}
+	tok := c.SubscribeMultiple([]TopicSubscription{
+		{Topic: "allowed/#", QoS: AtLeastOnce, Handler: noop},
+		{Topic: "secret/#", QoS: AtLeastOnce, Handler: noop},
+	})
+
+	sub := (<-c.outgoing).(*packets.SubscribePacket)
+	c.sessionLock.Lock()
+	c.handleIncoming(&packets.SubackPacket{PacketID: sub.PacketID, ReturnCodes: []uint8{0x01, 0x87}})
+	c.sessionLock.Unlock()
+
+	<-tok.Done()
+	if !errors.Is(tok.Error(), ErrSubscriptionFailed) {
+		t.Errorf("expected ErrSubscriptionFailed, got %v", tok.Error())
+	}
+	res := tok.Results()
+	if len(res) != 2 || !res[0].Success || res[1].Success || res[1].ReasonCode != ReasonCodeNotAuthorized {
+		t.Errorf("unexpected results %+v", res)
+	}
+}
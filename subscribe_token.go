@@ -0,0 +1,94 @@
+package mq
+
+// SubscribeResult describes the server's answer for a single topic filter
+// in a SUBSCRIBE request.
+type SubscribeResult struct {
+	// Topic is the topic filter as it was sent to the server.
+	Topic string
+
+	// Success is true if the server accepted the subscription.
+	Success bool
+
+	// GrantedQoS is the maximum QoS the server granted for this filter.
+	// Only meaningful when Success is true.
+	GrantedQoS QoS
+
+	// ReasonCode is the raw code returned in the SUBACK for this filter.
+	// For successful subscriptions it is the granted QoS (0x00, 0x01, 0x02).
+	// For failures it is the failure reason, e.g.:
+	//   - 0x87 Not authorized
+	//   - 0x8F Topic Filter invalid
+	//   - 0x97 Quota exceeded
+	//
+	// MQTT v3.1.1 servers only report 0x80 for failures.
+	ReasonCode ReasonCode
+}
+
+// SubscribeToken is the Token returned by Subscribe. In addition to the
+// overall outcome, it exposes the server's per-filter results.
+//
+// Example:
+//
+//	token := client.Subscribe("sensors/#", mq.AtLeastOnce, handler)
+//	_ = token.Wait(ctx)
+//	for _, r := range token.Results() {
+//	    if !r.Success {
+//	        log.Printf("filter %s rejected: %v", r.Topic, r.ReasonCode)
+//	    }
+//	}
+type SubscribeToken interface {
+	Token
+
+	// Results returns one entry per topic filter, in the order the filters
+	// were sent. It returns nil if the token completed without a SUBACK
+	// (e.g. validation failure or connection loss).
+	// Only valid after the token has completed.
+	Results() []SubscribeResult
+}
+
+// GrantedQoSReporter is implemented by the SubscribeTokens returned by the
+// client. It is a separate interface so that existing SubscribeToken
+// implementations, such as test mocks, keep compiling.
+//
+// Example checking for a downgraded QoS:
+//
+//	token := client.Subscribe("orders/#", mq.ExactlyOnce, handler)
+//	if err := token.Wait(ctx); err == nil {
+//	    if g, ok := token.(mq.GrantedQoSReporter); ok && g.GrantedQoS()[0] < mq.ExactlyOnce {
+//	        log.Print("server downgraded orders/#, expect duplicates")
+//	    }
+//	}
+type GrantedQoSReporter interface {
+	// GrantedQoS returns the QoS granted by the server for each topic
+	// filter, in the order the filters were sent. The server may grant a
+	// lower QoS than requested: an application that asked for
+	// ExactlyOnce but was granted AtLeastOnce must be prepared for
+	// duplicates. Entries for rejected filters hold the failure code
+	// (0x80 or above), so they are never valid QoS values.
+	// It returns nil if the token completed without a SUBACK.
+	// Only valid after the token has completed.
+	GrantedQoS() []QoS
+}
+
+var _ GrantedQoSReporter = (*token)(nil)
+
+// buildSubscribeResults pairs the filters of a SUBSCRIBE packet with the
+// return codes of the matching SUBACK.
+func buildSubscribeResults(topics []string, codes []uint8) []SubscribeResult {
+	results := make([]SubscribeResult, len(topics))
+	for i, topic := range topics {
+		results[i].Topic = topic
+		if i >= len(codes) {
+			// Server did not report on this filter; treat it as a failure.
+			results[i].ReasonCode = ReasonCodeUnspecifiedError
+			continue
+		}
+		code := codes[i]
+		results[i].ReasonCode = ReasonCode(code)
+		if code < 0x80 {
+			results[i].Success = true
+			results[i].GrantedQoS = QoS(code)
+		}
+	}
+	return results
+}
@@ -0,0 +1,164 @@
+package mq
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestSubscribeResultsMixedSuback(t *testing.T) {
+	tests := []struct {
+		name    string
+		version uint8
+		codes   []uint8
+		want    []SubscribeResult
+		wantErr bool
+	}{
+		{
+			name:    "v5 mixed success and failure",
+			version: ProtocolV50,
+			codes:   []uint8{0x01, 0x87, 0x97},
+			want: []SubscribeResult{
+				{Topic: "allowed/#", Success: true, GrantedQoS: 1, ReasonCode: ReasonCodeGrantedQoS1},
+				{Topic: "secret/#", Success: false, ReasonCode: ReasonCodeNotAuthorized},
+				{Topic: "busy/#", Success: false, ReasonCode: ReasonCodeQuotaExceeded},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "v3.1.1 mixed success and failure",
+			version: ProtocolV311,
+			codes:   []uint8{0x02, 0x80, 0x00},
+			want: []SubscribeResult{
+				{Topic: "allowed/#", Success: true, GrantedQoS: 2, ReasonCode: ReasonCodeGrantedQoS2},
+				{Topic: "secret/#", Success: false, ReasonCode: ReasonCodeUnspecifiedError},
+				{Topic: "busy/#", Success: true, GrantedQoS: 0, ReasonCode: ReasonCodeGrantedQoS0},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "missing return codes are reported as failures",
+			version: ProtocolV50,
+			codes:   []uint8{0x00},
+			want: []SubscribeResult{
+				{Topic: "allowed/#", Success: true, GrantedQoS: 0, ReasonCode: ReasonCodeGrantedQoS0},
+				{Topic: "secret/#", Success: false, ReasonCode: ReasonCodeUnspecifiedError},
+				{Topic: "busy/#", Success: false, ReasonCode: ReasonCodeUnspecifiedError},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{
+				opts: &clientOptions{
+					ProtocolVersion: tt.version,
+					Logger:          testLogger(),
+				},
+				pending: make(map[uint16]*pendingOp),
+			}
+
+			tok := newToken()
+			c.pending[1] = &pendingOp{
+				packet: &packets.SubscribePacket{
+					PacketID: 1,
+					Topics:   []string{"allowed/#", "secret/#", "busy/#"},
+					QoS:      []uint8{1, 1, 1},
+					Version:  tt.version,
+				},
+				token: tok,
+			}
+
+			c.handleSuback(&packets.SubackPacket{PacketID: 1, ReturnCodes: tt.codes})
+
+			var st SubscribeToken = tok
+			select {
+			case <-st.Done():
+			default:
+				t.Fatal("token not completed")
+			}
+
+			got := st.Results()
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %d results, got %d", len(tt.want), len(got))
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("result %d: expected %+v, got %+v", i, tt.want[i], got[i])
+				}
+			}
+
+			// The overall error still reports the failure
+			if gotErr := errors.Is(st.Error(), ErrSubscriptionFailed); gotErr != tt.wantErr {
+				t.Errorf("expected ErrSubscriptionFailed=%v, got %v", tt.wantErr, st.Error())
+			}
+		})
+	}
+}
+
+func TestSubscribeResultsNilWithoutSuback(t *testing.T) {
+	c := &Client{
+		opts: &clientOptions{
+			ProtocolVersion: ProtocolV50,
+			Logger:          testLogger(),
+		},
+	}
+
+	tok := c.Subscribe("#/invalid", 1, func(_ *Client, _ Message) {})
+	<-tok.Done()
+	if tok.Error() == nil {
+		t.Fatal("expected validation error")
+	}
+	if tok.Results() != nil {
+		t.Errorf("expected nil results, got %v", tok.Results())
+	}
+	if g := tok.(GrantedQoSReporter).GrantedQoS(); g != nil {
+		t.Errorf("expected nil granted QoS, got %v", g)
+	}
+}
+
+func TestSubscribeGrantedQoS(t *testing.T) {
+	c := &Client{
+		opts: &clientOptions{
+			ProtocolVersion: ProtocolV50,
+			Logger:          testLogger(),
+		},
+		pending: make(map[uint16]*pendingOp),
+	}
+
+	tok := newToken()
+	c.pending[1] = &pendingOp{
+		packet: &packets.SubscribePacket{
+			PacketID: 1,
+			Topics:   []string{"orders/#", "audit/#", "secret/#"},
+			QoS:      []uint8{2, 1, 2},
+			Version:  ProtocolV50,
+		},
+		token: tok,
+	}
+
+	// The server downgrades orders/# to QoS 1 and rejects secret/#
+	c.handleSuback(&packets.SubackPacket{PacketID: 1, ReturnCodes: []uint8{0x01, 0x01, 0x87}})
+
+	var st SubscribeToken = tok
+	<-st.Done()
+
+	g, ok := st.(GrantedQoSReporter)
+	if !ok {
+		t.Fatal("SubscribeToken does not implement GrantedQoSReporter")
+	}
+	got := g.GrantedQoS()
+	want := []QoS{AtLeastOnce, AtLeastOnce, QoS(ReasonCodeNotAuthorized)}
+	if len(got) != len(want) {
+		t.Fatalf("GrantedQoS() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("GrantedQoS()[%d] = %d, want %d", i, got[i], want[i])
+		}
+	}
+	if got[2] <= ExactlyOnce {
+		t.Errorf("expected the rejected filter to report an invalid QoS, got %d", got[2])
+	}
+}
@@ -0,0 +1,85 @@
+package mq
+
+// SubscriptionContext describes the subscription that led to the delivery of
+// a message. It is passed to handlers registered with WithSubscriptionContext.
+type SubscriptionContext struct {
+	// Filter is the topic filter of the matching subscription, as passed to
+	// Subscribe (e.g. "sensors/+/temp" or "$share/group/jobs/#").
+	Filter string
+
+	// Options are the options the subscription was made with.
+	Options SubscribeOptions
+
+	// QoS is the QoS requested in Subscribe.
+	QoS QoS
+
+	// GrantedQoS is the maximum QoS granted by the server in its SUBACK.
+	// It is only valid if Granted is true: a message can arrive before
+	// the SUBACK has been processed.
+	GrantedQoS QoS
+	Granted    bool
+}
+
+// SubscriptionContextHandler is a message handler that also receives the
+// SubscriptionContext of the subscription that matched the message.
+type SubscriptionContextHandler func(*Client, Message, SubscriptionContext)
+
+// WithSubscriptionContext registers handler as the subscription's message
+// handler, in place of the MessageHandler passed to Subscribe (which is
+// ignored and may be nil). Besides the message, the handler receives the
+// matched filter, the subscription options and the granted QoS.
+//
+// This lets a single shared handler behave differently depending on which
+// subscription delivered a message, and is useful for diagnostics. Handler
+// interceptors (WithHandlerInterceptor) apply as for any other handler.
+//
+// Example:
+//
+//	audit := func(c *mq.Client, msg mq.Message, sub mq.SubscriptionContext) {
+//	    log.Printf("%s via %s (id %d, granted QoS %d)",
+//	        msg.Topic, sub.Filter, sub.Options.SubscriptionID, sub.GrantedQoS)
+//	}
+//	client.Subscribe("alerts/#", mq.AtLeastOnce, nil,
+//	    mq.WithSubscriptionIdentifier(1), mq.WithSubscriptionContext(audit))
+//	client.Subscribe("$share/ops/alerts/critical", mq.ExactlyOnce, nil,
+//	    mq.WithSubscriptionIdentifier(2), mq.WithSubscriptionContext(audit))
+func WithSubscriptionContext(handler SubscriptionContextHandler) SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.contextHandler = handler
+	}
+}
+
+// messageHandler returns the handler to invoke for a message matching the
+// subscription filter. Must be called with sessionLock held.
+func (c *Client) messageHandler(filter string, entry subscriptionEntry) MessageHandler {
+	if entry.contextHandler == nil {
+		return entry.handler
+	}
+
+	sub := &SubscriptionContext{
+		Filter:     filter,
+		Options:    entry.options,
+		QoS:        QoS(entry.qos),
+		GrantedQoS: QoS(entry.granted),
+		Granted:    entry.hasGranted,
+	}
+	h := entry.handler
+	return func(client *Client, msg Message) {
+		msg.subscription = sub
+		h(client, msg)
+	}
+}
+
+// wrapContextHandler adapts a SubscriptionContextHandler to the
+// MessageHandler stored in the subscription entry, with handler interceptors
+// applied once when the subscription is registered. The context of each
+// delivery is attached to the message by messageHandler.
+func (c *Client) wrapContextHandler(h SubscriptionContextHandler) MessageHandler {
+	return c.wrapHandler(func(client *Client, msg Message) {
+		var sub SubscriptionContext
+		if msg.subscription != nil {
+			sub = *msg.subscription
+		}
+		h(client, msg, sub)
+	})
+}
@@ -0,0 +1,160 @@
+package mq
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestWithSubscriptionContext(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	intercepted := make(chan string, 4)
+	WithHandlerInterceptor(func(next MessageHandler) MessageHandler {
+		return func(c *Client, msg Message) {
+			intercepted <- msg.Topic
+			next(c, msg)
+		}
+	})(opts)
+	c := newTestClient(opts)
+	c.connected.Store(true)
+
+	got := make(chan SubscriptionContext, 4)
+	handler := func(_ *Client, _ Message, sub SubscriptionContext) {
+		got <- sub
+	}
+
+	c.Subscribe("sensors/+/temp", ExactlyOnce, nil,
+		WithNoLocal(true),
+		WithRetainHandling(2),
+		WithSubscriptionIdentifier(42),
+		WithSubscribeUserProperty("team", "ops"),
+		WithSubscriptionContext(handler))
+
+	sub := (<-c.outgoing).(*packets.SubscribePacket)
+
+	receive := func() SubscriptionContext {
+		t.Helper()
+		c.sessionLock.Lock()
+		c.handlePublish(&packets.PublishPacket{Topic: "sensors/kitchen/temp", Payload: []byte("21")})
+		c.sessionLock.Unlock()
+		select {
+		case sc := <-got:
+			return sc
+		case <-time.After(time.Second):
+			t.Fatal("handler not called")
+		}
+		return SubscriptionContext{}
+	}
+
+	// A message can arrive before the SUBACK
+	sc := receive()
+	if sc.Granted {
+		t.Errorf("expected no granted QoS before SUBACK, got %+v", sc)
+	}
+
+	c.sessionLock.Lock()
+	c.handleIncoming(&packets.SubackPacket{PacketID: sub.PacketID, ReturnCodes: []uint8{1}})
+	c.sessionLock.Unlock()
+
+	sc = receive()
+	if sc.Filter != "sensors/+/temp" {
+		t.Errorf("Filter = %q, want sensors/+/temp", sc.Filter)
+	}
+	if sc.QoS != ExactlyOnce || !sc.Granted || sc.GrantedQoS != AtLeastOnce {
+		t.Errorf("QoS = %d, Granted = %v, GrantedQoS = %d; want 2, true, 1", sc.QoS, sc.Granted, sc.GrantedQoS)
+	}
+	o := sc.Options
+	if !o.NoLocal || o.RetainHandling != 2 || o.SubscriptionID != 42 || o.UserProperties["team"] != "ops" {
+		t.Errorf("unexpected options %+v", o)
+	}
+
+	// Interceptors wrap context handlers too
+	for range 2 {
+		select {
+		case topic := <-intercepted:
+			if topic != "sensors/kitchen/temp" {
+				t.Errorf("interceptor saw %q", topic)
+			}
+		default:
+			t.Error("expected the interceptor to run for each message")
+		}
+	}
+}
+
+func TestWithSubscriptionContext_SharedHandler(t *testing.T) {
+	c := newTestClient(nil)
+	c.opts.Logger = testLogger()
+	c.connected.Store(true)
+
+	got := make(chan string, 4)
+	handler := func(_ *Client, _ Message, sub SubscriptionContext) {
+		got <- sub.Filter
+	}
+	called := make(chan struct{}, 1)
+	plain := func(*Client, Message) { called <- struct{}{} }
+
+	// The plain handler is ignored when a context handler is given
+	c.Subscribe("alerts/#", AtLeastOnce, plain, WithSubscriptionContext(handler))
+	c.Subscribe("alerts/critical", AtLeastOnce, nil, WithSubscriptionContext(handler))
+
+	c.sessionLock.Lock()
+	c.handlePublish(&packets.PublishPacket{Topic: "alerts/critical"})
+	c.sessionLock.Unlock()
+
+	seen := map[string]bool{}
+	for range 2 {
+		select {
+		case f := <-got:
+			seen[f] = true
+		case <-time.After(time.Second):
+			t.Fatal("handler not called for both subscriptions")
+		}
+	}
+	if !seen["alerts/#"] || !seen["alerts/critical"] {
+		t.Errorf("expected both filters, got %v", seen)
+	}
+	select {
+	case <-called:
+		t.Error("plain handler should not be called")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestWithSubscriptionContext_WrapsOnce(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	var wraps atomic.Int32
+	WithHandlerInterceptor(func(next MessageHandler) MessageHandler {
+		wraps.Add(1)
+		return next
+	})(opts)
+	c := newTestClient(opts)
+	c.connected.Store(true)
+
+	got := make(chan string, 3)
+	c.Subscribe("jobs/#", AtLeastOnce, nil, WithSubscriptionContext(func(_ *Client, _ Message, sub SubscriptionContext) {
+		got <- sub.Filter
+	}))
+	<-c.outgoing
+
+	for range 3 {
+		c.sessionLock.Lock()
+		c.handlePublish(&packets.PublishPacket{Topic: "jobs/1"})
+		c.sessionLock.Unlock()
+		select {
+		case filter := <-got:
+			if filter != "jobs/#" {
+				t.Errorf("Filter = %q, want jobs/#", filter)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("handler not called")
+		}
+	}
+
+	if n := wraps.Load(); n != 1 {
+		t.Errorf("interceptor chain built %d times, want once per subscription", n)
+	}
+}
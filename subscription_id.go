@@ -0,0 +1,31 @@
+package mq
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// routeBySubscriptionID returns the subscription identifiers of p that have
+// a handler registered with WithSubscriptionIDHandler, without duplicates.
+func (c *Client) routeBySubscriptionID(p *packets.PublishPacket) []int {
+	if len(c.idHandlers) == 0 || p.Properties == nil || len(p.Properties.SubscriptionIdentifier) == 0 {
+		return nil
+	}
+
+	var routed []int
+	for _, id := range p.Properties.SubscriptionIdentifier {
+		if _, ok := c.idHandlers[id]; ok && !slices.Contains(routed, id) {
+			routed = append(routed, id)
+		}
+	}
+	return routed
+}
+
+// subscriptionIDQueue is the ordered queue key of the handler registered for
+// a subscription identifier. It cannot collide with a topic filter, as '#'
+// is only valid on its own.
+func subscriptionIDQueue(id int) string {
+	return "#" + strconv.Itoa(id)
+}
@@ -0,0 +1,112 @@
+package mq
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestSubscriptionIDHandler(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	record := func(name string) MessageHandler {
+		return func(_ *Client, msg Message) {
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, name+" "+msg.Topic)
+		}
+	}
+
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	WithSubscriptionIDHandler(1, record("id1"))(opts)
+	WithSubscriptionIDHandler(2, record("id2"))(opts)
+	WithDefaultPublishHandler(record("default"))(opts)
+
+	c := newTestClient(opts)
+	c.idHandlers = map[int]MessageHandler{1: opts.SubscriptionIDHandlers[1], 2: opts.SubscriptionIDHandlers[2]}
+	c.defaultHandler = opts.DefaultPublishHandler
+	c.subscriptions["plant/+/alarm"] = subscriptionEntry{handler: record("alarm"), options: SubscribeOptions{SubscriptionID: 1}}
+	c.subscriptions["plant/#"] = subscriptionEntry{handler: record("plant"), options: SubscribeOptions{SubscriptionID: 3}}
+	c.subscriptions["other/#"] = subscriptionEntry{handler: record("other")}
+	c.subscriptions["muted/#"] = subscriptionEntry{handler: record("muted"), options: SubscribeOptions{SubscriptionID: 2}, suppressRetained: true}
+
+	withIDs := func(ids ...int) *packets.Properties {
+		return &packets.Properties{SubscriptionIdentifier: ids}
+	}
+	tests := []struct {
+		name   string
+		topic  string
+		props  *packets.Properties
+		retain bool
+		want   []string
+	}{
+		{
+			name:  "routed by identifier, other filters matched by topic",
+			topic: "plant/1/alarm",
+			props: withIDs(1, 2, 1),
+			want:  []string{"id1 plant/1/alarm", "id2 plant/1/alarm", "plant plant/1/alarm"},
+		},
+		{
+			name:  "identifier without handler falls back to matching",
+			topic: "plant/1/alarm",
+			props: withIDs(1, 3),
+			want:  []string{"id1 plant/1/alarm", "plant plant/1/alarm"},
+		},
+		{
+			name:  "no identifier",
+			topic: "plant/1/alarm",
+			want:  []string{"alarm plant/1/alarm", "plant plant/1/alarm"},
+		},
+		{
+			name:  "routed without a local subscription",
+			topic: "elsewhere",
+			props: withIDs(2),
+			want:  []string{"id2 elsewhere"},
+		},
+		{
+			name:   "retained suppressed for a routed subscription",
+			topic:  "muted/1",
+			props:  withIDs(2),
+			retain: true,
+		},
+		{
+			name:  "unknown identifier and no match",
+			topic: "elsewhere",
+			props: withIDs(9),
+			want:  []string{"default elsewhere"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			got = nil
+			mu.Unlock()
+
+			c.handlePublish(&packets.PublishPacket{Topic: tt.topic, Properties: tt.props, Retain: tt.retain, Version: ProtocolV50})
+
+			deadline := time.Now().Add(time.Second)
+			for {
+				mu.Lock()
+				n := len(got)
+				mu.Unlock()
+				if n >= len(tt.want) || time.Now().After(deadline) {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+			time.Sleep(10 * time.Millisecond) // Catch unexpected extra deliveries
+
+			mu.Lock()
+			defer mu.Unlock()
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("delivered to %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
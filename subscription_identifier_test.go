@@ -67,6 +67,7 @@ func TestWithSubscriptionIdentifier(t *testing.T) {
 				subscriptions: make(map[string]subscriptionEntry),
 				stop:          make(chan struct{}),
 			}
+			c.connected.Store(true)
 
 			// Subscribe with the test subscription ID
 			// Note: internalSubscribe is called directly by Subscribe
@@ -141,6 +142,7 @@ func TestSubscriptionIdentifierMQTTv311(t *testing.T) {
 		subscriptions: make(map[string]subscriptionEntry),
 		stop:          make(chan struct{}),
 	}
+	c.connected.Store(true)
 
 	token := c.Subscribe("test/topic", AtLeastOnce,
 		func(*Client, Message) {},
@@ -0,0 +1,73 @@
+package mq
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMaxSubscriptions(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	opts.MaxSubscriptions = 2
+	c := newTestClient(opts)
+	c.connected.Store(true)
+
+	handler := func(_ *Client, _ Message) {}
+
+	subscribe := func(topic string) error {
+		tok := c.Subscribe(topic, AtLeastOnce, handler)
+		select {
+		case <-tok.Done():
+			return tok.Error()
+		default:
+			// Accepted locally and queued for the server
+			<-c.outgoing
+			return nil
+		}
+	}
+
+	for _, topic := range []string{"a/1", "a/2"} {
+		if err := subscribe(topic); err != nil {
+			t.Fatalf("subscribe %q: unexpected error %v", topic, err)
+		}
+	}
+
+	if err := subscribe("a/3"); !errors.Is(err, ErrSubscriptionLimitExceeded) {
+		t.Fatalf("expected ErrSubscriptionLimitExceeded, got %v", err)
+	}
+	if _, ok := c.subscriptions["a/3"]; ok {
+		t.Error("rejected subscription must not be registered")
+	}
+
+	// Re-subscribing to an active filter does not take a new slot
+	if err := subscribe("a/1"); err != nil {
+		t.Errorf("re-subscribe: unexpected error %v", err)
+	}
+
+	// Unsubscribing frees a slot
+	c.Unsubscribe("a/1")
+	<-c.outgoing
+
+	if err := subscribe("a/3"); err != nil {
+		t.Errorf("subscribe after unsubscribe: unexpected error %v", err)
+	}
+	if got := len(c.subscriptions); got != 2 {
+		t.Errorf("expected 2 active subscriptions, got %d", got)
+	}
+}
+
+func TestMaxSubscriptionsUnlimitedByDefault(t *testing.T) {
+	c := newTestClient(nil)
+	c.connected.Store(true)
+	c.opts.Logger = testLogger()
+
+	for i := range 10 {
+		tok := c.Subscribe("t/"+string(rune('a'+i)), AtMostOnce, nil)
+		select {
+		case <-tok.Done():
+			t.Fatalf("unexpected completion: %v", tok.Error())
+		default:
+		}
+		<-c.outgoing
+	}
+}
@@ -0,0 +1,50 @@
+package mq
+
+// SubscriptionQoS returns the effective QoS of the subscription to filter:
+// the maximum QoS the server will use to deliver messages matching it.
+//
+// Servers may grant a lower QoS than requested (MQTT section 3.9.3), so a
+// subscription made at ExactlyOnce can end up delivering at AtLeastOnce.
+// Once the SUBACK has been received, the granted QoS is reported; until then
+// (or for subscriptions restored from a session store and not yet
+// re-acknowledged) the requested QoS, capped at the server's Maximum QoS, is
+// reported. The granted value is refreshed on every resubscription.
+//
+// The second return value is false if there is no active subscription for
+// filter. The filter must match the one passed to Subscribe exactly.
+//
+// Example:
+//
+//	client.Subscribe("orders/#", mq.ExactlyOnce, handler).Wait(ctx)
+//	if qos, ok := client.SubscriptionQoS("orders/#"); ok && qos < mq.ExactlyOnce {
+//	    log.Printf("server downgraded orders/# to QoS %d, enabling dedup", qos)
+//	}
+func (c *Client) SubscriptionQoS(filter string) (QoS, bool) {
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+
+	entry, ok := c.subscriptions[filter]
+	if !ok {
+		return 0, false
+	}
+	if entry.hasGranted {
+		return QoS(entry.granted), true
+	}
+	return QoS(min(entry.qos, c.serverCaps.MaximumQoS)), true
+}
+
+// recordGrantedQoS stores the QoS granted by a SUBACK on the matching
+// subscriptions. Failure codes (0x80 and above) are ignored. Must be called
+// with sessionLock held.
+func (c *Client) recordGrantedQoS(topics []string, codes []uint8) {
+	for i, topic := range topics {
+		if i >= len(codes) || codes[i] >= 0x80 {
+			continue
+		}
+		if entry, ok := c.subscriptions[topic]; ok {
+			entry.granted = codes[i]
+			entry.hasGranted = true
+			c.subscriptions[topic] = entry
+		}
+	}
+}
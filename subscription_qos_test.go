@@ -0,0 +1,65 @@
+package mq
+
+import (
+	"testing"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestSubscriptionQoS(t *testing.T) {
+	c := newTestClient(nil)
+	c.opts.Logger = testLogger()
+	c.serverCaps.MaximumQoS = 2
+
+	if _, ok := c.SubscriptionQoS("orders/#"); ok {
+		t.Fatal("expected no subscription before Subscribe")
+	}
+
+	c.subscriptions["orders/#"] = subscriptionEntry{qos: 2}
+	c.subscriptions["alerts"] = subscriptionEntry{qos: 2}
+	c.subscriptions["denied"] = subscriptionEntry{qos: 1}
+
+	// Before the SUBACK the requested QoS is reported
+	if qos, ok := c.SubscriptionQoS("orders/#"); !ok || qos != ExactlyOnce {
+		t.Errorf("before SUBACK: got (%d, %v), want (2, true)", qos, ok)
+	}
+
+	c.pending[1] = &pendingOp{
+		packet: &packets.SubscribePacket{
+			PacketID: 1,
+			Topics:   []string{"orders/#", "alerts", "denied"},
+			QoS:      []uint8{2, 2, 1},
+		},
+		token: newToken(),
+	}
+	c.handleSuback(&packets.SubackPacket{PacketID: 1, ReturnCodes: []uint8{1, 2, 0x80}})
+
+	tests := []struct {
+		filter string
+		want   QoS
+	}{
+		{"orders/#", AtLeastOnce}, // Downgraded by the server
+		{"alerts", ExactlyOnce},
+		{"denied", AtLeastOnce}, // Failure codes leave the entry untouched
+	}
+	for _, tt := range tests {
+		if qos, ok := c.SubscriptionQoS(tt.filter); !ok || qos != tt.want {
+			t.Errorf("SubscriptionQoS(%q) = (%d, %v), want (%d, true)", tt.filter, qos, ok, tt.want)
+		}
+	}
+
+	// The requested QoS is kept for resubscribing
+	if got := c.subscriptions["orders/#"].qos; got != 2 {
+		t.Errorf("requested QoS = %d, want 2", got)
+	}
+}
+
+func TestSubscriptionQoS_CappedByServerMaximum(t *testing.T) {
+	c := newTestClient(nil)
+	c.serverCaps.MaximumQoS = 1
+	c.subscriptions["restored"] = subscriptionEntry{qos: 2}
+
+	if qos, ok := c.SubscriptionQoS("restored"); !ok || qos != AtLeastOnce {
+		t.Errorf("got (%d, %v), want (1, true)", qos, ok)
+	}
+}
@@ -0,0 +1,62 @@
+package mq
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// SubscriptionInfo describes an active subscription, as returned by
+// Subscriptions.
+type SubscriptionInfo struct {
+	// Filter is the topic filter, as passed to Subscribe.
+	Filter string
+
+	// QoS is the QoS requested in Subscribe.
+	QoS QoS
+
+	// GrantedQoS is the QoS granted by the server in its last SUBACK. It is
+	// only valid if Granted is true (see SubscriptionQoS).
+	GrantedQoS QoS
+	Granted    bool
+
+	// Options are the options the subscription was made with.
+	Options SubscribeOptions
+}
+
+// Subscriptions returns a snapshot of the client's active subscriptions,
+// sorted by filter. It includes subscriptions made with Subscribe or
+// WithSubscription and those restored from a session store, and reflects
+// the unsubscriptions already requested.
+//
+// The result is a copy: changing it does not affect the client. It is
+// useful for diagnostics, for rebuilding a UI, and for checking the
+// subscriptions restored after a reconnect.
+//
+// Example:
+//
+//	for _, sub := range client.Subscriptions() {
+//	    fmt.Printf("%s (QoS %d)\n", sub.Filter, sub.QoS)
+//	}
+func (c *Client) Subscriptions() []SubscriptionInfo {
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+
+	subs := make([]SubscriptionInfo, 0, len(c.subscriptions))
+	for filter, entry := range c.subscriptions {
+		info := SubscriptionInfo{
+			Filter:     filter,
+			QoS:        QoS(entry.qos),
+			GrantedQoS: QoS(entry.granted),
+			Granted:    entry.hasGranted,
+			Options:    entry.options,
+		}
+		info.Options.UserProperties = maps.Clone(entry.options.UserProperties)
+		info.Options.contextHandler = nil
+		subs = append(subs, info)
+	}
+	slices.SortFunc(subs, func(a, b SubscriptionInfo) int {
+		return strings.Compare(a.Filter, b.Filter)
+	})
+	return subs
+}
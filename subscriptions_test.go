@@ -0,0 +1,50 @@
+package mq
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSubscriptions(t *testing.T) {
+	c := newTestClient(nil)
+	c.opts.Logger = testLogger()
+
+	if subs := c.Subscriptions(); len(subs) != 0 {
+		t.Fatalf("expected no subscriptions, got %v", subs)
+	}
+
+	c.subscriptions["b/#"] = subscriptionEntry{qos: 2, granted: 1, hasGranted: true}
+	c.subscriptions["a/+"] = subscriptionEntry{
+		qos:     1,
+		options: SubscribeOptions{NoLocal: true, SubscriptionID: 7, UserProperties: map[string]string{"k": "v"}},
+	}
+
+	want := []SubscriptionInfo{
+		{
+			Filter:  "a/+",
+			QoS:     AtLeastOnce,
+			Options: SubscribeOptions{NoLocal: true, SubscriptionID: 7, UserProperties: map[string]string{"k": "v"}},
+		},
+		{Filter: "b/#", QoS: ExactlyOnce, GrantedQoS: AtLeastOnce, Granted: true},
+	}
+	subs := c.Subscriptions()
+	if !reflect.DeepEqual(subs, want) {
+		t.Fatalf("Subscriptions() = %+v, want %+v", subs, want)
+	}
+
+	// The snapshot is a copy
+	subs[0].Options.UserProperties["k"] = "changed"
+	subs[0].Filter = "changed"
+	if got := c.subscriptions["a/+"].options.UserProperties["k"]; got != "v" {
+		t.Errorf("snapshot shares user properties with the client: %q", got)
+	}
+	if again := c.Subscriptions(); again[0].Filter != "a/+" {
+		t.Errorf("snapshot changed the client: %+v", again)
+	}
+
+	// Unsubscribing removes the entry at once
+	_ = c.Unsubscribe("b/#")
+	if subs := c.Subscriptions(); len(subs) != 1 || subs[0].Filter != "a/+" {
+		t.Errorf("after Unsubscribe: %+v", subs)
+	}
+}
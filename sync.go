@@ -0,0 +1,46 @@
+package mq
+
+import "context"
+
+// PublishSync publishes a message and waits for it to complete: for QoS 0
+// until it has been queued for sending, for QoS 1 and 2 until the server
+// acknowledged it. It is PublishContext followed by Wait with the same ctx,
+// so ctx bounds the whole operation.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//	if err := client.PublishSync(ctx, "sensors/temp", []byte("22.5"), mq.WithQoS(1)); err != nil {
+//	    log.Printf("publish failed: %v", err)
+//	}
+func (c *Client) PublishSync(ctx context.Context, topic string, payload []byte, opts ...PublishOption) error {
+	return c.PublishContext(ctx, topic, payload, opts...).Wait(ctx)
+}
+
+// SubscribeSync subscribes to a topic filter and waits for the SUBACK. It is
+// SubscribeContext followed by Wait with the same ctx, so ctx bounds the
+// whole operation. If the server rejected the subscription, the error wraps
+// ErrSubscriptionFailed.
+//
+// Example:
+//
+//	if err := client.SubscribeSync(ctx, "sensors/#", mq.AtLeastOnce, handler); err != nil {
+//	    return fmt.Errorf("subscribe: %w", err)
+//	}
+func (c *Client) SubscribeSync(ctx context.Context, topic string, qos QoS, handler MessageHandler, opts ...SubscribeOption) error {
+	return c.SubscribeContext(ctx, topic, qos, handler, opts...).Wait(ctx)
+}
+
+// UnsubscribeSync unsubscribes from a topic filter and waits for the
+// UNSUBACK. It is UnsubscribeContext followed by Wait with the same ctx, so
+// ctx bounds the whole operation.
+//
+// Example:
+//
+//	if err := client.UnsubscribeSync(ctx, "sensors/#"); err != nil {
+//	    log.Printf("unsubscribe failed: %v", err)
+//	}
+func (c *Client) UnsubscribeSync(ctx context.Context, topic string, opts ...UnsubscribeOption) error {
+	return c.UnsubscribeContext(ctx, topic, opts...).Wait(ctx)
+}
@@ -0,0 +1,94 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// serveAcks accepts one connection and acknowledges every PUBLISH,
+// SUBSCRIBE and UNSUBSCRIBE, rejecting subscriptions to "denied/" filters.
+// PUBLISH to "slow/" topics is never acknowledged.
+func serveAcks(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = packets.ReadPacket(conn, ProtocolV50, 0)
+		_, _ = (&packets.ConnackPacket{ReturnCode: 0}).WriteTo(conn)
+
+		for {
+			pkt, err := packets.ReadPacket(conn, ProtocolV50, 0)
+			if err != nil {
+				return
+			}
+			var ack packets.Packet
+			switch p := pkt.(type) {
+			case *packets.PublishPacket:
+				if !strings.HasPrefix(p.Topic, "slow/") {
+					ack = &packets.PubackPacket{PacketID: p.PacketID, Version: ProtocolV50}
+				}
+			case *packets.SubscribePacket:
+				code := p.QoS[0]
+				if strings.HasPrefix(p.Topics[0], "denied/") {
+					code = uint8(ReasonCodeNotAuthorized)
+				}
+				ack = &packets.SubackPacket{PacketID: p.PacketID, ReturnCodes: []uint8{code}, Version: ProtocolV50}
+			case *packets.UnsubscribePacket:
+				ack = &packets.UnsubackPacket{PacketID: p.PacketID, ReasonCodes: []uint8{0}, Version: ProtocolV50}
+			}
+			if ack != nil {
+				_, _ = ack.WriteTo(conn)
+			}
+		}
+	}()
+	return "tcp://" + ln.Addr().String()
+}
+
+func TestSyncOperations(t *testing.T) {
+	client, err := Dial(serveAcks(t),
+		WithClientID("sync-client"),
+		WithAutoReconnect(false),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.PublishSync(ctx, "t", []byte("x"), WithQoS(AtLeastOnce)); err != nil {
+		t.Errorf("PublishSync failed: %v", err)
+	}
+	if err := client.SubscribeSync(ctx, "jobs/#", AtLeastOnce, nil); err != nil {
+		t.Errorf("SubscribeSync failed: %v", err)
+	}
+	if err := client.SubscribeSync(ctx, "denied/#", AtLeastOnce, nil); !errors.Is(err, ErrSubscriptionFailed) {
+		t.Errorf("SubscribeSync error = %v, want ErrSubscriptionFailed", err)
+	}
+	if err := client.UnsubscribeSync(ctx, "jobs/#"); err != nil {
+		t.Errorf("UnsubscribeSync failed: %v", err)
+	}
+
+	// ctx bounds the wait for the acknowledgment
+	short, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.PublishSync(short, "slow/t", nil, WithQoS(AtLeastOnce)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("PublishSync error = %v, want context.DeadlineExceeded", err)
+	}
+}
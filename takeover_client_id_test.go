@@ -0,0 +1,117 @@
+package mq
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestClientIDOnTakeover(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the reconnect backoff")
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	clientIDs := make(chan string, 2)
+	go func() {
+		for n := 1; n <= 2; n++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			pkt, err := packets.ReadPacket(conn, ProtocolV50, 0)
+			if err != nil {
+				return
+			}
+			clientIDs <- pkt.(*packets.ConnectPacket).ClientID
+			if _, err := (&packets.ConnackPacket{ReturnCode: 0}).WriteTo(conn); err != nil {
+				return
+			}
+			if n == 1 {
+				// Another instance connected with the same ID
+				disc := &packets.DisconnectPacket{
+					ReasonCode: uint8(ReasonCodeSessionTakenOver),
+					Version:    ProtocolV50,
+				}
+				_, _ = disc.WriteTo(conn)
+				time.Sleep(100 * time.Millisecond)
+				conn.Close()
+				continue
+			}
+			defer conn.Close()
+			for {
+				if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	derivedFrom := make(chan string, 1)
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("worker"),
+		WithClientIDOnTakeover(func(current string) string {
+			derivedFrom <- current
+			return current + "-b"
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	for _, want := range []string{"worker", "worker-b"} {
+		select {
+		case got := <-clientIDs:
+			if got != want {
+				t.Fatalf("CONNECT client ID = %q, want %q", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for CONNECT with %q", want)
+		}
+	}
+
+	if got := <-derivedFrom; got != "worker" {
+		t.Errorf("derive called with %q, want worker", got)
+	}
+	if got := client.Config().ClientID; got != "worker-b" {
+		t.Errorf("Config().ClientID = %q, want worker-b", got)
+	}
+}
+
+func TestClientIDOnTakeover_OtherReasonsKeepID(t *testing.T) {
+	c := newTestClient(nil)
+	c.opts.Logger = testLogger()
+	c.opts.ClientID = "worker"
+	c.opts.ClientIDOnTakeover = func(current string) string { return current + "-b" }
+	c.connected.Store(true)
+
+	c.lastDisconnectReason = &DisconnectError{ReasonCode: ReasonCodeServerShuttingDown}
+	c.handleDisconnect()
+
+	if c.sessionTakenOver.Load() {
+		t.Fatal("only a session takeover may trigger a client ID change")
+	}
+
+	c.connected.Store(true)
+	c.lastDisconnectReason = &DisconnectError{ReasonCode: ReasonCodeSessionTakenOver}
+	c.handleDisconnect()
+	if !c.sessionTakenOver.Load() {
+		t.Fatal("expected takeover to be recorded")
+	}
+
+	// Returning the same ID leaves it unchanged
+	c.opts.ClientIDOnTakeover = func(current string) string { return current }
+	c.rotateClientID()
+	if c.opts.ClientID != "worker" {
+		t.Errorf("ClientID = %q, want worker", c.opts.ClientID)
+	}
+}
@@ -0,0 +1,126 @@
+package mq
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// wrappedConn hides the *net.TCPConn like a TLS or instrumentation wrapper.
+type wrappedConn struct {
+	net.Conn
+}
+
+func (w wrappedConn) NetConn() net.Conn { return w.Conn }
+
+func tcpSockopts(t *testing.T, conn *net.TCPConn) (enabled, idle, interval, count int) {
+	t.Helper()
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sockErr error
+	err = raw.Control(func(fd uintptr) {
+		get := func(level, opt int) int {
+			v, err := syscall.GetsockoptInt(int(fd), level, opt)
+			if err != nil && sockErr == nil {
+				sockErr = err
+			}
+			return v
+		}
+		enabled = get(syscall.SOL_SOCKET, syscall.SO_KEEPALIVE)
+		idle = get(syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE)
+		interval = get(syscall.IPPROTO_TCP, syscall.TCP_KEEPINTVL)
+		count = get(syscall.IPPROTO_TCP, syscall.TCP_KEEPCNT)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sockErr != nil {
+		t.Fatal(sockErr)
+	}
+	return
+}
+
+func TestTCPKeepAlive(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+					return
+				}
+				if _, err := (&packets.ConnackPacket{ReturnCode: 0}).WriteTo(conn); err != nil {
+					return
+				}
+				for {
+					if _, err := packets.ReadPacket(conn, ProtocolV50, 0); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	addr := ln.Addr().String()
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"built-in dialer", nil},
+		{"wrapped custom dialer", []Option{
+			WithDialer(DialFunc(func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				conn, err := d.DialContext(ctx, "tcp", addr)
+				if err != nil {
+					return nil, err
+				}
+				return wrappedConn{conn}, nil
+			})),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{
+				WithClientID("tcp-keepalive"),
+				WithAutoReconnect(false),
+				WithTCPKeepAlive(42*time.Second, 7*time.Second, 3),
+			}, tt.opts...)
+			client, err := Dial("tcp://"+addr, opts...)
+			if err != nil {
+				t.Fatalf("Dial failed: %v", err)
+			}
+			defer func() { _ = client.Disconnect(context.Background()) }()
+
+			client.connLock.RLock()
+			conn := client.conn
+			client.connLock.RUnlock()
+			if w, ok := conn.(wrappedConn); ok {
+				conn = w.Conn
+			}
+
+			enabled, idle, interval, count := tcpSockopts(t, conn.(*net.TCPConn))
+			if enabled == 0 {
+				t.Error("SO_KEEPALIVE not enabled")
+			}
+			if idle != 42 || interval != 7 || count != 3 {
+				t.Errorf("keepalive idle/interval/count = %d/%d/%d, want 42/7/3", idle, interval, count)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package mq
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTLSConfigComposition(t *testing.T) {
+	base := &tls.Config{ServerName: "original", MinVersion: tls.VersionTLS13}
+
+	tests := []struct {
+		name       string
+		opts       []Option
+		wantName   string
+		wantProtos []string
+	}{
+		{"WithTLS only", []Option{WithTLS(base)}, "original", nil},
+		{"server name after WithTLS", []Option{WithTLS(base), WithTLSServerName("sni.example")}, "sni.example", nil},
+		{"server name before WithTLS", []Option{WithTLSServerName("sni.example"), WithTLS(base)}, "sni.example", nil},
+		{"without WithTLS", []Option{WithTLSServerName("sni.example"), WithTLSNextProtos("mqtt")}, "sni.example", []string{"mqtt"}},
+		{"protos with WithTLS", []Option{WithTLSNextProtos("x-amzn-mqtt-ca", "mqtt"), WithTLS(base)}, "original", []string{"x-amzn-mqtt-ca", "mqtt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultOptions("tcp://localhost:1883")
+			for _, opt := range tt.opts {
+				opt(opts)
+			}
+			c := &Client{opts: opts, server: opts.Server}
+
+			if !c.tlsConfigured() {
+				t.Error("TLS not enabled")
+			}
+			cfg := c.tlsConfig()
+			if cfg.ServerName != tt.wantName {
+				t.Errorf("ServerName = %q, want %q", cfg.ServerName, tt.wantName)
+			}
+			if !slices.Equal(cfg.NextProtos, tt.wantProtos) {
+				t.Errorf("NextProtos = %v, want %v", cfg.NextProtos, tt.wantProtos)
+			}
+			if opts.TLSConfig != nil && cfg.MinVersion != tls.VersionTLS13 {
+				t.Error("settings of the WithTLS configuration were lost")
+			}
+		})
+	}
+
+	if base.ServerName != "original" || base.NextProtos != nil {
+		t.Errorf("WithTLS configuration was modified: %+v", base)
+	}
+
+	c := &Client{opts: defaultOptions("tcp://localhost:1883")}
+	if c.tlsConfigured() {
+		t.Error("TLS enabled without any TLS option")
+	}
+}
+
+// TestTLSServerNameHandshake verifies that the server name and ALPN
+// protocols are sent in the TLS handshake when connecting by IP address.
+func TestTLSServerNameHandshake(t *testing.T) {
+	cert := selfSignedCert(t, "broker.example")
+
+	type hello struct {
+		serverName string
+		protos     []string
+	}
+	hellos := make(chan hello, 1)
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		GetConfigForClient: func(chi *tls.ClientHelloInfo) (*tls.Config, error) {
+			hellos <- hello{chi.ServerName, chi.SupportedProtos}
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.(*tls.Conn).Handshake()
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert.Leaf)
+
+	opts := defaultOptions("tls://" + ln.Addr().String())
+	WithTLS(&tls.Config{RootCAs: roots})(opts)
+	WithTLSServerName("broker.example")(opts)
+	WithTLSNextProtos("mqtt")(opts)
+	c := &Client{opts: opts, server: opts.Server}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := c.dialServer(ctx)
+	if err != nil {
+		t.Fatalf("dialServer failed: %v", err)
+	}
+	conn.Close()
+
+	got := <-hellos
+	if got.serverName != "broker.example" {
+		t.Errorf("SNI = %q, want broker.example", got.serverName)
+	}
+	if !slices.Equal(got.protos, []string{"mqtt"}) {
+		t.Errorf("ALPN protocols = %v, want [mqtt]", got.protos)
+	}
+}
+
+// selfSignedCert returns a certificate for name, valid for one hour.
+func selfSignedCert(t *testing.T, name string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		DNSNames:              []string{name},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// TestClientCertificateGetter verifies that the getter provides the client
+// certificate on every handshake and overrides static Certificates.
+func TestClientCertificateGetter(t *testing.T) {
+	serverCert := selfSignedCert(t, "broker.example")
+	clientCert := selfSignedCert(t, "device-1")
+	staticCert := selfSignedCert(t, "static")
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert.Leaf)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	handshakes := make(chan error, 2)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			handshakes <- conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCert.Leaf)
+
+	calls := 0
+	opts := defaultOptions("tls://" + ln.Addr().String())
+	WithClientCertificateGetter(func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		calls++
+		return &clientCert, nil
+	})(opts)
+	WithTLS(&tls.Config{RootCAs: roots, Certificates: []tls.Certificate{staticCert}})(opts)
+	c := &Client{opts: opts, server: opts.Server}
+
+	// Each connect (e.g. a reconnect) asks for the certificate again
+	for i := range 2 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		conn, err := c.dialServer(ctx)
+		cancel()
+		if err != nil {
+			t.Fatalf("dial %d failed: %v", i, err)
+		}
+		if err := <-handshakes; err != nil {
+			t.Fatalf("server handshake %d failed: %v", i, err)
+		}
+		conn.Close()
+	}
+	if calls != 2 {
+		t.Errorf("getter called %d times, want 2", calls)
+	}
+}
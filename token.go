@@ -2,7 +2,10 @@ package mq
 
 import (
 	"context"
+	"errors"
+	"reflect"
 	"sync"
+	"sync/atomic"
 )
 
 // Token represents an asynchronous operation that can be waited on.
@@ -66,13 +69,42 @@ type Token interface {
 	Dropped() bool
 }
 
+// PublishToken is implemented by the Token returned by Publish,
+// PublishContext and PublishBatch. Publish returns a plain Token, so that
+// publish interceptors keep their signature; use a type assertion to reach
+// the packet ID.
+//
+// Example (correlating with broker logs):
+//
+//	token := client.Publish("orders/new", payload, mq.WithQoS(mq.AtLeastOnce))
+//	if err := token.Wait(ctx); err != nil {
+//	    if pt, ok := token.(mq.PublishToken); ok {
+//	        log.Printf("publish with packet ID %d failed: %v", pt.PacketID(), err)
+//	    }
+//	}
+type PublishToken interface {
+	Token
+
+	// PacketID returns the packet ID assigned to the PUBLISH. It is 0 for
+	// QoS 0 messages, which have no ID, and until the client has assigned
+	// one: a QoS 1/2 message waiting for the server's Receive Maximum, or
+	// rejected before being sent, has none yet. The ID identifies the
+	// message on the wire, but the client reuses it for a later message
+	// once this one is acknowledged. Safe to call at any time.
+	PacketID() uint16
+}
+
 // token is the internal implementation of Token.
 type token struct {
 	done       chan struct{}
 	err        error
 	reasonCode ReasonCode
 	dropped    bool
+	results    []SubscribeResult
 	once       sync.Once
+
+	// packetID is the ID assigned to a QoS 1/2 publish (0 until assigned).
+	packetID atomic.Uint32
 }
 
 // newToken creates a new token.
@@ -113,6 +145,28 @@ func (t *token) Dropped() bool {
 	return t.dropped
 }
 
+// PacketID returns the packet ID assigned to a QoS 1/2 publish.
+func (t *token) PacketID() uint16 {
+	return uint16(t.packetID.Load())
+}
+
+// Results returns the per-filter SUBACK results (subscribe tokens only).
+func (t *token) Results() []SubscribeResult {
+	return t.results
+}
+
+// GrantedQoS returns the per-filter granted QoS (subscribe tokens only).
+func (t *token) GrantedQoS() []QoS {
+	if t.results == nil {
+		return nil
+	}
+	granted := make([]QoS, len(t.results))
+	for i, r := range t.results {
+		granted[i] = QoS(r.ReasonCode)
+	}
+	return granted
+}
+
 // complete marks the token as complete with the given error.
 // This can only be called once; subsequent calls are ignored.
 func (t *token) complete(err error) {
@@ -121,3 +175,80 @@ func (t *token) complete(err error) {
 		close(t.done)
 	})
 }
+
+// WaitAll blocks until every token has completed or ctx is done.
+//
+// It returns nil if all operations succeeded. Otherwise it returns the
+// errors of the failed operations combined with errors.Join, in token
+// order, so errors.Is and errors.As match any of them. If ctx is done
+// first, ctx.Err() is returned. Nil tokens are ignored.
+//
+// Example:
+//
+//	tokens := make([]mq.Token, 0, len(readings))
+//	for _, r := range readings {
+//	    tokens = append(tokens, client.Publish("sensors/"+r.ID, r.Data, mq.WithQoS(1)))
+//	}
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//	if err := mq.WaitAll(ctx, tokens...); err != nil {
+//	    log.Printf("some publishes failed: %v", err)
+//	}
+func WaitAll(ctx context.Context, tokens ...Token) error {
+	var errs []error
+	for _, t := range tokens {
+		if t == nil {
+			continue
+		}
+		select {
+		case <-t.Done():
+			if err := t.Error(); err != nil {
+				errs = append(errs, err)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WaitAny blocks until at least one token has completed or ctx is done.
+//
+// It returns the index of a completed token and that token's error. If
+// several tokens are already complete, the lowest index is returned. If
+// ctx is done first, it returns -1 and ctx.Err(). With no (non-nil) tokens
+// it returns -1 and nil immediately.
+//
+// Example:
+//
+//	// Use whichever of two redundant brokers acknowledges first
+//	i, err := mq.WaitAny(ctx,
+//	    primary.Publish(topic, data, mq.WithQoS(1)),
+//	    backup.Publish(topic, data, mq.WithQoS(1)))
+func WaitAny(ctx context.Context, tokens ...Token) (int, error) {
+	cases := make([]reflect.SelectCase, 0, len(tokens)+1)
+	indexes := make([]int, 0, len(tokens))
+	for i, t := range tokens {
+		if t == nil {
+			continue
+		}
+		select {
+		case <-t.Done():
+			return i, t.Error()
+		default:
+		}
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(t.Done())})
+		indexes = append(indexes, i)
+	}
+	if len(cases) == 0 {
+		return -1, nil
+	}
+
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())})
+	chosen, _, _ := reflect.Select(cases)
+	if chosen == len(indexes) {
+		return -1, ctx.Err()
+	}
+	i := indexes[chosen]
+	return i, tokens[i].Error()
+}
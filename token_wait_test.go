@@ -0,0 +1,122 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func completedToken(err error) *token {
+	t := newToken()
+	t.complete(err)
+	return t
+}
+
+func TestWaitAll(t *testing.T) {
+	errNack := errors.New("nack")
+
+	tests := []struct {
+		name    string
+		tokens  func() []Token
+		timeout time.Duration
+		check   func(t *testing.T, err error)
+	}{
+		{
+			name: "all succeed",
+			tokens: func() []Token {
+				late := newToken()
+				time.AfterFunc(20*time.Millisecond, func() { late.complete(nil) })
+				return []Token{completedToken(nil), late, nil, completedToken(nil)}
+			},
+			timeout: time.Second,
+			check: func(t *testing.T, err error) {
+				if err != nil {
+					t.Errorf("expected nil, got %v", err)
+				}
+			},
+		},
+		{
+			name: "one fails",
+			tokens: func() []Token {
+				late := newToken()
+				time.AfterFunc(20*time.Millisecond, func() { late.complete(nil) })
+				return []Token{completedToken(nil), completedToken(errNack), late}
+			},
+			timeout: time.Second,
+			check: func(t *testing.T, err error) {
+				if !errors.Is(err, errNack) {
+					t.Errorf("expected the failure to be reported, got %v", err)
+				}
+			},
+		},
+		{
+			name: "several fail",
+			tokens: func() []Token {
+				return []Token{completedToken(errNack), completedToken(ErrPacketTooLarge)}
+			},
+			timeout: time.Second,
+			check: func(t *testing.T, err error) {
+				if !errors.Is(err, errNack) || !errors.Is(err, ErrPacketTooLarge) {
+					t.Errorf("expected both failures to be combined, got %v", err)
+				}
+			},
+		},
+		{
+			name: "context timeout",
+			tokens: func() []Token {
+				return []Token{completedToken(nil), newToken()}
+			},
+			timeout: 30 * time.Millisecond,
+			check: func(t *testing.T, err error) {
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("expected DeadlineExceeded, got %v", err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			tt.check(t, WaitAll(ctx, tt.tokens()...))
+		})
+	}
+}
+
+func TestWaitAny(t *testing.T) {
+	errNack := errors.New("nack")
+
+	t.Run("first to complete", func(t *testing.T) {
+		slow, fast := newToken(), newToken()
+		time.AfterFunc(20*time.Millisecond, func() { fast.complete(errNack) })
+
+		i, err := WaitAny(context.Background(), slow, nil, fast)
+		if i != 2 || !errors.Is(err, errNack) {
+			t.Errorf("WaitAny = (%d, %v), want (2, nack)", i, err)
+		}
+	})
+
+	t.Run("already complete prefers lowest index", func(t *testing.T) {
+		i, err := WaitAny(context.Background(), newToken(), completedToken(nil), completedToken(errNack))
+		if i != 1 || err != nil {
+			t.Errorf("WaitAny = (%d, %v), want (1, nil)", i, err)
+		}
+	})
+
+	t.Run("context timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+		defer cancel()
+		i, err := WaitAny(ctx, newToken(), newToken())
+		if i != -1 || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("WaitAny = (%d, %v), want (-1, DeadlineExceeded)", i, err)
+		}
+	})
+
+	t.Run("no tokens", func(t *testing.T) {
+		if i, err := WaitAny(context.Background()); i != -1 || err != nil {
+			t.Errorf("WaitAny() = (%d, %v), want (-1, nil)", i, err)
+		}
+	})
+}
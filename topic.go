@@ -111,6 +111,46 @@ func getLimit(configured, defaultLimit int) int {
 	return defaultLimit
 }
 
+// ValidateTopicName checks that name is a valid topic name for publishing:
+// not empty, without wildcards ('+' or '#') or null bytes, valid UTF-8 and
+// at most DefaultMaxTopicLength bytes long. The error wraps ErrInvalidTopic.
+//
+// Use it to check user-supplied topics before calling Publish, which applies
+// the same rules (with the limit set by WithMaxTopicLength).
+//
+// Example:
+//
+//	if err := mq.ValidateTopicName(input); err != nil {
+//	    return fmt.Errorf("bad destination: %w", err)
+//	}
+func ValidateTopicName(name string) error {
+	if err := validatePublishTopic(name, &clientOptions{}); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidTopic, err)
+	}
+	return nil
+}
+
+// ValidateTopicFilter checks that filter is a valid topic filter for
+// subscribing: not empty, with '+' and '#' occupying whole levels and '#'
+// only as the last level, without null bytes, valid UTF-8 and at most
+// DefaultMaxTopicLength bytes long. Empty levels ("a//b") are allowed. The
+// error wraps ErrInvalidTopicFilter.
+//
+// Use it to check user-supplied filters before calling Subscribe, which
+// applies the same rules (with the limit set by WithMaxTopicLength).
+//
+// Example:
+//
+//	if err := mq.ValidateTopicFilter("sensors/+temp"); err != nil {
+//	    fmt.Println(err) // invalid topic filter: single-level wildcard '+' must occupy entire topic level
+//	}
+func ValidateTopicFilter(filter string) error {
+	if err := validateSubscribeTopic(filter, &clientOptions{}); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidTopicFilter, err)
+	}
+	return nil
+}
+
 // validatePublishTopic validates a topic for publishing.
 // Publish topics must not contain wildcards and must follow MQTT rules.
 func validatePublishTopic(topic string, opts *clientOptions) error {
@@ -189,7 +229,7 @@ func validateSubscribeTopic(topic string, opts *clientOptions) error {
 func validatePayloadSize(payload []byte, opts *clientOptions) error {
 	maxSize := getLimit(opts.MaxPayloadSize, DefaultMaxPayloadSize)
 	if len(payload) > maxSize {
-		return fmt.Errorf("payload size %d exceeds maximum %d", len(payload), maxSize)
+		return fmt.Errorf("%w: payload size %d exceeds maximum %d", ErrPayloadTooLarge, len(payload), maxSize)
 	}
 	return nil
 }
@@ -202,7 +242,7 @@ func validatePayloadFormat(payload []byte, props *Properties) error {
 	}
 
 	if !utf8.Valid(payload) {
-		return fmt.Errorf("payload is not valid UTF-8 as required by PayloadFormat indicator")
+		return fmt.Errorf("%w: payload is not valid UTF-8 as required by PayloadFormat indicator", ErrPayloadFormatInvalid)
 	}
 	return nil
 }
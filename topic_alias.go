@@ -1,6 +1,10 @@
 package mq
 
-import "github.com/gonzalop/mq/internal/packets"
+import (
+	"fmt"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
 
 // applyTopicAlias applies topic alias optimization to a publish packet.
 // This is called automatically when WithAlias() is used.
@@ -14,15 +18,13 @@ import "github.com/gonzalop/mq/internal/packets"
 //   - Sends empty topic (bandwidth savings)
 //
 // If alias limit is reached, gracefully falls back to sending full topic.
-func (c *Client) applyTopicAlias(pkt *packets.PublishPacket) {
+//
+// An alias forced with WithAliasID() is validated against the limit instead;
+// the returned error rejects the publish.
+func (c *Client) applyTopicAlias(pkt *packets.PublishPacket) error {
 	c.topicAliasesLock.Lock()
 	defer c.topicAliasesLock.Unlock()
 
-	// Check if aliases are disabled
-	if c.maxAliases == 0 {
-		return
-	}
-
 	// Preserve original topic if not already set
 	if pkt.OriginalTopic == "" {
 		pkt.OriginalTopic = pkt.Topic
@@ -31,6 +33,31 @@ func (c *Client) applyTopicAlias(pkt *packets.PublishPacket) {
 		pkt.Topic = pkt.OriginalTopic
 	}
 
+	if pkt.AliasID != 0 {
+		return c.applyManualTopicAliasLocked(pkt)
+	}
+
+	// Check if aliases are disabled
+	if c.maxAliases == 0 {
+		return nil
+	}
+
+	// Announce an alias carried over from the previous connection
+	if aliasID, reserved := c.reservedAliases[pkt.Topic]; reserved {
+		delete(c.reservedAliases, pkt.Topic)
+		c.topicAliases[pkt.Topic] = aliasID
+		if pkt.Properties == nil {
+			pkt.Properties = &packets.Properties{}
+		}
+		pkt.Properties.TopicAlias = aliasID
+		pkt.Properties.Presence |= packets.PresTopicAlias
+		c.touchAliasLocked(pkt.Topic)
+		c.opts.Logger.Debug("re-established topic alias",
+			"topic", pkt.Topic,
+			"alias_id", aliasID)
+		return nil
+	}
+
 	// Check if we already have an alias for this topic
 	if aliasID, exists := c.topicAliases[pkt.Topic]; exists {
 		// Use existing alias - send empty topic
@@ -40,22 +67,44 @@ func (c *Client) applyTopicAlias(pkt *packets.PublishPacket) {
 		pkt.Properties.TopicAlias = aliasID
 		pkt.Properties.Presence |= packets.PresTopicAlias
 		pkt.Topic = "" // Empty topic when using alias
+		c.touchAliasLocked(pkt.OriginalTopic)
 		c.opts.Logger.Debug("using topic alias", "alias_id", aliasID)
-		return
+		return nil
+	}
+
+	// Auto-tune: only spend an alias slot on topics that are reused
+	if c.opts.TopicAliasAutoTune && !c.isAliasCandidateLocked(pkt.Topic) {
+		return nil
 	}
 
-	// Check if we can allocate a new alias
-	if c.nextAliasID > c.maxAliases {
+	// Skip IDs taken with WithAliasID
+	for c.nextAliasID <= c.maxAliases {
+		if _, manual := c.manualAliases[c.nextAliasID]; !manual {
+			break
+		}
+		c.nextAliasID++
+	}
+
+	// Allocate new alias
+	var aliasID uint16
+	if c.nextAliasID <= c.maxAliases {
+		aliasID = c.nextAliasID
+		c.nextAliasID++
+	} else if victim, id, ok := c.evictAliasLocked(); ok {
+		// Remap the least recently used alias; the full topic goes with it
+		aliasID = id
+		c.opts.Logger.Debug("reassigning least recently used topic alias",
+			"alias_id", aliasID,
+			"from", victim,
+			"to", pkt.Topic)
+	} else {
 		// At limit - just send full topic (graceful degradation)
 		c.opts.Logger.Debug("topic alias limit reached, sending full topic",
 			"limit", c.maxAliases)
-		return
+		return nil
 	}
-
-	// Allocate new alias
-	aliasID := c.nextAliasID
-	c.nextAliasID++
 	c.topicAliases[pkt.Topic] = aliasID
+	c.touchAliasLocked(pkt.Topic)
 
 	// Send both topic and alias on first use
 	if pkt.Properties == nil {
@@ -68,6 +117,56 @@ func (c *Client) applyTopicAlias(pkt *packets.PublishPacket) {
 		"topic", pkt.Topic,
 		"alias_id", aliasID,
 		"total_aliases", len(c.topicAliases))
+	return nil
+}
+
+// applyManualTopicAliasLocked applies the alias forced with WithAliasID() to
+// a publish packet, taking the ID over from any other topic that used it.
+// Must be called with topicAliasesLock held.
+func (c *Client) applyManualTopicAliasLocked(pkt *packets.PublishPacket) error {
+	aliasID := pkt.AliasID
+	if aliasID > c.maxAliases {
+		return fmt.Errorf("%w: alias %d exceeds the connection limit of %d", ErrTopicAliasInvalid, aliasID, c.maxAliases)
+	}
+
+	if pkt.Properties == nil {
+		pkt.Properties = &packets.Properties{}
+	}
+	pkt.Properties.TopicAlias = aliasID
+	pkt.Properties.Presence |= packets.PresTopicAlias
+
+	if current, exists := c.topicAliases[pkt.Topic]; exists && current == aliasID {
+		pkt.Topic = "" // Already announced
+		c.opts.Logger.Debug("using topic alias", "alias_id", aliasID)
+		return nil
+	}
+
+	// The full topic goes out with the alias, which remaps it on the server
+	for topic, id := range c.topicAliases {
+		if id == aliasID {
+			delete(c.topicAliases, topic)
+			c.aliasLRU.remove(topic)
+		}
+	}
+	for topic, id := range c.reservedAliases {
+		if id == aliasID || topic == pkt.Topic {
+			delete(c.reservedAliases, topic)
+		}
+	}
+	if old, exists := c.topicAliases[pkt.Topic]; exists {
+		delete(c.manualAliases, old)
+	}
+	c.aliasLRU.remove(pkt.Topic) // Manual aliases are never evicted
+	c.topicAliases[pkt.Topic] = aliasID
+	if c.manualAliases == nil {
+		c.manualAliases = make(map[uint16]struct{})
+	}
+	c.manualAliases[aliasID] = struct{}{}
+
+	c.opts.Logger.Debug("assigned manual topic alias",
+		"topic", pkt.Topic,
+		"alias_id", aliasID)
+	return nil
 }
 
 // resetPacketTopicAlias restores the original topic and removes the alias.
@@ -81,10 +180,57 @@ func (c *Client) resetPacketTopicAlias(pkt *packets.PublishPacket) {
 	}
 }
 
+// withCurrentAlias returns pkt as it must be written now. Aliases are
+// applied when a PUBLISH is created, so by the time a queued or retransmitted
+// one is written, its alias may have been given to another topic (LRU
+// eviction, WithAliasID). Such a PUBLISH is written as a copy carrying the
+// full topic and no alias; the queued packet itself is left untouched.
+func (c *Client) withCurrentAlias(pkt packets.Packet) packets.Packet {
+	pub, ok := unwrapPacket(pkt).(*packets.PublishPacket)
+	if !ok || !pub.UseAlias || pub.Properties == nil || pub.Properties.Presence&packets.PresTopicAlias == 0 {
+		return pkt
+	}
+
+	c.topicAliasesLock.Lock()
+	current, exists := c.topicAliases[pub.OriginalTopic]
+	c.topicAliasesLock.Unlock()
+	if exists && current == pub.Properties.TopicAlias {
+		return pkt
+	}
+
+	c.opts.Logger.Debug("topic alias remapped while queued, sending full topic",
+		"topic", pub.OriginalTopic,
+		"alias_id", pub.Properties.TopicAlias)
+	cp := *pub
+	props := *pub.Properties
+	cp.Properties = &props
+	c.resetPacketTopicAlias(&cp)
+	return &cp
+}
+
 // resetAllTopicAliases clears all topic alias state and resets all queued packets.
 func (c *Client) resetAllTopicAliases() {
 	c.topicAliasesLock.Lock()
+	if c.opts.EagerAliasReestablish {
+		// Remember what was in use (including aliases reserved but never
+		// announced on the previous connection) so reestablishTopicAliases
+		// can hand out the same IDs again.
+		prev := c.topicAliases
+		for topic, id := range c.reservedAliases {
+			if prev == nil {
+				prev = make(map[string]uint16)
+			}
+			prev[topic] = id
+		}
+		if len(prev) > 0 {
+			c.previousAliases = prev
+		}
+	}
 	c.topicAliases = make(map[string]uint16)
+	c.aliasCandidates = nil
+	c.manualAliases = nil
+	c.aliasLRU.reset()
+	c.reservedAliases = nil
 	c.nextAliasID = 1
 	c.maxAliases = 0
 	c.topicAliasesLock.Unlock()
@@ -106,18 +252,84 @@ func (c *Client) resetAllTopicAliases() {
 
 	// 3. Reset outgoing channel (mostly QoS 0)
 	// We drain and re-queue to ensure no stale aliases remain.
-	count := len(c.outgoing)
-	for range count {
+	c.requeueOutgoing(func(pkt packets.Packet) {
+		if pub, ok := unwrapPacket(pkt).(*packets.PublishPacket); ok {
+			c.resetPacketTopicAlias(pub)
+		}
+	})
+}
+
+// requeueOutgoing drains the packets queued in c.outgoing, passes each one
+// to fn and queues them again in the same order, without blocking: QoS 0
+// publishes and deferred acks are queued without sessionLock, so they may
+// take the freed slots meanwhile. Packets that no longer fit are queued from
+// a goroutine once the write loop makes room.
+func (c *Client) requeueOutgoing(fn func(packets.Packet)) {
+	var drained []packets.Packet
+	for range len(c.outgoing) {
 		select {
 		case pkt := <-c.outgoing:
-			if pub, ok := pkt.(*packets.PublishPacket); ok {
-				c.resetPacketTopicAlias(pub)
-			}
-			// Re-queue. Since we are holding sessionLock, logicLoop won't
-			// be pushing more packets that could fill the channel and block us.
-			c.outgoing <- pkt
+			fn(pkt)
+			drained = append(drained, pkt)
 		default:
 			// Channel was drained by something else
 		}
 	}
+
+	for i, pkt := range drained {
+		select {
+		case c.outgoing <- pkt:
+		default:
+			rest := drained[i:]
+			go func() {
+				for _, pkt := range rest {
+					select {
+					case c.outgoing <- pkt:
+					case <-c.stop:
+						return
+					}
+				}
+			}()
+			return
+		}
+	}
+}
+
+// reestablishTopicAliases restores the outgoing aliases used before the last
+// reconnect (WithEagerAliasReestablish). It must be called after the CONNACK
+// has been processed and before the write loop of the new connection starts.
+//
+// MQTT can only announce an alias inside a PUBLISH, so previously aliased
+// topics keep their IDs (if they fit within the new server limit) and the
+// next PUBLISH for each topic carries "topic + alias". Alias-enabled packets
+// already waiting in the outgoing queue are re-aliased right away, so the
+// first one per topic re-establishes the alias and the rest use it.
+func (c *Client) reestablishTopicAliases() {
+	c.topicAliasesLock.Lock()
+	prev := c.previousAliases
+	c.previousAliases = nil
+	if c.maxAliases == 0 || len(prev) == 0 {
+		c.topicAliasesLock.Unlock()
+		return
+	}
+
+	c.reservedAliases = make(map[string]uint16, len(prev))
+	for topic, id := range prev {
+		if id <= c.maxAliases {
+			c.reservedAliases[topic] = id
+			c.nextAliasID = max(c.nextAliasID, id+1)
+		}
+	}
+	c.opts.Logger.Debug("reserved topic aliases from previous connection",
+		"count", len(c.reservedAliases))
+	c.topicAliasesLock.Unlock()
+
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+
+	c.requeueOutgoing(func(pkt packets.Packet) {
+		if pub, ok := unwrapPacket(pkt).(*packets.PublishPacket); ok && pub.UseAlias {
+			_ = c.applyTopicAlias(pub) // Queued already: a forced alias over the new limit goes without one
+		}
+	})
 }
@@ -0,0 +1,88 @@
+package mq
+
+// defaultAutoTopicAliasMaximum is the receive alias maximum declared on the
+// first connection when auto-tuning is enabled without WithTopicAliasMaximum.
+const defaultAutoTopicAliasMaximum = 16
+
+// maxObservedTopics bounds the incoming topic tracking: no more aliases than
+// this can ever be declared.
+const maxObservedTopics = 65535
+
+// RecommendedReceiveAliasMaximum returns a receive topic alias maximum sized
+// to the traffic observed so far: the number of distinct topics of incoming
+// messages, capped at 65535.
+//
+// Tracking is only active with WithTopicAliasAutoTune; otherwise this returns
+// 0. The receive alias maximum is declared in CONNECT, so a new value only
+// takes effect on the next (re)connection. With auto-tuning enabled the client
+// applies it automatically on reconnect; operators can also use it to pick a
+// fixed WithTopicAliasMaximum from real traffic.
+//
+// Example:
+//
+//	log.Printf("distinct topics: %d (current alias maximum %d)",
+//	    client.RecommendedReceiveAliasMaximum(), client.Config().TopicAliasMaximum)
+func (c *Client) RecommendedReceiveAliasMaximum() int {
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+	return len(c.observedTopics)
+}
+
+// observeIncomingTopic records topic for auto-tuning. Must be called with
+// sessionLock held.
+func (c *Client) observeIncomingTopic(topic string) {
+	if _, ok := c.observedTopics[topic]; ok {
+		return
+	}
+	if c.observedTopics == nil {
+		c.observedTopics = make(map[string]struct{})
+	}
+	if len(c.observedTopics) < maxObservedTopics {
+		c.observedTopics[topic] = struct{}{}
+	}
+}
+
+// tuneTopicAliasMaximum picks the receive alias maximum for the next CONNECT.
+func (c *Client) tuneTopicAliasMaximum() {
+	recommended := c.RecommendedReceiveAliasMaximum()
+	previous := c.topicAliasMaximum()
+	switch {
+	case recommended > 0:
+		if uint16(recommended) != previous {
+			c.opts.Logger.Debug("auto-tuned topic alias maximum",
+				"previous", previous,
+				"recommended", recommended)
+		}
+		c.tunedAliasMaximum.Store(uint32(recommended))
+	case previous == 0:
+		c.tunedAliasMaximum.Store(defaultAutoTopicAliasMaximum)
+	}
+}
+
+// topicAliasMaximum returns the receive alias maximum declared in CONNECT:
+// the one picked by auto-tune, if any, or WithTopicAliasMaximum.
+func (c *Client) topicAliasMaximum() uint16 {
+	if tuned := c.tunedAliasMaximum.Load(); tuned > 0 {
+		return uint16(tuned)
+	}
+	return c.opts.TopicAliasMaximum
+}
+
+// isAliasCandidateLocked reports whether topic has been published before and
+// so deserves an outgoing alias; the first call for a topic records it and
+// returns false. Must be called with topicAliasesLock held.
+func (c *Client) isAliasCandidateLocked(topic string) bool {
+	if _, ok := c.aliasCandidates[topic]; ok {
+		delete(c.aliasCandidates, topic)
+		return true
+	}
+	if c.aliasCandidates == nil {
+		c.aliasCandidates = make(map[string]struct{})
+	}
+	// Bound memory when publishing to many one-off topics
+	if len(c.aliasCandidates) >= 4*int(c.maxAliases) {
+		clear(c.aliasCandidates)
+	}
+	c.aliasCandidates[topic] = struct{}{}
+	return false
+}
@@ -0,0 +1,109 @@
+package mq
+
+import (
+	"testing"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestRecommendedReceiveAliasMaximum(t *testing.T) {
+	tests := []struct {
+		name     string
+		autoTune bool
+		topics   []string
+		want     int
+	}{
+		{"disabled", false, []string{"a", "b"}, 0},
+		{"no traffic", true, nil, 0},
+		{"distinct topics", true, []string{"dev/1", "dev/2", "dev/1", "dev/3", "dev/2"}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultOptions("tcp://localhost:1883")
+			opts.Logger = testLogger()
+			opts.TopicAliasAutoTune = tt.autoTune
+			c := newTestClient(opts)
+
+			c.sessionLock.Lock()
+			for _, topic := range tt.topics {
+				c.handleIncoming(&packets.PublishPacket{Topic: topic})
+			}
+			c.sessionLock.Unlock()
+
+			if got := c.RecommendedReceiveAliasMaximum(); got != tt.want {
+				t.Errorf("RecommendedReceiveAliasMaximum() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTopicAliasAutoTuneOnReconnect(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	opts.TopicAliasAutoTune = true
+	c := newTestClient(opts)
+
+	// First connection: nothing observed yet, use the default
+	c.tuneTopicAliasMaximum()
+	if got := c.buildConnectPacket().Properties.TopicAliasMaximum; got != defaultAutoTopicAliasMaximum {
+		t.Errorf("expected initial alias maximum %d, got %d", defaultAutoTopicAliasMaximum, got)
+	}
+
+	c.sessionLock.Lock()
+	for _, topic := range []string{"a", "b", "c", "d", "e", "a"} {
+		c.handleIncoming(&packets.PublishPacket{Topic: topic})
+	}
+	c.sessionLock.Unlock()
+
+	// Next connection declares the observed cardinality
+	c.tuneTopicAliasMaximum()
+	if got := c.buildConnectPacket().Properties.TopicAliasMaximum; got != 5 {
+		t.Errorf("expected tuned alias maximum 5, got %d", got)
+	}
+	if got := c.Config().TopicAliasMaximum; got != 5 {
+		t.Errorf("Config().TopicAliasMaximum = %d, want 5", got)
+	}
+	if c.opts.TopicAliasMaximum != 0 {
+		t.Errorf("auto-tune changed the configured alias maximum to %d", c.opts.TopicAliasMaximum)
+	}
+}
+
+func TestTopicAliasAutoTuneOutgoingReuse(t *testing.T) {
+	c := &Client{
+		opts:         &clientOptions{Logger: testLogger(), TopicAliasAutoTune: true},
+		topicAliases: make(map[string]uint16),
+		maxAliases:   10,
+		nextAliasID:  1,
+	}
+
+	publish := func(topic string) *packets.PublishPacket {
+		pkt := &packets.PublishPacket{Topic: topic, UseAlias: true}
+		c.applyTopicAlias(pkt)
+		return pkt
+	}
+
+	// One-off topic: no alias slot used
+	if pkt := publish("once"); pkt.Properties != nil && pkt.Properties.TopicAlias != 0 {
+		t.Errorf("expected no alias on first publish, got %d", pkt.Properties.TopicAlias)
+	}
+
+	first := publish("reused")
+	if first.Properties != nil && first.Properties.TopicAlias != 0 {
+		t.Fatal("expected no alias on first publish of a topic")
+	}
+
+	second := publish("reused")
+	if second.Properties == nil || second.Properties.TopicAlias != 1 || second.Topic != "reused" {
+		t.Fatalf("expected alias 1 announced with the topic on reuse, got %+v", second)
+	}
+
+	third := publish("reused")
+	if third.Properties.TopicAlias != 1 || third.Topic != "" {
+		t.Errorf("expected alias-only publish, got topic %q alias %d", third.Topic, third.Properties.TopicAlias)
+	}
+
+	if len(c.topicAliases) != 1 {
+		t.Errorf("expected only the reused topic to hold an alias, got %v", c.topicAliases)
+	}
+}
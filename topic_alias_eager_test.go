@@ -0,0 +1,174 @@
+package mq
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestEagerAliasReestablish(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	opts.TopicAliasMaximum = 10
+	opts.EagerAliasReestablish = true
+	c := newTestClient(opts)
+	c.maxAliases = 10
+	c.nextAliasID = 1
+
+	alias := func(topic string) *packets.PublishPacket {
+		pkt := &packets.PublishPacket{Topic: topic, UseAlias: true, Version: ProtocolV50}
+		c.applyTopicAlias(pkt)
+		return pkt
+	}
+
+	// First connection: two hot topics get aliases 1 and 2
+	alias("hot/a")
+	alias("hot/b")
+
+	// Queued while offline
+	queuedA1 := &packets.PublishPacket{Topic: "hot/a", UseAlias: true, Version: ProtocolV50}
+	queuedA2 := &packets.PublishPacket{Topic: "hot/a", UseAlias: true, Version: ProtocolV50}
+	plain := &packets.PublishPacket{Topic: "hot/b", Version: ProtocolV50}
+	c.outgoing <- queuedA1
+	c.outgoing <- queuedA2
+	c.outgoing <- plain
+
+	// Reconnect
+	c.resetAllTopicAliases()
+	c.processConnackProperties(&packets.ConnackPacket{
+		Properties: &packets.Properties{
+			TopicAliasMaximum: 10,
+			Presence:          packets.PresTopicAliasMaximum,
+		},
+	})
+	c.reestablishTopicAliases()
+
+	// Queued packets were re-aliased in order
+	first := (<-c.outgoing).(*packets.PublishPacket)
+	second := (<-c.outgoing).(*packets.PublishPacket)
+	third := (<-c.outgoing).(*packets.PublishPacket)
+
+	if first != queuedA1 || first.Topic != "hot/a" || first.Properties.TopicAlias != 1 {
+		t.Errorf("expected first queued packet to re-announce alias 1 with topic, got topic=%q props=%+v", first.Topic, first.Properties)
+	}
+	if second.Topic != "" || second.Properties.TopicAlias != 1 {
+		t.Errorf("expected second queued packet to be alias-only, got topic=%q props=%+v", second.Topic, second.Properties)
+	}
+	if third.Topic != "hot/b" || (third.Properties != nil && third.Properties.Presence&packets.PresTopicAlias != 0) {
+		t.Errorf("expected packet without WithAlias to be untouched, got topic=%q props=%+v", third.Topic, third.Properties)
+	}
+
+	// The other hot topic keeps its ID and is announced on the next publish
+	b1 := alias("hot/b")
+	if b1.Topic != "hot/b" || b1.Properties.TopicAlias != 2 {
+		t.Errorf("expected hot/b to be re-sent with alias 2, got topic=%q alias=%d", b1.Topic, b1.Properties.TopicAlias)
+	}
+	b2 := alias("hot/b")
+	if b2.Topic != "" || b2.Properties.TopicAlias != 2 {
+		t.Errorf("expected hot/b to be alias-only after re-establishment, got topic=%q alias=%d", b2.Topic, b2.Properties.TopicAlias)
+	}
+
+	// New topics do not collide with reserved IDs
+	n := alias("new/topic")
+	if n.Properties.TopicAlias != 3 {
+		t.Errorf("expected new topic to get alias 3, got %d", n.Properties.TopicAlias)
+	}
+}
+
+func TestEagerAliasReestablishRespectsNewLimit(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	opts.TopicAliasMaximum = 10
+	opts.EagerAliasReestablish = true
+	c := newTestClient(opts)
+	c.maxAliases = 10
+	c.nextAliasID = 1
+
+	for _, topic := range []string{"t/1", "t/2", "t/3"} {
+		c.applyTopicAlias(&packets.PublishPacket{Topic: topic, UseAlias: true})
+	}
+
+	c.resetAllTopicAliases()
+	c.processConnackProperties(&packets.ConnackPacket{
+		Properties: &packets.Properties{
+			TopicAliasMaximum: 2,
+			Presence:          packets.PresTopicAliasMaximum,
+		},
+	})
+	c.reestablishTopicAliases()
+
+	if len(c.reservedAliases) != 2 {
+		t.Fatalf("expected 2 reserved aliases within the new limit, got %v", c.reservedAliases)
+	}
+	if _, ok := c.reservedAliases["t/3"]; ok {
+		t.Error("alias 3 exceeds the new server limit and must not be reserved")
+	}
+
+	pkt := &packets.PublishPacket{Topic: "t/3", UseAlias: true}
+	c.applyTopicAlias(pkt)
+	if pkt.Properties != nil && pkt.Properties.Presence&packets.PresTopicAlias != 0 {
+		t.Errorf("expected no alias when limit is exhausted, got %d", pkt.Properties.TopicAlias)
+	}
+}
+
+func TestAliasesNotReservedByDefault(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	c := newTestClient(opts)
+	c.maxAliases = 10
+	c.nextAliasID = 1
+
+	c.applyTopicAlias(&packets.PublishPacket{Topic: "hot/a", UseAlias: true})
+	c.resetAllTopicAliases()
+	c.maxAliases = 10
+	c.reestablishTopicAliases()
+
+	if len(c.reservedAliases) != 0 {
+		t.Errorf("expected no reserved aliases without the option, got %v", c.reservedAliases)
+	}
+}
+
+func TestRequeueOutgoing_DoesNotBlock(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	opts.OutgoingQueueSize = 2
+	c := newTestClient(opts)
+
+	a := &packets.PublishPacket{Topic: "a", Version: ProtocolV50}
+	b := &packets.PublishPacket{Topic: "b", Version: ProtocolV50}
+	late := &packets.PublishPacket{Topic: "late", Version: ProtocolV50}
+	c.outgoing <- a
+	c.outgoing <- b
+
+	// Another goroutine takes a freed slot while the queue is drained
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.requeueOutgoing(func(pkt packets.Packet) {
+			if pkt == a {
+				c.outgoing <- late
+			}
+		})
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("requeueOutgoing blocked on a full queue")
+	}
+
+	// Nothing is lost, and the requeued packets keep their order
+	var topics []string
+	for range 3 {
+		select {
+		case pkt := <-c.outgoing:
+			topics = append(topics, pkt.(*packets.PublishPacket).Topic)
+		case <-time.After(time.Second):
+			t.Fatalf("missing packets, got %v", topics)
+		}
+	}
+	if want := []string{"late", "a", "b"}; !slices.Equal(topics, want) {
+		t.Errorf("queued topics = %v, want %v", topics, want)
+	}
+}
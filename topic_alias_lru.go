@@ -0,0 +1,76 @@
+package mq
+
+import "container/list"
+
+// aliasLRU orders the topics holding an automatic outgoing alias from the
+// most to the least recently published (TopicAliasLRU). The zero value is
+// ready to use. Guarded by topicAliasesLock.
+type aliasLRU struct {
+	order *list.List               // topic strings, most recent first
+	elems map[string]*list.Element // topic → its element in order
+}
+
+// touch marks topic as the most recently published.
+func (l *aliasLRU) touch(topic string) {
+	if l.order == nil {
+		l.order = list.New()
+		l.elems = make(map[string]*list.Element)
+	}
+	if e, ok := l.elems[topic]; ok {
+		l.order.MoveToFront(e)
+		return
+	}
+	l.elems[topic] = l.order.PushFront(topic)
+}
+
+// remove forgets topic.
+func (l *aliasLRU) remove(topic string) {
+	if e, ok := l.elems[topic]; ok {
+		l.order.Remove(e)
+		delete(l.elems, topic)
+	}
+}
+
+// oldest returns the least recently published topic.
+func (l *aliasLRU) oldest() (string, bool) {
+	if l.order == nil || l.order.Len() == 0 {
+		return "", false
+	}
+	return l.order.Back().Value.(string), true
+}
+
+// reset forgets all topics.
+func (l *aliasLRU) reset() {
+	l.order = nil
+	l.elems = nil
+}
+
+// touchAliasLocked records a publish of an aliased topic for TopicAliasLRU.
+// Must be called with topicAliasesLock held.
+func (c *Client) touchAliasLocked(topic string) {
+	if c.opts.TopicAliasPolicy != TopicAliasLRU {
+		return
+	}
+	if _, manual := c.manualAliases[c.topicAliases[topic]]; manual {
+		return
+	}
+	c.aliasLRU.touch(topic)
+}
+
+// evictAliasLocked takes the alias away from the least recently published
+// topic so it can be reassigned (TopicAliasLRU). It returns the evicted topic
+// and its alias, or false if the policy is not LRU or no alias can be evicted.
+// Must be called with topicAliasesLock held.
+func (c *Client) evictAliasLocked() (string, uint16, bool) {
+	if c.opts.TopicAliasPolicy != TopicAliasLRU {
+		return "", 0, false
+	}
+	topic, ok := c.aliasLRU.oldest()
+	if !ok {
+		return "", 0, false
+	}
+	c.aliasLRU.remove(topic)
+	id := c.topicAliases[topic]
+	delete(c.topicAliases, topic)
+	return topic, id, true
+}
@@ -0,0 +1,145 @@
+package mq
+
+import (
+	"testing"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestTopicAliasPolicy(t *testing.T) {
+	type step struct {
+		topic     string
+		opt       PublishOption
+		wantTopic string // "" means alias-only
+		wantAlias uint16 // 0 means no alias
+	}
+	tests := []struct {
+		name   string
+		policy TopicAliasPolicy
+		steps  []step
+	}{
+		{
+			name:   "first come",
+			policy: TopicAliasFirstCome,
+			steps: []step{
+				{"a", WithAlias(), "a", 1},
+				{"b", WithAlias(), "b", 2},
+				{"c", WithAlias(), "c", 0}, // Pool exhausted
+				{"c", WithAlias(), "c", 0},
+				{"a", WithAlias(), "", 1},
+			},
+		},
+		{
+			name:   "lru",
+			policy: TopicAliasLRU,
+			steps: []step{
+				{"a", WithAlias(), "a", 1},
+				{"b", WithAlias(), "b", 2},
+				{"a", WithAlias(), "", 1},  // b is now the least recent
+				{"c", WithAlias(), "c", 2}, // Remapped with the full topic
+				{"c", WithAlias(), "", 2},
+				{"b", WithAlias(), "b", 1}, // Evicts a
+				{"a", WithAlias(), "a", 2}, // Evicts c
+				{"b", WithAlias(), "", 1},
+			},
+		},
+		{
+			name:   "lru keeps manual aliases",
+			policy: TopicAliasLRU,
+			steps: []step{
+				{"m", WithAliasID(1), "m", 1},
+				{"a", WithAlias(), "a", 2},
+				{"b", WithAlias(), "b", 2}, // Evicts a, never m
+				{"a", WithAlias(), "a", 2},
+				{"m", WithAlias(), "", 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newManualAliasClient(2)
+			c.opts.TopicAliasPolicy = tt.policy
+
+			for i, s := range tt.steps {
+				if err := c.Publish(s.topic, []byte("x"), s.opt).Error(); err != nil {
+					t.Fatalf("step %d: publish failed: %v", i, err)
+				}
+				topic, alias := sentAlias(t, c)
+				if topic != s.wantTopic || alias != s.wantAlias {
+					t.Errorf("step %d (%s): sent topic %q alias %d, want %q alias %d",
+						i, s.topic, topic, alias, s.wantTopic, s.wantAlias)
+				}
+			}
+			if len(c.topicAliases) > 2 {
+				t.Errorf("more aliases than allowed: %v", c.topicAliases)
+			}
+		})
+	}
+}
+
+func TestTopicAliasLRU_Reset(t *testing.T) {
+	c := newManualAliasClient(1)
+	c.opts.TopicAliasPolicy = TopicAliasLRU
+
+	_ = c.Publish("a", nil, WithAlias())
+	<-c.outgoing
+	c.resetAllTopicAliases()
+	if _, ok := c.aliasLRU.oldest(); ok {
+		t.Fatal("recency must be cleared on reconnect")
+	}
+
+	c.maxAliases = 1
+	_ = c.Publish("b", nil, WithAlias())
+	if topic, alias := sentAlias(t, c); topic != "b" || alias != 1 {
+		t.Errorf("sent topic %q alias %d, want b with alias 1", topic, alias)
+	}
+}
+
+func TestTopicAliasRemappedWhileQueued(t *testing.T) {
+	tests := []struct {
+		name  string
+		remap func(c *Client)
+	}{
+		{
+			name: "lru eviction",
+			remap: func(c *Client) {
+				c.Publish("b", []byte("x"), WithAlias())
+			},
+		},
+		{
+			name: "manual alias",
+			remap: func(c *Client) {
+				c.Publish("b", []byte("x"), WithAliasID(1))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newManualAliasClient(1)
+			c.opts.TopicAliasPolicy = TopicAliasLRU
+
+			// Still queued when alias 1 moves to "b"
+			c.Publish("a", []byte("x"), WithAlias())
+			c.Publish("a", []byte("x"), WithAlias())
+			tt.remap(c)
+
+			want := []struct {
+				topic string
+				alias uint16
+			}{{"a", 0}, {"a", 0}, {"b", 1}}
+			for i, w := range want {
+				pub := unwrapPacket(c.withCurrentAlias(<-c.outgoing)).(*packets.PublishPacket)
+				var alias uint16
+				if pub.Properties != nil && pub.Properties.Presence&packets.PresTopicAlias != 0 {
+					alias = pub.Properties.TopicAlias
+				}
+				if pub.Topic != w.topic || alias != w.alias {
+					t.Errorf("packet %d written with topic %q alias %d, want %q alias %d",
+						i, pub.Topic, alias, w.topic, w.alias)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package mq
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func newManualAliasClient(maxAliases uint16) *Client {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	c := newTestClient(opts)
+	c.serverCaps.MaximumQoS = 2
+	c.maxAliases = maxAliases
+	c.nextAliasID = 1
+	return c
+}
+
+// sentAlias returns the topic and alias of the next queued PUBLISH.
+func sentAlias(t *testing.T, c *Client) (string, uint16) {
+	t.Helper()
+	select {
+	case pkt := <-c.outgoing:
+		pub := unwrapPacket(pkt).(*packets.PublishPacket)
+		if pub.Properties == nil || pub.Properties.Presence&packets.PresTopicAlias == 0 {
+			return pub.Topic, 0
+		}
+		return pub.Topic, pub.Properties.TopicAlias
+	default:
+		t.Fatal("nothing was sent")
+		return "", 0
+	}
+}
+
+func TestWithAliasID(t *testing.T) {
+	c := newManualAliasClient(10)
+
+	steps := []struct {
+		topic     string
+		opt       PublishOption
+		wantTopic string
+		wantAlias uint16
+	}{
+		{"plant/line-1", WithAliasID(3), "plant/line-1", 3},
+		{"plant/line-1", WithAliasID(3), "", 3},
+		{"plant/line-1", WithAlias(), "", 3}, // Automatic use keeps the manual alias
+		{"plant/auto-a", WithAlias(), "plant/auto-a", 1},
+		{"plant/auto-b", WithAlias(), "plant/auto-b", 2},
+		{"plant/auto-c", WithAlias(), "plant/auto-c", 4},    // 3 is reserved
+		{"plant/line-2", WithAliasID(1), "plant/line-2", 1}, // Taken over from auto-a
+		{"plant/auto-a", WithAlias(), "plant/auto-a", 5},
+		{"plant/line-2", WithAliasID(1), "", 1},
+	}
+	for i, s := range steps {
+		if err := c.Publish(s.topic, []byte("x"), s.opt).Error(); err != nil {
+			t.Fatalf("step %d: publish failed: %v", i, err)
+		}
+		topic, alias := sentAlias(t, c)
+		if topic != s.wantTopic || alias != s.wantAlias {
+			t.Errorf("step %d: sent topic %q alias %d, want %q alias %d", i, topic, alias, s.wantTopic, s.wantAlias)
+		}
+	}
+}
+
+func TestWithAliasID_Invalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		version    uint8
+		maxAliases uint16
+		id         uint16
+	}{
+		{"above limit", ProtocolV50, 10, 11},
+		{"aliases not negotiated", ProtocolV50, 0, 1},
+		{"MQTT v3.1.1", ProtocolV311, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newManualAliasClient(tt.maxAliases)
+			c.opts.ProtocolVersion = tt.version
+
+			err := c.Publish("plant/line-1", []byte("x"), WithAliasID(tt.id)).Error()
+			if !errors.Is(err, ErrTopicAliasInvalid) {
+				t.Fatalf("expected ErrTopicAliasInvalid, got %v", err)
+			}
+			if len(c.outgoing) != 0 {
+				t.Error("rejected publish must not be sent")
+			}
+			if len(c.topicAliases) != 0 || len(c.manualAliases) != 0 {
+				t.Errorf("rejected publish changed the alias state: %v %v", c.topicAliases, c.manualAliases)
+			}
+		})
+	}
+}
+
+func TestWithAliasID_Reconnect(t *testing.T) {
+	c := newManualAliasClient(10)
+	if err := c.Publish("plant/line-1", []byte("x"), WithQoS(AtLeastOnce), WithAliasID(7)).Error(); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	<-c.outgoing
+
+	// Queued again, without any alias, until the new limit is known
+	c.resetAllTopicAliases()
+	if c.manualAliases != nil {
+		t.Error("manual aliases must be cleared on reconnect")
+	}
+
+	c.maxAliases = 5
+	if err := c.Publish("plant/line-1", []byte("x"), WithAliasID(7)).Error(); !errors.Is(err, ErrTopicAliasInvalid) {
+		t.Errorf("expected the new, smaller limit to apply, got %v", err)
+	}
+	if err := c.Publish("plant/line-1", []byte("x"), WithAliasID(5)).Error(); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if topic, alias := sentAlias(t, c); topic != "plant/line-1" || alias != 5 {
+		t.Errorf("sent topic %q alias %d, want the full topic with alias 5", topic, alias)
+	}
+}
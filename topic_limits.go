@@ -0,0 +1,65 @@
+package mq
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SetTopicPayloadLimit limits the payload size of messages published to
+// topics starting with prefix. Publishes that exceed the limit fail locally
+// with ErrPayloadTooLarge and are never transmitted.
+//
+// This complements WithMaxPayloadSize, which applies to every topic: a
+// per-prefix limit is checked in addition to the global one, so it can only
+// make the limit stricter. When several prefixes match a topic, the longest
+// one applies. Topics that match no prefix use the global limit only.
+//
+// Prefixes are plain string prefixes, not topic filters: "sensors/" matches
+// "sensors/a/temp" but not "sensors". A maxBytes of 0 or less removes the
+// limit for prefix.
+//
+// It is safe to call SetTopicPayloadLimit concurrently with Publish. The new
+// limit applies to publishes started after it returns.
+//
+// Example:
+//
+//	// Telemetry must fit in a single LoRa frame; firmware images may be large.
+//	client.SetTopicPayloadLimit("telemetry/", 222)
+//	client.SetTopicPayloadLimit("telemetry/bulk/", 64*1024)
+func (c *Client) SetTopicPayloadLimit(prefix string, maxBytes int) {
+	c.topicLimitsLock.Lock()
+	defer c.topicLimitsLock.Unlock()
+
+	if maxBytes <= 0 {
+		delete(c.topicLimits, prefix)
+		return
+	}
+	if c.topicLimits == nil {
+		c.topicLimits = make(map[string]int)
+	}
+	c.topicLimits[prefix] = maxBytes
+}
+
+// checkTopicPayloadLimit enforces the longest matching per-prefix limit set
+// with SetTopicPayloadLimit.
+func (c *Client) checkTopicPayloadLimit(topic string, payload []byte) error {
+	c.topicLimitsLock.RLock()
+	defer c.topicLimitsLock.RUnlock()
+
+	if len(c.topicLimits) == 0 {
+		return nil
+	}
+
+	match, limit := "", 0
+	for prefix, max := range c.topicLimits {
+		if strings.HasPrefix(topic, prefix) && (limit == 0 || len(prefix) > len(match)) {
+			match, limit = prefix, max
+		}
+	}
+
+	if limit > 0 && len(payload) > limit {
+		return fmt.Errorf("%w: payload size %d exceeds limit %d for topic prefix %q",
+			ErrPayloadTooLarge, len(payload), limit, match)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package mq
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSetTopicPayloadLimit(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.MaxPayloadSize = 1000
+	c := newTestClient(opts)
+	c.opts.Logger = testLogger()
+
+	c.SetTopicPayloadLimit("telemetry/", 10)
+	c.SetTopicPayloadLimit("telemetry/bulk/", 500)
+	c.SetTopicPayloadLimit("removed/", 5)
+	c.SetTopicPayloadLimit("removed/", 0)
+
+	tests := []struct {
+		name       string
+		topic      string
+		size       int
+		wantErr    bool
+		wantPrefix string
+	}{
+		{"under prefix limit", "telemetry/temp", 10, false, ""},
+		{"over prefix limit", "telemetry/temp", 11, true, "telemetry/"},
+		{"longest prefix wins", "telemetry/bulk/log", 400, false, ""},
+		{"over longest prefix limit", "telemetry/bulk/log", 501, true, "telemetry/bulk/"},
+		{"prefix is not a filter", "telemetry", 100, false, ""},
+		{"non-matching uses global", "images/cam1", 1000, false, ""},
+		{"non-matching over global", "images/cam1", 1001, true, ""},
+		{"removed limit", "removed/x", 100, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.Publish(tt.topic, make([]byte, tt.size)).Error()
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("expected publish to succeed, got %v", err)
+				}
+				<-c.outgoing
+				return
+			}
+
+			if !errors.Is(err, ErrPayloadTooLarge) {
+				t.Fatalf("expected ErrPayloadTooLarge, got %v", err)
+			}
+			if tt.wantPrefix != "" && !strings.Contains(err.Error(), `"`+tt.wantPrefix+`"`) {
+				t.Errorf("expected error to name prefix %q, got %v", tt.wantPrefix, err)
+			}
+			if len(c.outgoing) != 0 {
+				t.Error("rejected publish was queued for transmission")
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package mq
+
+import "sync/atomic"
+
+// TopicStat holds the counters of a subscription filter reported by
+// Client.TopicStats.
+type TopicStat struct {
+	// Messages is the number of messages received that matched the filter.
+	Messages uint64
+	// Bytes is the total payload size of those messages.
+	Bytes uint64
+}
+
+// topicCounter is the live, atomically updated form of a TopicStat.
+type topicCounter struct {
+	messages atomic.Uint64
+	bytes    atomic.Uint64
+}
+
+// TopicStats returns the number of messages and payload bytes received for
+// each subscription filter, keyed by the filter as passed to Subscribe
+// (shared subscriptions include their "$share/<group>/" prefix).
+//
+// Counting requires WithTopicStats; otherwise the map is empty. Counters
+// start when the client is created and persist across reconnects and after
+// an unsubscribe. The returned map is a copy that the caller may modify.
+//
+// Example:
+//
+//	stats := client.TopicStats()
+//	if s := stats["sensors/+/temp"]; s.Messages > 10000 {
+//	    log.Printf("noisy sensors: %d messages, %d bytes", s.Messages, s.Bytes)
+//	}
+func (c *Client) TopicStats() map[string]TopicStat {
+	stats := make(map[string]TopicStat)
+	c.topicStats.Range(func(key, value any) bool {
+		counter := value.(*topicCounter)
+		stats[key.(string)] = TopicStat{
+			Messages: counter.messages.Load(),
+			Bytes:    counter.bytes.Load(),
+		}
+		return true
+	})
+	return stats
+}
+
+// countTopicMessage adds a received message of size bytes to the counters of
+// filter.
+func (c *Client) countTopicMessage(filter string, size int) {
+	value, ok := c.topicStats.Load(filter)
+	if !ok {
+		value, _ = c.topicStats.LoadOrStore(filter, &topicCounter{})
+	}
+	counter := value.(*topicCounter)
+	counter.messages.Add(1)
+	counter.bytes.Add(uint64(size))
+}
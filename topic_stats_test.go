@@ -0,0 +1,93 @@
+package mq
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestTopicStats(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		want    map[string]TopicStat
+	}{
+		{
+			name: "disabled",
+			want: map[string]TopicStat{},
+		},
+		{
+			name:    "enabled",
+			enabled: true,
+			want: map[string]TopicStat{
+				"sensors/+/temp": {Messages: 3, Bytes: 12},
+				"sensors/#":      {Messages: 4, Bytes: 17},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultOptions("tcp://localhost:1883")
+			opts.Logger = testLogger()
+			opts.TopicStats = tt.enabled
+			c := newTestClient(opts)
+			c.connected.Store(true)
+
+			var wg sync.WaitGroup
+			handler := func(*Client, Message) { wg.Done() }
+			c.Subscribe("sensors/+/temp", AtMostOnce, handler)
+			c.Subscribe("sensors/#", AtMostOnce, handler)
+			c.Subscribe("alerts/#", AtMostOnce, handler)
+			for len(c.outgoing) > 0 {
+				<-c.outgoing
+			}
+
+			wg.Add(7)
+			for _, p := range []*packets.PublishPacket{
+				{Topic: "sensors/1/temp", Payload: []byte("21.5")},
+				{Topic: "sensors/2/temp", Payload: []byte("19.0")},
+				{Topic: "sensors/1/temp", Payload: []byte("21.6")},
+				{Topic: "sensors/1/humidity", Payload: []byte("40.25")},
+				{Topic: "other/topic", Payload: []byte("ignored")},
+			} {
+				c.sessionLock.Lock()
+				c.handleIncoming(p)
+				c.sessionLock.Unlock()
+			}
+			wg.Wait()
+
+			stats := c.TopicStats()
+			if !reflect.DeepEqual(stats, tt.want) {
+				t.Fatalf("TopicStats() = %v, want %v", stats, tt.want)
+			}
+
+			// The result is a copy
+			stats["sensors/#"] = TopicStat{}
+			if got := c.TopicStats()["sensors/#"]; got != tt.want["sensors/#"] {
+				t.Errorf("modifying the result changed the counters: %v", got)
+			}
+		})
+	}
+}
+
+func TestTopicStats_SuppressedRetained(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	opts.TopicStats = true
+	c := newTestClient(opts)
+	c.subscriptions["status/#"] = subscriptionEntry{
+		handler:          func(*Client, Message) {},
+		suppressRetained: true,
+	}
+
+	c.sessionLock.Lock()
+	c.handleIncoming(&packets.PublishPacket{Topic: "status/a", Payload: []byte("on"), Retain: true})
+	c.sessionLock.Unlock()
+
+	if stats := c.TopicStats(); len(stats) != 0 {
+		t.Errorf("expected suppressed retained messages not to be counted, got %v", stats)
+	}
+}
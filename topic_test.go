@@ -1,6 +1,7 @@
 package mq
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -337,3 +338,40 @@ func TestTopicMatch_WildcardStartingWithDollar_Compliance(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateTopicNameAndFilter(t *testing.T) {
+	tests := []struct {
+		topic    string
+		nameOK   bool
+		filterOK bool
+	}{
+		{"sensors/temp", true, true},
+		{"a//b", true, true},
+		{"sensors/+/temp", false, true},
+		{"sensors/#", false, true},
+		{"sensors/+temp", false, false},
+		{"sensors/#/temp", false, false},
+		{"", false, false},
+		{"bad\x00topic", false, false},
+		{"\xff", false, false},
+	}
+	for _, tt := range tests {
+		if err := ValidateTopicName(tt.topic); (err == nil) != tt.nameOK || (err != nil && !errors.Is(err, ErrInvalidTopic)) {
+			t.Errorf("ValidateTopicName(%q) = %v, want ok=%v", tt.topic, err, tt.nameOK)
+		}
+		if err := ValidateTopicFilter(tt.topic); (err == nil) != tt.filterOK || (err != nil && !errors.Is(err, ErrInvalidTopicFilter)) {
+			t.Errorf("ValidateTopicFilter(%q) = %v, want ok=%v", tt.topic, err, tt.filterOK)
+		}
+	}
+
+	// Publish and Subscribe fail with the same errors
+	c := newTestClient(nil)
+	c.opts.Logger = testLogger()
+	c.connected.Store(true)
+	if err := c.Publish("a/+", nil).Error(); !errors.Is(err, ErrInvalidTopic) {
+		t.Errorf("Publish error = %v, want ErrInvalidTopic", err)
+	}
+	if err := c.Subscribe("a/#/b", AtMostOnce, nil).Error(); !errors.Is(err, ErrInvalidTopicFilter) {
+		t.Errorf("Subscribe error = %v, want ErrInvalidTopicFilter", err)
+	}
+}
@@ -0,0 +1,84 @@
+package mq
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestDial_UnixSocket(t *testing.T) {
+	// Socket paths are limited to ~100 bytes, t.TempDir() may be longer
+	dir, err := os.MkdirTemp("", "mq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mqtt.sock")
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets not available: %v", err)
+	}
+	defer ln.Close()
+
+	published := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = packets.ReadPacket(conn, ProtocolV50, 0)
+		_, _ = (&packets.ConnackPacket{ReturnCode: 0}).WriteTo(conn)
+		for {
+			pkt, err := packets.ReadPacket(conn, ProtocolV50, 0)
+			if err != nil {
+				return
+			}
+			if p, ok := pkt.(*packets.PublishPacket); ok {
+				published <- p.Topic
+			}
+		}
+	}()
+
+	client, err := Dial("unix://"+path, WithAutoReconnect(false), WithLogger(testLogger()))
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer client.Disconnect(context.Background())
+
+	client.Publish("local/ping", nil)
+	select {
+	case topic := <-published:
+		if topic != "local/ping" {
+			t.Errorf("server received topic %q", topic)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for PUBLISH")
+	}
+}
+
+func TestDial_UnixSocketErrors(t *testing.T) {
+	tests := []struct {
+		server string
+		want   string
+	}{
+		{"unix://", "missing socket path"},
+		{"unix:///nonexistent/mq-test.sock", "failed to connect"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.server, func(t *testing.T) {
+			_, err := Dial(tt.server, WithAutoReconnect(false), WithLogger(testLogger()))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Dial(%q) error = %v, want it to contain %q", tt.server, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package mq
+
+import "context"
+
+// WaitForPending blocks until every outgoing QoS 1/2 operation has settled:
+// no PUBLISH, SUBSCRIBE or UNSUBSCRIBE is awaiting acknowledgment and no
+// publish is queued behind the server's Receive Maximum. It returns nil once
+// that is the case (at once if nothing is outstanding), ctx.Err() if ctx is
+// done first, or ErrClientDisconnected if the client stops
+// (ErrGracefulDisconnect after Disconnect).
+//
+// Operations keep settling across a reconnect, so WaitForPending also waits
+// through a connection outage. QoS 0 publishes are not tracked. New
+// operations started while waiting are waited for too; stop publishing
+// first to wait for a fixed set.
+//
+// Example (before a rolling deploy):
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	if err := client.WaitForPending(ctx); err != nil {
+//	    log.Printf("messages still unacknowledged: %v", err)
+//	}
+//	client.Disconnect(context.Background())
+func (c *Client) WaitForPending(ctx context.Context) error {
+	c.sessionLock.Lock()
+	for !c.pendingSettledLocked() {
+		if c.pendingSettled == nil {
+			c.pendingSettled = make(chan struct{})
+		}
+		settled := c.pendingSettled
+
+		c.sessionLock.Unlock()
+		select {
+		case <-settled:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.stop:
+			return c.stopError()
+		}
+		c.sessionLock.Lock()
+	}
+	c.sessionLock.Unlock()
+	return nil
+}
+
+// pendingSettledLocked reports whether no operation is pending or queued.
+// Must be called with sessionLock held.
+func (c *Client) pendingSettledLocked() bool {
+	return len(c.pending) == 0 && len(c.publishQueue) == 0
+}
+
+// signalPendingSettled wakes WaitForPending callers if no operation is
+// pending or queued any more. Must be called with sessionLock held.
+func (c *Client) signalPendingSettled() {
+	if c.pendingSettled != nil && c.pendingSettledLocked() {
+		close(c.pendingSettled)
+		c.pendingSettled = nil
+	}
+}
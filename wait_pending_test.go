@@ -0,0 +1,114 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestWaitForPending(t *testing.T) {
+	server, _ := serveDrain(t, 50*time.Millisecond)
+	client, err := Dial(server, WithAutoReconnect(false), WithLogger(testLogger()))
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer client.Disconnect(context.Background())
+
+	if err := client.WaitForPending(context.Background()); err != nil {
+		t.Fatalf("WaitForPending with nothing pending: %v", err)
+	}
+
+	// Receive Maximum is 1: two publishes are queued behind the first
+	var tokens []Token
+	for i := range 3 {
+		tokens = append(tokens, client.Publish("telemetry", []byte{byte(i)}, WithQoS(AtLeastOnce)))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := client.WaitForPending(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected DeadlineExceeded while publishes are outstanding, got %v", err)
+	}
+
+	if err := client.WaitForPending(context.Background()); err != nil {
+		t.Fatalf("WaitForPending failed: %v", err)
+	}
+	for i, tok := range tokens {
+		select {
+		case <-tok.Done():
+		default:
+			t.Errorf("publish %d not acknowledged when WaitForPending returned", i)
+		}
+	}
+}
+
+func TestWaitForPending_ClientStops(t *testing.T) {
+	server, _ := serveDrain(t, -1)
+	client, err := Dial(server, WithAutoReconnect(false), WithLogger(testLogger()))
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	client.Publish("telemetry", []byte("x"), WithQoS(AtLeastOnce))
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- client.WaitForPending(context.Background()) }()
+
+	time.Sleep(50 * time.Millisecond)
+	_ = client.Disconnect(context.Background())
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, ErrClientDisconnected) {
+			t.Errorf("expected ErrClientDisconnected, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("WaitForPending did not return after Disconnect")
+	}
+}
+
+func TestWaitForPending_AbandonedOperation(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.Logger = testLogger()
+	opts.OutgoingQueueSize = 1
+	c := newTestClient(opts)
+	c.connected.Store(true)
+	c.outgoing <- &packets.PingreqPacket{} // Stalled network: queue full
+
+	// Registered as pending, then abandoned before it could be queued
+	ctx, cancel := context.WithCancel(context.Background())
+	tokCh := make(chan SubscribeToken, 1)
+	go func() { tokCh <- c.SubscribeContext(ctx, "jobs", AtLeastOnce, func(*Client, Message) {}) }()
+	for {
+		c.sessionLock.Lock()
+		n := len(c.pending)
+		c.sessionLock.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- c.WaitForPending(context.Background()) }()
+	select {
+	case err := <-errCh:
+		t.Fatalf("WaitForPending returned %v while the SUBSCRIBE was pending", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Errorf("WaitForPending failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WaitForPending not woken when the pending SUBSCRIBE was abandoned")
+	}
+	if err := (<-tokCh).Wait(context.Background()); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// SetWill replaces the Last Will and Testament message configured with
+// WithWill. An empty topic removes the will.
+//
+// The will is part of the CONNECT packet, and MQTT offers no way to change it
+// on an open connection (neither v3.1.1 nor v5.0: AUTH and the CONNACK/PUBLISH
+// properties cannot carry a will). The new will is therefore only used from
+// the next connection on: after an automatic reconnect, or immediately if
+// RefreshWill is called.
+//
+// It is safe to call SetWill concurrently from any goroutine.
+func (c *Client) SetWill(topic string, payload []byte, qos uint8, retained bool, properties ...*Properties) {
+	var will *willMessage
+	if topic != "" {
+		will = &willMessage{
+			Topic:    topic,
+			Payload:  payload,
+			QoS:      qos,
+			Retained: retained,
+		}
+		if len(properties) > 0 && properties[0] != nil {
+			will.Properties = properties[0]
+		}
+	}
+
+	c.willLock.Lock()
+	c.opts.will = will
+	c.willLock.Unlock()
+}
+
+// currentWill returns the will to send in the next CONNECT, or nil.
+func (c *Client) currentWill() *willMessage {
+	c.willLock.Lock()
+	defer c.willLock.Unlock()
+	return c.opts.will
+}
+
+// RefreshWill pushes the will set with SetWill to the server by reconnecting.
+//
+// The client sends a normal DISCONNECT (so the server discards the old will
+// without publishing it), closes the connection and immediately reconnects
+// with the current will. It returns once the new connection is established,
+// or with an error if ctx is done or the reconnect fails. The reconnect is
+// transparent: OnConnectionLost and OnConnect are not called for it.
+//
+// This is expensive (a full MQTT handshake, plus TLS if enabled), so refresh
+// the will only when the state it reports actually changes, not on every
+// update. Use a persistent session (WithCleanSession(false) and, for MQTT v5.0,
+// a non-zero WithSessionExpiryInterval) so that subscriptions and in-flight
+// messages survive the reconnect; with a clean session, subscriptions are
+// restored automatically but messages published while reconnecting are lost.
+//
+// If the client is not connected, the new will is simply used by the next
+// automatic reconnect and RefreshWill returns nil. RefreshWill requires
+// automatic reconnection (the default).
+//
+// Example ("last known good state" will):
+//
+//	client, _ := mq.Dial(uri,
+//	    mq.WithClientID("sensor-1"),
+//	    mq.WithCleanSession(false),
+//	    mq.WithSessionExpiryInterval(3600),
+//	    mq.WithWill("devices/sensor-1/state", initialState, mq.AtLeastOnce, true))
+//
+//	// Later, when the device state changes:
+//	client.SetWill("devices/sensor-1/state", newState, mq.AtLeastOnce, true)
+//	if err := client.RefreshWill(ctx); err != nil {
+//	    log.Printf("will not refreshed: %v", err)
+//	}
+func (c *Client) RefreshWill(ctx context.Context) error {
+	if !c.opts.AutoReconnect {
+		return fmt.Errorf("RefreshWill requires automatic reconnection")
+	}
+	if !c.IsConnected() {
+		return nil
+	}
+
+	c.opts.Logger.Debug("reconnecting to refresh will")
+	c.willRefresh.Store(true)
+
+	// A normal DISCONNECT makes the server discard the current will
+	tok := newToken()
+	pkt := &writeNotifyPacket{
+		Packet: &packets.DisconnectPacket{
+			Version:    c.opts.ProtocolVersion,
+			ReasonCode: uint8(ReasonCodeNormalDisconnect),
+		},
+		token: tok,
+	}
+	select {
+	case c.outgoing <- pkt:
+	case <-ctx.Done():
+		c.willRefresh.Store(false)
+		return ctx.Err()
+	case <-c.stop:
+		c.willRefresh.Store(false)
+		return ErrClientDisconnected
+	}
+
+	var err error
+	select {
+	case <-tok.Done():
+	case <-ctx.Done():
+		// The DISCONNECT may already be on its way: reconnect anyway
+		err = ctx.Err()
+	case <-c.stop:
+		return ErrClientDisconnected
+	}
+
+	c.connLock.Lock()
+	if c.conn != nil {
+		c.conn.Close() // readLoop tears the connection down
+	}
+	c.connLock.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for c.willRefresh.Load() {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.stop:
+			return ErrClientDisconnected
+		}
+	}
+
+	if !c.IsConnected() {
+		return fmt.Errorf("%w: reconnect after will refresh failed", ErrClientDisconnected)
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package mq
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+func TestSetWill(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	WithWill("status", []byte("offline"), 1, true)(opts)
+	c := newTestClient(opts)
+
+	c.SetWill("state", []byte(`{"temp":21}`), 2, false)
+	pkt := c.buildConnectPacket()
+	if !pkt.WillFlag || pkt.WillTopic != "state" || string(pkt.WillMessage) != `{"temp":21}` ||
+		pkt.WillQoS != 2 || pkt.WillRetain {
+		t.Errorf("CONNECT does not carry the new will: %+v", pkt)
+	}
+
+	c.SetWill("", nil, 0, false)
+	if pkt := c.buildConnectPacket(); pkt.WillFlag {
+		t.Error("expected SetWill with an empty topic to remove the will")
+	}
+	if c.Config().HasWill {
+		t.Error("expected Config to report no will")
+	}
+}
+
+func TestRefreshWill(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	type session struct {
+		will       string
+		disconnect *packets.DisconnectPacket
+	}
+	sessions := make(chan session, 2)
+	serverErr := make(chan error, 1)
+	stopServer := make(chan struct{})
+	go func() {
+		serverErr <- func() error {
+			var conns []net.Conn
+			defer func() {
+				<-stopServer // Keep the connections open until the test is done
+				for _, conn := range conns {
+					conn.Close()
+				}
+			}()
+			for i := range 2 {
+				conn, err := ln.Accept()
+				if err != nil {
+					return err
+				}
+				conns = append(conns, conn)
+
+				pkt, err := packets.ReadPacket(conn, ProtocolV50, 0)
+				if err != nil {
+					return err
+				}
+				connect, ok := pkt.(*packets.ConnectPacket)
+				if !ok {
+					return fmt.Errorf("conn %d: expected CONNECT, got %T", i, pkt)
+				}
+				var s session
+				if connect.WillFlag {
+					s.will = string(connect.WillMessage)
+				}
+				connack := &packets.ConnackPacket{ReturnCode: 0, SessionPresent: i > 0}
+				if _, err := connack.WriteTo(conn); err != nil {
+					return err
+				}
+				if i == 0 {
+					// The server holds the will until the client says goodbye
+					pkt, err := packets.ReadPacket(conn, ProtocolV50, 0)
+					if err != nil {
+						return fmt.Errorf("conn 0: expected DISCONNECT: %w", err)
+					}
+					s.disconnect, _ = pkt.(*packets.DisconnectPacket)
+				}
+				sessions <- s
+			}
+			return nil
+		}()
+	}()
+
+	var connects, lost atomic.Int32
+	client, err := Dial("tcp://"+ln.Addr().String(),
+		WithClientID("will-refresh"),
+		WithCleanSession(false),
+		WithSessionExpiryInterval(3600),
+		WithWill("devices/will-refresh/state", []byte("state-1"), 1, true),
+		WithOnConnect(func(*Client) { connects.Add(1) }),
+		WithOnConnectionLost(func(*Client, error) { lost.Add(1) }),
+	)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	client.SetWill("devices/will-refresh/state", []byte("state-2"), 1, true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := client.RefreshWill(ctx); err != nil {
+		t.Fatalf("RefreshWill failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("expected RefreshWill to skip the reconnect backoff, took %s", elapsed)
+	}
+	if !client.IsConnected() {
+		t.Error("expected client to be connected after RefreshWill")
+	}
+
+	first, second := <-sessions, <-sessions
+	if first.will != "state-1" {
+		t.Errorf("first CONNECT will = %q, want state-1", first.will)
+	}
+	if first.disconnect == nil || first.disconnect.ReasonCode != uint8(ReasonCodeNormalDisconnect) {
+		t.Errorf("expected a normal DISCONNECT discarding the old will, got %#v", first.disconnect)
+	}
+	if second.will != "state-2" {
+		t.Errorf("second CONNECT will = %q, want state-2", second.will)
+	}
+
+	// Give any (wrongly) dispatched callbacks a chance to run
+	time.Sleep(50 * time.Millisecond)
+	if n := connects.Load(); n != 1 {
+		t.Errorf("OnConnect called %d times, want 1 (refresh is transparent)", n)
+	}
+	if n := lost.Load(); n != 0 {
+		t.Errorf("OnConnectionLost called %d times, want 0", n)
+	}
+
+	close(stopServer)
+	if err := <-serverErr; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRefreshWill_RequiresAutoReconnect(t *testing.T) {
+	opts := defaultOptions("tcp://localhost:1883")
+	opts.AutoReconnect = false
+	c := newTestClient(opts)
+	c.connected.Store(true)
+
+	if err := c.RefreshWill(context.Background()); err == nil {
+		t.Error("expected RefreshWill to fail without auto-reconnect")
+	}
+}
+
+// TestWillDelayRoundTrip verifies that the Will Delay Interval is encoded
+// in CONNECT and decoded by the server.
+func TestWillDelayRoundTrip(t *testing.T) {
+	delay := uint32(30)
+	tests := []struct {
+		name string
+		opts []Option
+		want uint32
+	}{
+		{"will properties", []Option{
+			WithWill("status", []byte("offline"), 1, true, &Properties{WillDelayInterval: &delay}),
+		}, 30},
+		{"WithWillDelay before WithWill", []Option{
+			WithWillDelay(45), WithWill("status", []byte("offline"), 1, true),
+		}, 45},
+		{"WithWillDelay overrides properties", []Option{
+			WithWill("status", []byte("offline"), 1, true, &Properties{WillDelayInterval: &delay}),
+			WithWillDelay(0),
+		}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultOptions("tcp://localhost:1883")
+			for _, opt := range tt.opts {
+				opt(opts)
+			}
+			c := newTestClient(opts)
+
+			var buf bytes.Buffer
+			if _, err := c.buildConnectPacket().WriteTo(&buf); err != nil {
+				t.Fatalf("WriteTo failed: %v", err)
+			}
+			pkt, err := packets.ReadPacket(&buf, ProtocolV50, 0)
+			if err != nil {
+				t.Fatalf("ReadPacket failed: %v", err)
+			}
+			connect := pkt.(*packets.ConnectPacket)
+			props := connect.WillProperties
+			if props == nil || props.Presence&packets.PresWillDelayInterval == 0 {
+				t.Fatalf("decoded CONNECT has no Will Delay Interval: %+v", props)
+			}
+			if props.WillDelayInterval != tt.want {
+				t.Errorf("Will Delay Interval = %d, want %d", props.WillDelayInterval, tt.want)
+			}
+		})
+	}
+
+	// The delay also applies to a will replaced with SetWill
+	opts := defaultOptions("tcp://localhost:1883")
+	WithWillDelay(60)(opts)
+	c := newTestClient(opts)
+	if pkt := c.buildConnectPacket(); pkt.WillFlag {
+		t.Error("WithWillDelay alone must not add a will")
+	}
+	c.SetWill("status", []byte("offline"), 1, true)
+	if pkt := c.buildConnectPacket(); pkt.WillProperties == nil || pkt.WillProperties.WillDelayInterval != 60 {
+		t.Errorf("SetWill will lacks the delay: %+v", pkt.WillProperties)
+	}
+}
@@ -0,0 +1,9 @@
+module github.com/gonzalop/mq/wstransport
+
+go 1.24.6
+
+replace github.com/gonzalop/mq => ../
+
+require github.com/gonzalop/mq v0.0.0-00010101000000-000000000000
+
+require github.com/coder/websocket v1.8.15
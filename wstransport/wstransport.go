@@ -0,0 +1,122 @@
+// Package wstransport connects the mq MQTT client to servers over
+// WebSockets (MQTT over WebSocket, ws:// and wss:// URLs).
+//
+// Dialer returns an mq.ContextDialer for mq.WithDialer. It negotiates the
+// "mqtt" WebSocket subprotocol that servers require, uses binary messages,
+// and adapts the WebSocket to the net.Conn the client expects.
+//
+// The server URL given to mq.Dial is dialed as is, path included: ws:// for
+// plain connections, wss:// for TLS. Set the TLS configuration of wss://
+// with WithTLSConfig, since mq's own TLS options only apply to its built-in
+// dialer. A server that does not accept the "mqtt" subprotocol fails the
+// connection attempt.
+//
+// Example:
+//
+//	client, err := mq.Dial("wss://broker.example.com:8884/mqtt",
+//	    mq.WithClientID("dashboard"),
+//	    mq.WithDialer(wstransport.Dialer(
+//	        wstransport.WithHeader("Authorization", "Bearer "+token),
+//	    )),
+//	)
+package wstransport
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/coder/websocket"
+	"github.com/gonzalop/mq"
+)
+
+// Subprotocol is the WebSocket subprotocol of MQTT.
+const Subprotocol = "mqtt"
+
+// Option configures a Dialer.
+type Option func(*config)
+
+type config struct {
+	tlsConfig  *tls.Config
+	header     http.Header
+	httpClient *http.Client
+}
+
+// WithTLSConfig sets the TLS configuration of wss:// connections (for
+// example a private CA or a client certificate). mq.WithTLS does not
+// apply to custom dialers; use this option instead.
+//
+// Ignored when WithHTTPClient is given.
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(c *config) {
+		c.tlsConfig = tlsConfig
+	}
+}
+
+// WithHeader adds an HTTP header to the WebSocket handshake request, such
+// as Authorization for authenticated endpoints. It can be given several
+// times; values of the same key accumulate.
+func WithHeader(key, value string) Option {
+	return func(c *config) {
+		if c.header == nil {
+			c.header = make(http.Header)
+		}
+		c.header.Add(key, value)
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for the handshake, for proxies
+// or other custom transports. Its Transport must support the WebSocket
+// upgrade, as http.Transport does.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *config) {
+		c.httpClient = client
+	}
+}
+
+// Dialer returns a dialer for mq.WithDialer that connects to the ws:// or
+// wss:// server URL given to mq.Dial, including its path (often "/mqtt").
+//
+// Dialing is bounded by the connect context (mq.WithConnectTimeout);
+// once established, the connection is not tied to that context.
+func Dialer(opts ...Option) mq.ContextDialer {
+	cfg := &config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	dialOpts := &websocket.DialOptions{
+		HTTPClient:   cfg.httpClient,
+		HTTPHeader:   cfg.header,
+		Subprotocols: []string{Subprotocol},
+	}
+	if dialOpts.HTTPClient == nil && cfg.tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = cfg.tlsConfig
+		dialOpts.HTTPClient = &http.Client{Transport: transport}
+	}
+
+	return mq.DialFunc(func(ctx context.Context, _ string, addr string) (net.Conn, error) {
+		u, err := url.Parse(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid server URL: %w", err)
+		}
+		if u.Scheme != "ws" && u.Scheme != "wss" {
+			return nil, fmt.Errorf("unsupported scheme: %s (supported: ws, wss)", u.Scheme)
+		}
+
+		conn, _, err := websocket.Dial(ctx, addr, dialOpts)
+		if err != nil {
+			return nil, err
+		}
+		if conn.Subprotocol() != Subprotocol {
+			conn.Close(websocket.StatusProtocolError, "mqtt subprotocol required")
+			return nil, fmt.Errorf("server did not accept the %q subprotocol", Subprotocol)
+		}
+
+		return websocket.NetConn(context.Background(), conn, websocket.MessageBinary), nil
+	})
+}
@@ -0,0 +1,137 @@
+package wstransport
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/gonzalop/mq"
+	"github.com/gonzalop/mq/internal/packets"
+)
+
+// mqttHandler accepts MQTT over WebSocket, answers CONNECT with a CONNACK
+// and reports the topic of the first PUBLISH.
+func mqttHandler(t *testing.T, subprotocols []string, published chan<- string, headers chan<- http.Header) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if headers != nil {
+			headers <- r.Header.Clone()
+		}
+		ws, err := websocket.Accept(w, r, &websocket.AcceptOptions{Subprotocols: subprotocols})
+		if err != nil {
+			t.Errorf("Accept failed: %v", err)
+			return
+		}
+		conn := websocket.NetConn(context.Background(), ws, websocket.MessageBinary)
+		defer conn.Close()
+
+		if _, err := packets.ReadPacket(conn, mq.ProtocolV50, 0); err != nil {
+			return
+		}
+		if _, err := (&packets.ConnackPacket{ReturnCode: 0}).WriteTo(conn); err != nil {
+			return
+		}
+		for {
+			pkt, err := packets.ReadPacket(conn, mq.ProtocolV50, 0)
+			if err != nil {
+				return
+			}
+			if p, ok := pkt.(*packets.PublishPacket); ok {
+				published <- p.Topic
+			}
+		}
+	})
+}
+
+func wsURL(srv *httptest.Server, path string) string {
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + path
+}
+
+func TestDialer(t *testing.T) {
+	tests := []struct {
+		name  string
+		start func(http.Handler) *httptest.Server
+		opts  func(srv *httptest.Server) []Option
+	}{
+		{
+			name:  "ws",
+			start: httptest.NewServer,
+			opts: func(*httptest.Server) []Option {
+				return []Option{WithHeader("Authorization", "Bearer secret")}
+			},
+		},
+		{
+			name:  "wss",
+			start: httptest.NewTLSServer,
+			opts: func(srv *httptest.Server) []Option {
+				roots := srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+				return []Option{
+					WithHeader("Authorization", "Bearer secret"),
+					WithTLSConfig(&tls.Config{RootCAs: roots}),
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			published := make(chan string, 1)
+			headers := make(chan http.Header, 1)
+			srv := tt.start(mqttHandler(t, []string{Subprotocol}, published, headers))
+			defer srv.Close()
+
+			client, err := mq.Dial(wsURL(srv, "/mqtt"),
+				mq.WithClientID("ws-client"),
+				mq.WithAutoReconnect(false),
+				mq.WithDialer(Dialer(tt.opts(srv)...)),
+			)
+			if err != nil {
+				t.Fatalf("Dial failed: %v", err)
+			}
+			defer client.Disconnect(context.Background())
+
+			if got := (<-headers).Get("Authorization"); got != "Bearer secret" {
+				t.Errorf("Authorization header = %q", got)
+			}
+
+			// The connection outlives the connect context
+			time.Sleep(50 * time.Millisecond)
+			client.Publish("dashboard/ping", []byte("1"))
+			select {
+			case topic := <-published:
+				if topic != "dashboard/ping" {
+					t.Errorf("server received topic %q", topic)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("timeout waiting for PUBLISH")
+			}
+		})
+	}
+}
+
+func TestDialer_Errors(t *testing.T) {
+	srv := httptest.NewServer(mqttHandler(t, nil, make(chan string, 1), nil))
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		addr string
+		want string
+	}{
+		{"no subprotocol", wsURL(srv, "/"), "subprotocol"},
+		{"tcp scheme", "tcp://localhost:1883", "unsupported scheme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Dialer().DialContext(context.Background(), "", tt.addr)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("DialContext(%s) error = %v, want it to mention %q", tt.addr, err, tt.want)
+			}
+		})
+	}
+}